@@ -1,5 +1,7 @@
 package cmd
 
 import (
+	_ "github.com/pymba86/bingo/pkg/exchange/binance"
+	_ "github.com/pymba86/bingo/pkg/exchange/okx"
 	_ "github.com/pymba86/bingo/pkg/strategy/grid"
 )
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/engine"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	ConfigCmd.AddCommand(ConfigValidateCmd)
+	RootCmd.AddCommand(ConfigCmd)
+}
+
+var ConfigCmd = &cobra.Command{
+	Use:          "config",
+	Short:        "config file utilities",
+	SilenceUsage: true,
+
+	// the config file is validated by the sub-commands, so we only load the dotenv file here
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadDotEnv(cmd)
+	},
+}
+
+var ConfigValidateCmd = &cobra.Command{
+	Use:          "validate",
+	Short:        "validate the config file in the strict mode",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configFile, err := cmd.Flags().GetString("config")
+		if err != nil {
+			return err
+		}
+
+		if len(configFile) == 0 {
+			return errors.New("--config option is required")
+		}
+
+		if err := engine.ValidateConfigFile(configFile); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s is valid\n", configFile)
+		return nil
+	},
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/migrations"
+)
+
+// openDatabase connects to the database of the synced history, the pending migrations of the driver are applied
+// when migrate is true. The mysql dsn needs parseTime=true, e.g. user:password@tcp(127.0.0.1:3306)/bingo?parseTime=true
+func openDatabase(ctx context.Context, driver, dsn string, migrate bool) (*sqlx.DB, error) {
+	db, err := sqlx.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrapf(err, "can not connect to the %s database", driver)
+	}
+
+	if migrate {
+		if err := migrations.Up(ctx, db, driver); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
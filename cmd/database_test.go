@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/service"
+	"github.com/pymba86/bingo/pkg/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bingo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	dsn := filepath.Join(dir, "bingo.sqlite3")
+
+	db, err := openDatabase(ctx, "sqlite3", dsn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marginService := service.NewMarginService(db)
+	err = marginService.InsertLoan(types.MarginLoanRecord{
+		TransactionID: 1,
+		Exchange:      types.ExchangeBinance,
+		Asset:         "BTC",
+		Principal:     fixedpoint.NewFromFloat(0.1),
+		Time:          types.Time(time.Date(2021, time.October, 19, 0, 0, 0, 0, time.UTC)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = db.Close()
+
+	// the applied migrations are skipped
+	db, err = openDatabase(ctx, "sqlite3", dsn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	loans, err := service.NewMarginService(db).QueryLoans(types.ExchangeBinance, "BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loans) != 1 || loans[0].Principal.Float64() != 0.1 {
+		t.Fatalf("unexpected loans: %+v", loans)
+	}
+
+	requestService := service.NewWithdrawalRequestService(db)
+	requestTime := time.Date(2021, time.October, 20, 10, 0, 0, 0, time.UTC)
+	err = requestService.Insert(types.WithdrawalRequest{
+		Exchange:        types.ExchangeBinance,
+		WithdrawOrderID: "abc",
+		Asset:           "BTC",
+		Amount:          fixedpoint.NewFromFloat(0.2),
+		Address:         "addr",
+		Status:          types.WithdrawalRequestSent,
+		Time:            types.Time(requestTime),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := requestService.QuerySince(types.ExchangeBinance, "BTC", requestTime.Truncate(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 1 || requests[0].WithdrawOrderID != "abc" || requests[0].Amount.Float64() != 0.2 {
+		t.Fatalf("unexpected withdrawal requests: %+v", requests)
+	}
+
+	for _, table := range []string{"trades", "deposits", "withdraws", "withdrawal_requests", "margin_repays", "margin_interests"} {
+		var n int
+		if err := db.Get(&n, "SELECT COUNT(*) FROM "+table); err != nil {
+			t.Errorf("table %s: %v", table, err)
+		}
+	}
+}
+
+func TestOpenDatabaseUnknownDriver(t *testing.T) {
+	if _, err := openDatabase(context.Background(), "postgres", "dsn", true); err == nil {
+		t.Fatal("expected an error for the unknown driver")
+	}
+}
@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+func init() {
+	MarginCmd.PersistentFlags().String("session", "", "margin session name")
+	MarginCmd.PersistentFlags().String("asset", "", "the asset to borrow, repay or transfer, e.g. USDT")
+
+	for _, cmd := range []*cobra.Command{marginBorrowCmd, marginRepayCmd, marginTransferCmd} {
+		cmd.Flags().String("amount", "", "the amount of the asset")
+	}
+
+	marginTransferCmd.Flags().String("direction", "in", "transfer direction, in: spot to margin, out: margin to spot")
+
+	for _, cmd := range []*cobra.Command{marginLoansCmd, marginRepaysCmd, marginInterestsCmd} {
+		cmd.Flags().Duration("since", 7*24*time.Hour, "query the records since the given duration ago")
+	}
+
+	MarginCmd.AddCommand(marginBorrowCmd, marginRepayCmd, marginMaxBorrowableCmd, marginTransferCmd,
+		marginLoansCmd, marginRepaysCmd, marginInterestsCmd)
+	RootCmd.AddCommand(MarginCmd)
+}
+
+var MarginCmd = &cobra.Command{
+	Use:          "margin",
+	Short:        "manage the loans of the margin session",
+	SilenceUsage: true,
+}
+
+var marginBorrowCmd = &cobra.Command{
+	Use:   "borrow",
+	Short: "borrow the asset in the margin account",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, asset, err := marginBorrowRepayService(cmd)
+		if err != nil {
+			return err
+		}
+
+		amount, err := marginAmount(cmd)
+		if err != nil {
+			return err
+		}
+
+		return service.BorrowMarginAsset(context.Background(), asset, amount)
+	},
+}
+
+var marginRepayCmd = &cobra.Command{
+	Use:   "repay",
+	Short: "repay the borrowed asset in the margin account",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, asset, err := marginBorrowRepayService(cmd)
+		if err != nil {
+			return err
+		}
+
+		amount, err := marginAmount(cmd)
+		if err != nil {
+			return err
+		}
+
+		return service.RepayMarginAsset(context.Background(), asset, amount)
+	},
+}
+
+var marginMaxBorrowableCmd = &cobra.Command{
+	Use:   "max-borrowable",
+	Short: "query the max borrowable amount of the asset",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, asset, err := marginBorrowRepayService(cmd)
+		if err != nil {
+			return err
+		}
+
+		amount, err := service.QueryMarginAssetMaxBorrowable(context.Background(), asset)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("max borrowable %s: %f", asset, amount.Float64())
+		return nil
+	},
+}
+
+var marginTransferCmd = &cobra.Command{
+	Use:   "transfer",
+	Short: "transfer the asset between the spot account and the margin account",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, asset, err := marginBorrowRepayService(cmd)
+		if err != nil {
+			return err
+		}
+
+		amount, err := marginAmount(cmd)
+		if err != nil {
+			return err
+		}
+
+		directionName, err := cmd.Flags().GetString("direction")
+		if err != nil {
+			return err
+		}
+
+		var direction types.TransferDirection
+		switch directionName {
+		case "in":
+			direction = types.TransferIn
+		case "out":
+			direction = types.TransferOut
+		default:
+			return fmt.Errorf("invalid transfer direction: %s, valid directions are: in, out", directionName)
+		}
+
+		return service.TransferMarginAccountAsset(context.Background(), asset, amount, direction)
+	},
+}
+
+var marginLoansCmd = &cobra.Command{
+	Use:   "loans",
+	Short: "query the loan history of the asset",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, asset, err := marginBorrowRepayService(cmd)
+		if err != nil {
+			return err
+		}
+
+		since, err := marginSince(cmd)
+		if err != nil {
+			return err
+		}
+
+		records, err := service.QueryLoanHistory(context.Background(), asset, &since, nil)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			log.Info(record.String())
+		}
+
+		return nil
+	},
+}
+
+var marginRepaysCmd = &cobra.Command{
+	Use:   "repays",
+	Short: "query the repay history of the asset",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, asset, err := marginBorrowRepayService(cmd)
+		if err != nil {
+			return err
+		}
+
+		since, err := marginSince(cmd)
+		if err != nil {
+			return err
+		}
+
+		records, err := service.QueryRepayHistory(context.Background(), asset, &since, nil)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			log.Info(record.String())
+		}
+
+		return nil
+	},
+}
+
+var marginInterestsCmd = &cobra.Command{
+	Use:   "interests",
+	Short: "query the interest history of the asset",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, asset, err := marginBorrowRepayService(cmd)
+		if err != nil {
+			return err
+		}
+
+		since, err := marginSince(cmd)
+		if err != nil {
+			return err
+		}
+
+		records, err := service.QueryInterestHistory(context.Background(), asset, &since, nil)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			log.Info(record.String())
+		}
+
+		return nil
+	},
+}
+
+// marginBorrowRepayService finds the margin session of the --session option and returns its borrow repay service
+func marginBorrowRepayService(cmd *cobra.Command) (types.MarginBorrowRepayService, string, error) {
+	sessionName, err := cmd.Flags().GetString("session")
+	if err != nil {
+		return nil, "", err
+	}
+
+	asset, err := cmd.Flags().GetString("asset")
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(asset) == 0 {
+		return nil, "", errors.New("--asset option is required")
+	}
+
+	session, err := findSession(userConfig, sessionName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !session.Margin {
+		return nil, "", fmt.Errorf("session %s is not a margin session", sessionName)
+	}
+
+	service, ok := session.Exchange.(types.MarginBorrowRepayService)
+	if !ok {
+		return nil, "", fmt.Errorf("exchange %s does not support margin borrow and repay", session.ExchangeName)
+	}
+
+	return service, asset, nil
+}
+
+func marginAmount(cmd *cobra.Command) (fixedpoint.Value, error) {
+	amountString, err := cmd.Flags().GetString("amount")
+	if err != nil {
+		return 0, err
+	}
+
+	if len(amountString) == 0 {
+		return 0, errors.New("--amount option is required")
+	}
+
+	amount, err := fixedpoint.NewFromString(amountString)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid amount %s", amountString)
+	}
+
+	if amount <= 0 {
+		return 0, fmt.Errorf("amount must be positive, got %s", amountString)
+	}
+
+	return amount, nil
+}
+
+func marginSince(cmd *cobra.Command) (time.Time, error) {
+	since, err := cmd.Flags().GetDuration("since")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Now().Add(-since), nil
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/cmdutil"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"syscall"
+)
+
+func init() {
+	MarketDataCmd.Flags().String("session", "", "session name")
+	MarketDataCmd.Flags().StringSlice("symbol", nil, "the symbols to subscribe, e.g. BTCUSDT")
+	MarketDataCmd.Flags().StringSlice("interval", nil, "the kline intervals to subscribe, e.g. 1m,5m")
+	MarketDataCmd.Flags().Bool("book", false, "subscribe the order book")
+	MarketDataCmd.Flags().String("depth", "", "the book levels, e.g. 5, 10, 20, the full book is subscribed if it's not given")
+	MarketDataCmd.Flags().String("speed", "", "the book update speed, e.g. 100ms")
+	MarketDataCmd.Flags().Bool("trade", false, "subscribe the market trades")
+	MarketDataCmd.Flags().Bool("book-ticker", false, "subscribe the best bid and ask")
+	MarketDataCmd.Flags().String("dump", "", "dump the raw websocket messages into the given file")
+	MarketDataCmd.Flags().String("record", "", "record the stream into the given gzip file for replaying")
+	RootCmd.AddCommand(MarketDataCmd)
+}
+
+var MarketDataCmd = &cobra.Command{
+	Use:          "marketdata",
+	Short:        "listen to the session market data stream events (kline closed, book snapshot, market trade, book ticker)",
+	SilenceUsage: true,
+	RunE:         marketData,
+}
+
+func marketData(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sessionName, err := cmd.Flags().GetString("session")
+	if err != nil {
+		return err
+	}
+
+	symbols, err := cmd.Flags().GetStringSlice("symbol")
+	if err != nil {
+		return err
+	}
+
+	if len(symbols) == 0 {
+		return errors.New("--symbol option is required")
+	}
+
+	intervals, err := cmd.Flags().GetStringSlice("interval")
+	if err != nil {
+		return err
+	}
+
+	subscribeBook, err := cmd.Flags().GetBool("book")
+	if err != nil {
+		return err
+	}
+
+	bookDepth, err := cmd.Flags().GetString("depth")
+	if err != nil {
+		return err
+	}
+
+	bookSpeed, err := cmd.Flags().GetString("speed")
+	if err != nil {
+		return err
+	}
+
+	subscribeTrade, err := cmd.Flags().GetBool("trade")
+	if err != nil {
+		return err
+	}
+
+	subscribeBookTicker, err := cmd.Flags().GetBool("book-ticker")
+	if err != nil {
+		return err
+	}
+
+	if len(intervals) == 0 && !subscribeBook && !subscribeTrade && !subscribeBookTicker {
+		return errors.New("nothing to subscribe, please specify --interval, --book, --trade or --book-ticker")
+	}
+
+	dumpFile, err := cmd.Flags().GetString("dump")
+	if err != nil {
+		return err
+	}
+
+	recordFile, err := cmd.Flags().GetString("record")
+	if err != nil {
+		return err
+	}
+
+	session, err := findSession(userConfig, sessionName)
+	if err != nil {
+		return err
+	}
+
+	s := session.Exchange.NewStream()
+	s.SetPublicOnly()
+
+	for _, symbol := range symbols {
+		for _, interval := range intervals {
+			if _, ok := types.SupportedIntervals[types.Interval(interval)]; !ok {
+				return errors.Errorf("unsupported interval: %s", interval)
+			}
+
+			s.Subscribe(types.KLineChannel, symbol, types.SubscribeOptions{Interval: interval})
+		}
+
+		if subscribeBook {
+			s.Subscribe(types.BookChannel, symbol, types.SubscribeOptions{Depth: bookDepth, Speed: bookSpeed})
+		}
+
+		if subscribeTrade {
+			s.Subscribe(types.MarketTradeChannel, symbol, types.SubscribeOptions{})
+		}
+
+		if subscribeBookTicker {
+			s.Subscribe(types.BookTickerChannel, symbol, types.SubscribeOptions{})
+		}
+	}
+
+	s.OnKLineClosed(func(kline types.KLine) {
+		log.Infof("[kLineClosed] %s", kline.String())
+	})
+	s.OnBookSnapshot(func(book types.SliceOrderBook) {
+		log.Infof("[bookSnapshot] %s", book.String())
+	})
+	s.OnMarketTrade(func(trade types.Trade) {
+		log.Infof("[marketTrade] %s", trade.String())
+	})
+	s.OnBookTickerUpdate(func(bookTicker types.BookTicker) {
+		log.Infof("[bookTickerUpdate] %s", bookTicker.String())
+	})
+
+	if len(dumpFile) > 0 {
+		dumper, err := newRawMessageDumper(s, dumpFile)
+		if err != nil {
+			return err
+		}
+
+		defer dumper.Close()
+	}
+
+	if len(recordFile) > 0 {
+		recorder, err := newStreamRecorder(s, recordFile)
+		if err != nil {
+			return err
+		}
+
+		defer recorder.Close()
+	}
+
+	log.Infof("connecting...")
+	if err := s.Connect(ctx); err != nil {
+		return errors.Wrapf(err, "failed to connect to %s", sessionName)
+	}
+
+	log.Infof("connected")
+
+	cmdutil.WaitForSignal(ctx, syscall.SIGINT, syscall.SIGTERM)
+	return s.Close()
+}
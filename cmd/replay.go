@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/exchange/binance"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	ReplayCmd.Flags().String("file", "", "the stream recording file")
+	ReplayCmd.Flags().Float64("speed", 0, "the playback speed, 1 replays at the original pace, 0 replays without delays")
+	RootCmd.AddCommand(ReplayCmd)
+}
+
+var ReplayCmd = &cobra.Command{
+	Use:          "replay",
+	Short:        "replay a binance stream recording and print the stream events",
+	SilenceUsage: true,
+	RunE:         replay,
+}
+
+func replay(cmd *cobra.Command, args []string) error {
+	filename, err := cmd.Flags().GetString("file")
+	if err != nil {
+		return err
+	}
+
+	if len(filename) == 0 {
+		return errors.New("--file option is required")
+	}
+
+	speed, err := cmd.Flags().GetFloat64("speed")
+	if err != nil {
+		return err
+	}
+
+	s := binance.NewReplayStream(filename)
+	s.Speed = speed
+
+	s.OnOrderUpdate(func(order types.Order) {
+		log.Infof("[orderUpdate] %s", order.String())
+	})
+	s.OnTradeUpdate(func(trade types.Trade) {
+		log.Infof("[tradeUpdate] %s", trade.String())
+	})
+	s.OnBalanceUpdate(func(balances types.BalanceMap) {
+		log.Infof("[balanceUpdate] %s", balances.String())
+	})
+	s.OnBalanceSnapshot(func(balances types.BalanceMap) {
+		log.Infof("[balanceSnapshot] %s", balances.String())
+	})
+	s.OnKLineClosed(func(kline types.KLine) {
+		log.Infof("[kLineClosed] %s", kline.String())
+	})
+	s.OnBookSnapshot(func(book types.SliceOrderBook) {
+		log.Infof("[bookSnapshot] %s", book.String())
+	})
+	s.OnBookUpdate(func(book types.SliceOrderBook) {
+		log.Infof("[bookUpdate] %s", book.String())
+	})
+	s.OnMarketTrade(func(trade types.Trade) {
+		log.Infof("[marketTrade] %s", trade.String())
+	})
+	s.OnBookTickerUpdate(func(bookTicker types.BookTicker) {
+		log.Infof("[bookTickerUpdate] %s", bookTicker.String())
+	})
+
+	return s.Replay(context.Background())
+}
@@ -18,24 +18,10 @@ var RootCmd = &cobra.Command{
 	Short:        "bingo is a crypto trading bot",
 	SilenceUsage: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		disableDotEnv, err := cmd.Flags().GetBool("no-dotenv")
-		if err != nil {
+		if err := loadDotEnv(cmd); err != nil {
 			return err
 		}
 
-		if !disableDotEnv {
-			dotenvFile, err := cmd.Flags().GetString("dotenv")
-			if err != nil {
-				return err
-			}
-
-			if _, err := os.Stat(dotenvFile); err == nil {
-				if err := godotenv.Load(dotenvFile); err != nil {
-					return errors.Wrap(err, "error loading dotenv file")
-				}
-			}
-		}
-
 		configFile, err := cmd.Flags().GetString("config")
 		if err != nil {
 			return errors.Wrapf(err, "failed to get the config flag")
@@ -67,6 +53,30 @@ var RootCmd = &cobra.Command{
 	},
 }
 
+func loadDotEnv(cmd *cobra.Command) error {
+	disableDotEnv, err := cmd.Flags().GetBool("no-dotenv")
+	if err != nil {
+		return err
+	}
+
+	if disableDotEnv {
+		return nil
+	}
+
+	dotenvFile, err := cmd.Flags().GetString("dotenv")
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dotenvFile); err == nil {
+		if err := godotenv.Load(dotenvFile); err != nil {
+			return errors.Wrap(err, "error loading dotenv file")
+		}
+	}
+
+	return nil
+}
+
 func Execute() error {
 
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
@@ -97,4 +107,8 @@ func init() {
 
 	RootCmd.PersistentFlags().String("binance-api-key", "", "binance api key")
 	RootCmd.PersistentFlags().String("binance-api-secret", "", "binance api secret")
+
+	RootCmd.PersistentFlags().String("db-driver", "mysql", "the database driver of the synced history, mysql or sqlite3")
+	RootCmd.PersistentFlags().String("db-dsn", "", "the database dsn of the synced history, the history is not synced if it's empty")
+	RootCmd.PersistentFlags().Bool("db-migrate", true, "apply the pending migrations of the database driver on start")
 }
\ No newline at end of file
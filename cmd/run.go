@@ -7,6 +7,7 @@ import (
 	"github.com/pymba86/bingo/pkg/engine"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"os"
 	"syscall"
 	"time"
@@ -16,6 +17,8 @@ func init() {
 	RunCmd.Flags().Bool("setup", false, "use setup mode")
 	RunCmd.Flags().Bool("enable-webserver", false, "enable webserver")
 	RunCmd.Flags().String("webserver-bind", ":8080", "webserver binding")
+	RunCmd.Flags().Bool("strict-config", false, "fail on unknown config keys, strategies and fields")
+	RunCmd.Flags().Bool("watch-config", true, "reload the strategies when the config file is changed")
 	RootCmd.AddCommand(RunCmd)
 }
 
@@ -28,14 +31,27 @@ var RunCmd = &cobra.Command{
 
 func BootstrapEnvironment(ctx context.Context, environ *engine.Environment, userConfig *engine.Config) error {
 
+	// the history is synced into the database if it's configured
+	if dsn := viper.GetString("db-dsn"); len(dsn) > 0 {
+		db, err := openDatabase(ctx, viper.GetString("db-driver"), dsn, viper.GetBool("db-migrate"))
+		if err != nil {
+			return errors.Wrap(err, "database configure error")
+		}
+
+		environ.ConfigureDatabase(db)
+	}
+
 	if err := environ.ConfigureExchangeSessions(userConfig); err != nil {
 		return errors.Wrap(err, "exchange session configure error")
 	}
 	return nil
 }
 
+// configLoader loads the config file with the strategies, it's used for reloading the config
+type configLoader func() (*engine.Config, error)
+
 func runConfig(basectx context.Context, userConfig *engine.Config,
-	enableWebServer bool, webServerBind string) error {
+	enableWebServer bool, webServerBind string, configFile string, reloadConfig configLoader) error {
 
 	ctx, cancelTrading := context.WithCancel(basectx)
 	defer cancelTrading()
@@ -63,6 +79,28 @@ func runConfig(basectx context.Context, userConfig *engine.Config,
 		return err
 	}
 
+	if reloadConfig != nil {
+		watcher := engine.NewConfigWatcher(configFile)
+		watcher.OnChange(func() {
+			newConfig, err := reloadConfig()
+			if err != nil {
+				log.WithError(err).Errorf("config reload error, keep running with the current config")
+				environ.Notify("config reload is rejected: %v", err)
+				return
+			}
+
+			if err := trader.Reload(ctx, newConfig); err != nil {
+				log.WithError(err).Errorf("config reload error, keep running with the current config")
+				environ.Notify("config reload is rejected: %v", err)
+				return
+			}
+
+			log.Infof("config %s is reloaded", configFile)
+		})
+
+		go watcher.Run(ctx)
+	}
+
 	cmdutil.WaitForSignal(ctx, syscall.SIGINT, syscall.SIGTERM)
 
 	log.Infof("shutting down strategies...")
@@ -99,6 +137,16 @@ func run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	strictConfig, err := cmd.Flags().GetBool("strict-config")
+	if err != nil {
+		return err
+	}
+
+	watchConfig, err := cmd.Flags().GetBool("watch-config")
+	if err != nil {
+		return err
+	}
+
 	var userConfig = &engine.Config{}
 
 	if !setup {
@@ -124,10 +172,22 @@ func run(cmd *cobra.Command, args []string) error {
 		return runSetup(ctx, userConfig, true)
 	}
 
-	userConfig, err = engine.Load(configFile, true)
+	var loadConfig configLoader = func() (*engine.Config, error) {
+		if strictConfig {
+			return engine.LoadStrict(configFile, true)
+		}
+
+		return engine.Load(configFile, true)
+	}
+
+	userConfig, err = loadConfig()
 	if err != nil {
 		return err
 	}
 
-	return runConfig(ctx, userConfig, enableWebServer, webServerBind)
+	if !watchConfig {
+		loadConfig = nil
+	}
+
+	return runConfig(ctx, userConfig, enableWebServer, webServerBind, configFile, loadConfig)
 }
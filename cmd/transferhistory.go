@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"sort"
+	"time"
+)
+
+func init() {
+	TransferHistoryCmd.Flags().String("session", "", "session name")
+	TransferHistoryCmd.Flags().String("asset", "", "the asset to query, all assets are queried if it's not given")
+	TransferHistoryCmd.Flags().String("since", "", "query the transfers since the given date, e.g. 2021-01-01, defaults to 90 days ago")
+	RootCmd.AddCommand(TransferHistoryCmd)
+}
+
+var TransferHistoryCmd = &cobra.Command{
+	Use:          "transfer-history",
+	Short:        "show the deposit and withdraw history of the session",
+	SilenceUsage: true,
+	RunE:         transferHistory,
+}
+
+// transferRecord is the merged view of the deposits and the withdraws
+type transferRecord struct {
+	Type   string
+	Time   time.Time
+	Asset  string
+	Amount float64
+	Status string
+	TxnID  string
+}
+
+func (r transferRecord) String() string {
+	return fmt.Sprintf("%s %-8s %-6s %f %s %s", r.Time.Format(time.RFC3339), r.Type, r.Asset, r.Amount, r.Status, r.TxnID)
+}
+
+func transferHistory(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	sessionName, err := cmd.Flags().GetString("session")
+	if err != nil {
+		return err
+	}
+
+	asset, err := cmd.Flags().GetString("asset")
+	if err != nil {
+		return err
+	}
+
+	sinceString, err := cmd.Flags().GetString("since")
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().AddDate(0, 0, -90)
+	if len(sinceString) > 0 {
+		since, err = time.ParseInLocation("2006-01-02", sinceString, time.Local)
+		if err != nil {
+			return err
+		}
+	}
+
+	session, err := findSession(userConfig, sessionName)
+	if err != nil {
+		return err
+	}
+
+	transferService, ok := session.Exchange.(types.ExchangeTransferService)
+	if !ok {
+		return fmt.Errorf("exchange %s does not support the transfer history", session.ExchangeName)
+	}
+
+	until := time.Now()
+
+	deposits, err := transferService.QueryDepositHistory(ctx, asset, since, until)
+	if err != nil {
+		return err
+	}
+
+	withdraws, err := transferService.QueryWithdrawHistory(ctx, asset, since, until)
+	if err != nil {
+		return err
+	}
+
+	var records []transferRecord
+	for _, deposit := range deposits {
+		records = append(records, transferRecord{
+			Type:   "DEPOSIT",
+			Time:   deposit.EffectiveTime(),
+			Asset:  deposit.Asset,
+			Amount: deposit.Amount,
+			Status: deposit.Status,
+			TxnID:  deposit.TransactionID,
+		})
+	}
+
+	for _, withdraw := range withdraws {
+		records = append(records, transferRecord{
+			Type:   "WITHDRAW",
+			Time:   withdraw.EffectiveTime(),
+			Asset:  withdraw.Asset,
+			Amount: withdraw.Amount,
+			Status: withdraw.Status,
+			TxnID:  withdraw.TransactionID,
+		})
+	}
+
+	// group the records by asset and sort them by time
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Asset != records[j].Asset {
+			return records[i].Asset < records[j].Asset
+		}
+
+		return records[i].Time.Before(records[j].Time)
+	})
+
+	var lastAsset string
+	for _, record := range records {
+		if record.Asset != lastAsset {
+			log.Infof("%s transfers:", record.Asset)
+			lastAsset = record.Asset
+		}
+
+		log.Info(record.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/cmdutil"
+	"github.com/pymba86/bingo/pkg/engine"
+	"github.com/pymba86/bingo/pkg/exchange/binance"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func init() {
+	UserDataStreamCmd.Flags().String("session", "", "session name")
+	UserDataStreamCmd.Flags().String("dump", "", "dump the raw websocket messages into the given file")
+	UserDataStreamCmd.Flags().String("record", "", "record the stream into the given gzip file for replaying")
+	RootCmd.AddCommand(UserDataStreamCmd)
+}
+
+var UserDataStreamCmd = &cobra.Command{
+	Use:          "userdatastream",
+	Short:        "listen to the session user data stream events (orderUpdate, tradeUpdate, balanceUpdate, balanceSnapshot)",
+	SilenceUsage: true,
+	RunE:         userDataStream,
+}
+
+func userDataStream(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sessionName, err := cmd.Flags().GetString("session")
+	if err != nil {
+		return err
+	}
+
+	dumpFile, err := cmd.Flags().GetString("dump")
+	if err != nil {
+		return err
+	}
+
+	recordFile, err := cmd.Flags().GetString("record")
+	if err != nil {
+		return err
+	}
+
+	session, err := findSession(userConfig, sessionName)
+	if err != nil {
+		return err
+	}
+
+	s := session.Exchange.NewStream()
+	s.OnOrderUpdate(func(order types.Order) {
+		log.Infof("[orderUpdate] %s", order.String())
+	})
+	s.OnTradeUpdate(func(trade types.Trade) {
+		log.Infof("[tradeUpdate] %s", trade.String())
+	})
+	s.OnBalanceUpdate(func(balances types.BalanceMap) {
+		log.Infof("[balanceUpdate] %s", balances.String())
+	})
+	s.OnBalanceSnapshot(func(balances types.BalanceMap) {
+		log.Infof("[balanceSnapshot] %s", balances.String())
+	})
+
+	if len(dumpFile) > 0 {
+		dumper, err := newRawMessageDumper(s, dumpFile)
+		if err != nil {
+			return err
+		}
+
+		defer dumper.Close()
+	}
+
+	if len(recordFile) > 0 {
+		recorder, err := newStreamRecorder(s, recordFile)
+		if err != nil {
+			return err
+		}
+
+		defer recorder.Close()
+	}
+
+	log.Infof("connecting...")
+	if err := s.Connect(ctx); err != nil {
+		return errors.Wrapf(err, "failed to connect to %s", sessionName)
+	}
+
+	log.Infof("connected")
+
+	cmdutil.WaitForSignal(ctx, syscall.SIGINT, syscall.SIGTERM)
+	return s.Close()
+}
+
+// findSession configures the exchange sessions of the given config and returns the session with the given name
+func findSession(config *engine.Config, sessionName string) (*engine.ExchangeSession, error) {
+	if config == nil {
+		return nil, errors.New("config is not loaded, please check the --config option")
+	}
+
+	environ := engine.NewEnvironment()
+	if err := environ.ConfigureExchangeSessions(config); err != nil {
+		return nil, err
+	}
+
+	session, ok := environ.Session(sessionName)
+	if !ok {
+		return nil, fmt.Errorf("session %s not found", sessionName)
+	}
+
+	return session, nil
+}
+
+// rawMessageHub is implemented by the exchange streams that can expose the raw websocket payloads
+type rawMessageHub interface {
+	OnRawMessage(cb func(message []byte))
+}
+
+type rawMessageDumper struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newRawMessageDumper(stream types.Stream, filename string) (*rawMessageDumper, error) {
+	hub, ok := stream.(rawMessageHub)
+	if !ok {
+		return nil, fmt.Errorf("stream %T does not support raw message dumping", stream)
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	dumper := &rawMessageDumper{file: file}
+	hub.OnRawMessage(dumper.write)
+
+	log.Infof("dumping raw messages to %s", filename)
+	return dumper, nil
+}
+
+func (d *rawMessageDumper) write(message []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := fmt.Fprintf(d.file, "%s\t%s\n", time.Now().Format(time.RFC3339Nano), message); err != nil {
+		log.WithError(err).Errorf("can not write raw message to %s", d.file.Name())
+	}
+}
+
+func (d *rawMessageDumper) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.file.Close()
+}
+
+// newStreamRecorder records the stream into the gzip file, the recording can be replayed by the replay command
+func newStreamRecorder(stream types.Stream, filename string) (*binance.Recorder, error) {
+	binanceStream, ok := stream.(*binance.Stream)
+	if !ok {
+		return nil, fmt.Errorf("stream %T does not support recording", stream)
+	}
+
+	recorder, err := binanceStream.Record(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("recording the stream to %s", filename)
+	return recorder, nil
+}
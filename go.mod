@@ -4,10 +4,13 @@ go 1.17
 
 require (
 	github.com/adshao/go-binance/v2 v2.3.1
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/google/uuid v1.1.2
 	github.com/gorilla/websocket v1.4.2
+	github.com/jmoiron/sqlx v1.2.0
 	github.com/joho/godotenv v1.4.0
 	github.com/leekchan/accounting v0.0.0-20191218023648-17a4ce5f94d4
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/pkg/errors v0.9.1
 	github.com/robfig/cron/v3 v3.0.0
 	github.com/sirupsen/logrus v1.8.1
@@ -15,7 +18,6 @@ require (
 	github.com/spf13/viper v1.8.1
 	github.com/valyala/fastjson v1.5.1
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
-	github.com/jmoiron/sqlx v1.2.0
 )
 
 require (
@@ -35,7 +37,7 @@ require (
 	github.com/subosito/gotenv v1.2.0 // indirect
 	golang.org/x/sys v0.0.0-20210510120138-977fb7262007 // indirect
 	golang.org/x/text v0.3.5 // indirect
+	golang.org/x/time v0.0.0-20201208040808-7e3f01d25324
 	gopkg.in/ini.v1 v1.62.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	golang.org/x/time v0.0.0-20201208040808-7e3f01d25324
 )
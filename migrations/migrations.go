@@ -0,0 +1,154 @@
+// Package migrations contains the database schema of the synced history, the migrations are embedded
+// for each database driver and applied in the version order by Up.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed mysql/*.sql sqlite3/*.sql
+var files embed.FS
+
+// Migration is a schema change, the version is the timestamp prefix of the file name
+type Migration struct {
+	Version    int64
+	Name       string
+	Statements []string
+}
+
+// Load returns the migrations of the driver in the version order
+func Load(driver string) ([]Migration, error) {
+	entries, err := files.ReadDir(driver)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database driver %s", driver)
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		content, err := files.ReadFile(path.Join(driver, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, err := parse(entry.Name(), string(content))
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// parse reads the statements of the "-- +up" section, each statement is wrapped in "-- +begin" and "-- +end"
+func parse(name, content string) (*Migration, error) {
+	prefix := strings.SplitN(name, "_", 2)[0]
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "migration %s: invalid version", name)
+	}
+
+	migration := &Migration{
+		Version: version,
+		Name:    strings.TrimSuffix(name, ".sql"),
+	}
+
+	var up, inStatement bool
+	var statement []string
+	for _, line := range strings.Split(content, "\n") {
+		switch strings.TrimSpace(line) {
+		case "-- +up":
+			up = true
+
+		case "-- +down":
+			up = false
+
+		case "-- +begin":
+			inStatement = true
+			statement = nil
+
+		case "-- +end":
+			if !inStatement {
+				return nil, fmt.Errorf("migration %s: unexpected -- +end", name)
+			}
+
+			inStatement = false
+			if up {
+				migration.Statements = append(migration.Statements, strings.Join(statement, "\n"))
+			}
+
+		default:
+			if inStatement {
+				statement = append(statement, line)
+			}
+		}
+	}
+
+	if inStatement {
+		return nil, fmt.Errorf("migration %s: missing -- +end", name)
+	}
+
+	return migration, nil
+}
+
+// Up applies the migrations of the driver that are not applied yet,
+// the applied versions are recorded in the migration_versions table.
+func Up(ctx context.Context, db *sqlx.DB, driver string) error {
+	migrations, err := Load(driver)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS migration_versions (
+    version    BIGINT       NOT NULL PRIMARY KEY,
+    name       VARCHAR(128) NOT NULL DEFAULT '',
+    applied_at DATETIME     NOT NULL
+)`); err != nil {
+		return errors.Wrap(err, "can not create the migration_versions table")
+	}
+
+	var versions []int64
+	if err := db.SelectContext(ctx, &versions, "SELECT version FROM migration_versions"); err != nil {
+		return err
+	}
+
+	var applied = make(map[int64]struct{}, len(versions))
+	for _, version := range versions {
+		applied[version] = struct{}{}
+	}
+
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		log.Infof("applying migration %s...", migration.Name)
+		for _, statement := range migration.Statements {
+			if _, err := db.ExecContext(ctx, statement); err != nil {
+				return errors.Wrapf(err, "migration %s", migration.Name)
+			}
+		}
+
+		if _, err := db.ExecContext(ctx, db.Rebind("INSERT INTO migration_versions (version, name, applied_at) VALUES (?, ?, ?)"),
+			migration.Version, migration.Name, time.Now()); err != nil {
+			return errors.Wrapf(err, "migration %s", migration.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+-- +up
+-- +begin
+CREATE TABLE `trades`
+(
+    `gid`            BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+
+    `id`             BIGINT UNSIGNED NOT NULL,
+    `order_id`       BIGINT UNSIGNED NOT NULL,
+    `exchange`       VARCHAR(24)     NOT NULL DEFAULT '',
+    `symbol`         VARCHAR(20)     NOT NULL,
+    `price`          DECIMAL(16, 8)  NOT NULL,
+    `quantity`       DECIMAL(16, 8)  NOT NULL,
+    `quote_quantity` DECIMAL(16, 8)  NOT NULL,
+    `fee`            DECIMAL(16, 8)  NOT NULL,
+    `fee_currency`   VARCHAR(10)     NOT NULL,
+    `side`           VARCHAR(4)      NOT NULL DEFAULT '',
+    `is_buyer`       BOOLEAN         NOT NULL DEFAULT FALSE,
+    `is_maker`       BOOLEAN         NOT NULL DEFAULT FALSE,
+    `is_margin`      BOOLEAN         NOT NULL DEFAULT FALSE,
+    `is_isolated`    BOOLEAN         NOT NULL DEFAULT FALSE,
+    `is_futures`     BOOLEAN         NOT NULL DEFAULT FALSE,
+    `strategy`       VARCHAR(64)     NULL,
+    `pnl`            DECIMAL(16, 8)  NULL,
+
+    `traded_at`      DATETIME(3)     NOT NULL,
+
+    PRIMARY KEY (`gid`),
+    UNIQUE KEY `trades_id` (`exchange`, `symbol`, `side`, `id`),
+    KEY `trades_symbol` (`exchange`, `symbol`),
+    KEY `trades_traded_at` (`exchange`, `traded_at`),
+    KEY `trades_strategy` (`strategy`)
+);
+-- +end
+
+-- +down
+
+-- +begin
+DROP TABLE IF EXISTS `trades`;
+-- +end
@@ -0,0 +1,75 @@
+-- +up
+-- +begin
+CREATE TABLE `margin_loans`
+(
+    `gid`             BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+
+    `transaction_id`  BIGINT UNSIGNED NOT NULL,
+    `exchange`        VARCHAR(24)     NOT NULL DEFAULT '',
+    `asset`           VARCHAR(24)     NOT NULL DEFAULT '',
+    `isolated_symbol` VARCHAR(24)     NOT NULL DEFAULT '',
+
+    `principal`       DECIMAL(16, 8)  NOT NULL,
+
+    `time`            DATETIME(3)     NOT NULL,
+
+    PRIMARY KEY (`gid`),
+    UNIQUE KEY `margin_loans_transaction_id` (`exchange`, `transaction_id`),
+    KEY `margin_loans_time` (`exchange`, `asset`, `isolated_symbol`, `time`)
+);
+-- +end
+
+-- +begin
+CREATE TABLE `margin_repays`
+(
+    `gid`             BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+
+    `transaction_id`  BIGINT UNSIGNED NOT NULL,
+    `exchange`        VARCHAR(24)     NOT NULL DEFAULT '',
+    `asset`           VARCHAR(24)     NOT NULL DEFAULT '',
+    `isolated_symbol` VARCHAR(24)     NOT NULL DEFAULT '',
+
+    `principal`       DECIMAL(16, 8)  NOT NULL,
+    `interest`        DECIMAL(16, 8)  NOT NULL,
+
+    `time`            DATETIME(3)     NOT NULL,
+
+    PRIMARY KEY (`gid`),
+    UNIQUE KEY `margin_repays_transaction_id` (`exchange`, `transaction_id`),
+    KEY `margin_repays_time` (`exchange`, `asset`, `isolated_symbol`, `time`)
+);
+-- +end
+
+-- +begin
+CREATE TABLE `margin_interests`
+(
+    `gid`             BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+
+    `exchange`        VARCHAR(24)     NOT NULL DEFAULT '',
+    `asset`           VARCHAR(24)     NOT NULL DEFAULT '',
+    `isolated_symbol` VARCHAR(24)     NOT NULL DEFAULT '',
+
+    `principal`       DECIMAL(16, 8)  NOT NULL,
+    `interest`        DECIMAL(20, 16) NOT NULL,
+    `interest_rate`   DECIMAL(20, 16) NOT NULL,
+
+    `time`            DATETIME(3)     NOT NULL,
+
+    PRIMARY KEY (`gid`),
+    KEY `margin_interests_time` (`exchange`, `asset`, `isolated_symbol`, `time`)
+);
+-- +end
+
+-- +down
+
+-- +begin
+DROP TABLE IF EXISTS `margin_loans`;
+-- +end
+
+-- +begin
+DROP TABLE IF EXISTS `margin_repays`;
+-- +end
+
+-- +begin
+DROP TABLE IF EXISTS `margin_interests`;
+-- +end
@@ -0,0 +1,52 @@
+-- +up
+-- +begin
+CREATE TABLE `deposits`
+(
+    `gid`      BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+
+    `exchange` VARCHAR(24)     NOT NULL DEFAULT '',
+    `asset`    VARCHAR(10)     NOT NULL DEFAULT '',
+    `address`  VARCHAR(128)    NOT NULL DEFAULT '',
+    `network`  VARCHAR(32)     NOT NULL DEFAULT '',
+    `amount`   DECIMAL(16, 8)  NOT NULL,
+    `txn_id`   VARCHAR(256)    NOT NULL DEFAULT '',
+
+    `time`     DATETIME(3)     NOT NULL,
+
+    PRIMARY KEY (`gid`),
+    UNIQUE KEY `deposits_txn_id` (`exchange`, `txn_id`(128)),
+    KEY `deposits_time` (`exchange`, `time`)
+);
+-- +end
+
+-- +begin
+CREATE TABLE `withdraws`
+(
+    `gid`              BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+
+    `exchange`         VARCHAR(24)     NOT NULL DEFAULT '',
+    `asset`            VARCHAR(10)     NOT NULL DEFAULT '',
+    `address`          VARCHAR(128)    NOT NULL DEFAULT '',
+    `network`          VARCHAR(32)     NOT NULL DEFAULT '',
+    `amount`           DECIMAL(16, 8)  NOT NULL,
+    `txn_id`           VARCHAR(256)    NOT NULL DEFAULT '',
+    `txn_fee`          DECIMAL(16, 8)  NOT NULL DEFAULT 0,
+    `txn_fee_currency` VARCHAR(32)     NOT NULL DEFAULT '',
+
+    `time`             DATETIME(3)     NOT NULL,
+
+    PRIMARY KEY (`gid`),
+    UNIQUE KEY `withdraws_txn_id` (`exchange`, `txn_id`(128)),
+    KEY `withdraws_time` (`exchange`, `time`)
+);
+-- +end
+
+-- +down
+
+-- +begin
+DROP TABLE IF EXISTS `deposits`;
+-- +end
+
+-- +begin
+DROP TABLE IF EXISTS `withdraws`;
+-- +end
@@ -0,0 +1,27 @@
+-- +up
+-- +begin
+CREATE TABLE `withdrawal_requests`
+(
+    `gid`               BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+
+    `exchange`          VARCHAR(24)     NOT NULL DEFAULT '',
+    `withdraw_order_id` VARCHAR(64)     NOT NULL DEFAULT '',
+    `asset`             VARCHAR(10)     NOT NULL DEFAULT '',
+    `amount`            DECIMAL(16, 8)  NOT NULL,
+    `address`           VARCHAR(128)    NOT NULL DEFAULT '',
+    `network`           VARCHAR(32)     NOT NULL DEFAULT '',
+    `status`            VARCHAR(16)     NOT NULL DEFAULT '',
+    `reason`            VARCHAR(256)    NOT NULL DEFAULT '',
+
+    `time`              DATETIME(3)     NOT NULL,
+
+    PRIMARY KEY (`gid`),
+    KEY `withdrawal_requests_time` (`exchange`, `asset`, `time`)
+);
+-- +end
+
+-- +down
+
+-- +begin
+DROP TABLE IF EXISTS `withdrawal_requests`;
+-- +end
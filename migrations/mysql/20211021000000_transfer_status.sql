@@ -0,0 +1,48 @@
+-- +up
+-- +begin
+ALTER TABLE `deposits`
+    ADD COLUMN `status` VARCHAR(32) NOT NULL DEFAULT '' AFTER `txn_id`;
+-- +end
+
+-- +begin
+ALTER TABLE `withdraws`
+    ADD COLUMN `withdraw_id` VARCHAR(64) NOT NULL DEFAULT '' AFTER `exchange`,
+    ADD COLUMN `status`      VARCHAR(32) NOT NULL DEFAULT '' AFTER `txn_fee_currency`;
+-- +end
+
+-- +begin
+ALTER TABLE `withdraws` DROP INDEX `withdraws_txn_id`;
+-- +end
+
+-- +begin
+CREATE INDEX `withdraws_txn_id` ON `withdraws` (`exchange`, `txn_id`(128));
+-- +end
+
+-- +begin
+CREATE INDEX `withdraws_withdraw_id` ON `withdraws` (`exchange`, `withdraw_id`);
+-- +end
+
+-- +down
+
+-- +begin
+ALTER TABLE `withdraws` DROP INDEX `withdraws_withdraw_id`;
+-- +end
+
+-- +begin
+ALTER TABLE `withdraws` DROP INDEX `withdraws_txn_id`;
+-- +end
+
+-- +begin
+CREATE UNIQUE INDEX `withdraws_txn_id` ON `withdraws` (`exchange`, `txn_id`(128));
+-- +end
+
+-- +begin
+ALTER TABLE `withdraws`
+    DROP COLUMN `withdraw_id`,
+    DROP COLUMN `status`;
+-- +end
+
+-- +begin
+ALTER TABLE `deposits`
+    DROP COLUMN `status`;
+-- +end
@@ -0,0 +1,49 @@
+-- +up
+-- +begin
+CREATE TABLE `trades`
+(
+    `gid`            INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    `id`             INTEGER         NOT NULL,
+    `order_id`       INTEGER         NOT NULL,
+    `exchange`       VARCHAR(24)     NOT NULL DEFAULT '',
+    `symbol`         VARCHAR(20)     NOT NULL,
+    `price`          DECIMAL(16, 8)  NOT NULL,
+    `quantity`       DECIMAL(16, 8)  NOT NULL,
+    `quote_quantity` DECIMAL(16, 8)  NOT NULL,
+    `fee`            DECIMAL(16, 8)  NOT NULL,
+    `fee_currency`   VARCHAR(10)     NOT NULL,
+    `side`           VARCHAR(4)      NOT NULL DEFAULT '',
+    `is_buyer`       BOOLEAN         NOT NULL DEFAULT FALSE,
+    `is_maker`       BOOLEAN         NOT NULL DEFAULT FALSE,
+    `is_margin`      BOOLEAN         NOT NULL DEFAULT FALSE,
+    `is_isolated`    BOOLEAN         NOT NULL DEFAULT FALSE,
+    `is_futures`     BOOLEAN         NOT NULL DEFAULT FALSE,
+    `strategy`       VARCHAR(64)     NULL,
+    `pnl`            DECIMAL(16, 8)  NULL,
+
+    `traded_at`      DATETIME(3)     NOT NULL
+);
+-- +end
+
+-- +begin
+CREATE UNIQUE INDEX `trades_id` ON `trades` (`exchange`, `symbol`, `side`, `id`);
+-- +end
+
+-- +begin
+CREATE INDEX `trades_symbol` ON `trades` (`exchange`, `symbol`);
+-- +end
+
+-- +begin
+CREATE INDEX `trades_traded_at` ON `trades` (`exchange`, `traded_at`);
+-- +end
+
+-- +begin
+CREATE INDEX `trades_strategy` ON `trades` (`strategy`);
+-- +end
+
+-- +down
+
+-- +begin
+DROP TABLE IF EXISTS `trades`;
+-- +end
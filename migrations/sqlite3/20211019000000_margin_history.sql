@@ -0,0 +1,78 @@
+-- +up
+-- +begin
+CREATE TABLE `margin_loans`
+(
+    `gid`             INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    `transaction_id`  INTEGER         NOT NULL,
+    `exchange`        VARCHAR(24)     NOT NULL DEFAULT '',
+    `asset`           VARCHAR(24)     NOT NULL DEFAULT '',
+    `isolated_symbol` VARCHAR(24)     NOT NULL DEFAULT '',
+    `principal`       DECIMAL(16, 8)  NOT NULL,
+    `time`            DATETIME(3)     NOT NULL
+);
+-- +end
+
+-- +begin
+CREATE UNIQUE INDEX `margin_loans_transaction_id` ON `margin_loans` (`exchange`, `transaction_id`);
+-- +end
+
+-- +begin
+CREATE INDEX `margin_loans_time` ON `margin_loans` (`exchange`, `asset`, `isolated_symbol`, `time`);
+-- +end
+
+-- +begin
+CREATE TABLE `margin_repays`
+(
+    `gid`             INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    `transaction_id`  INTEGER         NOT NULL,
+    `exchange`        VARCHAR(24)     NOT NULL DEFAULT '',
+    `asset`           VARCHAR(24)     NOT NULL DEFAULT '',
+    `isolated_symbol` VARCHAR(24)     NOT NULL DEFAULT '',
+    `principal`       DECIMAL(16, 8)  NOT NULL,
+    `interest`        DECIMAL(16, 8)  NOT NULL,
+    `time`            DATETIME(3)     NOT NULL
+);
+-- +end
+
+-- +begin
+CREATE UNIQUE INDEX `margin_repays_transaction_id` ON `margin_repays` (`exchange`, `transaction_id`);
+-- +end
+
+-- +begin
+CREATE INDEX `margin_repays_time` ON `margin_repays` (`exchange`, `asset`, `isolated_symbol`, `time`);
+-- +end
+
+-- +begin
+CREATE TABLE `margin_interests`
+(
+    `gid`             INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    `exchange`        VARCHAR(24)     NOT NULL DEFAULT '',
+    `asset`           VARCHAR(24)     NOT NULL DEFAULT '',
+    `isolated_symbol` VARCHAR(24)     NOT NULL DEFAULT '',
+    `principal`       DECIMAL(16, 8)  NOT NULL,
+    `interest`        DECIMAL(20, 16) NOT NULL,
+    `interest_rate`   DECIMAL(20, 16) NOT NULL,
+    `time`            DATETIME(3)     NOT NULL
+);
+-- +end
+
+-- +begin
+CREATE INDEX `margin_interests_time` ON `margin_interests` (`exchange`, `asset`, `isolated_symbol`, `time`);
+-- +end
+
+-- +down
+
+-- +begin
+DROP TABLE IF EXISTS `margin_loans`;
+-- +end
+
+-- +begin
+DROP TABLE IF EXISTS `margin_repays`;
+-- +end
+
+-- +begin
+DROP TABLE IF EXISTS `margin_interests`;
+-- +end
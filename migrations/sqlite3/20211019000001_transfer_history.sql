@@ -0,0 +1,58 @@
+-- +up
+-- +begin
+CREATE TABLE `deposits`
+(
+    `gid`      INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    `exchange` VARCHAR(24)     NOT NULL DEFAULT '',
+    `asset`    VARCHAR(10)     NOT NULL DEFAULT '',
+    `address`  VARCHAR(128)    NOT NULL DEFAULT '',
+    `network`  VARCHAR(32)     NOT NULL DEFAULT '',
+    `amount`   DECIMAL(16, 8)  NOT NULL,
+    `txn_id`   VARCHAR(256)    NOT NULL DEFAULT '',
+    `time`     DATETIME(3)     NOT NULL
+);
+-- +end
+
+-- +begin
+CREATE UNIQUE INDEX `deposits_txn_id` ON `deposits` (`exchange`, `txn_id`);
+-- +end
+
+-- +begin
+CREATE INDEX `deposits_time` ON `deposits` (`exchange`, `time`);
+-- +end
+
+-- +begin
+CREATE TABLE `withdraws`
+(
+    `gid`              INTEGER PRIMARY KEY AUTOINCREMENT,
+
+    `exchange`         VARCHAR(24)     NOT NULL DEFAULT '',
+    `asset`            VARCHAR(10)     NOT NULL DEFAULT '',
+    `address`          VARCHAR(128)    NOT NULL DEFAULT '',
+    `network`          VARCHAR(32)     NOT NULL DEFAULT '',
+    `amount`           DECIMAL(16, 8)  NOT NULL,
+    `txn_id`           VARCHAR(256)    NOT NULL DEFAULT '',
+    `txn_fee`          DECIMAL(16, 8)  NOT NULL DEFAULT 0,
+    `txn_fee_currency` VARCHAR(32)     NOT NULL DEFAULT '',
+    `time`             DATETIME(3)     NOT NULL
+);
+-- +end
+
+-- +begin
+CREATE UNIQUE INDEX `withdraws_txn_id` ON `withdraws` (`exchange`, `txn_id`);
+-- +end
+
+-- +begin
+CREATE INDEX `withdraws_time` ON `withdraws` (`exchange`, `time`);
+-- +end
+
+-- +down
+
+-- +begin
+DROP TABLE IF EXISTS `deposits`;
+-- +end
+
+-- +begin
+DROP TABLE IF EXISTS `withdraws`;
+-- +end
@@ -0,0 +1,50 @@
+-- +up
+-- +begin
+ALTER TABLE `deposits` ADD COLUMN `status` VARCHAR(32) NOT NULL DEFAULT '';
+-- +end
+
+-- +begin
+ALTER TABLE `withdraws` ADD COLUMN `withdraw_id` VARCHAR(64) NOT NULL DEFAULT '';
+-- +end
+
+-- +begin
+ALTER TABLE `withdraws` ADD COLUMN `status` VARCHAR(32) NOT NULL DEFAULT '';
+-- +end
+
+-- +begin
+DROP INDEX IF EXISTS `withdraws_txn_id`;
+-- +end
+
+-- +begin
+CREATE INDEX `withdraws_txn_id` ON `withdraws` (`exchange`, `txn_id`);
+-- +end
+
+-- +begin
+CREATE INDEX `withdraws_withdraw_id` ON `withdraws` (`exchange`, `withdraw_id`);
+-- +end
+
+-- +down
+
+-- +begin
+DROP INDEX IF EXISTS `withdraws_withdraw_id`;
+-- +end
+
+-- +begin
+DROP INDEX IF EXISTS `withdraws_txn_id`;
+-- +end
+
+-- +begin
+CREATE UNIQUE INDEX `withdraws_txn_id` ON `withdraws` (`exchange`, `txn_id`);
+-- +end
+
+-- +begin
+ALTER TABLE `withdraws` DROP COLUMN `withdraw_id`;
+-- +end
+
+-- +begin
+ALTER TABLE `withdraws` DROP COLUMN `status`;
+-- +end
+
+-- +begin
+ALTER TABLE `deposits` DROP COLUMN `status`;
+-- +end
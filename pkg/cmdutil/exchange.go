@@ -2,39 +2,53 @@ package cmdutil
 
 import (
 	"fmt"
-	"github.com/pymba86/bingo/pkg/exchange/binance"
 	"github.com/pymba86/bingo/pkg/types"
 	"os"
 	"strings"
 )
 
-func NewExchangeStandard(n types.ExchangeName, key, secret string) (types.Exchange, error) {
-	switch n {
-	case types.ExchangeBinance:
-		return binance.New(key, secret), nil
-
-	default:
-		return nil, fmt.Errorf("unsupported exchange: %v", n)
+// credentialEnvVarSuffixes are the env var suffixes of the credential fields
+var credentialEnvVarSuffixes = map[types.CredentialField]string{
+	types.CredentialKey:        "_API_KEY",
+	types.CredentialSecret:     "_API_SECRET",
+	types.CredentialPassphrase: "_API_PASSPHRASE",
+	types.CredentialSubAccount: "_SUBACCOUNT",
+}
 
+// NewExchangeStandard creates the registered exchange with the api credentials
+func NewExchangeStandard(n types.ExchangeName, credentials types.ExchangeCredentials) (types.Exchange, error) {
+	r, ok := types.LookupExchange(n.String())
+	if !ok {
+		return nil, fmt.Errorf("unsupported exchange: %v, valid names are: %v", n, types.SupportedExchanges)
 	}
+
+	return r.New(credentials)
 }
 
 func NewExchangeWithEnvVarPrefix(n types.ExchangeName, varPrefix string) (types.Exchange, error) {
+	r, ok := types.LookupExchange(n.String())
+	if !ok {
+		return nil, fmt.Errorf("unsupported exchange: %v, valid names are: %v", n, types.SupportedExchanges)
+	}
+
 	if len(varPrefix) == 0 {
-		varPrefix = n.String()
+		varPrefix = r.Name.String()
 	}
 
 	varPrefix = strings.ToUpper(varPrefix)
 
-	key := os.Getenv(varPrefix + "_API_KEY")
-	secret := os.Getenv(varPrefix + "_API_SECRET")
-	if len(key) == 0 || len(secret) == 0 {
-		return nil, fmt.Errorf("can not initialize exchange %s: empty key or secret, env var prefix: %s", n, varPrefix)
+	var credentials types.ExchangeCredentials
+	for _, field := range r.CredentialFields {
+		credentials.Set(field, os.Getenv(varPrefix+credentialEnvVarSuffixes[field]))
+	}
+
+	if err := r.ValidateCredentials(credentials); err != nil {
+		return nil, fmt.Errorf("can not initialize exchange %s: %v, env var prefix: %s", n, err, varPrefix)
 	}
 
-	return NewExchangeStandard(n, key, secret)
+	return r.New(credentials)
 }
 
 func NewExchange(n types.ExchangeName) (types.Exchange, error) {
 	return NewExchangeWithEnvVarPrefix(n, "")
-}
\ No newline at end of file
+}
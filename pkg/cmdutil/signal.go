@@ -21,6 +21,4 @@ func WaitForSignal(ctx context.Context, signals ...os.Signal) os.Signal {
 		return nil
 
 	}
-
-	return nil
 }
\ No newline at end of file
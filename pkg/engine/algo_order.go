@@ -0,0 +1,468 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	algoOrderCancelTimeout = 30 * time.Second
+
+	// algoOrderReconcileInterval is the interval of querying the active child orders,
+	// the updates of the user data stream can be lost while it's reconnecting
+	algoOrderReconcileInterval = time.Minute
+
+	// defaultAlgoOrderMaxSubmitFailures is the number of the consecutive submit failures that stops an algorithmic order
+	defaultAlgoOrderMaxSubmitFailures = 5
+)
+
+// AlgoOrderProgress is the execution progress of an algorithmic order
+type AlgoOrderProgress struct {
+	Symbol           string
+	Side             types.SideType
+	TargetQuantity   float64
+	ExecutedQuantity float64
+
+	// AveragePrice is the volume weighted price of the fills, zero if nothing is filled
+	AveragePrice float64
+}
+
+func (p AlgoOrderProgress) String() string {
+	return fmt.Sprintf("%s %s %f/%f @ %f", p.Symbol, p.Side, p.ExecutedQuantity, p.TargetQuantity, p.AveragePrice)
+}
+
+type algoChildOrder struct {
+	order types.Order
+
+	// the fills are counted by the trades, the order updates can report a larger executed quantity
+	// when the trades are not received yet
+	tradeQuantity float64
+	active        bool
+}
+
+// algoOrderTracker tracks the child orders and the fills of an algorithmic order
+type algoOrderTracker struct {
+	symbol         string
+	side           types.SideType
+	targetQuantity float64
+
+	mu            sync.Mutex
+	children      map[uint64]*algoChildOrder
+	quoteQuantity float64
+	tradeQuantity float64
+
+	// the updates of the unknown orders are buffered while the child orders are being submitted
+	submitting    int
+	pendingOrders map[uint64]types.Order
+	pendingTrades map[uint64][]types.Trade
+
+	// updateC is notified when a child order is updated or filled
+	updateC chan struct{}
+
+	dispatcher *algoOrderDispatcher
+
+	// queryService queries the active child orders for reconciling, nil if the exchange doesn't support it
+	queryService  types.ExchangeOrderQueryService
+	lastReconcile time.Time
+
+	progressCallbacks []func(progress AlgoOrderProgress)
+}
+
+func newAlgoOrderTracker(symbol string, side types.SideType, targetQuantity float64) *algoOrderTracker {
+	return &algoOrderTracker{
+		symbol:         symbol,
+		side:           side,
+		targetQuantity: targetQuantity,
+		children:       make(map[uint64]*algoChildOrder),
+		pendingOrders:  make(map[uint64]types.Order),
+		pendingTrades:  make(map[uint64][]types.Trade),
+		updateC:        make(chan struct{}, 1),
+	}
+}
+
+func (t *algoOrderTracker) OnProgress(cb func(progress AlgoOrderProgress)) {
+	t.progressCallbacks = append(t.progressCallbacks, cb)
+}
+
+func (t *algoOrderTracker) EmitProgress(progress AlgoOrderProgress) {
+	for _, cb := range t.progressCallbacks {
+		cb(progress)
+	}
+}
+
+// algoOrderDispatcher dispatches the updates of an order executor to its running algorithmic orders.
+// The executor callbacks can not be removed, so they are bound once per executor,
+// and the finished algorithmic orders are removed from the dispatcher instead.
+type algoOrderDispatcher struct {
+	mu       sync.Mutex
+	trackers map[*algoOrderTracker]struct{}
+}
+
+var algoOrderDispatchersMu sync.Mutex
+var algoOrderDispatchers = make(map[OrderExecutor]*algoOrderDispatcher)
+
+func getAlgoOrderDispatcher(executor OrderExecutor) *algoOrderDispatcher {
+	algoOrderDispatchersMu.Lock()
+	defer algoOrderDispatchersMu.Unlock()
+
+	if d, ok := algoOrderDispatchers[executor]; ok {
+		return d
+	}
+
+	d := &algoOrderDispatcher{trackers: make(map[*algoOrderTracker]struct{})}
+	executor.OnTradeUpdate(d.handleTradeUpdate)
+	executor.OnOrderUpdate(d.handleOrderUpdate)
+	algoOrderDispatchers[executor] = d
+	return d
+}
+
+func (d *algoOrderDispatcher) add(t *algoOrderTracker) {
+	d.mu.Lock()
+	d.trackers[t] = struct{}{}
+	d.mu.Unlock()
+}
+
+func (d *algoOrderDispatcher) remove(t *algoOrderTracker) {
+	d.mu.Lock()
+	delete(d.trackers, t)
+	d.mu.Unlock()
+}
+
+// Len returns the number of the running algorithmic orders of the executor
+func (d *algoOrderDispatcher) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.trackers)
+}
+
+func (d *algoOrderDispatcher) snapshot() (trackers []*algoOrderTracker) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for t := range d.trackers {
+		trackers = append(trackers, t)
+	}
+
+	return trackers
+}
+
+func (d *algoOrderDispatcher) handleOrderUpdate(order types.Order) {
+	for _, t := range d.snapshot() {
+		t.handleOrderUpdate(order)
+	}
+}
+
+func (d *algoOrderDispatcher) handleTradeUpdate(trade types.Trade) {
+	for _, t := range d.snapshot() {
+		t.handleTradeUpdate(trade)
+	}
+}
+
+// bind receives the updates of the executor until unbind is called
+func (t *algoOrderTracker) bind(executor OrderExecutor) {
+	t.dispatcher = getAlgoOrderDispatcher(executor)
+	t.dispatcher.add(t)
+	t.queryService = orderQueryService(executor)
+}
+
+// orderQueryService returns the order query service of the executor or the exchange of its session
+func orderQueryService(executor OrderExecutor) types.ExchangeOrderQueryService {
+	for {
+		if service, ok := executor.(types.ExchangeOrderQueryService); ok {
+			return service
+		}
+
+		switch e := executor.(type) {
+		case *StrategyOrderExecutor:
+			executor = e.OrderExecutor
+
+		case *ExchangeOrderExecutor:
+			if e.Session != nil {
+				if service, ok := e.Session.Exchange.(types.ExchangeOrderQueryService); ok {
+					return service
+				}
+			}
+
+			return nil
+
+		default:
+			return nil
+		}
+	}
+}
+
+// unbind stops receiving the updates, it's called when the algorithmic order is finished
+func (t *algoOrderTracker) unbind() {
+	if t.dispatcher != nil {
+		t.dispatcher.remove(t)
+	}
+}
+
+func (t *algoOrderTracker) notifyUpdate() {
+	select {
+	case t.updateC <- struct{}{}:
+	default:
+	}
+}
+
+// submit submits the child orders, the updates of the orders received before the submission returns are buffered and applied
+func (t *algoOrderTracker) submit(ctx context.Context, executor OrderExecutor, orders ...types.SubmitOrder) (types.OrderSlice, error) {
+	t.mu.Lock()
+	t.submitting++
+	t.mu.Unlock()
+
+	createdOrders, err := executor.SubmitOrders(ctx, orders...)
+
+	t.mu.Lock()
+	t.submitting--
+
+	var filled bool
+	for _, o := range createdOrders {
+		child := &algoChildOrder{
+			order:  o,
+			active: o.Status == types.OrderStatusNew || o.Status == types.OrderStatusPartiallyFilled,
+		}
+		t.children[o.OrderID] = child
+
+		if update, ok := t.pendingOrders[o.OrderID]; ok {
+			t.applyOrderUpdate(child, update)
+		}
+
+		for _, trade := range t.pendingTrades[o.OrderID] {
+			t.applyTrade(child, trade)
+			filled = true
+		}
+	}
+
+	if t.submitting == 0 {
+		t.pendingOrders = make(map[uint64]types.Order)
+		t.pendingTrades = make(map[uint64][]types.Trade)
+	}
+
+	progress := t.progress()
+	t.mu.Unlock()
+
+	if filled {
+		t.EmitProgress(progress)
+	}
+
+	return createdOrders, err
+}
+
+func (t *algoOrderTracker) applyOrderUpdate(child *algoChildOrder, order types.Order) {
+	child.order.Status = order.Status
+	if order.ExecutedQuantity > child.order.ExecutedQuantity {
+		child.order.ExecutedQuantity = order.ExecutedQuantity
+	}
+
+	switch order.Status {
+	case types.OrderStatusFilled, types.OrderStatusCanceled, types.OrderStatusRejected:
+		child.active = false
+	}
+}
+
+func (t *algoOrderTracker) applyTrade(child *algoChildOrder, trade types.Trade) {
+	child.tradeQuantity += trade.Quantity
+	t.tradeQuantity += trade.Quantity
+	t.quoteQuantity += trade.Quantity * trade.Price
+}
+
+func (t *algoOrderTracker) handleOrderUpdate(order types.Order) {
+	if order.Symbol != t.symbol {
+		return
+	}
+
+	t.mu.Lock()
+	child, ok := t.children[order.OrderID]
+	if ok {
+		t.applyOrderUpdate(child, order)
+	} else if t.submitting > 0 {
+		t.pendingOrders[order.OrderID] = order
+	}
+	t.mu.Unlock()
+
+	if ok {
+		t.notifyUpdate()
+	}
+}
+
+func (t *algoOrderTracker) handleTradeUpdate(trade types.Trade) {
+	if trade.Symbol != t.symbol {
+		return
+	}
+
+	t.mu.Lock()
+	child, ok := t.children[trade.OrderID]
+	if !ok {
+		if t.submitting > 0 {
+			t.pendingTrades[trade.OrderID] = append(t.pendingTrades[trade.OrderID], trade)
+		}
+
+		t.mu.Unlock()
+		return
+	}
+
+	t.applyTrade(child, trade)
+	progress := t.progress()
+	t.mu.Unlock()
+
+	t.EmitProgress(progress)
+	t.notifyUpdate()
+}
+
+func (t *algoOrderTracker) progress() AlgoOrderProgress {
+	progress := AlgoOrderProgress{
+		Symbol:           t.symbol,
+		Side:             t.side,
+		TargetQuantity:   t.targetQuantity,
+		ExecutedQuantity: t.executedQuantity(),
+	}
+
+	if t.tradeQuantity > 0 {
+		progress.AveragePrice = t.quoteQuantity / t.tradeQuantity
+	}
+
+	return progress
+}
+
+// executedQuantity sums the executed quantity of the child orders, the larger one of the order update and the trades is used
+func (t *algoOrderTracker) executedQuantity() (quantity float64) {
+	for _, child := range t.children {
+		if child.order.ExecutedQuantity > child.tradeQuantity {
+			quantity += child.order.ExecutedQuantity
+		} else {
+			quantity += child.tradeQuantity
+		}
+	}
+
+	return quantity
+}
+
+func (t *algoOrderTracker) Progress() AlgoOrderProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.progress()
+}
+
+// activeOrders returns the child orders that are still on the book
+func (t *algoOrderTracker) activeOrders() (orders []types.Order) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, child := range t.children {
+		if child.active {
+			orders = append(orders, child.order)
+		}
+	}
+
+	return orders
+}
+
+// reconcileActiveOrders queries the active child orders at the reconcile interval and applies their states,
+// so that a child order that is closed while its update is lost doesn't block the algorithmic order
+func (t *algoOrderTracker) reconcileActiveOrders(ctx context.Context) {
+	if t.queryService == nil || time.Since(t.lastReconcile) < algoOrderReconcileInterval {
+		return
+	}
+
+	t.lastReconcile = time.Now()
+
+	var updated bool
+	for _, o := range t.activeOrders() {
+		order, err := t.queryService.QueryOrder(ctx, types.OrderQuery{Symbol: o.Symbol, OrderID: o.OrderID})
+		if err != nil {
+			log.WithError(err).Warnf("can not query the %s child order %d", t.symbol, o.OrderID)
+			continue
+		}
+
+		t.mu.Lock()
+		child, ok := t.children[o.OrderID]
+		changed := ok && child.active &&
+			(order.Status != child.order.Status || order.ExecutedQuantity > child.order.ExecutedQuantity)
+		if changed {
+			log.Infof("reconciled the %s child order %d: %s", t.symbol, o.OrderID, order.Status)
+			t.applyOrderUpdate(child, *order)
+		}
+		progress := t.progress()
+		t.mu.Unlock()
+
+		if changed {
+			updated = true
+			t.EmitProgress(progress)
+		}
+	}
+
+	if updated {
+		t.notifyUpdate()
+	}
+}
+
+// cancelActiveOrders cancels the active child orders and waits for their final updates, so that the executed
+// quantity is final. It doesn't use the given context so that the child orders are canceled even when
+// the algorithmic order is canceled by the context.
+func (t *algoOrderTracker) cancelActiveOrders(executor OrderExecutor) error {
+	orders := t.activeOrders()
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), algoOrderCancelTimeout)
+	defer cancel()
+
+	// the order could be filled before it's canceled, the final update tells
+	if err := executor.CancelOrders(ctx, orders...); err != nil {
+		log.WithError(err).Warnf("can not cancel the %s child orders", t.symbol)
+	}
+
+	return t.waitForClosedOrders(ctx, orders)
+}
+
+// waitForClosedOrders waits until the given child orders are filled, canceled or rejected
+func (t *algoOrderTracker) waitForClosedOrders(ctx context.Context, orders []types.Order) error {
+	for {
+		if t.isClosed(orders) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the %s child orders are not closed: %w", t.symbol, ctx.Err())
+
+		case <-t.updateC:
+		}
+	}
+}
+
+func (t *algoOrderTracker) isClosed(orders []types.Order) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, o := range orders {
+		if child, ok := t.children[o.OrderID]; ok && child.active {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isDustQuantity checks if the quantity can not be placed as an order of the market
+func isDustQuantity(market types.Market, quantity, price float64) bool {
+	if quantity <= 0 {
+		return true
+	}
+
+	if market.MinQuantity > 0 && quantity < market.MinQuantity {
+		return true
+	}
+
+	if market.MinNotional > 0 && price > 0 && quantity*price < market.MinNotional {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+	"time"
+)
+
+// algoTestExecutor creates the orders locally, the canceled orders are partially filled before the cancel
+type algoTestExecutor struct {
+	ExchangeOrderExecutor
+
+	lastOrderID uint64
+	submitted   []types.SubmitOrder
+}
+
+func (e *algoTestExecutor) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (createdOrders types.OrderSlice, err error) {
+	for _, o := range orders {
+		e.lastOrderID++
+		e.submitted = append(e.submitted, o)
+		createdOrders = append(createdOrders, types.Order{
+			SubmitOrder: o,
+			OrderID:     e.lastOrderID,
+			Status:      types.OrderStatusNew,
+		})
+	}
+
+	return createdOrders, nil
+}
+
+func (e *algoTestExecutor) CancelOrders(ctx context.Context, orders ...types.Order) error {
+	for _, o := range orders {
+		o := o
+		o.Status = types.OrderStatusCanceled
+		o.ExecutedQuantity = 0.3
+
+		// the final update arrives after the cancel request returns
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			e.EmitOrderUpdate(o)
+		}()
+	}
+
+	return nil
+}
+
+func TestAlgoOrderTracker_CancelWaitsForTheFinalUpdate(t *testing.T) {
+	executor := &algoTestExecutor{}
+
+	tracker := newAlgoOrderTracker("BTCUSDT", types.SideTypeBuy, 1.0)
+	tracker.bind(executor)
+	defer tracker.unbind()
+
+	if _, err := tracker.submit(context.Background(), executor, types.SubmitOrder{
+		Symbol:   "BTCUSDT",
+		Side:     types.SideTypeBuy,
+		Type:     types.OrderTypeLimit,
+		Quantity: 1.0,
+		Price:    100.0,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tracker.cancelActiveOrders(executor); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracker.activeOrders()) != 0 {
+		t.Fatal("expected no active orders")
+	}
+
+	if executed := tracker.Progress().ExecutedQuantity; executed != 0.3 {
+		t.Fatalf("expected the executed quantity of the final update, got %f", executed)
+	}
+}
+
+func TestAlgoOrderTracker_Unbind(t *testing.T) {
+	executor := &algoTestExecutor{}
+
+	first := newAlgoOrderTracker("BTCUSDT", types.SideTypeBuy, 1.0)
+	first.bind(executor)
+
+	second := newAlgoOrderTracker("BTCUSDT", types.SideTypeSell, 1.0)
+	second.bind(executor)
+
+	dispatcher := getAlgoOrderDispatcher(executor)
+	if dispatcher.Len() != 2 || len(executor.orderUpdateCallbacks) != 1 || len(executor.tradeUpdateCallbacks) != 1 {
+		t.Fatalf("expected the executor callbacks to be bound once")
+	}
+
+	first.unbind()
+	second.unbind()
+
+	if dispatcher.Len() != 0 {
+		t.Fatalf("expected the finished trackers to be removed")
+	}
+}
+
+// algoQueryService returns the orders as filled
+type algoQueryService struct {
+	queries int
+}
+
+func (s *algoQueryService) QueryOrder(ctx context.Context, q types.OrderQuery) (*types.Order, error) {
+	s.queries++
+	return &types.Order{
+		SubmitOrder:      types.SubmitOrder{Symbol: q.Symbol, Quantity: 1.0},
+		OrderID:          q.OrderID,
+		Status:           types.OrderStatusFilled,
+		ExecutedQuantity: 1.0,
+	}, nil
+}
+
+func (s *algoQueryService) QueryOrderTrades(ctx context.Context, q types.OrderQuery) ([]types.Trade, error) {
+	return nil, nil
+}
+
+func TestAlgoOrderTracker_ReconcileActiveOrders(t *testing.T) {
+	executor := &algoTestExecutor{}
+	queryService := &algoQueryService{}
+
+	tracker := newAlgoOrderTracker("BTCUSDT", types.SideTypeBuy, 1.0)
+	tracker.bind(executor)
+	defer tracker.unbind()
+	tracker.queryService = queryService
+
+	if _, err := tracker.submit(context.Background(), executor, types.SubmitOrder{
+		Symbol:   "BTCUSDT",
+		Side:     types.SideTypeBuy,
+		Type:     types.OrderTypeLimit,
+		Quantity: 1.0,
+		Price:    100.0,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fill update of the child order is lost
+	tracker.reconcileActiveOrders(context.Background())
+
+	if len(tracker.activeOrders()) != 0 {
+		t.Fatal("expected the filled child order to be reconciled")
+	}
+
+	if executed := tracker.Progress().ExecutedQuantity; executed != 1.0 {
+		t.Fatalf("expected the executed quantity of the queried order, got %f", executed)
+	}
+
+	// the orders are queried at the reconcile interval only
+	tracker.reconcileActiveOrders(context.Background())
+	if queryService.queries != 1 {
+		t.Fatalf("expected 1 query, got %d", queryService.queries)
+	}
+}
+
+// algoFailingExecutor rejects all orders
+type algoFailingExecutor struct {
+	ExchangeOrderExecutor
+
+	submits int
+}
+
+func (e *algoFailingExecutor) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (createdOrders types.OrderSlice, err error) {
+	e.submits++
+	return nil, errors.New("insufficient balance")
+}
+
+func TestIcebergExecutor_StopsAfterSubmitFailures(t *testing.T) {
+	executor := &algoFailingExecutor{}
+	market := types.Market{Symbol: "BTCUSDT", BaseCurrency: "BTC", QuoteCurrency: "USDT"}
+
+	iceberg := NewIcebergExecutor(executor, market, types.SideTypeBuy, 100.0, 1.0, 0.1)
+	iceberg.MaxSubmitFailures = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := iceberg.Run(ctx); err == nil || ctx.Err() != nil {
+		t.Fatalf("expected the iceberg to stop by the submit failure, got %v", err)
+	}
+
+	if executor.submits != 1 {
+		t.Fatalf("expected 1 submit, got %d", executor.submits)
+	}
+}
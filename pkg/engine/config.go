@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
-	"io/ioutil"
 	"reflect"
 )
 
@@ -57,9 +56,30 @@ func loadStash(config []byte) (Stash, error) {
 }
 
 func Load(configFile string, loadStrategies bool) (*Config, error) {
+	stash, err := loadStashFile(configFile, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadFromStash(stash, loadStrategies)
+}
+
+// LoadStrict loads the config file like Load, but fails on unknown keys, unknown strategy IDs,
+// unknown strategy fields, undefined session mounts, invalid exchange names and strategy validation errors.
+func LoadStrict(configFile string, loadStrategies bool) (*Config, error) {
+	if err := ValidateConfigFile(configFile); err != nil {
+		return nil, err
+	}
+
+	return Load(configFile, loadStrategies)
+}
+
+func loadFromStash(stash Stash, loadStrategies bool) (*Config, error) {
 	var config Config
 
-	content, err := ioutil.ReadFile(configFile)
+	// the stash contains the merged content of the included files,
+	// encode it again so that the config struct can be decoded from it.
+	content, err := reloadStash(stash)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +88,7 @@ func Load(configFile string, loadStrategies bool) (*Config, error) {
 		return nil, err
 	}
 
-	stash, err := loadStash(content)
+	stash, err = loadStash(content)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +130,7 @@ func loadCrossExchangeStrategies(config *Config, stash Stash) (err error) {
 		for id, conf := range configStash {
 			// look up the real struct type
 			if st, ok := LoadedCrossExchangeStrategies[id]; ok {
-				val, err := reUnmarshal(conf, st)
+				val, err := reUnmarshal(applyStrategyDefaults(stash, id, conf), st)
 				if err != nil {
 					return err
 				}
@@ -177,7 +197,7 @@ func loadExchangeStrategies(config *Config, stash Stash) (err error) {
 
 			// look up the real struct type
 			if _, ok := LoadedExchangeStrategies[id]; ok {
-				st, err := NewStrategyFromMap(id, conf)
+				st, err := NewStrategyFromMap(id, applyStrategyDefaults(stash, id, conf))
 				if err != nil {
 					return err
 				}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envVarPattern matches $$, ${VAR} and ${VAR:-default}
+var envVarPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnvVars replaces ${VAR} with the value of the environment variable VAR,
+// and ${VAR:-default} with the default value when VAR is unset or empty, $$ is an escaped $.
+// An unset variable without a default is an error.
+func expandEnvVars(s string) (string, error) {
+	var missing []string
+	expanded := envVarPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+
+		groups := envVarPattern.FindStringSubmatch(match)
+		name := groups[1]
+
+		if val, ok := os.LookupEnv(name); ok && len(val) > 0 {
+			return val
+		}
+
+		// groups[2] is the ":-default" part
+		if len(groups[2]) > 0 {
+			return groups[3]
+		}
+
+		missing = append(missing, name)
+		return ""
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set and has no default", strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
+
+// expandEnvVarNodes expands the env vars in the decoded scalar values of the document,
+// so that a value can never change the structure of the document.
+func expandEnvVarNodes(file string, node *yaml.Node) (errs ConfigErrors) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "$") {
+			return nil
+		}
+
+		expanded, err := expandEnvVars(node.Value)
+		if err != nil {
+			return ConfigErrors{{File: file, Line: node.Line, Message: err.Error()}}
+		}
+
+		if expanded != node.Value {
+			node.Value = expanded
+
+			// the tag of a plain scalar is resolved from its value, e.g. ${PORT} is expanded into an int
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+
+	case yaml.AliasNode:
+		// the anchored node is expanded where it's defined
+
+	default:
+		for _, child := range node.Content {
+			errs = append(errs, expandEnvVarNodes(file, child)...)
+		}
+	}
+
+	return errs
+}
+
+// parseConfigNode parses the config content and expands the env vars in its values
+func parseConfigNode(file string, content []byte) (*yaml.Node, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+
+	if errs := expandEnvVarNodes(file, &root); len(errs) > 0 {
+		return nil, errs
+	}
+
+	return &root, nil
+}
+
+// loadStashFromContent parses the config content into a stash, the env vars in the values are expanded
+func loadStashFromContent(file string, content []byte) (Stash, error) {
+	root, err := parseConfigNode(file, content)
+	if err != nil {
+		return nil, err
+	}
+
+	stash := make(Stash)
+	if len(root.Content) == 0 {
+		return stash, nil
+	}
+
+	if err := root.Content[0].Decode(&stash); err != nil {
+		return nil, err
+	}
+
+	return stash, nil
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadStashFromContentEnvVars(t *testing.T) {
+	secret := "a#b: *c&d!e'f\ng"
+	os.Setenv("BINGO_TEST_SECRET", secret)
+	os.Setenv("BINGO_TEST_PORT", "8080")
+	defer os.Unsetenv("BINGO_TEST_SECRET")
+	defer os.Unsetenv("BINGO_TEST_PORT")
+
+	content := []byte(`
+secret: ${BINGO_TEST_SECRET}
+quoted: "${BINGO_TEST_SECRET}"
+port: ${BINGO_TEST_PORT}
+quotedPort: "${BINGO_TEST_PORT}"
+fallback: ${BINGO_TEST_UNSET:-default}
+escaped: $${BINGO_TEST_PORT}
+`)
+
+	stash, err := loadStashFromContent("test.yaml", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stash["secret"] != secret || stash["quoted"] != secret {
+		t.Errorf("secret is changed: %q %q", stash["secret"], stash["quoted"])
+	}
+
+	if stash["port"] != 8080 || stash["quotedPort"] != "8080" {
+		t.Errorf("unexpected port: %#v %#v", stash["port"], stash["quotedPort"])
+	}
+
+	if stash["fallback"] != "default" {
+		t.Errorf("unexpected fallback: %#v", stash["fallback"])
+	}
+
+	if stash["escaped"] != "${BINGO_TEST_PORT}" {
+		t.Errorf("unexpected escaped value: %#v", stash["escaped"])
+	}
+}
+
+func TestLoadStashFromContentUnsetEnvVar(t *testing.T) {
+	_, err := loadStashFromContent("test.yaml", []byte("a: 1\nkey: ${BINGO_TEST_UNSET}\n"))
+	if err == nil {
+		t.Fatal("expected an error for the unset variable")
+	}
+
+	if !strings.Contains(err.Error(), "test.yaml:2:") || !strings.Contains(err.Error(), "BINGO_TEST_UNSET") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateConfigFileUnsetEnvVar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config.yaml")
+	content := []byte("sessions:\n  binance:\n    exchange: binance\n    key: ${BINGO_TEST_UNSET}\n")
+	if err := ioutil.WriteFile(configFile, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = ValidateConfigFile(configFile)
+	errs, ok := err.(ConfigErrors)
+	if !ok || len(errs) != 1 || errs[0].Line != 4 {
+		t.Fatalf("expected the unset variable error at line 4, got %v", err)
+	}
+}
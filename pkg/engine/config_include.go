@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// loadStashFile loads the config file into a stash, the files listed in the "include" key are loaded
+// and merged in order, then the content of the config file itself is merged on top of them.
+func loadStashFile(configFile string, visited map[string]struct{}) (Stash, error) {
+	absPath, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if visited == nil {
+		visited = make(map[string]struct{})
+	}
+
+	if _, ok := visited[absPath]; ok {
+		return nil, fmt.Errorf("config file %s is included recursively", configFile)
+	}
+
+	visited[absPath] = struct{}{}
+	defer delete(visited, absPath)
+
+	content, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	stash, err := loadStashFromContent(configFile, content)
+	if err != nil {
+		if _, ok := err.(ConfigErrors); ok {
+			return nil, err
+		}
+
+		return nil, errors.Wrapf(err, "config file %s", configFile)
+	}
+
+	includes, err := stashIncludes(stash)
+	if err != nil {
+		return nil, errors.Wrapf(err, "config file %s", configFile)
+	}
+
+	delete(stash, "include")
+
+	if len(includes) == 0 {
+		return stash, nil
+	}
+
+	merged := make(Stash)
+	for _, include := range includes {
+		includedStash, err := loadStashFile(resolveIncludePath(configFile, include), visited)
+		if err != nil {
+			return nil, err
+		}
+
+		mergeStash(merged, includedStash, true)
+	}
+
+	mergeStash(merged, stash, true)
+	return merged, nil
+}
+
+// ConfigFiles returns the config file and all the files it includes
+func ConfigFiles(configFile string) ([]string, error) {
+	var files []string
+	err := collectConfigFiles(configFile, &files, make(map[string]struct{}))
+	return files, err
+}
+
+func collectConfigFiles(configFile string, files *[]string, visited map[string]struct{}) error {
+	absPath, err := filepath.Abs(configFile)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := visited[absPath]; ok {
+		return nil
+	}
+
+	visited[absPath] = struct{}{}
+	*files = append(*files, configFile)
+
+	content, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+
+	stash, err := loadStashFromContent(configFile, content)
+	if err != nil {
+		return err
+	}
+
+	includes, err := stashIncludes(stash)
+	if err != nil {
+		return err
+	}
+
+	for _, include := range includes {
+		if err := collectConfigFiles(resolveIncludePath(configFile, include), files, visited); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveIncludePath resolves the include path relative to the directory of the including file
+func resolveIncludePath(configFile, include string) string {
+	if filepath.IsAbs(include) {
+		return include
+	}
+
+	return filepath.Join(filepath.Dir(configFile), include)
+}
+
+func stashIncludes(stash Stash) ([]string, error) {
+	val, ok := stash["include"]
+	if !ok {
+		return nil, nil
+	}
+
+	switch tv := val.(type) {
+
+	case string:
+		return []string{tv}, nil
+
+	case []interface{}:
+		var includes []string
+		for _, f := range tv {
+			s, ok := f.(string)
+			if !ok {
+				return nil, fmt.Errorf("include %+v (%T) is not a string", f, f)
+			}
+
+			includes = append(includes, s)
+		}
+
+		return includes, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected include type: %T value: %+v", val, val)
+	}
+}
+
+// mergeStash deep-merges src into dst. Maps are merged recursively, other values in src override the ones in dst.
+// When appendSlices is true, lists are concatenated instead of being overridden.
+func mergeStash(dst, src map[string]interface{}, appendSlices bool) {
+	for key, srcVal := range src {
+		dstVal, ok := dst[key]
+		if !ok {
+			dst[key] = copyStashValue(srcVal)
+			continue
+		}
+
+		if dstMap, ok := toStringMap(dstVal); ok {
+			if srcMap, ok := toStringMap(srcVal); ok {
+				mergeStash(dstMap, srcMap, appendSlices)
+				continue
+			}
+		}
+
+		if appendSlices {
+			if dstSlice, ok := dstVal.([]interface{}); ok {
+				if srcSlice, ok := srcVal.([]interface{}); ok {
+					dst[key] = append(dstSlice, copyStashValue(srcSlice).([]interface{})...)
+					continue
+				}
+			}
+		}
+
+		dst[key] = copyStashValue(srcVal)
+	}
+}
+
+// copyStashValue deep copies the maps and lists so that merging never modifies the source stash
+func copyStashValue(val interface{}) interface{} {
+	if m, ok := toStringMap(val); ok {
+		c := make(Stash, len(m))
+		for k, v := range m {
+			c[k] = copyStashValue(v)
+		}
+		return c
+	}
+
+	if s, ok := val.([]interface{}); ok {
+		c := make([]interface{}, len(s))
+		for i, v := range s {
+			c[i] = copyStashValue(v)
+		}
+		return c
+	}
+
+	return val
+}
+
+func toStringMap(val interface{}) (map[string]interface{}, bool) {
+	switch tv := val.(type) {
+	case Stash:
+		return tv, true
+
+	case map[string]interface{}:
+		return tv, true
+	}
+
+	return nil, false
+}
+
+// applyStrategyDefaults merges the config of the strategy on top of the strategyDefaults entry of the strategy ID
+func applyStrategyDefaults(stash Stash, id string, conf interface{}) interface{} {
+	defaults, ok := toStringMap(stash["strategyDefaults"])
+	if !ok {
+		return conf
+	}
+
+	strategyDefaults, ok := toStringMap(defaults[id])
+	if !ok {
+		return conf
+	}
+
+	confMap, ok := toStringMap(conf)
+	if !ok {
+		// a strategy without any parameter, e.g. "grid: ~"
+		if conf != nil {
+			return conf
+		}
+
+		confMap = Stash{}
+	}
+
+	merged := copyStashValue(strategyDefaults).(Stash)
+	mergeStash(merged, confMap, false)
+	return merged
+}
+
+// reloadStash re-encodes the merged stash, so that the config struct and the stash can be decoded from the merged content
+func reloadStash(stash Stash) ([]byte, error) {
+	return yaml.Marshal(stash)
+}
@@ -0,0 +1,523 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/types"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// knownConfigKeys are the top-level keys the config loader understands
+var knownConfigKeys = []string{
+	"include",
+	"sessions",
+	"strategyDefaults",
+	"exchangeStrategies",
+	"strategies",
+	"crossExchangeStrategies",
+}
+
+// ConfigError is a config problem found at the given line of the YAML file
+type ConfigError struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	switch {
+	case len(e.File) > 0 && e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+
+	case len(e.File) > 0:
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+
+	case e.Line > 0:
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+
+	return e.Message
+}
+
+// ConfigErrors collects all the problems found in the config files
+type ConfigErrors []ConfigError
+
+func (errs ConfigErrors) Error() string {
+	var ss []string
+	for _, err := range errs {
+		ss = append(ss, err.Error())
+	}
+
+	return fmt.Sprintf("%d config error(s):\n", len(errs)) + strings.Join(ss, "\n")
+}
+
+// configDocument is a parsed config file, it's used for reporting the line numbers
+type configDocument struct {
+	File string
+	Root *yaml.Node
+}
+
+type configValidator struct {
+	errs ConfigErrors
+
+	sessionNames     map[string]struct{}
+	strategyDefaults Stash
+}
+
+func (v *configValidator) add(doc *configDocument, node *yaml.Node, format string, args ...interface{}) {
+	var line int
+	if node != nil {
+		line = node.Line
+	}
+
+	v.errs = append(v.errs, ConfigError{
+		File:    doc.File,
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// ValidateConfigFile validates the config file and the files it includes in the strict mode, it reports unknown top-level keys,
+// unknown strategy IDs, unknown fields in the strategy blocks, mounts that point at undefined sessions,
+// invalid exchange names and the errors returned from the strategies' Validate().
+// The included files are resolved from the directory of the including file.
+// All the problems found are returned as ConfigErrors.
+func ValidateConfigFile(configFile string) error {
+	v := &configValidator{
+		sessionNames:     make(map[string]struct{}),
+		strategyDefaults: make(Stash),
+	}
+
+	var docs []*configDocument
+	v.loadDocuments(&docs, nil, nil, configFile, make(map[string]struct{}))
+	return v.validate(docs)
+}
+
+func parseConfigDocument(file string, content []byte) (*yaml.Node, error) {
+	root, err := parseConfigNode(file, content)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty document
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+
+	return root.Content[0], nil
+}
+
+// loadDocuments parses the config file and the files it includes, the included documents are placed before the including one
+func (v *configValidator) loadDocuments(docs *[]*configDocument, parent *configDocument, node *yaml.Node, configFile string, visited map[string]struct{}) {
+	absPath, err := filepath.Abs(configFile)
+	if err != nil {
+		v.add(&configDocument{File: configFile}, nil, "%v", err)
+		return
+	}
+
+	if _, ok := visited[absPath]; ok {
+		v.add(parent, node, "config file %s is included recursively", configFile)
+		return
+	}
+
+	visited[absPath] = struct{}{}
+	defer delete(visited, absPath)
+
+	content, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		if parent != nil {
+			v.add(parent, node, "can not read the included file: %v", err)
+		} else {
+			v.add(&configDocument{File: configFile}, nil, "%v", err)
+		}
+		return
+	}
+
+	doc := &configDocument{File: configFile}
+	doc.Root, err = parseConfigDocument(configFile, content)
+	if err != nil {
+		if errs, ok := err.(ConfigErrors); ok {
+			v.errs = append(v.errs, errs...)
+		} else {
+			v.add(doc, nil, "%v", err)
+		}
+		return
+	}
+
+	v.loadIncludedDocuments(docs, doc, filepath.Dir(configFile), visited)
+	*docs = append(*docs, doc)
+}
+
+func (v *configValidator) loadIncludedDocuments(docs *[]*configDocument, doc *configDocument, dir string, visited map[string]struct{}) {
+	includeNode := mappingValue(doc.Root, "include")
+	if includeNode == nil {
+		return
+	}
+
+	var fileNodes []*yaml.Node
+	switch includeNode.Kind {
+	case yaml.ScalarNode:
+		fileNodes = append(fileNodes, includeNode)
+
+	case yaml.SequenceNode:
+		fileNodes = append(fileNodes, includeNode.Content...)
+
+	default:
+		v.add(doc, includeNode, "include should be a file name or a list of file names")
+		return
+	}
+
+	for _, fileNode := range fileNodes {
+		if fileNode.Kind != yaml.ScalarNode {
+			v.add(doc, fileNode, "include should be a file name")
+			continue
+		}
+
+		file := fileNode.Value
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+
+		v.loadDocuments(docs, doc, fileNode, file, visited)
+	}
+}
+
+func (v *configValidator) validate(docs []*configDocument) error {
+	// collect the sessions and the strategy defaults from all the documents first,
+	// since a strategy can be mounted on a session defined in another file.
+	for _, doc := range docs {
+		if doc.Root == nil {
+			continue
+		}
+
+		if doc.Root.Kind != yaml.MappingNode {
+			v.add(doc, doc.Root, "config should be a map")
+			continue
+		}
+
+		v.validateSessions(doc, mappingValue(doc.Root, "sessions"))
+		v.collectStrategyDefaults(doc, mappingValue(doc.Root, "strategyDefaults"))
+	}
+
+	for _, doc := range docs {
+		if doc.Root == nil || doc.Root.Kind != yaml.MappingNode {
+			continue
+		}
+
+		for i := 0; i+1 < len(doc.Root.Content); i += 2 {
+			keyNode, valueNode := doc.Root.Content[i], doc.Root.Content[i+1]
+			switch keyNode.Value {
+
+			case "exchangeStrategies", "strategies":
+				v.validateExchangeStrategies(doc, valueNode)
+
+			case "crossExchangeStrategies":
+				v.validateCrossExchangeStrategies(doc, valueNode)
+
+			default:
+				if !isKnownConfigKey(keyNode.Value) {
+					v.add(doc, keyNode, "unknown config key %q", keyNode.Value)
+				}
+			}
+		}
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		if v.errs[i].File != v.errs[j].File {
+			return v.errs[i].File < v.errs[j].File
+		}
+
+		return v.errs[i].Line < v.errs[j].Line
+	})
+
+	return v.errs
+}
+
+func isKnownConfigKey(key string) bool {
+	for _, k := range knownConfigKeys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mappingValue returns the value node of the given key in the mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func (v *configValidator) validateSessions(doc *configDocument, node *yaml.Node) {
+	if node == nil {
+		return
+	}
+
+	if node.Kind != yaml.MappingNode {
+		v.add(doc, node, "sessions should be a map")
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		nameNode, sessionNode := node.Content[i], node.Content[i+1]
+		v.sessionNames[nameNode.Value] = struct{}{}
+
+		exchangeNode := mappingValue(sessionNode, "exchange")
+		if exchangeNode == nil {
+			v.add(doc, sessionNode, "session %s: exchange is not defined", nameNode.Value)
+			continue
+		}
+
+		r, ok := types.LookupExchange(exchangeNode.Value)
+		if !ok {
+			v.add(doc, exchangeNode, "session %s: invalid exchange name %q, valid names are: %v",
+				nameNode.Value, exchangeNode.Value, types.SupportedExchanges)
+			continue
+		}
+
+		v.validateSessionExchange(doc, nameNode.Value, sessionNode, r)
+	}
+}
+
+// sessionCapabilityKeys are the session keys that require the exchange capabilities
+var sessionCapabilityKeys = []struct {
+	key        string
+	capability types.ExchangeCapability
+}{
+	{"margin", types.ExchangeCapabilityMargin},
+	{"isolatedMargin", types.ExchangeCapabilityMargin},
+	{"futures", types.ExchangeCapabilityFutures},
+	{"testnet", types.ExchangeCapabilityEndpoints},
+	{"restBaseURL", types.ExchangeCapabilityEndpoints},
+	{"wsBaseURL", types.ExchangeCapabilityEndpoints},
+	{"withdrawal", types.ExchangeCapabilityWithdrawal},
+}
+
+// sessionCredentialKeys are the session keys of the credential fields
+var sessionCredentialKeys = []struct {
+	key   string
+	field types.CredentialField
+}{
+	{"passphrase", types.CredentialPassphrase},
+	{"passphraseFile", types.CredentialPassphrase},
+	{"subAccount", types.CredentialSubAccount},
+}
+
+// validateSessionExchange checks the session options against the capabilities and the credentials of the exchange
+func (v *configValidator) validateSessionExchange(doc *configDocument, name string, sessionNode *yaml.Node, r *types.ExchangeRegistration) {
+	for _, c := range sessionCapabilityKeys {
+		node := mappingValue(sessionNode, c.key)
+		if node == nil || node.Value == "" || node.Value == "false" {
+			continue
+		}
+
+		if !r.HasCapability(c.capability) {
+			v.add(doc, node, "session %s: exchange %s does not support %s", name, r.Name, c.key)
+		}
+	}
+
+	for _, c := range sessionCredentialKeys {
+		node := mappingValue(sessionNode, c.key)
+		if node == nil || node.Value == "" {
+			continue
+		}
+
+		if !r.UsesCredential(c.field) {
+			v.add(doc, node, "session %s: exchange %s does not use %s", name, r.Name, c.key)
+		}
+	}
+}
+
+func (v *configValidator) collectStrategyDefaults(doc *configDocument, node *yaml.Node) {
+	if node == nil {
+		return
+	}
+
+	if node.Kind != yaml.MappingNode {
+		v.add(doc, node, "strategyDefaults should be a map")
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		id := keyNode.Value
+
+		_, isExchangeStrategy := LoadedExchangeStrategies[id]
+		_, isCrossExchangeStrategy := LoadedCrossExchangeStrategies[id]
+		if !isExchangeStrategy && !isCrossExchangeStrategy {
+			v.add(doc, keyNode, "strategyDefaults: unknown strategy %q", id)
+			continue
+		}
+
+		if valueNode.Kind != yaml.MappingNode {
+			v.add(doc, valueNode, "strategyDefaults: %s should be a map", id)
+			continue
+		}
+
+		var defaults Stash
+		if err := valueNode.Decode(&defaults); err != nil {
+			v.add(doc, valueNode, "strategyDefaults: %s: %v", id, err)
+			continue
+		}
+
+		mergeStash(v.strategyDefaults, Stash{id: defaults}, false)
+	}
+}
+
+func (v *configValidator) validateExchangeStrategies(doc *configDocument, node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		v.add(doc, node, "expecting list in exchangeStrategies")
+		return
+	}
+
+	for _, entry := range node.Content {
+		if entry.Kind != yaml.MappingNode {
+			v.add(doc, entry, "strategy config should be a map")
+			continue
+		}
+
+		var numStrategies = 0
+		var hasMounts = false
+		for i := 0; i+1 < len(entry.Content); i += 2 {
+			keyNode, valueNode := entry.Content[i], entry.Content[i+1]
+			if keyNode.Value == "on" {
+				hasMounts = true
+				v.validateMounts(doc, valueNode)
+				continue
+			}
+
+			numStrategies++
+
+			st, ok := LoadedExchangeStrategies[keyNode.Value]
+			if !ok {
+				v.add(doc, keyNode, "unknown exchange strategy %q", keyNode.Value)
+				continue
+			}
+
+			v.validateStrategyNode(doc, keyNode.Value, valueNode, st)
+		}
+
+		if numStrategies == 0 {
+			v.add(doc, entry, "no strategy is defined")
+		}
+
+		if !hasMounts {
+			v.add(doc, entry, "the strategy is not mounted on any session, please define the \"on\" field")
+		}
+	}
+}
+
+func (v *configValidator) validateMounts(doc *configDocument, node *yaml.Node) {
+	var mountNodes []*yaml.Node
+	switch node.Kind {
+	case yaml.ScalarNode:
+		mountNodes = append(mountNodes, node)
+
+	case yaml.SequenceNode:
+		mountNodes = append(mountNodes, node.Content...)
+
+	default:
+		v.add(doc, node, "unexpected mount type, expecting a session name or a list of session names")
+		return
+	}
+
+	for _, mountNode := range mountNodes {
+		if mountNode.Kind != yaml.ScalarNode {
+			v.add(doc, mountNode, "mount should be a session name")
+			continue
+		}
+
+		if _, ok := v.sessionNames[mountNode.Value]; !ok {
+			v.add(doc, mountNode, "session %s is not defined", mountNode.Value)
+		}
+	}
+}
+
+func (v *configValidator) validateCrossExchangeStrategies(doc *configDocument, node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		v.add(doc, node, "expecting list in crossExchangeStrategies")
+		return
+	}
+
+	for _, entry := range node.Content {
+		if entry.Kind != yaml.MappingNode {
+			v.add(doc, entry, "strategy config should be a map")
+			continue
+		}
+
+		for i := 0; i+1 < len(entry.Content); i += 2 {
+			keyNode, valueNode := entry.Content[i], entry.Content[i+1]
+			st, ok := LoadedCrossExchangeStrategies[keyNode.Value]
+			if !ok {
+				v.add(doc, keyNode, "unknown cross exchange strategy %q", keyNode.Value)
+				continue
+			}
+
+			v.validateStrategyNode(doc, keyNode.Value, valueNode, st)
+		}
+	}
+}
+
+// validateStrategyNode applies the strategy defaults, unmarshals the strategy block without allowing unknown fields
+// and runs the strategy Validate()
+func (v *configValidator) validateStrategyNode(doc *configDocument, id string, node *yaml.Node, tpe interface{}) {
+	var conf interface{}
+	if err := node.Decode(&conf); err != nil {
+		v.add(doc, node, "strategy %s: %v", id, err)
+		return
+	}
+
+	conf = applyStrategyDefaults(Stash{"strategyDefaults": v.strategyDefaults}, id, conf)
+
+	val, err := strictReUnmarshal(conf, tpe)
+	if err != nil {
+		v.add(doc, node, "strategy %s: %v", id, err)
+		return
+	}
+
+	if validator, ok := val.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			v.add(doc, node, "strategy %s: validation failed: %v", id, err)
+		}
+	}
+}
+
+// strictReUnmarshal works like reUnmarshal, but it rejects the fields that are not defined in the struct
+func strictReUnmarshal(conf interface{}, tpe interface{}) (interface{}, error) {
+	rt := reflect.TypeOf(tpe)
+	val := reflect.New(rt)
+	valRef := val.Interface()
+
+	plain, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(plain))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(valRef); err != nil {
+		return nil, err
+	}
+
+	return val.Elem().Interface(), nil
+}
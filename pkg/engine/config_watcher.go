@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"time"
+)
+
+const defaultConfigWatchInterval = 3 * time.Second
+
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+// ConfigWatcher polls the config file and the files it includes, and emits the change event when any of them is changed.
+// Polling is used instead of file system notifications since the mounted secrets and config maps are replaced by symlink swaps.
+type ConfigWatcher struct {
+	ConfigFile string
+	Interval   time.Duration
+
+	stats map[string]fileStat
+
+	changeCallbacks []func()
+}
+
+func NewConfigWatcher(configFile string) *ConfigWatcher {
+	return &ConfigWatcher{
+		ConfigFile: configFile,
+		Interval:   defaultConfigWatchInterval,
+	}
+}
+
+func (w *ConfigWatcher) OnChange(cb func()) {
+	w.changeCallbacks = append(w.changeCallbacks, cb)
+}
+
+func (w *ConfigWatcher) EmitChange() {
+	for _, cb := range w.changeCallbacks {
+		cb()
+	}
+}
+
+func (w *ConfigWatcher) Run(ctx context.Context) {
+	w.stats = w.scan()
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			stats := w.scan()
+			if w.changed(stats) {
+				log.Infof("config file %s is changed", w.ConfigFile)
+				w.stats = stats
+				w.EmitChange()
+			}
+		}
+	}
+}
+
+func (w *ConfigWatcher) changed(stats map[string]fileStat) bool {
+	if len(stats) != len(w.stats) {
+		return true
+	}
+
+	for file, stat := range stats {
+		if prev, ok := w.stats[file]; !ok || prev != stat {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *ConfigWatcher) scan() map[string]fileStat {
+	var stats = make(map[string]fileStat)
+
+	files, err := ConfigFiles(w.ConfigFile)
+	if err != nil {
+		log.WithError(err).Warnf("can not resolve the included files of %s", w.ConfigFile)
+		files = []string{w.ConfigFile}
+	}
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+
+		stats[file] = fileStat{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	return stats
+}
@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 	"fmt"
+	"github.com/jmoiron/sqlx"
 	"github.com/pymba86/bingo/pkg/service"
 	log "github.com/sirupsen/logrus"
 	"sync"
@@ -43,6 +44,9 @@ type Environment struct {
 	SyncService  *service.SyncService
 	TradeService *service.TradeService
 
+	// WithdrawalRequestService persists the requests of the session withdrawers
+	WithdrawalRequestService *service.WithdrawalRequestService
+
 	// startTime is the time of start point (which is used in the backtest)
 	startTime time.Time
 
@@ -63,6 +67,19 @@ func NewEnvironment() *Environment {
 	}
 }
 
+// ConfigureDatabase creates the services on the database, so that the trade, the margin and the transfer history
+// of the sessions are synced into it. The tables are created by the migrations of the database driver, see the migrations directory.
+func (environ *Environment) ConfigureDatabase(db *sqlx.DB) {
+	environ.TradeService = service.NewTradeService(db)
+	environ.SyncService = &service.SyncService{
+		TradeService:    environ.TradeService,
+		MarginService:   service.NewMarginService(db),
+		WithdrawService: service.NewWithdrawService(db),
+		DepositService:  service.NewDepositService(db),
+	}
+	environ.WithdrawalRequestService = service.NewWithdrawalRequestService(db)
+}
+
 func (e *Environment) Start(ctx context.Context) error {
 	for n := range e.sessions {
 		var session = e.sessions[n]
@@ -74,6 +91,13 @@ func (e *Environment) Start(ctx context.Context) error {
 }
 
 func (e *Environment) Connect(ctx context.Context) error {
+	for n := range e.sessions {
+		var session = e.sessions[n]
+		if session.Margin && session.MarginLevelThreshold > 0 {
+			go session.watchMarginLevel(ctx)
+		}
+	}
+
 	return nil
 }
 
@@ -101,6 +125,15 @@ func (e *Environment) AddExchangeSession(name string, session *ExchangeSession)
 	return session
 }
 
+func (e *Environment) Session(name string) (*ExchangeSession, bool) {
+	s, ok := e.sessions[name]
+	return s, ok
+}
+
+func (e *Environment) Sessions() map[string]*ExchangeSession {
+	return e.sessions
+}
+
 func (environ *Environment) Init(ctx context.Context) (err error) {
 	for n := range environ.sessions {
 		var session = environ.sessions[n]
@@ -157,6 +190,11 @@ func (environ *Environment) Sync(ctx context.Context) error {
 }
 
 func (environ *Environment) syncSession(ctx context.Context, session *ExchangeSession, defaultSymbols ...string) error {
+	// the paper trading session has no trade history on the exchange
+	if session.PaperTrade {
+		return nil
+	}
+
 	symbols, err := getSessionSymbols(session, defaultSymbols...)
 	if err != nil {
 		return err
@@ -164,7 +202,45 @@ func (environ *Environment) syncSession(ctx context.Context, session *ExchangeSe
 
 	log.Infof("syncing symbols %v from session %s", symbols, session.Name)
 
-	return environ.SyncService.SyncSessionSymbols(ctx, session.Exchange, environ.syncStartTime, symbols...)
+	if err := environ.SyncService.SyncSessionSymbols(ctx, session.Exchange, environ.syncStartTime, symbols...); err != nil {
+		return err
+	}
+
+	log.Infof("syncing deposit and withdraw history from session %s", session.Name)
+	if err := environ.SyncService.SyncTransferHistory(ctx, session.Exchange, environ.syncStartTime); err != nil {
+		return err
+	}
+
+	if !session.Margin {
+		return nil
+	}
+
+	assets := getSessionAssets(session, symbols...)
+	log.Infof("syncing margin history of assets %v from session %s", assets, session.Name)
+
+	return environ.SyncService.SyncMarginHistory(ctx, session.Exchange, environ.syncStartTime, assets...)
+}
+
+// getSessionAssets returns the base and quote currencies of the given session symbols
+func getSessionAssets(session *ExchangeSession, symbols ...string) (assets []string) {
+	var assetSet = map[string]struct{}{}
+	for _, symbol := range symbols {
+		market, ok := session.Markets()[symbol]
+		if !ok {
+			continue
+		}
+
+		for _, asset := range []string{market.BaseCurrency, market.QuoteCurrency} {
+			if _, exists := assetSet[asset]; exists {
+				continue
+			}
+
+			assetSet[asset] = struct{}{}
+			assets = append(assets, asset)
+		}
+	}
+
+	return assets
 }
 
 func getSessionSymbols(session *ExchangeSession, defaultSymbols ...string) ([]string, error) {
@@ -2,4 +2,9 @@ package engine
 
 import "errors"
 
-var ErrSessionAlreadyInitialized = errors.New("session is already initialized")
\ No newline at end of file
+var ErrSessionAlreadyInitialized = errors.New("session is already initialized")
+
+var ErrWithdrawalDisabled = errors.New("withdrawal is not enabled in the session config")
+
+// ErrStrategyStopped is returned when a stopped strategy submits orders
+var ErrStrategyStopped = errors.New("strategy is stopped")
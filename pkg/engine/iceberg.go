@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"time"
+)
+
+const icebergCheckInterval = 30 * time.Second
+
+// IcebergExecutor places the total quantity at the price as a series of limit orders,
+// only one slice of the visible quantity is on the book, and the next slice is placed once it's filled.
+type IcebergExecutor struct {
+	*algoOrderTracker
+
+	Market          types.Market
+	Side            types.SideType
+	Price           float64
+	TotalQuantity   float64
+	VisibleQuantity float64
+
+	// MaxSubmitFailures is the number of the consecutive submit failures that stops the iceberg, defaults to 5
+	MaxSubmitFailures int
+
+	OrderExecutor OrderExecutor
+}
+
+func NewIcebergExecutor(executor OrderExecutor, market types.Market, side types.SideType, price, totalQuantity, visibleQuantity float64) *IcebergExecutor {
+	e := &IcebergExecutor{
+		algoOrderTracker: newAlgoOrderTracker(market.Symbol, side, totalQuantity),
+		Market:           market,
+		Side:             side,
+		Price:            price,
+		TotalQuantity:    totalQuantity,
+		VisibleQuantity:  visibleQuantity,
+
+		MaxSubmitFailures: defaultAlgoOrderMaxSubmitFailures,
+		OrderExecutor:     executor,
+	}
+
+	e.bind(executor)
+	return e
+}
+
+func (e *IcebergExecutor) validate() error {
+	if e.Side != types.SideTypeBuy && e.Side != types.SideTypeSell {
+		return fmt.Errorf("invalid iceberg side: %s", e.Side)
+	}
+
+	if e.Price <= 0 {
+		return fmt.Errorf("iceberg price must be positive, got %f", e.Price)
+	}
+
+	if e.TotalQuantity <= 0 {
+		return fmt.Errorf("iceberg total quantity must be positive, got %f", e.TotalQuantity)
+	}
+
+	if e.VisibleQuantity <= 0 || e.VisibleQuantity > e.TotalQuantity {
+		return fmt.Errorf("iceberg visible quantity must be between 0 and %f, got %f", e.TotalQuantity, e.VisibleQuantity)
+	}
+
+	return nil
+}
+
+// Run places the slices until the total quantity is filled or the slices can not be submitted,
+// the visible slice is canceled when the context is canceled. The executor stops receiving
+// the order updates when it returns, so it can only be run once.
+func (e *IcebergExecutor) Run(ctx context.Context) error {
+	defer e.unbind()
+
+	if err := e.validate(); err != nil {
+		return err
+	}
+
+	if e.MaxSubmitFailures <= 0 {
+		e.MaxSubmitFailures = defaultAlgoOrderMaxSubmitFailures
+	}
+
+	ticker := time.NewTicker(icebergCheckInterval)
+	defer ticker.Stop()
+
+	log.Infof("iceberg %s %s %f @ %f started, visible quantity %f", e.Market.Symbol, e.Side, e.TotalQuantity, e.Price, e.VisibleQuantity)
+
+	var failures int
+	for {
+		remaining := e.TotalQuantity - e.Progress().ExecutedQuantity
+		if isDustQuantity(e.Market, remaining, e.Price) {
+			log.Infof("iceberg %s finished: %s", e.Market.Symbol, e.Progress())
+			return nil
+		}
+
+		if len(e.activeOrders()) == 0 {
+			_, err := e.submit(ctx, e.OrderExecutor, types.SubmitOrder{
+				Symbol:   e.Market.Symbol,
+				Side:     e.Side,
+				Type:     types.OrderTypeLimit,
+				Quantity: math.Min(e.VisibleQuantity, remaining),
+				Price:    e.Price,
+				Market:   e.Market,
+			})
+			if err != nil {
+				failures++
+				log.WithError(err).Errorf("iceberg %s can not submit the visible slice (%d/%d)", e.Market.Symbol, failures, e.MaxSubmitFailures)
+
+				if failures >= e.MaxSubmitFailures {
+					_ = e.cancelActiveOrders(e.OrderExecutor)
+					return fmt.Errorf("iceberg %s stopped after %d consecutive submit failures: %w", e.Market.Symbol, failures, err)
+				}
+			} else {
+				failures = 0
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = e.cancelActiveOrders(e.OrderExecutor)
+			return ctx.Err()
+
+		case <-ticker.C:
+			e.reconcileActiveOrders(ctx)
+
+		case <-e.updateC:
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const defaultMarginLevelCheckInterval = time.Minute
+
+// watchMarginLevel refreshes the margin account balances periodically and
+// notifies when the margin level falls below the configured threshold.
+func (session *ExchangeSession) watchMarginLevel(ctx context.Context) {
+	var log = log.WithField("session", session.Name)
+
+	interval := session.MarginLevelCheckInterval
+	if interval <= 0 {
+		interval = defaultMarginLevelCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// alerted is used for notifying once until the margin level recovers
+	alerted := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			account, err := session.Exchange.QueryAccount(ctx)
+			if err != nil {
+				log.WithError(err).Errorf("margin account query error")
+				continue
+			}
+
+			// the stream events don't carry the liabilities, so the balances are refreshed here
+			session.Account.UpdateBalances(account.Balances())
+
+			marginLevel := account.MarginLevel
+			if marginLevel == 0 {
+				continue
+			}
+
+			if marginLevel < session.MarginLevelThreshold {
+				if !alerted {
+					log.Warnf("margin level %f is below the threshold %f", marginLevel.Float64(), session.MarginLevelThreshold.Float64())
+					session.Notify("%s margin level %f is below the threshold %f", session.Name, marginLevel.Float64(), session.MarginLevelThreshold.Float64())
+					alerted = true
+				}
+			} else if alerted {
+				log.Infof("margin level %f is recovered above the threshold %f", marginLevel.Float64(), session.MarginLevelThreshold.Float64())
+				session.Notify("%s margin level %f is recovered above the threshold %f", session.Name, marginLevel.Float64(), session.MarginLevelThreshold.Float64())
+				alerted = false
+			}
+		}
+	}
+}
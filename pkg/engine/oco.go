@@ -0,0 +1,232 @@
+package engine
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const ocoCancelTimeout = 30 * time.Second
+
+type emulatedOCOOrder struct {
+	types.SubmitOCOOrder
+
+	limitOrder types.Order
+	done       bool
+
+	// stopTriggered is set once the stop price is crossed before the limit leg is filled or canceled,
+	// the final update of the canceled limit leg is sent to finalC
+	stopTriggered bool
+	finalC        chan types.Order
+}
+
+// OCOOrderEmulator emulates the OCO orders for the exchanges without the native OCO orders.
+// Only the limit leg rests on the exchange, so the balance is locked once. The stop leg is triggered
+// on the client side by the market data of the session: the limit leg is canceled and the stop order
+// is submitted for the unfilled quantity. The stop is dropped once the limit leg is filled, partially filled or canceled.
+type OCOOrderEmulator struct {
+	session *ExchangeSession
+
+	mu         sync.Mutex
+	lastListID uint64
+
+	// lists maps the order id of the limit leg to its order list
+	lists map[uint64]*emulatedOCOOrder
+}
+
+func NewOCOOrderEmulator(session *ExchangeSession) *OCOOrderEmulator {
+	return &OCOOrderEmulator{
+		session: session,
+		lists:   make(map[uint64]*emulatedOCOOrder),
+	}
+}
+
+func (e *OCOOrderEmulator) BindStream(stream types.Stream) {
+	stream.OnOrderUpdate(e.handleOrderUpdate)
+}
+
+// SubmitOCOOrder places the limit leg and returns it, the symbol needs to be subscribed
+// by the market data stream of the session to trigger the stop leg.
+func (e *OCOOrderEmulator) SubmitOCOOrder(ctx context.Context, order types.SubmitOCOOrder) (types.OrderSlice, error) {
+	createdOrders, err := e.session.Exchange.SubmitOrders(ctx, types.SubmitOrder{
+		Symbol:           order.Symbol,
+		Side:             order.Side,
+		Type:             types.OrderTypeLimit,
+		Quantity:         order.Quantity,
+		Price:            order.Price,
+		PriceString:      formatOCOPrice(order.Market, order.Price),
+		Market:           order.Market,
+		MarginSideEffect: order.MarginSideEffect,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "submit %s oco order error", order.Symbol)
+	}
+
+	if len(createdOrders) == 0 {
+		return nil, errors.Errorf("submit %s oco order error: no order is created", order.Symbol)
+	}
+
+	e.mu.Lock()
+	e.lastListID++
+	createdOrders[0].OrderListID = e.lastListID
+	list := &emulatedOCOOrder{
+		SubmitOCOOrder: order,
+		limitOrder:     createdOrders[0],
+		finalC:         make(chan types.Order, 1),
+	}
+	e.lists[list.limitOrder.OrderID] = list
+	e.mu.Unlock()
+
+	e.session.priceTriggers.Add(order.Symbol, func(price float64) bool {
+		return e.checkStop(list, price)
+	})
+
+	// the order updates of the limit leg could be received before the list is registered
+	if list.limitOrder.Status != types.OrderStatusNew {
+		e.handleOrderUpdate(list.limitOrder)
+	}
+
+	return createdOrders[:1], nil
+}
+
+// checkStop triggers the stop leg once the price crosses the stop price, it returns true once the list is done
+func (e *OCOOrderEmulator) checkStop(list *emulatedOCOOrder, price float64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if list.done {
+		return true
+	}
+
+	switch list.Side {
+	case types.SideTypeSell:
+		if price > list.StopPrice {
+			return false
+		}
+
+	case types.SideTypeBuy:
+		if price < list.StopPrice {
+			return false
+		}
+	}
+
+	list.done = true
+	list.stopTriggered = true
+
+	go e.triggerStop(list, price)
+	return true
+}
+
+func (e *OCOOrderEmulator) triggerStop(list *emulatedOCOOrder, price float64) {
+	ctx, cancel := context.WithTimeout(context.Background(), ocoCancelTimeout)
+	defer cancel()
+
+	log.Infof("oco order %s: stop price %f is crossed at %f, canceling the limit order %d", list.Symbol, list.StopPrice, price, list.limitOrder.OrderID)
+
+	// the limit order could be filled meanwhile, so the cancel error is only logged and the final update decides
+	if err := e.session.Exchange.CancelOrders(ctx, list.limitOrder); err != nil {
+		log.WithError(err).Warnf("can not cancel the limit order %d of the %s oco order", list.limitOrder.OrderID, list.Symbol)
+	}
+
+	var final types.Order
+	select {
+	case final = <-list.finalC:
+	case <-ctx.Done():
+		log.Errorf("oco order %s: the limit order %d is not closed, the stop order is not submitted", list.Symbol, list.limitOrder.OrderID)
+		e.session.Notify("%s: the limit order %d of the %s oco order is not closed, the stop order is not submitted", e.session.Name, list.limitOrder.OrderID, list.Symbol)
+
+		e.mu.Lock()
+		delete(e.lists, list.limitOrder.OrderID)
+		e.mu.Unlock()
+		return
+	}
+
+	quantity := list.Quantity - final.ExecutedQuantity
+	if final.Status == types.OrderStatusFilled || quantity <= 0 {
+		log.Infof("oco order %s: the limit order %d is filled, the stop order is not submitted", list.Symbol, final.OrderID)
+		return
+	}
+
+	stopOrder := types.SubmitOrder{
+		Symbol:           list.Symbol,
+		Side:             list.Side,
+		Type:             types.OrderTypeMarket,
+		Quantity:         quantity,
+		Market:           list.Market,
+		MarginSideEffect: list.MarginSideEffect,
+	}
+
+	if list.StopLimitPrice > 0 {
+		stopOrder.Type = types.OrderTypeLimit
+		stopOrder.Price = list.StopLimitPrice
+		stopOrder.PriceString = formatOCOPrice(list.Market, list.StopLimitPrice)
+	}
+
+	createdOrders, err := e.session.Exchange.SubmitOrders(ctx, stopOrder)
+	if err != nil {
+		log.WithError(err).Errorf("can not submit the stop order of the %s oco order", list.Symbol)
+		e.session.Notify("%s: can not submit the stop order of the %s oco order at %f: %v", e.session.Name, list.Symbol, price, err)
+		return
+	}
+
+	for _, o := range createdOrders {
+		log.Infof("oco order %s: stop order %d is submitted for %f", list.Symbol, o.OrderID, quantity)
+	}
+}
+
+func (e *OCOOrderEmulator) handleOrderUpdate(order types.Order) {
+	e.mu.Lock()
+	list, ok := e.lists[order.OrderID]
+	if !ok {
+		e.mu.Unlock()
+		return
+	}
+
+	if list.stopTriggered {
+		// wait for the final update of the canceled limit order
+		switch order.Status {
+		case types.OrderStatusFilled, types.OrderStatusCanceled, types.OrderStatusRejected:
+			delete(e.lists, order.OrderID)
+			e.mu.Unlock()
+			list.finalC <- order
+			return
+		}
+
+		e.mu.Unlock()
+		return
+	}
+
+	switch order.Status {
+	case types.OrderStatusPartiallyFilled, types.OrderStatusFilled, types.OrderStatusCanceled, types.OrderStatusRejected:
+		// the stop trigger is dropped by the next price update
+		list.done = true
+		delete(e.lists, order.OrderID)
+		log.Infof("oco order %s: limit order %d is %s, the stop is dropped", order.Symbol, order.OrderID, order.Status)
+	}
+	e.mu.Unlock()
+}
+
+func formatOCOPrice(market types.Market, price float64) string {
+	if market.Symbol != "" {
+		return market.FormatPrice(price)
+	}
+
+	return strconv.FormatFloat(price, 'f', 8, 64)
+}
+
+// SubmitOCOOrder places the native OCO order of the exchange,
+// or emulates it on the client side if the exchange does not support the OCO orders.
+func (session *ExchangeSession) SubmitOCOOrder(ctx context.Context, order types.SubmitOCOOrder) (types.OrderSlice, error) {
+	if service, ok := session.Exchange.(types.ExchangeOCOOrderService); ok {
+		createdOrders, err := service.SubmitOCOOrder(ctx, order)
+		if err == nil || !errors.Is(err, types.ErrOCOOrderNotSupported) {
+			return createdOrders, err
+		}
+	}
+
+	return session.ocoOrderEmulator.SubmitOCOOrder(ctx, order)
+}
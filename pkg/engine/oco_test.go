@@ -0,0 +1,181 @@
+package engine_test
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/engine"
+	"github.com/pymba86/bingo/pkg/exchange/mock"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+	"time"
+)
+
+var ocoTestMarket = types.Market{
+	Symbol:          "BTCUSDT",
+	PricePrecision:  2,
+	VolumePrecision: 6,
+	BaseCurrency:    "BTC",
+	QuoteCurrency:   "USDT",
+	MinQuantity:     0.000001,
+	StepSize:        0.000001,
+	TickSize:        0.01,
+}
+
+func newOCOTestHarness(t *testing.T) (*mock.Harness, *mock.Exchange) {
+	ctx := context.Background()
+
+	exchange := mock.New()
+	exchange.AddMarkets(ocoTestMarket)
+	exchange.SetBalance("BTC", fixedpoint.NewFromFloat(1.0))
+
+	harness, err := mock.NewHarness(ctx, exchange)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := harness.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = harness.Shutdown(context.Background())
+	})
+
+	return harness, exchange
+}
+
+func pushOCOTestKLine(exchange *mock.Exchange, low, high, close float64) {
+	now := exchange.Now()
+	exchange.PushKLine(types.KLine{
+		Symbol:    ocoTestMarket.Symbol,
+		Interval:  types.Interval1m,
+		StartTime: now,
+		EndTime:   now.Add(time.Minute),
+		Open:      close,
+		High:      high,
+		Low:       low,
+		Close:     close,
+		Closed:    true,
+	})
+}
+
+func submitOCOTestOrder(t *testing.T, session *engine.ExchangeSession) types.Order {
+	createdOrders, err := session.SubmitOCOOrder(context.Background(), types.SubmitOCOOrder{
+		Symbol:    ocoTestMarket.Symbol,
+		Side:      types.SideTypeSell,
+		Quantity:  1.0,
+		Price:     110.0,
+		StopPrice: 90.0,
+		Market:    ocoTestMarket,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(createdOrders) != 1 {
+		t.Fatalf("expected only the limit leg, got %d orders", len(createdOrders))
+	}
+
+	return createdOrders[0]
+}
+
+func queryOpenOrders(t *testing.T, exchange *mock.Exchange) []types.Order {
+	orders, err := exchange.QueryOpenOrders(context.Background(), ocoTestMarket.Symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return orders
+}
+
+func queryClosedOrders(t *testing.T, exchange *mock.Exchange) []types.Order {
+	orders, err := exchange.QueryClosedOrders(context.Background(), ocoTestMarket.Symbol, time.Time{}, time.Now(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return orders
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOCOOrderEmulator_LocksTheBalanceOnce(t *testing.T) {
+	harness, exchange := newOCOTestHarness(t)
+	pushOCOTestKLine(exchange, 99, 101, 100)
+
+	limitOrder := submitOCOTestOrder(t, harness.Session)
+	if limitOrder.Type != types.OrderTypeLimit || limitOrder.OrderListID == 0 {
+		t.Fatalf("unexpected limit leg: %+v", limitOrder)
+	}
+
+	if orders := queryOpenOrders(t, exchange); len(orders) != 1 {
+		t.Fatalf("expected 1 open order, got %d", len(orders))
+	}
+
+	balances, err := exchange.QueryAccountBalances(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if locked := balances["BTC"].Locked.Float64(); locked != 1.0 {
+		t.Fatalf("expected 1 BTC locked, got %f", locked)
+	}
+}
+
+func TestOCOOrderEmulator_StopTriggered(t *testing.T) {
+	harness, exchange := newOCOTestHarness(t)
+	pushOCOTestKLine(exchange, 99, 101, 100)
+
+	limitOrder := submitOCOTestOrder(t, harness.Session)
+
+	// the stop price is crossed, the limit leg is canceled and the stop leg is sold at the market
+	pushOCOTestKLine(exchange, 89, 95, 89)
+
+	var closedOrders []types.Order
+	waitFor(t, func() bool {
+		closedOrders = queryClosedOrders(t, exchange)
+		return len(closedOrders) == 2
+	})
+
+	if closedOrders[0].OrderID != limitOrder.OrderID || closedOrders[0].Status != types.OrderStatusCanceled {
+		t.Fatalf("expected the limit leg to be canceled, got %+v", closedOrders[0])
+	}
+
+	stopOrder := closedOrders[1]
+	if stopOrder.Type != types.OrderTypeMarket || stopOrder.Status != types.OrderStatusFilled || stopOrder.ExecutedQuantity != 1.0 {
+		t.Fatalf("expected the stop leg to be filled at the market, got %+v", stopOrder)
+	}
+
+	// the stop is dropped after it's triggered
+	pushOCOTestKLine(exchange, 80, 85, 80)
+	time.Sleep(50 * time.Millisecond)
+	if orders := queryClosedOrders(t, exchange); len(orders) != 2 {
+		t.Fatalf("expected no more orders, got %d", len(orders))
+	}
+}
+
+func TestOCOOrderEmulator_LimitFilled(t *testing.T) {
+	harness, exchange := newOCOTestHarness(t)
+	pushOCOTestKLine(exchange, 99, 101, 100)
+
+	submitOCOTestOrder(t, harness.Session)
+
+	// the limit leg is filled, then the stop price is crossed
+	pushOCOTestKLine(exchange, 100, 111, 110)
+	pushOCOTestKLine(exchange, 80, 85, 80)
+	time.Sleep(50 * time.Millisecond)
+
+	closedOrders := queryClosedOrders(t, exchange)
+	if len(closedOrders) != 1 || closedOrders[0].Status != types.OrderStatusFilled {
+		t.Fatalf("expected only the filled limit leg, got %+v", closedOrders)
+	}
+}
@@ -2,11 +2,18 @@ package engine
 
 import (
 	"context"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"strings"
+	"time"
 )
 
 type OrderExecutor interface {
 	SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (createdOrders types.OrderSlice, err error)
+	CancelOrders(ctx context.Context, orders ...types.Order) error
 	OnTradeUpdate(cb func(trade types.Trade))
 	OnOrderUpdate(cb func(order types.Order))
 	EmitTradeUpdate(trade types.Trade)
@@ -26,12 +33,36 @@ type ExchangeOrderExecutionRouter struct {
 	executors map[string]OrderExecutor
 }
 
+// OrderRetryPolicy submits the orders again when they are rejected by the transient errors,
+// e.g. the network timeouts or the timestamp errors.
+type OrderRetryPolicy struct {
+	MaxRetries int           `json:"maxRetries" yaml:"maxRetries"`
+	Backoff    types.Backoff `json:"backoff" yaml:"backoff"`
+}
+
+// retryClientOrderIDLength is the length of the client order ids generated for the retries, they fit in
+// the 32 characters of the binance spot client order id with the 10 characters of the broker prefix
+const retryClientOrderIDLength = 22
+
+var DefaultOrderRetryPolicy = OrderRetryPolicy{
+	MaxRetries: 3,
+	Backoff: types.Backoff{
+		Initial:    500 * time.Millisecond,
+		Max:        5 * time.Second,
+		Multiplier: 2.0,
+		Jitter:     0.2,
+	},
+}
+
 type ExchangeOrderExecutor struct {
 
 	Notifiability `json:"-" yaml:"-"`
 
 	Session *ExchangeSession `json:"-" yaml:"-"`
 
+	// RetryPolicy is DefaultOrderRetryPolicy if it's nil, set MaxRetries to 0 to disable the retries
+	RetryPolicy *OrderRetryPolicy `json:"retryPolicy,omitempty" yaml:"retryPolicy,omitempty"`
+
 	tradeUpdateCallbacks []func(trade types.Trade)
 
 	orderUpdateCallbacks []func(order types.Order)
@@ -39,7 +70,131 @@ type ExchangeOrderExecutor struct {
 
 func (e *ExchangeOrderExecutor) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (
 	createdOrders types.OrderSlice, err error) {
-	panic("implement me")
+	policy := DefaultOrderRetryPolicy
+	if e.RetryPolicy != nil {
+		policy = *e.RetryPolicy
+	}
+
+	// the orders are filled with the markets and the client order ids, the caller's orders are not modified
+	orders = append([]types.SubmitOrder(nil), orders...)
+
+	for i, order := range orders {
+		if order.Market.Symbol == "" {
+			if market, ok := e.Session.Markets()[order.Symbol]; ok {
+				orders[i].Market = market
+			}
+		}
+
+		// a retried order keeps its client order id, so the exchange rejects it as a duplicate
+		// if the previous request timed out after the order was placed
+		if policy.MaxRetries > 0 && order.ClientOrderId == "" {
+			orders[i].ClientOrderId = strings.ReplaceAll(uuid.New().String(), "-", "")[:retryClientOrderIDLength]
+		}
+	}
+
+	createdOrders, err = e.Session.Exchange.SubmitOrders(ctx, orders...)
+
+	for attempt := 0; err != nil && attempt < policy.MaxRetries; attempt++ {
+		retryOrders, failures := e.splitRetryableOrders(ctx, orders, len(createdOrders), err)
+		if len(retryOrders) == 0 {
+			break
+		}
+
+		delay := policy.Backoff.Duration(attempt)
+		log.WithError(err).Warnf("retrying %d orders in %s, attempt %d/%d", len(retryOrders), delay, attempt+1, policy.MaxRetries)
+
+		select {
+		case <-ctx.Done():
+			return createdOrders, err
+
+		case <-time.After(delay):
+		}
+
+		retriedOrders, retryErr := e.Session.Exchange.SubmitOrders(ctx, retryOrders...)
+		createdOrders = append(createdOrders, retriedOrders...)
+
+		if retryErr == nil && len(failures) == 0 {
+			return createdOrders, nil
+		}
+
+		err = mergeSubmitOrdersError(failures, retryOrders, len(retriedOrders), retryErr)
+	}
+
+	return createdOrders, err
+}
+
+// splitRetryableOrders returns the orders rejected by the transient errors and the failures that can not be retried.
+// The exchanges without *types.SubmitOrdersError stop at the first error, so the orders after the created ones are retried.
+func (e *ExchangeOrderExecutor) splitRetryableOrders(ctx context.Context, orders []types.SubmitOrder, numCreated int, err error) (retryOrders []types.SubmitOrder, failures []types.SubmitOrderFailure) {
+	var submitErr *types.SubmitOrdersError
+	if !errors.As(err, &submitErr) {
+		if numCreated > len(orders) {
+			numCreated = len(orders)
+		}
+
+		for _, order := range orders[numCreated:] {
+			failures = append(failures, types.SubmitOrderFailure{Order: order, Err: err})
+		}
+
+		submitErr = &types.SubmitOrdersError{Failures: failures}
+		failures = nil
+	}
+
+	for _, failure := range submitErr.Failures {
+		if e.isTransientError(ctx, failure.Err) {
+			retryOrders = append(retryOrders, failure.Order)
+		} else {
+			failures = append(failures, failure)
+		}
+	}
+
+	return retryOrders, failures
+}
+
+func (e *ExchangeOrderExecutor) isTransientError(ctx context.Context, err error) bool {
+	// the caller gives up
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if classifier, ok := e.Session.Exchange.(types.ExchangeErrorClassifier); ok && classifier.IsTransientError(err) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// mergeSubmitOrdersError merges the failures that are not retried with the failures of the retry
+func mergeSubmitOrdersError(failures []types.SubmitOrderFailure, retryOrders []types.SubmitOrder, numCreated int, retryErr error) error {
+	if retryErr != nil {
+		var submitErr *types.SubmitOrdersError
+		if errors.As(retryErr, &submitErr) {
+			failures = append(failures, submitErr.Failures...)
+		} else {
+			if numCreated > len(retryOrders) {
+				numCreated = len(retryOrders)
+			}
+
+			for _, order := range retryOrders[numCreated:] {
+				failures = append(failures, types.SubmitOrderFailure{Order: order, Err: retryErr})
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return &types.SubmitOrdersError{Failures: failures}
+}
+
+func (e *ExchangeOrderExecutor) CancelOrders(ctx context.Context, orders ...types.Order) error {
+	return e.Session.Exchange.CancelOrders(ctx, orders...)
 }
 
 func (e *ExchangeOrderExecutor) OnTradeUpdate(cb func(trade types.Trade)) {
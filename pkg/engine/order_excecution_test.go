@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+)
+
+// submitTestExchange creates the submitted orders
+type submitTestExchange struct {
+	types.Exchange
+
+	submitted []types.SubmitOrder
+}
+
+func (e *submitTestExchange) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (createdOrders types.OrderSlice, err error) {
+	for i, o := range orders {
+		e.submitted = append(e.submitted, o)
+		createdOrders = append(createdOrders, types.Order{SubmitOrder: o, OrderID: uint64(i + 1), Status: types.OrderStatusNew})
+	}
+
+	return createdOrders, nil
+}
+
+func TestExchangeOrderExecutor_SubmitOrdersKeepsTheCallerOrders(t *testing.T) {
+	exchange := &submitTestExchange{}
+	market := types.Market{Symbol: "BTCUSDT", BaseCurrency: "BTC", QuoteCurrency: "USDT"}
+	executor := &ExchangeOrderExecutor{
+		Session: &ExchangeSession{
+			Exchange: exchange,
+			markets:  map[string]types.Market{market.Symbol: market},
+		},
+	}
+
+	orders := []types.SubmitOrder{
+		{Symbol: "BTCUSDT", Side: types.SideTypeBuy, Type: types.OrderTypeLimit, Quantity: 1, Price: 100},
+	}
+
+	if _, err := executor.SubmitOrders(context.Background(), orders...); err != nil {
+		t.Fatal(err)
+	}
+
+	if orders[0].ClientOrderId != "" || orders[0].Market.Symbol != "" {
+		t.Errorf("expected the caller's order to be unchanged, got %+v", orders[0])
+	}
+
+	submitted := exchange.submitted[0]
+	if submitted.Market.Symbol != market.Symbol {
+		t.Errorf("expected the market to be filled, got %+v", submitted.Market)
+	}
+
+	// the generated client order id fits the binance broker prefix
+	if len(submitted.ClientOrderId) != retryClientOrderIDLength {
+		t.Errorf("expected a client order id of %d characters, got %q", retryClientOrderIDLength, submitted.ClientOrderId)
+	}
+}
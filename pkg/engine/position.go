@@ -27,6 +27,9 @@ type Position struct {
 
 	ExchangeFeeRates map[types.ExchangeName]ExchangeFee `json:"exchangeFeeRates"`
 
+	// futuresPositions keeps the last position reported by a futures exchange for each position side
+	futuresPositions map[types.PositionSide]types.FuturesPosition
+
 	sync.Mutex
 }
 
@@ -84,6 +87,71 @@ func (p *Position) BindStream(stream types.Stream) {
 			p.AddTrade(trade)
 		}
 	})
+
+	stream.OnPositionUpdate(func(positions []types.FuturesPosition) {
+		p.UpdateFuturesPositions(positions...)
+	})
+}
+
+// UpdateFuturesPositions syncs the position with the positions reported by a futures exchange,
+// in the hedge mode the long and short sides are netted. Once a side is known, only its amount,
+// entry price and unrealized pnl are merged, since the account updates don't carry the mark price,
+// liquidation price and leverage.
+func (p *Position) UpdateFuturesPositions(positions ...types.FuturesPosition) {
+	p.Lock()
+	defer p.Unlock()
+
+	var updated bool
+	for _, position := range positions {
+		if position.Symbol != p.Symbol {
+			continue
+		}
+
+		if p.futuresPositions == nil {
+			p.futuresPositions = make(map[types.PositionSide]types.FuturesPosition)
+		}
+
+		if current, ok := p.futuresPositions[position.PositionSide]; ok {
+			current.Base = position.Base
+			current.EntryPrice = position.EntryPrice
+			current.UnrealizedPnL = position.UnrealizedPnL
+			current.UpdateTime = position.UpdateTime
+			position = current
+		}
+
+		p.futuresPositions[position.PositionSide] = position
+		updated = true
+	}
+
+	if !updated {
+		return
+	}
+
+	var base, cost fixedpoint.Value
+	for _, position := range p.futuresPositions {
+		base += position.Base
+		cost += position.EntryPrice.Mul(position.Base)
+	}
+
+	p.Base = base
+	p.Quote = -cost
+	p.AverageCost = 0
+	if base != 0 {
+		p.AverageCost = cost.Div(base)
+	}
+	p.ApproximateAverageCost = p.AverageCost
+}
+
+// FuturesPositions returns the last positions reported by a futures exchange
+func (p *Position) FuturesPositions() (positions []types.FuturesPosition) {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, position := range p.futuresPositions {
+		positions = append(positions, position)
+	}
+
+	return positions
 }
 
 func (p *Position) AddTrades(trades []types.Trade) (fixedpoint.Value, fixedpoint.Value, bool) {
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+)
+
+func TestPosition_UpdateFuturesPositionsMerge(t *testing.T) {
+	position := &Position{Symbol: "BTCUSDT"}
+
+	position.UpdateFuturesPositions(types.FuturesPosition{
+		Symbol:           "BTCUSDT",
+		PositionSide:     types.PositionSideBoth,
+		Base:             fixedpoint.NewFromFloat(1),
+		EntryPrice:       fixedpoint.NewFromFloat(100),
+		MarkPrice:        fixedpoint.NewFromFloat(101),
+		LiquidationPrice: fixedpoint.NewFromFloat(50),
+		Leverage:         10,
+	})
+
+	// account updates don't carry the mark price, liquidation price and leverage
+	position.UpdateFuturesPositions(types.FuturesPosition{
+		Symbol:        "BTCUSDT",
+		PositionSide:  types.PositionSideBoth,
+		Base:          fixedpoint.NewFromFloat(2),
+		EntryPrice:    fixedpoint.NewFromFloat(110),
+		UnrealizedPnL: fixedpoint.NewFromFloat(5),
+	})
+
+	positions := position.FuturesPositions()
+	if len(positions) != 1 {
+		t.Fatalf("expected 1 position, got %d", len(positions))
+	}
+
+	merged := positions[0]
+	if merged.Base.Float64() != 2 || merged.EntryPrice.Float64() != 110 || merged.UnrealizedPnL.Float64() != 5 {
+		t.Fatalf("account update is not merged: %s", merged)
+	}
+
+	if merged.MarkPrice.Float64() != 101 || merged.LiquidationPrice.Float64() != 50 || merged.Leverage != 10 {
+		t.Fatalf("position risk fields are lost: %s", merged)
+	}
+
+	if position.Base.Float64() != 2 || position.AverageCost.Float64() != 110 {
+		t.Fatalf("unexpected position: %s", position)
+	}
+}
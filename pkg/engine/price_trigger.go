@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"github.com/pymba86/bingo/pkg/types"
+	"sync"
+)
+
+// priceTrigger is called with the prices of its symbol, it returns true once it's done
+type priceTrigger func(price float64) (done bool)
+
+// priceTriggerRegistry dispatches the market prices of the session to the client-side triggers,
+// e.g. the trailing stops and the stop legs of the emulated OCO orders. The stream callbacks are bound once,
+// and the triggers are dropped once they are done, so the finished triggers are not called anymore.
+type priceTriggerRegistry struct {
+	mu       sync.Mutex
+	triggers map[string][]*priceTrigger
+}
+
+func newPriceTriggerRegistry() *priceTriggerRegistry {
+	return &priceTriggerRegistry{triggers: make(map[string][]*priceTrigger)}
+}
+
+// BindStream updates the triggers with the market trades and the kline updates,
+// the symbols need to be subscribed with one of these channels.
+func (r *priceTriggerRegistry) BindStream(stream types.Stream) {
+	stream.OnMarketTrade(func(trade types.Trade) {
+		r.Update(trade.Symbol, trade.Price)
+	})
+
+	stream.OnKLine(func(kline types.KLine) {
+		r.Update(kline.Symbol, kline.Close)
+	})
+}
+
+// Add registers the trigger of the symbol
+func (r *priceTriggerRegistry) Add(symbol string, trigger priceTrigger) {
+	r.mu.Lock()
+	r.triggers[symbol] = append(r.triggers[symbol], &trigger)
+	r.mu.Unlock()
+}
+
+// Len returns the number of the triggers of the symbol
+func (r *priceTriggerRegistry) Len(symbol string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.triggers[symbol])
+}
+
+// Update calls the triggers of the symbol with the price and drops the triggers that are done
+func (r *priceTriggerRegistry) Update(symbol string, price float64) {
+	if price <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	triggers := append([]*priceTrigger(nil), r.triggers[symbol]...)
+	r.mu.Unlock()
+
+	if len(triggers) == 0 {
+		return
+	}
+
+	// the triggers are called without the lock, so they can add the new triggers
+	done := make(map[*priceTrigger]struct{})
+	for _, trigger := range triggers {
+		if (*trigger)(price) {
+			done[trigger] = struct{}{}
+		}
+	}
+
+	if len(done) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var remaining []*priceTrigger
+	for _, trigger := range r.triggers[symbol] {
+		if _, ok := done[trigger]; !ok {
+			remaining = append(remaining, trigger)
+		}
+	}
+
+	if len(remaining) == 0 {
+		delete(r.triggers, symbol)
+	} else {
+		r.triggers[symbol] = remaining
+	}
+}
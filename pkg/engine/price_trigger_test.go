@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestPriceTriggerRegistry(t *testing.T) {
+	registry := newPriceTriggerRegistry()
+
+	var prices []float64
+	registry.Add("BTCUSDT", func(price float64) bool {
+		prices = append(prices, price)
+		return price < 90
+	})
+
+	var ethPrices []float64
+	registry.Add("ETHUSDT", func(price float64) bool {
+		ethPrices = append(ethPrices, price)
+		return false
+	})
+
+	registry.Update("BTCUSDT", 100)
+	registry.Update("BTCUSDT", 0)
+	registry.Update("BTCUSDT", 89)
+	registry.Update("BTCUSDT", 80)
+
+	if len(prices) != 2 || prices[0] != 100 || prices[1] != 89 {
+		t.Fatalf("unexpected prices: %v", prices)
+	}
+
+	if registry.Len("BTCUSDT") != 0 {
+		t.Fatalf("expected the done trigger to be dropped")
+	}
+
+	if len(ethPrices) != 0 || registry.Len("ETHUSDT") != 1 {
+		t.Fatalf("expected the ETHUSDT trigger to be kept")
+	}
+}
+
+func TestTrailingStop_DroppedWhenCanceled(t *testing.T) {
+	session := &ExchangeSession{priceTriggers: newPriceTriggerRegistry()}
+
+	trailingStop, err := session.SubmitTrailingStopOrder(TrailingStopOrder{
+		Symbol:       "BTCUSDT",
+		Side:         "SELL",
+		Quantity:     1.0,
+		CallbackRate: 0.01,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session.priceTriggers.Update("BTCUSDT", 100)
+	if trailingStop.StopPrice() != 99 || session.priceTriggers.Len("BTCUSDT") != 1 {
+		t.Fatalf("expected the trailing stop to be activated, stop price %f", trailingStop.StopPrice())
+	}
+
+	trailingStop.Cancel()
+	session.priceTriggers.Update("BTCUSDT", 100)
+	if session.priceTriggers.Len("BTCUSDT") != 0 {
+		t.Fatalf("expected the canceled trailing stop to be dropped")
+	}
+}
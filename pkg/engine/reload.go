@@ -0,0 +1,307 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reloadable is implemented by the strategies that can apply new parameters without being restarted.
+// newConfig is the strategy struct re-unmarshalled from the changed config, it has the same type as the strategy.
+type Reloadable interface {
+	Reload(ctx context.Context, newConfig interface{}) error
+}
+
+// InstanceIDProvider is implemented by the strategies that can run multiple instances on the same session,
+// the instance ID is used for matching the running strategy with the strategy in the reloaded config.
+type InstanceIDProvider interface {
+	InstanceID() string
+}
+
+const strategyShutdownTimeout = 30 * time.Second
+
+// liveSubscriber is implemented by the streams that can subscribe the channels on the current connection
+type liveSubscriber interface {
+	SubscribeLive(subscriptions ...types.Subscription) error
+}
+
+// subscriptionRecorder records the channels subscribed by a strategy
+type subscriptionRecorder struct {
+	types.Stream
+
+	subscriptions []types.Subscription
+}
+
+func (r *subscriptionRecorder) Subscribe(channel types.Channel, symbol string, options types.SubscribeOptions) {
+	r.Stream.Subscribe(channel, symbol, options)
+	r.subscriptions = append(r.subscriptions, types.Subscription{Channel: channel, Symbol: symbol, Options: options})
+}
+
+// strategyInstance is a single exchange strategy mounted on a session
+type strategyInstance struct {
+	session  string
+	key      string
+	strategy SingleExchangeStrategy
+
+	// config is the marshalled strategy parameters, it's used for detecting the parameter changes
+	config []byte
+
+	// guard drops the callbacks of the strategy once it's stopped
+	guard *strategyGuard
+
+	graceful *Graceful
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+}
+
+func newStrategyInstance(session string, strategy SingleExchangeStrategy, keys map[string]int) *strategyInstance {
+	config, err := json.Marshal(strategy)
+	if err != nil {
+		log.WithError(err).Errorf("can not marshal the config of strategy %s", strategy.Id())
+	}
+
+	return &strategyInstance{
+		session:  session,
+		key:      strategyInstanceKey(session, strategy, keys),
+		strategy: strategy,
+		config:   config,
+	}
+}
+
+// strategyInstanceKey returns the key of the strategy instance, e.g. "binance/grid:BTCUSDT".
+// keys counts the used keys, so that the instances without an instance ID are numbered in the config order.
+func strategyInstanceKey(session string, strategy SingleExchangeStrategy, keys map[string]int) string {
+	key := session + "/" + strategy.Id()
+
+	if provider, ok := strategy.(InstanceIDProvider); ok {
+		key += ":" + provider.InstanceID()
+	} else if rs := reflect.ValueOf(strategy); rs.Kind() == reflect.Ptr && rs.Elem().Kind() == reflect.Struct {
+		if symbol, ok := isSymbolBasedStrategy(rs.Elem()); ok {
+			key += ":" + symbol
+		}
+	}
+
+	n := keys[key]
+	keys[key] = n + 1
+	if n > 0 {
+		key += fmt.Sprintf("#%d", n)
+	}
+
+	return key
+}
+
+// stop deactivates the strategy and cancels its context after the shutdown callbacks of the strategy are done
+func (instance *strategyInstance) stop(ctx context.Context) {
+	instance.stopOnce.Do(func() {
+		if instance.graceful != nil {
+			instance.graceful.Shutdown(ctx)
+		}
+
+		instance.deactivate()
+	})
+}
+
+// shutdown stops the strategy, the strategy is deactivated after the timeout even if its shutdown callbacks are not done
+func (instance *strategyInstance) shutdown(ctx context.Context, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		instance.stop(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Warnf("strategy %s shutdown timed out after %s", instance.key, timeout)
+		instance.deactivate()
+	}
+}
+
+func (instance *strategyInstance) deactivate() {
+	if instance.guard != nil {
+		instance.guard.deactivate()
+	}
+
+	if instance.cancel != nil {
+		instance.cancel()
+	}
+}
+
+func (trader *Trader) startStrategyInstance(ctx context.Context, instance *strategyInstance) error {
+	session, ok := trader.environment.sessions[instance.session]
+	if !ok {
+		return fmt.Errorf("session %s is not defined", instance.session)
+	}
+
+	strategyCtx, cancel := context.WithCancel(ctx)
+	instance.cancel = cancel
+	instance.graceful = &Graceful{}
+	instance.guard = newStrategyGuard()
+
+	trader.Graceful.OnShutdown(func(ctx context.Context, wg *sync.WaitGroup) {
+		defer wg.Done()
+		instance.stop(ctx)
+	})
+
+	// the orders of the strategy are tagged with the strategy instance for the trade attribution,
+	// and the callbacks of the strategy are guarded so that the strategy can be stopped on reload
+	executor := &guardedOrderExecutor{OrderExecutor: trader.getSessionOrderExecutor(instance.session), guard: instance.guard}
+	orderExecutor := NewStrategyOrderExecutor(executor, session, instance.strategy.Id(), instance.instanceID())
+	return trader.runSingleExchangeStrategy(strategyCtx, instance.strategy, instance.guard.guardSession(session), orderExecutor, instance.graceful)
+}
+
+// subscribeAddedStrategy subscribes the channels of the strategy added to the running session,
+// the symbols of the channels are initialized and the channels are subscribed on the current connection.
+func (trader *Trader) subscribeAddedStrategy(ctx context.Context, session *ExchangeSession, strategy SingleExchangeStrategy) error {
+	recorder := &subscriptionRecorder{Stream: session.MarketDataStream}
+	recordingSession := *session
+	recordingSession.MarketDataStream = recorder
+	subscribeStrategy(&recordingSession, strategy)
+
+	if len(recorder.subscriptions) == 0 {
+		return nil
+	}
+
+	for _, subscription := range recorder.subscriptions {
+		if len(subscription.Symbol) == 0 {
+			continue
+		}
+
+		session.usedSymbols[subscription.Symbol] = struct{}{}
+		if err := session.initSymbol(ctx, trader.environment, subscription.Symbol); err != nil {
+			return err
+		}
+	}
+
+	subscriber, ok := session.MarketDataStream.(liveSubscriber)
+	if !ok {
+		log.Warnf("the market data stream of session %s can not subscribe on the connection, please restart to subscribe %+v",
+			session.Name, recorder.subscriptions)
+		return nil
+	}
+
+	return subscriber.SubscribeLive(recorder.subscriptions...)
+}
+
+// instanceID returns the instance part of the key, e.g. "BTCUSDT" of "binance/grid:BTCUSDT"
+func (instance *strategyInstance) instanceID() string {
+	id := strings.TrimPrefix(instance.key, instance.session+"/"+instance.strategy.Id())
+	return strings.TrimPrefix(id, ":")
+}
+
+// Reload applies the changed strategy parameters of the given config.
+// The changed strategies that implement Reloadable are reloaded, the added strategies are started,
+// and the removed strategies are gracefully stopped. The channels of the added strategies are subscribed
+// on the connected market data streams.
+// An invalid config is rejected as a whole, and the running strategies are left untouched.
+func (trader *Trader) Reload(ctx context.Context, userConfig *Config) error {
+	if len(userConfig.CrossExchangeStrategies) > 0 {
+		log.Warnf("reloading cross exchange strategies is not supported, please restart to apply the changes")
+	}
+
+	var keys = make(map[string]int)
+	var newInstances []*strategyInstance
+
+	for _, entry := range userConfig.ExchangeStrategies {
+		for _, mount := range entry.Mounts {
+			if _, ok := trader.environment.sessions[mount]; !ok {
+				return fmt.Errorf("session %s is not defined", mount)
+			}
+
+			if v, ok := entry.Strategy.(Validator); ok {
+				if err := v.Validate(); err != nil {
+					return errors.Wrapf(err, "failed to validate the config of strategy %s", entry.Strategy.Id())
+				}
+			}
+
+			newInstances = append(newInstances, newStrategyInstance(mount, entry.Strategy, keys))
+		}
+	}
+
+	trader.instanceMu.Lock()
+	defer trader.instanceMu.Unlock()
+
+	var current = make(map[string]*strategyInstance)
+	for _, instance := range trader.instances {
+		current[instance.key] = instance
+	}
+
+	var instances []*strategyInstance
+	var added []*strategyInstance
+	for _, newInstance := range newInstances {
+		instance, ok := current[newInstance.key]
+		if !ok {
+			added = append(added, newInstance)
+			continue
+		}
+
+		delete(current, newInstance.key)
+		instances = append(instances, instance)
+
+		if bytes.Equal(instance.config, newInstance.config) {
+			continue
+		}
+
+		reloadable, ok := instance.strategy.(Reloadable)
+		if !ok {
+			// the config is stored so that the same change is not warned on every reload
+			log.Warnf("strategy %s does not implement Reloadable, please restart to apply the changes", instance.key)
+			instance.config = newInstance.config
+			continue
+		}
+
+		log.Infof("reloading strategy %s...", instance.key)
+		if err := reloadable.Reload(ctx, newInstance.strategy); err != nil {
+			log.WithError(err).Errorf("strategy %s reload error", instance.key)
+			trader.environment.Notify("strategy %s reload is rejected: %v", instance.key, err)
+			continue
+		}
+
+		instance.config = newInstance.config
+	}
+
+	// stop the removed strategies, their callbacks are dropped by the guards since they can not be unbound
+	for _, instance := range current {
+		log.Infof("stopping removed strategy %s...", instance.key)
+		instance.shutdown(ctx, strategyShutdownTimeout)
+		trader.environment.Notify("strategy %s is removed from the config and stopped", instance.key)
+	}
+
+	// start the added strategies
+	for _, instance := range added {
+		log.Infof("starting added strategy %s...", instance.key)
+		session := trader.environment.sessions[instance.session]
+		if err := trader.subscribeAddedStrategy(ctx, session, instance.strategy); err != nil {
+			log.WithError(err).Errorf("strategy %s subscribe error", instance.key)
+			trader.environment.Notify("strategy %s subscribe error: %v", instance.key, err)
+			continue
+		}
+
+		if err := trader.startStrategyInstance(ctx, instance); err != nil {
+			log.WithError(err).Errorf("strategy %s start error", instance.key)
+			trader.environment.Notify("strategy %s start error: %v", instance.key, err)
+			continue
+		}
+
+		instances = append(instances, instance)
+	}
+
+	trader.instances = instances
+	trader.exchangeStrategies = make(map[string][]SingleExchangeStrategy)
+	for _, instance := range instances {
+		trader.exchangeStrategies[instance.session] = append(trader.exchangeStrategies[instance.session], instance.strategy)
+	}
+
+	return nil
+}
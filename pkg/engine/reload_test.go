@@ -0,0 +1,157 @@
+package engine_test
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/engine"
+	"github.com/pymba86/bingo/pkg/exchange/mock"
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+	"time"
+)
+
+type reloadTestStrategy struct {
+	Symbol string `json:"symbol"`
+	Param  int    `json:"param"`
+
+	ctx    context.Context
+	klines int
+}
+
+func (s *reloadTestStrategy) Id() string {
+	return "reloadTest"
+}
+
+func (s *reloadTestStrategy) Subscribe(session *engine.ExchangeSession) {
+	session.MarketDataStream.Subscribe(types.KLineChannel, s.Symbol, types.SubscribeOptions{Interval: "1m"})
+}
+
+func (s *reloadTestStrategy) Run(ctx context.Context, orderExecutor engine.OrderExecutor, session *engine.ExchangeSession) error {
+	s.ctx = ctx
+	session.MarketDataStream.OnKLineClosed(func(kline types.KLine) {
+		if kline.Symbol == s.Symbol {
+			s.klines++
+		}
+	})
+	return nil
+}
+
+func reloadTestConfig(strategies ...engine.SingleExchangeStrategy) *engine.Config {
+	config := &engine.Config{}
+	for _, strategy := range strategies {
+		config.ExchangeStrategies = append(config.ExchangeStrategies, engine.ExchangeStrategyMount{
+			Mounts:   []string{mock.SessionName},
+			Strategy: strategy,
+		})
+	}
+
+	return config
+}
+
+func reloadTestMarket(symbol, baseCurrency string) types.Market {
+	return types.Market{
+		Symbol:          symbol,
+		PricePrecision:  2,
+		VolumePrecision: 6,
+		BaseCurrency:    baseCurrency,
+		QuoteCurrency:   "USDT",
+		MinQuantity:     0.000001,
+		StepSize:        0.000001,
+		TickSize:        0.01,
+	}
+}
+
+func TestTrader_Reload(t *testing.T) {
+	ctx := context.Background()
+
+	exchange := mock.New()
+	exchange.AddMarkets(reloadTestMarket("BTCUSDT", "BTC"), reloadTestMarket("ETHUSDT", "ETH"))
+
+	harness, err := mock.NewHarness(ctx, exchange)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	btc := &reloadTestStrategy{Symbol: "BTCUSDT", Param: 1}
+	if err := harness.Run(ctx, btc); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		_ = harness.Shutdown(ctx)
+	}()
+
+	stream := harness.Session.MarketDataStream.(*mock.Stream)
+	stream.ReconnectC = make(chan types.ConnectionReason, 1)
+
+	// the added strategy is started, and its channels are subscribed on the connected stream
+	eth := &reloadTestStrategy{Symbol: "ETHUSDT", Param: 1}
+	if err := harness.Trader.Reload(ctx, reloadTestConfig(&reloadTestStrategy{Symbol: "BTCUSDT", Param: 2}, eth)); err != nil {
+		t.Fatal(err)
+	}
+
+	if eth.ctx == nil {
+		t.Fatal("expected the added strategy to be started")
+	}
+
+	if len(stream.LiveSubscriptions) != 1 || stream.LiveSubscriptions[0].Symbol != "ETHUSDT" {
+		t.Fatalf("expected ETHUSDT to be subscribed on the connected stream, got %+v", stream.LiveSubscriptions)
+	}
+
+	var subscribed bool
+	for _, subscription := range stream.Subscriptions {
+		subscribed = subscribed || subscription.Symbol == "ETHUSDT"
+	}
+
+	if !subscribed {
+		t.Fatal("expected ETHUSDT to be subscribed again on reconnect")
+	}
+
+	if _, ok := harness.Session.Trades["ETHUSDT"]; !ok {
+		t.Fatal("expected the symbol of the added strategy to be initialized")
+	}
+
+	if len(stream.ReconnectC) != 0 {
+		t.Fatal("expected the market data stream not to be reconnected")
+	}
+
+	// the unchanged config does not subscribe again
+	if err := harness.Trader.Reload(ctx, reloadTestConfig(&reloadTestStrategy{Symbol: "BTCUSDT", Param: 2}, eth)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stream.LiveSubscriptions) != 1 {
+		t.Fatal("expected no subscription without the added strategies")
+	}
+
+	// the removed strategy is stopped, and its callbacks are dropped
+	if err := harness.Trader.Reload(ctx, reloadTestConfig(eth)); err != nil {
+		t.Fatal(err)
+	}
+
+	if btc.ctx.Err() == nil {
+		t.Fatal("expected the removed strategy to be stopped")
+	}
+
+	startTime := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, symbol := range []string{"BTCUSDT", "ETHUSDT"} {
+		exchange.PushKLine(types.KLine{
+			Symbol:    symbol,
+			Interval:  types.Interval1m,
+			StartTime: startTime,
+			EndTime:   startTime.Add(time.Minute),
+			Open:      100,
+			High:      100,
+			Low:       100,
+			Close:     100,
+			Closed:    true,
+		})
+	}
+
+	if btc.klines != 0 {
+		t.Fatalf("expected the removed strategy not to receive klines, got %d", btc.klines)
+	}
+
+	if eth.klines != 1 {
+		t.Fatalf("expected the running strategy to receive 1 kline, got %d", eth.klines)
+	}
+}
@@ -3,12 +3,15 @@ package engine
 import (
 	"context"
 	"fmt"
+	"github.com/pkg/errors"
 	"github.com/pymba86/bingo/pkg/cmdutil"
+	"github.com/pymba86/bingo/pkg/exchange/paper"
 	"github.com/pymba86/bingo/pkg/fixedpoint"
 	"github.com/pymba86/bingo/pkg/service"
 	"github.com/pymba86/bingo/pkg/types"
 	"github.com/pymba86/bingo/pkg/util"
 	log "github.com/sirupsen/logrus"
+	"io/ioutil"
 	"strings"
 	"time"
 )
@@ -42,10 +45,20 @@ type ExchangeSession struct {
 	EnvVarPrefix string             `json:"envVarPrefix" yaml:"envVarPrefix"`
 	Key          string             `json:"key,omitempty" yaml:"key,omitempty"`
 	Secret       string             `json:"secret,omitempty" yaml:"secret,omitempty"`
+	Passphrase   string             `json:"passphrase,omitempty" yaml:"passphrase,omitempty"`
 	SubAccount   string             `json:"subAccount,omitempty" yaml:"subAccount,omitempty"`
 
+	// KeyFile, SecretFile and PassphraseFile are the files that contain the api credentials, e.g. docker or kubernetes secrets
+	KeyFile        string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+	SecretFile     string `json:"secretFile,omitempty" yaml:"secretFile,omitempty"`
+	PassphraseFile string `json:"passphraseFile,omitempty" yaml:"passphraseFile,omitempty"`
+
 	// Withdrawal is used for enabling withdrawal functions
-	Withdrawal   bool             `json:"withdrawal,omitempty" yaml:"withdrawal,omitempty"`
+	Withdrawal bool `json:"withdrawal,omitempty" yaml:"withdrawal,omitempty"`
+
+	// WithdrawalPolicy is the address allowlist and the daily limits used by the session withdrawer
+	WithdrawalPolicy *WithdrawalPolicy `json:"withdrawalPolicy,omitempty" yaml:"withdrawalPolicy,omitempty"`
+
 	MakerFeeRate fixedpoint.Value `json:"makerFeeRate,omitempty" yaml:"makerFeeRate,omitempty"`
 	TakerFeeRate fixedpoint.Value `json:"takerFeeRate,omitempty" yaml:"takerFeeRate,omitempty"`
 
@@ -54,6 +67,35 @@ type ExchangeSession struct {
 	IsolatedMargin       bool   `json:"isolatedMargin,omitempty" yaml:"isolatedMargin,omitempty"`
 	IsolatedMarginSymbol string `json:"isolatedMarginSymbol,omitempty" yaml:"isolatedMarginSymbol,omitempty"`
 
+	// MarginLevelThreshold sends a notification when the margin level of the margin session falls below it
+	MarginLevelThreshold fixedpoint.Value `json:"marginLevelThreshold,omitempty" yaml:"marginLevelThreshold,omitempty"`
+
+	// MarginLevelCheckInterval is the interval of the margin level checks, defaults to 1 minute
+	MarginLevelCheckInterval time.Duration `json:"marginLevelCheckInterval,omitempty" yaml:"marginLevelCheckInterval,omitempty"`
+
+	// StreamReconnectBackoff is the delay policy between the stream reconnect attempts
+	StreamReconnectBackoff *types.Backoff `json:"streamReconnectBackoff,omitempty" yaml:"streamReconnectBackoff,omitempty"`
+
+	// MarketDataStallTimeout reconnects the market data stream when no message is received within it
+	MarketDataStallTimeout time.Duration `json:"marketDataStallTimeout,omitempty" yaml:"marketDataStallTimeout,omitempty"`
+
+	// Futures switches the session to the USDⓈ-M futures account
+	Futures bool `json:"futures,omitempty" yaml:"futures,omitempty"`
+
+	// Testnet switches the session to the testnet endpoints of the exchange
+	Testnet bool `json:"testnet,omitempty" yaml:"testnet,omitempty"`
+
+	// RestBaseURL and WsBaseURL override the REST and the websocket endpoints, e.g. for a local mock server
+	RestBaseURL string `json:"restBaseURL,omitempty" yaml:"restBaseURL,omitempty"`
+	WsBaseURL   string `json:"wsBaseURL,omitempty" yaml:"wsBaseURL,omitempty"`
+
+	// PaperTrade keeps the live market data of the exchange, and simulates the orders, the balances
+	// and the user data stream locally. The symbols need the book, the book ticker or the trade subscription for the fills.
+	PaperTrade bool `json:"paperTrade,omitempty" yaml:"paperTrade,omitempty"`
+
+	// PaperTradeBalances are the virtual balances that the paper trading session starts with
+	PaperTradeBalances map[string]fixedpoint.Value `json:"paperTradeBalances,omitempty" yaml:"paperTradeBalances,omitempty"`
+
 	// ---------------------------
 	// Runtime fields
 	// ---------------------------
@@ -99,6 +141,15 @@ type ExchangeSession struct {
 
 	orderStores map[string]*OrderStore
 
+	withdrawer *Withdrawer
+
+	ocoOrderEmulator *OCOOrderEmulator
+
+	// priceTriggers are the client-side stops driven by the market data stream
+	priceTriggers *priceTriggerRegistry
+
+	tradeAttributor *TradeAttributor
+
 	usedSymbols        map[string]struct{}
 	initializedSymbols map[string]struct{}
 
@@ -109,14 +160,24 @@ func InitExchangeSession(name string, session *ExchangeSession) error {
 	var err error
 	var exchangeName = session.ExchangeName
 	var exchange types.Exchange
+
+	if err := session.loadCredentialFiles(); err != nil {
+		return errors.Wrapf(err, "session %s", name)
+	}
+
 	if session.Key != "" && session.Secret != "" {
 		if !session.PublicOnly {
-			if len(session.Key) == 0 || len(session.Secret) == 0 {
-				return fmt.Errorf("can not create exchange %s: empty key or secret", exchangeName)
+			if r, ok := types.LookupExchange(exchangeName.String()); ok {
+				if err := r.ValidateCredentials(session.credentials()); err != nil {
+					return fmt.Errorf("can not create exchange %s: %v", exchangeName, err)
+				}
 			}
 		}
 
-		exchange, err = cmdutil.NewExchangeStandard(exchangeName, session.Key, session.Secret)
+		exchange, err = cmdutil.NewExchangeStandard(exchangeName, session.credentials())
+	} else if session.PaperTrade {
+		// the paper trading session only uses the public market data
+		exchange, err = cmdutil.NewExchangeStandard(exchangeName, types.ExchangeCredentials{})
 	} else {
 		exchange, err = cmdutil.NewExchangeWithEnvVarPrefix(exchangeName, session.EnvVarPrefix)
 	}
@@ -125,6 +186,14 @@ func InitExchangeSession(name string, session *ExchangeSession) error {
 		return err
 	}
 
+	return InitExchangeSessionWithExchange(name, session, exchange)
+}
+
+// InitExchangeSessionWithExchange initializes the session on the given exchange instance instead of
+// creating it by the exchange name, e.g. the mock exchange of the strategy tests
+func InitExchangeSessionWithExchange(name string, session *ExchangeSession, exchange types.Exchange) error {
+	var exchangeName = exchange.Name()
+
 	// configure exchange
 	if session.Margin {
 		marginExchange, ok := exchange.(types.MarginExchange)
@@ -139,7 +208,59 @@ func InitExchangeSession(name string, session *ExchangeSession) error {
 		}
 	}
 
+	if session.Futures {
+		if session.Margin {
+			return fmt.Errorf("session %s: futures and margin can not be enabled at the same time", name)
+		}
+
+		futuresExchange, ok := exchange.(types.FuturesExchange)
+		if !ok {
+			return fmt.Errorf("exchange %s does not support futures", exchangeName)
+		}
+
+		futuresExchange.UseFutures()
+	}
+
+	if session.Testnet || len(session.RestBaseURL) > 0 || len(session.WsBaseURL) > 0 {
+		endpointExchange, ok := exchange.(types.EndpointExchange)
+		if !ok {
+			return fmt.Errorf("exchange %s does not support custom endpoints", exchangeName)
+		}
+
+		if session.Testnet {
+			endpointExchange.UseTestnet()
+		}
+
+		if len(session.RestBaseURL) > 0 || len(session.WsBaseURL) > 0 {
+			endpointExchange.UseEndpoints(session.RestBaseURL, session.WsBaseURL)
+		}
+	}
+
+	var paperExchange *paper.Exchange
+	if session.PaperTrade {
+		if session.Margin || session.Futures {
+			return fmt.Errorf("session %s: paper trading only supports the spot account", name)
+		}
+
+		balances := make(types.BalanceMap)
+		for currency, amount := range session.PaperTradeBalances {
+			balances[currency] = types.Balance{Currency: currency, Available: amount}
+		}
+
+		paperExchange = paper.New(exchange, balances)
+		if session.MakerFeeRate > 0 {
+			paperExchange.MakerFeeRate = session.MakerFeeRate.Float64()
+		}
+
+		if session.TakerFeeRate > 0 {
+			paperExchange.TakerFeeRate = session.TakerFeeRate.Float64()
+		}
+
+		exchange = paperExchange
+	}
+
 	session.Name = name
+	session.ExchangeName = exchangeName
 	session.Notifiability = Notifiability{
 		SymbolChannelRouter:  NewPatternChannelRouter(nil),
 		SessionChannelRouter: NewPatternChannelRouter(nil),
@@ -150,6 +271,24 @@ func InitExchangeSession(name string, session *ExchangeSession) error {
 	session.MarketDataStream = exchange.NewStream()
 	session.MarketDataStream.SetPublicOnly()
 
+	// the simulated user data stream is driven by the live market data,
+	// the book tickers of the subscribed symbols are subscribed for matching the orders
+	if paperExchange != nil {
+		session.UserDataStream = paperExchange.UserDataStream()
+		paperExchange.BindMarketData(session.MarketDataStream)
+		session.MarketDataStream = paper.NewMarketDataStream(session.MarketDataStream)
+	}
+
+	if session.StreamReconnectBackoff != nil {
+		session.UserDataStream.SetReconnectBackoff(*session.StreamReconnectBackoff)
+		session.MarketDataStream.SetReconnectBackoff(*session.StreamReconnectBackoff)
+	}
+
+	// the user data stream can be quiet for a long time, so the watchdog is only enabled for the market data stream
+	if session.MarketDataStallTimeout > 0 {
+		session.MarketDataStream.SetStallTimeout(session.MarketDataStallTimeout)
+	}
+
 	// pointer fields
 	session.Subscriptions = make(map[types.Subscription]types.Subscription)
 	session.Account = &types.Account{}
@@ -169,12 +308,68 @@ func InitExchangeSession(name string, session *ExchangeSession) error {
 		Session:       session,
 	}
 
+	session.withdrawer = NewWithdrawer(session)
+	session.priceTriggers = newPriceTriggerRegistry()
+	session.priceTriggers.BindStream(session.MarketDataStream)
+	session.ocoOrderEmulator = NewOCOOrderEmulator(session)
+	session.tradeAttributor = NewTradeAttributor(session)
+
 	session.usedSymbols = make(map[string]struct{})
 	session.initializedSymbols = make(map[string]struct{})
 	session.logger = log.WithField("session", name)
 	return nil
 }
 
+func (session *ExchangeSession) credentials() types.ExchangeCredentials {
+	return types.ExchangeCredentials{
+		Key:        session.Key,
+		Secret:     session.Secret,
+		Passphrase: session.Passphrase,
+		SubAccount: session.SubAccount,
+	}
+}
+
+// loadCredentialFiles reads the api key, secret and passphrase from the credential files
+func (session *ExchangeSession) loadCredentialFiles() error {
+	if len(session.KeyFile) > 0 {
+		key, err := readSecretFile(session.KeyFile)
+		if err != nil {
+			return errors.Wrap(err, "can not read the key file")
+		}
+
+		session.Key = key
+	}
+
+	if len(session.SecretFile) > 0 {
+		secret, err := readSecretFile(session.SecretFile)
+		if err != nil {
+			return errors.Wrap(err, "can not read the secret file")
+		}
+
+		session.Secret = secret
+	}
+
+	if len(session.PassphraseFile) > 0 {
+		passphrase, err := readSecretFile(session.PassphraseFile)
+		if err != nil {
+			return errors.Wrap(err, "can not read the passphrase file")
+		}
+
+		session.Passphrase = passphrase
+	}
+
+	return nil
+}
+
+func readSecretFile(filename string) (string, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
 func (session *ExchangeSession) Init(ctx context.Context, environ *Environment) error {
 	if session.IsInitialized {
 		return ErrSessionAlreadyInitialized
@@ -201,10 +396,22 @@ func (session *ExchangeSession) Init(ctx context.Context, environ *Environment)
 
 	session.Account.UpdateBalances(balances)
 
+	if environ.WithdrawalRequestService != nil {
+		session.withdrawer.Store = environ.WithdrawalRequestService
+	}
+
 	// forward trade updates and order updates to the order executor
 	session.UserDataStream.OnTradeUpdate(session.OrderExecutor.EmitTradeUpdate)
 	session.UserDataStream.OnOrderUpdate(session.OrderExecutor.EmitOrderUpdate)
 	session.Account.BindStream(session.UserDataStream)
+	session.ocoOrderEmulator.BindStream(session.UserDataStream)
+
+	// attribute the trades to the strategies by the strategy tags of the orders
+	// the simulated trades of the paper trading session are not recorded
+	if !session.PaperTrade {
+		session.tradeAttributor.tradeService = environ.TradeService
+	}
+	session.tradeAttributor.BindStream(session.UserDataStream)
 
 	session.MarketDataStream.OnKLineClosed(func(kline types.KLine) {
 		log.WithField("marketData", "kline").Infof("kline closed: %+v", kline)
@@ -283,6 +490,18 @@ func (session *ExchangeSession) initSymbol(ctx context.Context, environ *Environ
 		QuoteCurrency: market.QuoteCurrency,
 	}
 	position.AddTrades(trades)
+
+	if session.Futures {
+		if futuresExchange, ok := session.Exchange.(types.FuturesExchange); ok {
+			positions, err := futuresExchange.QueryPositions(ctx, symbol)
+			if err != nil {
+				return err
+			}
+
+			position.UpdateFuturesPositions(positions...)
+		}
+	}
+
 	position.BindStream(session.UserDataStream)
 	session.positions[symbol] = position
 
@@ -331,6 +550,16 @@ func (session *ExchangeSession) FindPossibleSymbols() (symbols []string, err err
 	return symbols, nil
 }
 
+// Withdrawer returns the withdrawer of the session, the withdrawals are rejected unless withdrawal is enabled
+func (session *ExchangeSession) Withdrawer() *Withdrawer {
+	return session.withdrawer
+}
+
+// TradeAttributor returns the attribution of the session trades to the strategies
+func (session *ExchangeSession) TradeAttributor() *TradeAttributor {
+	return session.tradeAttributor
+}
+
 func (session *ExchangeSession) Markets() map[string]types.Market {
 	return session.markets
 }
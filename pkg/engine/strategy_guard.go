@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/types"
+	"sync/atomic"
+)
+
+// strategyGuard drops the callbacks of a stopped strategy,
+// since the callbacks bound to the session streams and the order executor can not be unbound.
+type strategyGuard struct {
+	active int32
+}
+
+func newStrategyGuard() *strategyGuard {
+	return &strategyGuard{active: 1}
+}
+
+func (g *strategyGuard) isActive() bool {
+	return atomic.LoadInt32(&g.active) == 1
+}
+
+func (g *strategyGuard) deactivate() {
+	atomic.StoreInt32(&g.active, 0)
+}
+
+// guardSession returns a copy of the session for the strategy, the callbacks the strategy binds to
+// the streams of the copy are only called while the guard is active
+func (g *strategyGuard) guardSession(session *ExchangeSession) *ExchangeSession {
+	guarded := *session
+	guarded.UserDataStream = &guardedStream{Stream: session.UserDataStream, guard: g}
+	guarded.MarketDataStream = &guardedStream{Stream: session.MarketDataStream, guard: g}
+	return &guarded
+}
+
+// guardedStream guards the callbacks bound to the stream
+type guardedStream struct {
+	types.Stream
+
+	guard *strategyGuard
+}
+
+func (s *guardedStream) OnStart(cb func()) {
+	s.Stream.OnStart(func() {
+		if s.guard.isActive() {
+			cb()
+		}
+	})
+}
+
+func (s *guardedStream) OnConnect(cb func(reason types.ConnectionReason)) {
+	s.Stream.OnConnect(func(reason types.ConnectionReason) {
+		if s.guard.isActive() {
+			cb(reason)
+		}
+	})
+}
+
+func (s *guardedStream) OnDisconnect(cb func(reason types.ConnectionReason)) {
+	s.Stream.OnDisconnect(func(reason types.ConnectionReason) {
+		if s.guard.isActive() {
+			cb(reason)
+		}
+	})
+}
+
+func (s *guardedStream) OnTradeUpdate(cb func(trade types.Trade)) {
+	s.Stream.OnTradeUpdate(func(trade types.Trade) {
+		if s.guard.isActive() {
+			cb(trade)
+		}
+	})
+}
+
+func (s *guardedStream) OnOrderUpdate(cb func(order types.Order)) {
+	s.Stream.OnOrderUpdate(func(order types.Order) {
+		if s.guard.isActive() {
+			cb(order)
+		}
+	})
+}
+
+func (s *guardedStream) OnBalanceSnapshot(cb func(balances types.BalanceMap)) {
+	s.Stream.OnBalanceSnapshot(func(balances types.BalanceMap) {
+		if s.guard.isActive() {
+			cb(balances)
+		}
+	})
+}
+
+func (s *guardedStream) OnBalanceUpdate(cb func(balances types.BalanceMap)) {
+	s.Stream.OnBalanceUpdate(func(balances types.BalanceMap) {
+		if s.guard.isActive() {
+			cb(balances)
+		}
+	})
+}
+
+func (s *guardedStream) OnPositionUpdate(cb func(positions []types.FuturesPosition)) {
+	s.Stream.OnPositionUpdate(func(positions []types.FuturesPosition) {
+		if s.guard.isActive() {
+			cb(positions)
+		}
+	})
+}
+
+func (s *guardedStream) OnKLineClosed(cb func(kline types.KLine)) {
+	s.Stream.OnKLineClosed(func(kline types.KLine) {
+		if s.guard.isActive() {
+			cb(kline)
+		}
+	})
+}
+
+func (s *guardedStream) OnKLine(cb func(kline types.KLine)) {
+	s.Stream.OnKLine(func(kline types.KLine) {
+		if s.guard.isActive() {
+			cb(kline)
+		}
+	})
+}
+
+func (s *guardedStream) OnBookUpdate(cb func(book types.SliceOrderBook)) {
+	s.Stream.OnBookUpdate(func(book types.SliceOrderBook) {
+		if s.guard.isActive() {
+			cb(book)
+		}
+	})
+}
+
+func (s *guardedStream) OnBookSnapshot(cb func(book types.SliceOrderBook)) {
+	s.Stream.OnBookSnapshot(func(book types.SliceOrderBook) {
+		if s.guard.isActive() {
+			cb(book)
+		}
+	})
+}
+
+func (s *guardedStream) OnMarketTrade(cb func(trade types.Trade)) {
+	s.Stream.OnMarketTrade(func(trade types.Trade) {
+		if s.guard.isActive() {
+			cb(trade)
+		}
+	})
+}
+
+func (s *guardedStream) OnBookTickerUpdate(cb func(bookTicker types.BookTicker)) {
+	s.Stream.OnBookTickerUpdate(func(bookTicker types.BookTicker) {
+		if s.guard.isActive() {
+			cb(bookTicker)
+		}
+	})
+}
+
+// guardedOrderExecutor guards the order and the trade callbacks bound to the order executor,
+// and rejects the orders of the stopped strategy
+type guardedOrderExecutor struct {
+	OrderExecutor
+
+	guard *strategyGuard
+}
+
+func (e *guardedOrderExecutor) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (types.OrderSlice, error) {
+	if !e.guard.isActive() {
+		return nil, ErrStrategyStopped
+	}
+
+	return e.OrderExecutor.SubmitOrders(ctx, orders...)
+}
+
+func (e *guardedOrderExecutor) OnTradeUpdate(cb func(trade types.Trade)) {
+	e.OrderExecutor.OnTradeUpdate(func(trade types.Trade) {
+		if e.guard.isActive() {
+			cb(trade)
+		}
+	})
+}
+
+func (e *guardedOrderExecutor) OnOrderUpdate(cb func(order types.Order)) {
+	e.OrderExecutor.OnOrderUpdate(func(order types.Order) {
+		if e.guard.isActive() {
+			cb(order)
+		}
+	})
+}
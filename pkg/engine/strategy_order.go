@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"github.com/pymba86/bingo/pkg/types"
+	"strings"
+)
+
+// the client order id is "<strategy>ZC<instance>ZC<nonce>", the parts are escaped into the alphanumerics,
+// so it's accepted by all the exchanges and fits in the 32 characters of okx.
+const (
+	clientOrderIDStrategyLength = 10
+	clientOrderIDInstanceLength = 10
+	clientOrderIDNonceLength    = 8
+)
+
+// clientOrderIDEscape starts the escape sequences of the client order id parts
+const clientOrderIDEscape = 'Z'
+
+// clientOrderIDSeparator separates the parts of the client order id
+const clientOrderIDSeparator = "ZC"
+
+var clientOrderIDEscapes = map[rune]string{
+	'Z': "ZZ",
+	'_': "ZU",
+	'-': "ZD",
+	'.': "ZP",
+}
+
+var clientOrderIDUnescapes = map[byte]rune{
+	'Z': 'Z',
+	'U': '_',
+	'D': '-',
+	'P': '.',
+}
+
+// StrategyTag returns the tag of the strategy instance that is encoded in the client order ids
+// and marked on the trades, the ids are sanitized and truncated to fit in the client order id.
+func StrategyTag(strategyID, instanceID string) string {
+	strategyID = sanitizeClientOrderIDPart(strategyID, clientOrderIDStrategyLength)
+	instanceID = sanitizeClientOrderIDPart(instanceID, clientOrderIDInstanceLength)
+	if len(instanceID) == 0 {
+		return strategyID
+	}
+
+	return strategyID + ":" + instanceID
+}
+
+// NewStrategyClientOrderID returns a new client order id of the strategy instance
+func NewStrategyClientOrderID(strategyID, instanceID string) string {
+	strategyID = sanitizeClientOrderIDPart(strategyID, clientOrderIDStrategyLength)
+	instanceID = sanitizeClientOrderIDPart(instanceID, clientOrderIDInstanceLength)
+
+	nonce := uuid.New()
+	return escapeClientOrderIDPart(strategyID) + clientOrderIDSeparator +
+		escapeClientOrderIDPart(instanceID) + clientOrderIDSeparator +
+		hex.EncodeToString(nonce[:])[:clientOrderIDNonceLength]
+}
+
+// ParseStrategyTag returns the strategy tag encoded in the client order id,
+// ok is false if the order is not placed by a strategy order executor.
+func ParseStrategyTag(clientOrderID string) (tag string, ok bool) {
+	parts := []string{""}
+	for i := 0; i < len(clientOrderID); i++ {
+		c := clientOrderID[i]
+		if !isAlphanumeric(rune(c)) {
+			return "", false
+		}
+
+		if c != clientOrderIDEscape {
+			parts[len(parts)-1] += string(c)
+			continue
+		}
+
+		if i+1 >= len(clientOrderID) {
+			return "", false
+		}
+
+		i++
+		if clientOrderID[i] == clientOrderIDSeparator[1] {
+			parts = append(parts, "")
+			continue
+		}
+
+		r, ok := clientOrderIDUnescapes[clientOrderID[i]]
+		if !ok {
+			return "", false
+		}
+
+		parts[len(parts)-1] += string(r)
+	}
+
+	if len(parts) != 3 || len(parts[0]) == 0 || len(parts[2]) != clientOrderIDNonceLength {
+		return "", false
+	}
+
+	if _, err := hex.DecodeString(parts[2]); err != nil {
+		return "", false
+	}
+
+	if len(parts[1]) == 0 {
+		return parts[0], true
+	}
+
+	return parts[0] + ":" + parts[1], true
+}
+
+// sanitizeClientOrderIDPart replaces the characters that are not allowed in the client order id,
+// the part is truncated when its escaped form exceeds the max length
+func sanitizeClientOrderIDPart(s string, maxLength int) string {
+	s = strings.Map(func(r rune) rune {
+		if isAlphanumeric(r) || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+
+		return '_'
+	}, s)
+
+	length := 0
+	for i, r := range s {
+		length += len(escapeClientOrderIDPart(string(r)))
+		if length > maxLength {
+			return s[:i]
+		}
+	}
+
+	return s
+}
+
+// escapeClientOrderIDPart escapes the sanitized part into the alphanumerics
+func escapeClientOrderIDPart(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if escaped, ok := clientOrderIDEscapes[r]; ok {
+			b.WriteString(escaped)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+func isAlphanumeric(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// StrategyOrderExecutor tags the orders of a strategy instance, the strategy tag is encoded in the client order ids
+// so that the trades of the orders are attributed to the strategy.
+type StrategyOrderExecutor struct {
+	OrderExecutor
+
+	StrategyID string
+	InstanceID string
+
+	attributor *TradeAttributor
+}
+
+func NewStrategyOrderExecutor(executor OrderExecutor, session *ExchangeSession, strategyID, instanceID string) *StrategyOrderExecutor {
+	return &StrategyOrderExecutor{
+		OrderExecutor: executor,
+		StrategyID:    strategyID,
+		InstanceID:    instanceID,
+		attributor:    session.tradeAttributor,
+	}
+}
+
+func (e *StrategyOrderExecutor) Tag() string {
+	return StrategyTag(e.StrategyID, e.InstanceID)
+}
+
+func (e *StrategyOrderExecutor) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (types.OrderSlice, error) {
+	// the client order ids are filled into a copy, the caller's slice is left untouched
+	orders = append([]types.SubmitOrder(nil), orders...)
+
+	var clientOrderIDs = make([]string, 0, len(orders))
+	for i := range orders {
+		if orders[i].ClientOrderId == "" {
+			orders[i].ClientOrderId = NewStrategyClientOrderID(e.StrategyID, e.InstanceID)
+		}
+
+		clientOrderIDs = append(clientOrderIDs, orders[i].ClientOrderId)
+	}
+
+	// the client order ids are registered before the orders are submitted,
+	// so the updates of the orders that are filled before the response are attributed too
+	if e.attributor != nil {
+		e.attributor.RegisterClientOrders(e.Tag(), clientOrderIDs...)
+	}
+
+	createdOrders, err := e.OrderExecutor.SubmitOrders(ctx, orders...)
+
+	if e.attributor != nil {
+		e.attributor.ResolveOrders(clientOrderIDs, createdOrders)
+	}
+
+	return createdOrders, err
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestStrategyClientOrderID(t *testing.T) {
+	tests := []struct {
+		strategy, instance string
+		tag                string
+	}{
+		{strategy: "grid", instance: "btcusdt", tag: "grid:btcusdt"},
+		{strategy: "grid_a", instance: "BTC-USDT", tag: "grid_a:BTC-USDT"},
+		{strategy: "grid_a", instance: "BTC-USDT.1", tag: "grid_a:BTC-USDT"},
+		{strategy: "Zeta", instance: "", tag: "Zeta"},
+		{strategy: "grid a", instance: "x:y", tag: "grid_a:x_y"},
+		{strategy: "a_b_c_d_e_f", instance: "ZZZZZZ", tag: "a_b_c_d:ZZZZZ"},
+	}
+
+	for _, test := range tests {
+		if tag := StrategyTag(test.strategy, test.instance); tag != test.tag {
+			t.Errorf("StrategyTag(%q, %q) = %q, expected %q", test.strategy, test.instance, tag, test.tag)
+		}
+
+		clientOrderID := NewStrategyClientOrderID(test.strategy, test.instance)
+		if len(clientOrderID) > 32 {
+			t.Errorf("client order id %s is longer than 32 characters", clientOrderID)
+		}
+
+		for _, r := range clientOrderID {
+			if !isAlphanumeric(r) {
+				t.Errorf("client order id %s is not alphanumeric", clientOrderID)
+				break
+			}
+		}
+
+		tag, ok := ParseStrategyTag(clientOrderID)
+		if !ok || tag != test.tag {
+			t.Errorf("ParseStrategyTag(%s) = %q %v, expected %q", clientOrderID, tag, ok, test.tag)
+		}
+	}
+}
+
+func TestParseStrategyTagOfCustomClientOrderIDs(t *testing.T) {
+	for _, clientOrderID := range []string{
+		"",
+		"web_1234",
+		"x-ZCyZC12345678",
+		"gridZCbtcZC1234567",
+		"gridZCbtcZC1234567g",
+		"gridZXbtcZC12345678",
+		"ZCbtcZC12345678",
+		"gridZCbtcZC12345678Z",
+	} {
+		if tag, ok := ParseStrategyTag(clientOrderID); ok {
+			t.Errorf("ParseStrategyTag(%q) = %q, expected not a strategy client order id", clientOrderID, tag)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/service"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const tradeAttributionTimeout = 30 * time.Second
+
+// TradeAttributor attributes the trades of the user data stream to the strategies by the order id.
+// The orders are recognized by the strategy tag in the client order id of the order updates,
+// or by the client order ids registered by the strategy order executors before the orders are submitted,
+// so the trades that are received before the submit response are attributed too.
+// With the trade service, the trades are recorded, marked with the strategy tag and updated
+// with the profit of the strategy position.
+type TradeAttributor struct {
+	session      *ExchangeSession
+	tradeService *service.TradeService
+
+	mu sync.Mutex
+
+	// orders maps the order id to the strategy tag
+	orders map[uint64]string
+
+	// clientOrders maps the client order ids of the submitted orders to the strategy tag,
+	// they are resolved into the order ids by the order updates or the submit responses,
+	// and released by the final order updates
+	clientOrders map[string]string
+
+	// positions are the positions of the strategies, keyed by the strategy tag and the symbol
+	positions map[string]*Position
+
+	tradeCallbacks []func(tag string, trade types.Trade)
+}
+
+func NewTradeAttributor(session *ExchangeSession) *TradeAttributor {
+	return &TradeAttributor{
+		session:   session,
+		orders:       make(map[uint64]string),
+		clientOrders: make(map[string]string),
+		positions:    make(map[string]*Position),
+	}
+}
+
+func (a *TradeAttributor) BindStream(stream types.Stream) {
+	stream.OnOrderUpdate(a.handleOrderUpdate)
+	stream.OnTradeUpdate(a.handleTradeUpdate)
+}
+
+// OnTrade is called with the trades attributed to the strategies
+func (a *TradeAttributor) OnTrade(cb func(tag string, trade types.Trade)) {
+	a.tradeCallbacks = append(a.tradeCallbacks, cb)
+}
+
+func (a *TradeAttributor) EmitTrade(tag string, trade types.Trade) {
+	for _, cb := range a.tradeCallbacks {
+		cb(tag, trade)
+	}
+}
+
+// RegisterClientOrders registers the client order ids of the orders that are about to be submitted
+func (a *TradeAttributor) RegisterClientOrders(tag string, clientOrderIDs ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, clientOrderID := range clientOrderIDs {
+		if len(clientOrderID) > 0 {
+			a.clientOrders[clientOrderID] = tag
+		}
+	}
+}
+
+// ResolveOrders resolves the registered client order ids into the order ids of the created orders.
+// The orders that are done already are skipped, since their final updates have released the client order ids,
+// and the client order ids of the orders that are not created are released.
+func (a *TradeAttributor) ResolveOrders(clientOrderIDs []string, createdOrders types.OrderSlice) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var created = make(map[string]struct{}, len(createdOrders))
+	for _, o := range createdOrders {
+		created[o.ClientOrderId] = struct{}{}
+
+		if tag, ok := a.clientOrders[o.ClientOrderId]; ok && len(o.ClientOrderId) > 0 {
+			a.orders[o.OrderID] = tag
+		}
+	}
+
+	for _, clientOrderID := range clientOrderIDs {
+		if _, ok := created[clientOrderID]; !ok {
+			delete(a.clientOrders, clientOrderID)
+		}
+	}
+}
+
+// StrategyOf returns the strategy tag of the order
+func (a *TradeAttributor) StrategyOf(orderID uint64) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	tag, ok := a.orders[orderID]
+	return tag, ok
+}
+
+// Position returns the position of the strategy on the symbol, nil if the strategy has no trade of the symbol
+func (a *TradeAttributor) Position(tag, symbol string) *Position {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.positions[tag+"/"+symbol]
+}
+
+func (a *TradeAttributor) handleOrderUpdate(order types.Order) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch order.Status {
+	case types.OrderStatusFilled, types.OrderStatusCanceled, types.OrderStatusRejected:
+		// the trades of the order are received before its final update
+		delete(a.orders, order.OrderID)
+		delete(a.clientOrders, order.ClientOrderId)
+		return
+	}
+
+	if tag, ok := a.clientOrders[order.ClientOrderId]; ok && len(order.ClientOrderId) > 0 {
+		a.orders[order.OrderID] = tag
+	} else if tag, ok := ParseStrategyTag(order.ClientOrderId); ok {
+		a.orders[order.OrderID] = tag
+	}
+}
+
+func (a *TradeAttributor) handleTradeUpdate(trade types.Trade) {
+	tag, ok := a.StrategyOf(trade.OrderID)
+	if !ok {
+		if a.tradeService != nil {
+			go a.recordTrade(trade, "", nil)
+		}
+
+		return
+	}
+
+	trade.StrategyID.String = tag
+	trade.StrategyID.Valid = true
+
+	position := a.strategyPosition(tag, trade.Symbol)
+	profit, _, madeProfit := position.AddTrade(trade)
+
+	var pnl *float64
+	if madeProfit {
+		p := profit.Float64()
+		pnl = &p
+		trade.PnL.Float64 = p
+		trade.PnL.Valid = true
+	}
+
+	if a.tradeService != nil {
+		go a.recordTrade(trade, tag, pnl)
+	}
+
+	a.EmitTrade(tag, trade)
+}
+
+// strategyPosition returns the position of the strategy, a new position is restored from the marked trades.
+// The trades are loaded without the lock, so the order updates are not blocked by the query.
+func (a *TradeAttributor) strategyPosition(tag, symbol string) *Position {
+	key := tag + "/" + symbol
+
+	a.mu.Lock()
+	position, ok := a.positions[key]
+	a.mu.Unlock()
+
+	if ok {
+		return position
+	}
+
+	if market, ok := a.session.Markets()[symbol]; ok {
+		position = NewPositionFromMarket(market)
+	} else {
+		position = &Position{Symbol: symbol}
+	}
+
+	// the position is restored from the trades of the tag only, the trades of the other instances are not included
+	if a.tradeService != nil {
+		trades, err := a.tradeService.Query(service.QueryTradesOptions{
+			Exchange: a.session.Exchange.Name(),
+			Symbol:   symbol,
+			Strategy: tag,
+		})
+		if err != nil {
+			log.WithError(err).Errorf("can not query the %s trades of strategy %s", symbol, tag)
+		}
+
+		position.AddTrades(trades)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// the position could be restored by another caller meanwhile
+	if existing, ok := a.positions[key]; ok {
+		return existing
+	}
+
+	a.positions[key] = position
+	return position
+}
+
+// recordTrade inserts the trade of the user data stream if it's not synced yet, then marks the strategy and the pnl of it
+func (a *TradeAttributor) recordTrade(trade types.Trade, tag string, pnl *float64) {
+	ctx, cancel := context.WithTimeout(context.Background(), tradeAttributionTimeout)
+	defer cancel()
+
+	if _, err := a.tradeService.Load(ctx, trade.ID); err != nil {
+		if !errors.Is(err, service.ErrTradeNotFound) {
+			log.WithError(err).Errorf("can not load trade %d", trade.ID)
+			return
+		}
+
+		if err := a.tradeService.Insert(trade); err != nil {
+			log.WithError(err).Errorf("can not insert trade %d", trade.ID)
+			return
+		}
+	}
+
+	if len(tag) == 0 {
+		return
+	}
+
+	if err := a.tradeService.Mark(ctx, trade.ID, tag); err != nil {
+		log.WithError(err).Errorf("can not mark trade %d with strategy %s", trade.ID, tag)
+		return
+	}
+
+	if pnl != nil {
+		if err := a.tradeService.UpdatePnL(ctx, trade.ID, *pnl); err != nil {
+			log.WithError(err).Errorf("can not update the pnl of trade %d", trade.ID)
+		}
+	}
+}
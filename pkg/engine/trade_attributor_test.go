@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+)
+
+// fastFillOrderExecutor fills the orders before the submit response, like a market order of a fast exchange
+type fastFillOrderExecutor struct {
+	OrderExecutor
+
+	attributor *TradeAttributor
+	orderID    uint64
+}
+
+func (e *fastFillOrderExecutor) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (createdOrders types.OrderSlice, err error) {
+	for _, o := range orders {
+		e.orderID++
+		order := types.Order{SubmitOrder: o, OrderID: e.orderID, Status: types.OrderStatusNew}
+
+		e.attributor.handleOrderUpdate(order)
+		e.attributor.handleTradeUpdate(types.Trade{ID: int64(e.orderID), OrderID: e.orderID, Symbol: o.Symbol,
+			Side: o.Side, Price: 100, Quantity: o.Quantity})
+
+		order.Status = types.OrderStatusFilled
+		e.attributor.handleOrderUpdate(order)
+
+		createdOrders = append(createdOrders, order)
+	}
+
+	return createdOrders, nil
+}
+
+func TestTradeAttributor_FastFills(t *testing.T) {
+	session := &ExchangeSession{}
+	attributor := NewTradeAttributor(session)
+	session.tradeAttributor = attributor
+
+	var tags []string
+	attributor.OnTrade(func(tag string, trade types.Trade) {
+		tags = append(tags, tag)
+	})
+
+	executor := NewStrategyOrderExecutor(&fastFillOrderExecutor{attributor: attributor}, session, "grid", "btc")
+
+	orders := []types.SubmitOrder{
+		{Symbol: "BTCUSDT", Side: types.SideTypeBuy, Type: types.OrderTypeMarket, Quantity: 1, ClientOrderId: "custom1"},
+		{Symbol: "BTCUSDT", Side: types.SideTypeBuy, Type: types.OrderTypeMarket, Quantity: 1},
+	}
+
+	if _, err := executor.SubmitOrders(context.Background(), orders...); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tags) != 2 || tags[0] != "grid:btc" || tags[1] != "grid:btc" {
+		t.Fatalf("expected both trades to be attributed to grid:btc, got %v", tags)
+	}
+
+	if orders[1].ClientOrderId != "" {
+		t.Errorf("the client order id is filled into the caller's orders: %s", orders[1].ClientOrderId)
+	}
+
+	// the orders that are done before the response are not registered again
+	if len(attributor.orders) != 0 || len(attributor.clientOrders) != 0 {
+		t.Errorf("expected the done orders to be released, got %v and %v", attributor.orders, attributor.clientOrders)
+	}
+}
@@ -38,6 +38,11 @@ type Trader struct {
 
 	exchangeStrategies map[string][]SingleExchangeStrategy
 
+	// instances are the attached single exchange strategies in the attaching order
+	instances    []*strategyInstance
+	instanceKeys map[string]int
+	instanceMu   sync.Mutex
+
 	logger Logger
 
 	Graceful Graceful
@@ -47,6 +52,7 @@ func NewTrader(environ *Environment) *Trader {
 	return &Trader{
 		environment:        environ,
 		exchangeStrategies: make(map[string][]SingleExchangeStrategy),
+		instanceKeys:       make(map[string]int),
 		logger:             log.StandardLogger(),
 	}
 }
@@ -106,14 +112,13 @@ func (trader *Trader) getSessionOrderExecutor(sessionName string) OrderExecutor
 }
 
 func (trader *Trader) RunAllSingleExchangeStrategy(ctx context.Context) error {
+	trader.instanceMu.Lock()
+	defer trader.instanceMu.Unlock()
+
 	// load and run Session strategies
-	for sessionName, strategies := range trader.exchangeStrategies {
-		var session = trader.environment.sessions[sessionName]
-		var orderExecutor = trader.getSessionOrderExecutor(sessionName)
-		for _, strategy := range strategies {
-			if err := trader.RunSingleExchangeStrategy(ctx, strategy, session, orderExecutor); err != nil {
-				return err
-			}
+	for _, instance := range trader.instances {
+		if err := trader.startStrategyInstance(ctx, instance); err != nil {
+			return err
 		}
 	}
 
@@ -122,6 +127,11 @@ func (trader *Trader) RunAllSingleExchangeStrategy(ctx context.Context) error {
 
 func (trader *Trader) RunSingleExchangeStrategy(ctx context.Context, strategy SingleExchangeStrategy,
 	session *ExchangeSession, orderExecutor OrderExecutor) error {
+	return trader.runSingleExchangeStrategy(ctx, strategy, session, orderExecutor, &trader.Graceful)
+}
+
+func (trader *Trader) runSingleExchangeStrategy(ctx context.Context, strategy SingleExchangeStrategy,
+	session *ExchangeSession, orderExecutor OrderExecutor, graceful *Graceful) error {
 
 	rs := reflect.ValueOf(strategy)
 
@@ -132,7 +142,7 @@ func (trader *Trader) RunSingleExchangeStrategy(ctx context.Context, strategy Si
 		return errors.New("strategy object is not a struct")
 	}
 
-	if err := trader.injectCommonServices(rs); err != nil {
+	if err := trader.injectCommonServices(rs, graceful); err != nil {
 		return err
 	}
 
@@ -146,8 +156,8 @@ func (trader *Trader) RunSingleExchangeStrategy(ctx context.Context, strategy Si
 	return strategy.Run(ctx, orderExecutor, session)
 }
 
-func (trader *Trader) injectCommonServices(rs reflect.Value) error {
-	if err := injectField(rs, "Graceful", &trader.Graceful, true); err != nil {
+func (trader *Trader) injectCommonServices(rs reflect.Value, graceful *Graceful) error {
+	if err := injectField(rs, "Graceful", graceful, true); err != nil {
 		return errors.Wrap(err, "failed to inject Graceful")
 	}
 
@@ -170,11 +180,7 @@ func (trader *Trader) Subscribe() {
 	for sessionName, strategies := range trader.exchangeStrategies {
 		session := trader.environment.sessions[sessionName]
 		for _, strategy := range strategies {
-			if subscriber, ok := strategy.(ExchangeSessionSubscriber); ok {
-				subscriber.Subscribe(session)
-			} else {
-				log.Errorf("strategy %s does not implement ExchangeSessionSubscriber", strategy.Id())
-			}
+			subscribeStrategy(session, strategy)
 		}
 	}
 
@@ -187,6 +193,14 @@ func (trader *Trader) Subscribe() {
 	}
 }
 
+func subscribeStrategy(session *ExchangeSession, strategy SingleExchangeStrategy) {
+	if subscriber, ok := strategy.(ExchangeSessionSubscriber); ok {
+		subscriber.Subscribe(session)
+	} else {
+		log.Errorf("strategy %s does not implement ExchangeSessionSubscriber", strategy.Id())
+	}
+}
+
 func (trader *Trader) AttachStrategyOn(session string, strategies ...SingleExchangeStrategy) error {
 
 	if len(trader.environment.sessions) == 0 {
@@ -203,8 +217,12 @@ func (trader *Trader) AttachStrategyOn(session string, strategies ...SingleExcha
 		return fmt.Errorf("session %s is not defined, valid sessions are: %v", session, keys)
 	}
 
+	trader.instanceMu.Lock()
+	defer trader.instanceMu.Unlock()
+
 	for _, s := range strategies {
 		trader.exchangeStrategies[session] = append(trader.exchangeStrategies[session], s)
+		trader.instances = append(trader.instances, newStrategyInstance(session, s, trader.instanceKeys))
 	}
 
 	return nil
@@ -0,0 +1,219 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const trailingStopSubmitTimeout = 30 * time.Second
+
+// TrailingStopOrder is a client-side order driven by the market data stream. It's activated once the price
+// reaches the activation price, then it follows the highest (sell) or the lowest (buy) price and submits
+// a market order when the price retraces from it by the callback rate.
+type TrailingStopOrder struct {
+	Symbol string `json:"symbol"`
+
+	// Side is SELL for closing a long position, BUY for closing a short position
+	Side     types.SideType `json:"side"`
+	Quantity float64        `json:"quantity"`
+
+	// ActivationPrice is optional, the order is activated by the first price if it's zero
+	ActivationPrice float64 `json:"activationPrice,omitempty"`
+
+	// CallbackRate is the retracement ratio that triggers the order, e.g. 0.01 for 1%
+	CallbackRate float64 `json:"callbackRate"`
+
+	Market types.Market `json:"-"`
+
+	MarginSideEffect types.MarginOrderSideEffectType `json:"marginSideEffect,omitempty"`
+}
+
+func (o TrailingStopOrder) Validate() error {
+	if len(o.Symbol) == 0 {
+		return fmt.Errorf("trailing stop symbol is required")
+	}
+
+	if o.Side != types.SideTypeBuy && o.Side != types.SideTypeSell {
+		return fmt.Errorf("invalid trailing stop side: %s", o.Side)
+	}
+
+	if o.Quantity <= 0 {
+		return fmt.Errorf("trailing stop quantity must be positive, got %f", o.Quantity)
+	}
+
+	if o.CallbackRate <= 0 || o.CallbackRate >= 1 {
+		return fmt.Errorf("trailing stop callback rate must be between 0 and 1, got %f", o.CallbackRate)
+	}
+
+	return nil
+}
+
+type TrailingStop struct {
+	TrailingStopOrder
+
+	session *ExchangeSession
+
+	mu           sync.Mutex
+	activated    bool
+	done         bool
+	extremePrice float64
+
+	triggerCallbacks []func(order types.Order)
+}
+
+func NewTrailingStop(session *ExchangeSession, order TrailingStopOrder) *TrailingStop {
+	return &TrailingStop{
+		TrailingStopOrder: order,
+		session:           session,
+	}
+}
+
+func (s *TrailingStop) OnTrigger(cb func(order types.Order)) {
+	s.triggerCallbacks = append(s.triggerCallbacks, cb)
+}
+
+func (s *TrailingStop) EmitTrigger(order types.Order) {
+	for _, cb := range s.triggerCallbacks {
+		cb(order)
+	}
+}
+
+// StopPrice returns the current stop price, zero if the trailing stop is not activated yet
+func (s *TrailingStop) StopPrice() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.activated {
+		return 0
+	}
+
+	return s.stopPrice()
+}
+
+func (s *TrailingStop) stopPrice() float64 {
+	if s.Side == types.SideTypeSell {
+		return s.extremePrice * (1.0 - s.CallbackRate)
+	}
+
+	return s.extremePrice * (1.0 + s.CallbackRate)
+}
+
+// Cancel stops the trailing stop, it does nothing if the order is already triggered
+func (s *TrailingStop) Cancel() {
+	s.mu.Lock()
+	s.done = true
+	s.mu.Unlock()
+}
+
+func (s *TrailingStop) Done() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// Update moves the trailing stop with the given price and triggers the order once the stop price is crossed
+func (s *TrailingStop) Update(price float64) {
+	s.update(price)
+}
+
+// update returns true once the trailing stop is triggered or canceled, so the session drops it
+func (s *TrailingStop) update(price float64) (done bool) {
+	s.mu.Lock()
+	if s.done || price <= 0 {
+		done = s.done
+		s.mu.Unlock()
+		return done
+	}
+
+	if !s.activated {
+		switch {
+		case s.ActivationPrice == 0,
+			s.Side == types.SideTypeSell && price >= s.ActivationPrice,
+			s.Side == types.SideTypeBuy && price <= s.ActivationPrice:
+			s.activated = true
+			s.extremePrice = price
+			log.Infof("trailing stop %s %s activated at %f", s.Symbol, s.Side, price)
+
+		default:
+			s.mu.Unlock()
+			return false
+		}
+	}
+
+	var triggered bool
+	switch s.Side {
+	case types.SideTypeSell:
+		if price > s.extremePrice {
+			s.extremePrice = price
+		}
+
+		triggered = price <= s.stopPrice()
+
+	case types.SideTypeBuy:
+		if price < s.extremePrice {
+			s.extremePrice = price
+		}
+
+		triggered = price >= s.stopPrice()
+	}
+
+	if triggered {
+		s.done = true
+	}
+	s.mu.Unlock()
+
+	if triggered {
+		go s.trigger(price)
+	}
+
+	return triggered
+}
+
+func (s *TrailingStop) trigger(price float64) {
+	ctx, cancel := context.WithTimeout(context.Background(), trailingStopSubmitTimeout)
+	defer cancel()
+
+	log.Infof("trailing stop %s %s triggered at %f", s.Symbol, s.Side, price)
+
+	createdOrders, err := s.session.Exchange.SubmitOrders(ctx, types.SubmitOrder{
+		Symbol:           s.Symbol,
+		Side:             s.Side,
+		Type:             types.OrderTypeMarket,
+		Quantity:         s.Quantity,
+		Market:           s.Market,
+		MarginSideEffect: s.MarginSideEffect,
+	})
+	if err != nil {
+		log.WithError(err).Errorf("can not submit the %s trailing stop order", s.Symbol)
+		s.session.Notify("%s: can not submit the %s %s trailing stop order at %f: %v", s.session.Name, s.Symbol, s.Side, price, err)
+		return
+	}
+
+	s.session.Notify("%s: %s %s trailing stop triggered at %f", s.session.Name, s.Symbol, s.Side, price)
+
+	for _, order := range createdOrders {
+		s.EmitTrigger(order)
+	}
+}
+
+// SubmitTrailingStopOrder starts a client-side trailing stop driven by the market data stream of the session,
+// the trailing stop is dropped from the stream once it's triggered or canceled
+func (session *ExchangeSession) SubmitTrailingStopOrder(order TrailingStopOrder) (*TrailingStop, error) {
+	if err := order.Validate(); err != nil {
+		return nil, err
+	}
+
+	if order.Market.Symbol == "" {
+		if market, ok := session.markets[order.Symbol]; ok {
+			order.Market = market
+		}
+	}
+
+	trailingStop := NewTrailingStop(session, order)
+	session.priceTriggers.Add(order.Symbol, trailingStop.update)
+	return trailingStop, nil
+}
@@ -0,0 +1,273 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"math"
+	"time"
+)
+
+const (
+	defaultTwapNumOfSlices    = 10
+	defaultTwapUpdateInterval = 5 * time.Second
+)
+
+// TwapExecutor executes the target quantity over the duration. The target is split into slices,
+// the current slice is placed as a limit order at the best price of the order side (the best bid
+// for buying, the best ask for selling) and re-priced when the best price moves away from it.
+// The quantity left at the end of the duration is placed as a market order.
+type TwapExecutor struct {
+	*algoOrderTracker
+
+	Market         types.Market
+	Side           types.SideType
+	TargetQuantity float64
+	Duration       time.Duration
+
+	// NumOfSlices is the number of slices of the target quantity, defaults to 10
+	NumOfSlices int
+
+	// UpdateInterval is the interval of re-pricing the child order, defaults to 5 seconds
+	UpdateInterval time.Duration
+
+	// MaxSubmitFailures is the number of the consecutive child order failures that stops the twap, defaults to 5
+	MaxSubmitFailures int
+
+	OrderBook     *types.StreamOrderBook
+	OrderExecutor OrderExecutor
+}
+
+func NewTwapExecutor(executor OrderExecutor, orderBook *types.StreamOrderBook, market types.Market, side types.SideType, targetQuantity float64, duration time.Duration) *TwapExecutor {
+	e := &TwapExecutor{
+		algoOrderTracker: newAlgoOrderTracker(market.Symbol, side, targetQuantity),
+		Market:           market,
+		Side:             side,
+		TargetQuantity:   targetQuantity,
+		Duration:         duration,
+		NumOfSlices:      defaultTwapNumOfSlices,
+		UpdateInterval:   defaultTwapUpdateInterval,
+
+		MaxSubmitFailures: defaultAlgoOrderMaxSubmitFailures,
+		OrderBook:         orderBook,
+		OrderExecutor:     executor,
+	}
+
+	e.bind(executor)
+	return e
+}
+
+func (e *TwapExecutor) validate() error {
+	if e.Side != types.SideTypeBuy && e.Side != types.SideTypeSell {
+		return fmt.Errorf("invalid twap side: %s", e.Side)
+	}
+
+	if e.TargetQuantity <= 0 {
+		return fmt.Errorf("twap target quantity must be positive, got %f", e.TargetQuantity)
+	}
+
+	if e.Duration <= 0 {
+		return fmt.Errorf("twap duration must be positive, got %s", e.Duration)
+	}
+
+	return nil
+}
+
+// Run executes the order until the target quantity is filled, the duration is over or the child order
+// can not be updated for MaxSubmitFailures times in a row, the child orders are canceled when the context is canceled. The executor stops receiving
+// the order updates when it returns, so it can only be run once.
+func (e *TwapExecutor) Run(ctx context.Context) error {
+	defer e.unbind()
+
+	if err := e.validate(); err != nil {
+		return err
+	}
+
+	if e.NumOfSlices <= 0 {
+		e.NumOfSlices = defaultTwapNumOfSlices
+	}
+
+	if e.UpdateInterval <= 0 {
+		e.UpdateInterval = defaultTwapUpdateInterval
+	}
+
+	if e.MaxSubmitFailures <= 0 {
+		e.MaxSubmitFailures = defaultAlgoOrderMaxSubmitFailures
+	}
+
+	startTime := time.Now()
+	deadline := startTime.Add(e.Duration)
+	sliceDuration := e.Duration / time.Duration(e.NumOfSlices)
+
+	ticker := time.NewTicker(e.UpdateInterval)
+	defer ticker.Stop()
+
+	log.Infof("twap %s %s %f started, duration %s, %d slices", e.Symbol(), e.Side, e.TargetQuantity, e.Duration, e.NumOfSlices)
+
+	var failures int
+	for {
+		now := time.Now()
+		if !now.Before(deadline) {
+			return e.finish(ctx)
+		}
+
+		if e.isDone() {
+			log.Infof("twap %s finished: %s", e.Symbol(), e.Progress())
+			return nil
+		}
+
+		slice := int(now.Sub(startTime)/sliceDuration) + 1
+		if slice > e.NumOfSlices {
+			slice = e.NumOfSlices
+		}
+
+		sliceTarget := e.TargetQuantity * float64(slice) / float64(e.NumOfSlices)
+		if err := e.updateChildOrder(ctx, sliceTarget); err != nil {
+			failures++
+			log.WithError(err).Errorf("twap %s can not update the child order (%d/%d)", e.Symbol(), failures, e.MaxSubmitFailures)
+
+			if failures >= e.MaxSubmitFailures {
+				_ = e.cancelActiveOrders(e.OrderExecutor)
+				return fmt.Errorf("twap %s stopped after %d consecutive child order failures: %w", e.Symbol(), failures, err)
+			}
+		} else {
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = e.cancelActiveOrders(e.OrderExecutor)
+			return ctx.Err()
+
+		case <-ticker.C:
+			e.reconcileActiveOrders(ctx)
+
+		case <-e.updateC:
+		}
+	}
+}
+
+func (e *TwapExecutor) Symbol() string {
+	return e.Market.Symbol
+}
+
+func (e *TwapExecutor) remainingQuantity() float64 {
+	return e.TargetQuantity - e.Progress().ExecutedQuantity
+}
+
+func (e *TwapExecutor) isDone() bool {
+	price, _ := e.bestPrice()
+	return isDustQuantity(e.Market, e.remainingQuantity(), price)
+}
+
+// bestPrice returns the price of the passive child order, the best bid for buying and the best ask for selling
+func (e *TwapExecutor) bestPrice() (float64, bool) {
+	if e.OrderBook == nil {
+		return 0, false
+	}
+
+	var pv types.PriceVolume
+	var ok bool
+	if e.Side == types.SideTypeBuy {
+		pv, ok = e.OrderBook.BestBid()
+	} else {
+		pv, ok = e.OrderBook.BestAsk()
+	}
+
+	if !ok {
+		return 0, false
+	}
+
+	return pv.Price.Float64(), true
+}
+
+// updateChildOrder keeps a child order of the slice target at the best price
+func (e *TwapExecutor) updateChildOrder(ctx context.Context, sliceTarget float64) error {
+	price, ok := e.bestPrice()
+	if !ok {
+		log.Warnf("twap %s: the order book is not ready", e.Symbol())
+		return nil
+	}
+
+	sliceTarget = math.Min(sliceTarget, e.TargetQuantity)
+
+	activeOrders := e.activeOrders()
+	if len(activeOrders) > 0 {
+		child := activeOrders[0]
+
+		// keep the child order if it's still at the best price and covers the quantity of the current slice
+		unfilled := child.Quantity - child.ExecutedQuantity
+		missing := sliceTarget - e.Progress().ExecutedQuantity - unfilled
+		if e.isSamePrice(child.Price, price) && isDustQuantity(e.Market, missing, price) {
+			return nil
+		}
+
+		log.Infof("twap %s: replacing the child order %f @ %f with the price %f", e.Symbol(), child.Quantity, child.Price, price)
+		if err := e.cancelActiveOrders(e.OrderExecutor); err != nil {
+			return err
+		}
+	}
+
+	quantity := sliceTarget - e.Progress().ExecutedQuantity
+	if isDustQuantity(e.Market, quantity, price) {
+		return nil
+	}
+
+	_, err := e.submit(ctx, e.OrderExecutor, types.SubmitOrder{
+		Symbol:   e.Symbol(),
+		Side:     e.Side,
+		Type:     types.OrderTypeLimit,
+		Quantity: quantity,
+		Price:    price,
+		Market:   e.Market,
+	})
+	return err
+}
+
+func (e *TwapExecutor) isSamePrice(a, b float64) bool {
+	tickSize := e.Market.TickSize
+	if tickSize <= 0 {
+		return a == b
+	}
+
+	return math.Abs(a-b) < tickSize/2
+}
+
+// finish cancels the child order and places the remaining quantity as a market order
+func (e *TwapExecutor) finish(ctx context.Context) error {
+	if err := e.cancelActiveOrders(e.OrderExecutor); err != nil {
+		return err
+	}
+
+	price, _ := e.bestPrice()
+	quantity := e.remainingQuantity()
+	if isDustQuantity(e.Market, quantity, price) {
+		log.Infof("twap %s finished: %s", e.Symbol(), e.Progress())
+		return nil
+	}
+
+	log.Infof("twap %s: the duration is over, placing a market order of the remaining quantity %f", e.Symbol(), quantity)
+
+	createdOrders, err := e.submit(ctx, e.OrderExecutor, types.SubmitOrder{
+		Symbol:   e.Symbol(),
+		Side:     e.Side,
+		Type:     types.OrderTypeMarket,
+		Quantity: quantity,
+		Market:   e.Market,
+	})
+	if err != nil {
+		return err
+	}
+
+	// wait for the fills of the market order before the updates are unbound
+	waitCtx, cancel := context.WithTimeout(ctx, algoOrderCancelTimeout)
+	defer cancel()
+
+	if err := e.waitForClosedOrders(waitCtx, createdOrders); err != nil {
+		return err
+	}
+
+	log.Infof("twap %s finished: %s", e.Symbol(), e.Progress())
+	return nil
+}
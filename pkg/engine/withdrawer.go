@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithdrawalPolicy defines the guard rails of the session withdrawals
+type WithdrawalPolicy struct {
+	// DryRun records the withdrawal requests without sending them to the exchange
+	DryRun bool `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
+
+	// Assets is the per-asset policy, the assets that are not listed can not be withdrawn
+	Assets map[string]WithdrawalAssetPolicy `json:"assets,omitempty" yaml:"assets,omitempty"`
+}
+
+type WithdrawalAssetPolicy struct {
+	// Addresses is the allowlist of the destination addresses
+	Addresses []string `json:"addresses" yaml:"addresses"`
+
+	Network    string `json:"network,omitempty" yaml:"network,omitempty"`
+	AddressTag string `json:"addressTag,omitempty" yaml:"addressTag,omitempty"`
+
+	// DailyLimit is the max amount that can be withdrawn in a day (UTC)
+	DailyLimit fixedpoint.Value `json:"dailyLimit" yaml:"dailyLimit"`
+}
+
+// WithdrawalRequestStore persists the withdrawal requests, so that the daily limits survive restarts
+type WithdrawalRequestStore interface {
+	Insert(request types.WithdrawalRequest) error
+	QuerySince(ex types.ExchangeName, asset string, since time.Time) ([]types.WithdrawalRequest, error)
+}
+
+// Withdrawer sends the withdrawal requests of the session with the address allowlist and the daily limits
+type Withdrawer struct {
+	session *ExchangeSession
+
+	// Store persists the requests, the requests are kept in memory only when it's not set
+	Store WithdrawalRequestStore
+
+	mu sync.Mutex
+
+	// records are the requests made since the start of the process
+	records []types.WithdrawalRequest
+}
+
+func NewWithdrawer(session *ExchangeSession) *Withdrawer {
+	return &Withdrawer{session: session}
+}
+
+// Records returns the withdrawal requests that have been made since the start of the process,
+// including the rejected ones
+func (w *Withdrawer) Records() []types.WithdrawalRequest {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]types.WithdrawalRequest(nil), w.records...)
+}
+
+func (w *Withdrawer) Withdraw(ctx context.Context, asset string, amount fixedpoint.Value, address string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	session := w.session
+	record := types.WithdrawalRequest{
+		Exchange:        session.ExchangeName,
+		WithdrawOrderID: strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Asset:           asset,
+		Amount:          amount,
+		Address:         address,
+		Time:            types.Time(time.Now().UTC()),
+	}
+
+	if !session.Withdrawal {
+		return w.reject(record, ErrWithdrawalDisabled)
+	}
+
+	service, ok := session.Exchange.(types.ExchangeWithdrawalService)
+	if !ok {
+		return w.reject(record, fmt.Errorf("exchange %s does not support withdrawal", session.ExchangeName))
+	}
+
+	if amount <= 0 {
+		return w.reject(record, fmt.Errorf("invalid withdrawal amount %f", amount.Float64()))
+	}
+
+	var policy WithdrawalPolicy
+	if session.WithdrawalPolicy != nil {
+		policy = *session.WithdrawalPolicy
+	}
+
+	assetPolicy, ok := policy.Assets[asset]
+	if !ok {
+		return w.reject(record, fmt.Errorf("asset %s is not allowed to be withdrawn", asset))
+	}
+
+	record.Network = assetPolicy.Network
+
+	if !assetPolicy.allowAddress(address) {
+		return w.reject(record, fmt.Errorf("address %s is not in the %s withdrawal allowlist", address, asset))
+	}
+
+	if assetPolicy.DailyLimit <= 0 {
+		return w.reject(record, fmt.Errorf("daily limit of %s is not configured", asset))
+	}
+
+	withdrawn, err := w.withdrawnToday(ctx, asset)
+	if err != nil {
+		return w.reject(record, errors.Wrap(err, "can not query the withdrawn amount of today"))
+	}
+
+	if withdrawn+amount > assetPolicy.DailyLimit {
+		return w.reject(record, fmt.Errorf("withdrawal of %f %s exceeds the daily limit %f, withdrawn today: %f",
+			amount.Float64(), asset, assetPolicy.DailyLimit.Float64(), withdrawn.Float64()))
+	}
+
+	session.Notify("%s: withdrawing %f %s to %s", session.Name, amount.Float64(), asset, address)
+
+	if policy.DryRun {
+		record.Status = types.WithdrawalRequestDryRun
+		log.Infof("[dry run] %s", record.String())
+	} else {
+		err = service.Withdrawal(ctx, asset, amount, address, &types.WithdrawalOptions{
+			Network:         assetPolicy.Network,
+			AddressTag:      assetPolicy.AddressTag,
+			WithdrawOrderID: record.WithdrawOrderID,
+		})
+		if err != nil {
+			record.Status = types.WithdrawalRequestFailed
+			record.Reason = err.Error()
+		} else {
+			record.Status = types.WithdrawalRequestSent
+		}
+	}
+
+	w.addRecord(record)
+
+	if err != nil {
+		session.Notify("%s: withdrawal of %f %s to %s failed: %v", session.Name, amount.Float64(), asset, address, err)
+		return err
+	}
+
+	session.Notify("%s: %s", session.Name, record.String())
+	return nil
+}
+
+// reject records the request rejected by the withdrawal policy with the reason
+func (w *Withdrawer) reject(record types.WithdrawalRequest, err error) error {
+	record.Status = types.WithdrawalRequestRejected
+	record.Reason = err.Error()
+	w.addRecord(record)
+	return err
+}
+
+func (w *Withdrawer) addRecord(record types.WithdrawalRequest) {
+	w.records = append(w.records, record)
+	log.Infof("%s: %s", w.session.Name, record.String())
+
+	if w.Store == nil {
+		return
+	}
+
+	if err := w.Store.Insert(record); err != nil {
+		log.WithError(err).Errorf("%s: can not persist the %s", w.session.Name, record.String())
+	}
+}
+
+// withdrawnToday returns the amount of the asset withdrawn since the start of the day in UTC.
+// The exchange withdraw history is used if it's available, the sent requests are added to it
+// unless the history includes them already, which is matched by the withdraw order id.
+func (w *Withdrawer) withdrawnToday(ctx context.Context, asset string) (fixedpoint.Value, error) {
+	now := time.Now().UTC()
+	startOfDay := now.Truncate(24 * time.Hour)
+
+	var withdrawn fixedpoint.Value
+	var withdrawOrderIDs = map[string]struct{}{}
+
+	if transferService, ok := w.session.Exchange.(types.ExchangeTransferService); ok {
+		withdraws, err := transferService.QueryWithdrawHistory(ctx, asset, startOfDay, now)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, withdraw := range withdraws {
+			// the canceled withdraws still mark the requests that reached the exchange
+			if len(withdraw.WithdrawOrderID) > 0 {
+				withdrawOrderIDs[withdraw.WithdrawOrderID] = struct{}{}
+			}
+
+			switch withdraw.Status {
+			case "cancelled", "rejected", "failure":
+				continue
+			}
+
+			withdrawn += fixedpoint.NewFromFloat(withdraw.Amount)
+		}
+	}
+
+	records, err := w.queryRecords(asset, startOfDay)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, record := range records {
+		if record.Status != types.WithdrawalRequestSent {
+			continue
+		}
+
+		if _, ok := withdrawOrderIDs[record.WithdrawOrderID]; ok {
+			continue
+		}
+
+		withdrawn += record.Amount
+	}
+
+	return withdrawn, nil
+}
+
+// queryRecords returns the requests of the asset since the given time, the persisted requests
+// are used if the store is set so that the requests made before a restart are included
+func (w *Withdrawer) queryRecords(asset string, since time.Time) ([]types.WithdrawalRequest, error) {
+	if w.Store != nil {
+		return w.Store.QuerySince(w.session.ExchangeName, asset, since)
+	}
+
+	var records []types.WithdrawalRequest
+	for _, record := range w.records {
+		if record.Asset == asset && !record.Time.Time().Before(since) {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+func (p WithdrawalAssetPolicy) allowAddress(address string) bool {
+	for _, a := range p.Addresses {
+		if a == address {
+			return true
+		}
+	}
+
+	return false
+}
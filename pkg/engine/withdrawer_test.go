@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+	"time"
+)
+
+type withdrawTestExchange struct {
+	types.Exchange
+
+	history          []types.Withdraw
+	withdrawn        []fixedpoint.Value
+	withdrawOrderIDs []string
+}
+
+func (e *withdrawTestExchange) Withdrawal(ctx context.Context, asset string, amount fixedpoint.Value, address string, options *types.WithdrawalOptions) error {
+	e.withdrawn = append(e.withdrawn, amount)
+	e.withdrawOrderIDs = append(e.withdrawOrderIDs, options.WithdrawOrderID)
+	return nil
+}
+
+func (e *withdrawTestExchange) QueryDepositHistory(ctx context.Context, asset string, since, until time.Time) ([]types.Deposit, error) {
+	return nil, nil
+}
+
+func (e *withdrawTestExchange) QueryWithdrawHistory(ctx context.Context, asset string, since, until time.Time) ([]types.Withdraw, error) {
+	return e.history, nil
+}
+
+type withdrawalRequestTestStore struct {
+	requests []types.WithdrawalRequest
+}
+
+func (s *withdrawalRequestTestStore) Insert(request types.WithdrawalRequest) error {
+	s.requests = append(s.requests, request)
+	return nil
+}
+
+func (s *withdrawalRequestTestStore) QuerySince(ex types.ExchangeName, asset string, since time.Time) (requests []types.WithdrawalRequest, err error) {
+	for _, request := range s.requests {
+		if request.Exchange == ex && request.Asset == asset && !request.Time.Time().Before(since) {
+			requests = append(requests, request)
+		}
+	}
+
+	return requests, nil
+}
+
+func newWithdrawTestSession(exchange types.Exchange) *ExchangeSession {
+	return &ExchangeSession{
+		Withdrawal: true,
+		WithdrawalPolicy: &WithdrawalPolicy{
+			Assets: map[string]WithdrawalAssetPolicy{
+				"BTC": {Addresses: []string{"addr"}, DailyLimit: fixedpoint.NewFromFloat(1.0)},
+			},
+		},
+		Exchange:     exchange,
+		ExchangeName: types.ExchangeBinance,
+	}
+}
+
+func TestWithdrawer_DailyLimit(t *testing.T) {
+	exchange := &withdrawTestExchange{}
+	withdrawer := NewWithdrawer(newWithdrawTestSession(exchange))
+	ctx := context.Background()
+
+	// a withdraw of the previous process is in the exchange history
+	exchange.history = []types.Withdraw{
+		{ID: "1", Asset: "BTC", Amount: 0.3, Status: "completed", ApplyTime: types.Time(time.Now().Add(-time.Second))},
+	}
+
+	if err := withdrawer.Withdraw(ctx, "BTC", fixedpoint.NewFromFloat(0.3), "addr"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the local request is not in the history yet, so both are counted
+	if err := withdrawer.Withdraw(ctx, "BTC", fixedpoint.NewFromFloat(0.5), "addr"); err == nil {
+		t.Fatal("expected the daily limit to be exceeded")
+	}
+
+	// the local request appears in the history as a pending withdraw, it's not counted twice
+	exchange.history = append(exchange.history, types.Withdraw{
+		ID: "2", Asset: "BTC", Amount: 0.3, Status: "processing", WithdrawOrderID: exchange.withdrawOrderIDs[0],
+		ApplyTime: types.Time(time.Now().Add(-time.Hour)),
+	})
+
+	if err := withdrawer.Withdraw(ctx, "BTC", fixedpoint.NewFromFloat(0.4), "addr"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exchange.withdrawn) != 2 {
+		t.Fatalf("expected 2 withdrawals, got %d", len(exchange.withdrawn))
+	}
+}
+
+func TestWithdrawer_RecordsRejections(t *testing.T) {
+	exchange := &withdrawTestExchange{}
+	store := &withdrawalRequestTestStore{}
+	withdrawer := NewWithdrawer(newWithdrawTestSession(exchange))
+	withdrawer.Store = store
+	ctx := context.Background()
+
+	if err := withdrawer.Withdraw(ctx, "BTC", fixedpoint.NewFromFloat(0.1), "unknown"); err == nil {
+		t.Fatal("expected the address to be rejected")
+	}
+
+	if err := withdrawer.Withdraw(ctx, "BTC", fixedpoint.NewFromFloat(2.0), "addr"); err == nil {
+		t.Fatal("expected the daily limit to be exceeded")
+	}
+
+	records := withdrawer.Records()
+	if len(records) != 2 || len(store.requests) != 2 {
+		t.Fatalf("expected 2 records, got %d records and %d persisted requests", len(records), len(store.requests))
+	}
+
+	for _, record := range records {
+		if record.Status != types.WithdrawalRequestRejected || len(record.Reason) == 0 {
+			t.Errorf("expected a rejection with the reason, got %s", record.String())
+		}
+	}
+
+	if len(exchange.withdrawn) != 0 {
+		t.Fatalf("expected no withdrawals, got %d", len(exchange.withdrawn))
+	}
+}
+
+func TestWithdrawer_DailyLimitSurvivesRestart(t *testing.T) {
+	exchange := &withdrawTestExchange{}
+	store := &withdrawalRequestTestStore{}
+	ctx := context.Background()
+
+	withdrawer := NewWithdrawer(newWithdrawTestSession(exchange))
+	withdrawer.Store = store
+
+	if err := withdrawer.Withdraw(ctx, "BTC", fixedpoint.NewFromFloat(0.6), "addr"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the withdraw is not in the exchange history yet when the process restarts
+	restarted := NewWithdrawer(newWithdrawTestSession(exchange))
+	restarted.Store = store
+
+	if err := restarted.Withdraw(ctx, "BTC", fixedpoint.NewFromFloat(0.6), "addr"); err == nil {
+		t.Fatal("expected the daily limit to be exceeded after the restart")
+	}
+
+	if len(exchange.withdrawn) != 1 {
+		t.Fatalf("expected 1 withdrawal, got %d", len(exchange.withdrawn))
+	}
+}
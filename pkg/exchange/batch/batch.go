@@ -10,6 +10,10 @@ import (
 
 type TradeBatchQuery struct {
 	types.Exchange
+
+	// Limiter throttles the trade queries, by default the exchanges that do not limit their own requests
+	// are queried every 5 seconds
+	Limiter *rate.Limiter
 }
 
 func (e TradeBatchQuery) Query(ctx context.Context, symbol string, options *types.TradeQueryOptions) (c chan types.Trade, errC chan error) {
@@ -28,7 +32,14 @@ func (e TradeBatchQuery) Query(ctx context.Context, symbol string, options *type
 	var lastTradeID = options.LastTradeID
 
 	go func() {
-		limiter := rate.NewLimiter(rate.Every(5*time.Second), 2) // from binance (original 1200, use 1000 for safety)
+		limiter := e.Limiter
+		if limiter == nil {
+			if rateLimited, ok := e.Exchange.(types.RateLimitedExchange); ok && rateLimited.IsRateLimited() {
+				limiter = rate.NewLimiter(rate.Inf, 1)
+			} else {
+				limiter = rate.NewLimiter(rate.Every(5*time.Second), 2)
+			}
+		}
 
 		defer close(c)
 		defer close(errC)
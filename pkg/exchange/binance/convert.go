@@ -3,6 +3,7 @@ package binance
 import (
 	"fmt"
 	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/futures"
 	"github.com/pkg/errors"
 	"github.com/pymba86/bingo/pkg/fixedpoint"
 	"github.com/pymba86/bingo/pkg/types"
@@ -136,12 +137,13 @@ func toGlobalOrders(binanceOrders []*binance.Order) (orders []types.Order, err e
 func toGlobalOrder(binanceOrder *binance.Order, isMargin bool) (*types.Order, error) {
 	return &types.Order{
 		SubmitOrder: types.SubmitOrder{
-			ClientOrderId: binanceOrder.ClientOrderID,
+			ClientOrderId: toGlobalSpotClientOrderID(binanceOrder.ClientOrderID),
 			Symbol:        binanceOrder.Symbol,
 			Side:          toGlobalSideType(binanceOrder.Side),
 			Type:          toGlobalOrderType(binanceOrder.Type),
 			Quantity:      util.MustParseFloat(binanceOrder.OrigQuantity),
 			Price:         util.MustParseFloat(binanceOrder.Price),
+			StopPrice:     util.MustParseFloat(binanceOrder.StopPrice),
 			TimeInForce:   string(binanceOrder.TimeInForce),
 		},
 		Exchange:         types.ExchangeBinance,
@@ -153,9 +155,38 @@ func toGlobalOrder(binanceOrder *binance.Order, isMargin bool) (*types.Order, er
 		UpdateTime:       types.Time(millisecondTime(binanceOrder.UpdateTime)),
 		IsMargin:         isMargin,
 		IsIsolated:       binanceOrder.IsIsolated,
+		OrderListID:      toGlobalOrderListID(binanceOrder.OrderListId),
 	}, nil
 }
 
+// toGlobalOrderListID converts the order list id, binance uses -1 for the orders that are not in an order list
+func toGlobalOrderListID(orderListID int64) uint64 {
+	if orderListID < 0 {
+		return 0
+	}
+
+	return uint64(orderListID)
+}
+
+func toGlobalCreatedOrder(response *binance.CreateOrderResponse, isMargin bool) (*types.Order, error) {
+	return toGlobalOrder(&binance.Order{
+		Symbol:                   response.Symbol,
+		OrderID:                  response.OrderID,
+		ClientOrderID:            response.ClientOrderID,
+		Price:                    response.Price,
+		OrigQuantity:             response.OrigQuantity,
+		ExecutedQuantity:         response.ExecutedQuantity,
+		CummulativeQuoteQuantity: response.CummulativeQuoteQuantity,
+		Status:                   response.Status,
+		TimeInForce:              response.TimeInForce,
+		Type:                     response.Type,
+		Side:                     response.Side,
+		UpdateTime:               response.TransactTime,
+		Time:                     response.TransactTime,
+		IsIsolated:               response.IsIsolated,
+	}, isMargin)
+}
+
 func millisecondTime(t int64) time.Time {
 	return time.Unix(0, t*int64(time.Millisecond))
 }
@@ -287,14 +318,214 @@ func ConvertTrades(remoteTrades []*binance.TradeV3) (trades []types.Trade, err e
 func convertSubscription(s types.Subscription) string {
 	// binance uses lower case symbol name,
 	// for kline, it's "<symbol>@kline_<interval>"
-	// for depth, it's "<symbol>@depth OR <symbol>@depth@100ms"
+	// for depth, it's "<symbol>@depth<levels>@<speed>", e.g. "<symbol>@depth", "<symbol>@depth@100ms" or "<symbol>@depth5@100ms"
+	// for market trade, it's "<symbol>@aggTrade", the aggregated trades are supported by both spot and futures
 	switch s.Channel {
 	case types.KLineChannel:
 		return fmt.Sprintf("%s@%s_%s", strings.ToLower(s.Symbol), s.Channel, s.Options.String())
 
 	case types.BookChannel:
-		return fmt.Sprintf("%s@depth", strings.ToLower(s.Symbol))
+		name := fmt.Sprintf("%s@depth%s", strings.ToLower(s.Symbol), s.Options.Depth)
+		if len(s.Options.Speed) > 0 {
+			name += "@" + s.Options.Speed
+		}
+
+		return name
+
+	case types.MarketTradeChannel:
+		return fmt.Sprintf("%s@aggTrade", strings.ToLower(s.Symbol))
+
+	case types.BookTickerChannel:
+		return fmt.Sprintf("%s@bookTicker", strings.ToLower(s.Symbol))
 	}
 
 	return fmt.Sprintf("%s@%s", strings.ToLower(s.Symbol), s.Channel)
-}
\ No newline at end of file
+}
+func toLocalFuturesOrderType(orderType types.OrderType) (futures.OrderType, error) {
+	switch orderType {
+	case types.OrderTypeLimit, types.OrderTypeLimitMaker:
+		return futures.OrderTypeLimit, nil
+
+	case types.OrderTypeStopLimit:
+		return futures.OrderTypeStop, nil
+
+	case types.OrderTypeStopMarket:
+		return futures.OrderTypeStopMarket, nil
+
+	case types.OrderTypeMarket:
+		return futures.OrderTypeMarket, nil
+	}
+
+	return "", fmt.Errorf("futures order type %s not supported", orderType)
+}
+
+func toGlobalFuturesOrderType(orderType futures.OrderType) types.OrderType {
+	switch orderType {
+	case futures.OrderTypeLimit, futures.OrderTypeTakeProfit:
+		return types.OrderTypeLimit
+
+	case futures.OrderTypeMarket:
+		return types.OrderTypeMarket
+
+	case futures.OrderTypeStop:
+		return types.OrderTypeStopLimit
+
+	case futures.OrderTypeStopMarket, futures.OrderTypeTakeProfitMarket, futures.OrderTypeTrailingStopMarket:
+		return types.OrderTypeStopMarket
+
+	default:
+		log.Errorf("unsupported futures order type: %v", orderType)
+		return ""
+	}
+}
+
+func toGlobalFuturesOrders(futuresOrders []*futures.Order) (orders []types.Order, err error) {
+	for _, futuresOrder := range futuresOrders {
+		order, err := toGlobalFuturesOrder(futuresOrder)
+		if err != nil {
+			return orders, err
+		}
+
+		orders = append(orders, *order)
+	}
+
+	return orders, err
+}
+
+func toGlobalFuturesOrder(futuresOrder *futures.Order) (*types.Order, error) {
+	return &types.Order{
+		SubmitOrder: types.SubmitOrder{
+			ClientOrderId: futuresOrder.ClientOrderID,
+			Symbol:        futuresOrder.Symbol,
+			Side:          toGlobalSideType(binance.SideType(futuresOrder.Side)),
+			Type:          toGlobalFuturesOrderType(futuresOrder.Type),
+			Quantity:      util.MustParseFloat(futuresOrder.OrigQuantity),
+			Price:         util.MustParseFloat(futuresOrder.Price),
+			TimeInForce:   string(futuresOrder.TimeInForce),
+			ReduceOnly:    futuresOrder.ReduceOnly,
+			PositionSide:  types.PositionSide(futuresOrder.PositionSide),
+		},
+		Exchange:         types.ExchangeBinance,
+		OrderID:          uint64(futuresOrder.OrderID),
+		Status:           toGlobalOrderStatus(binance.OrderStatusType(futuresOrder.Status)),
+		ExecutedQuantity: util.MustParseFloat(futuresOrder.ExecutedQuantity),
+		IsWorking:        futuresOrder.Status == futures.OrderStatusTypeNew || futuresOrder.Status == futures.OrderStatusTypePartiallyFilled,
+		CreationTime:     types.Time(millisecondTime(futuresOrder.Time)),
+		UpdateTime:       types.Time(millisecondTime(futuresOrder.UpdateTime)),
+		IsFutures:        true,
+	}, nil
+}
+
+func toGlobalFuturesTrade(t futures.AccountTrade) (*types.Trade, error) {
+	price, err := strconv.ParseFloat(t.Price, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "price parse error, price: %+v", t.Price)
+	}
+
+	quantity, err := strconv.ParseFloat(t.Quantity, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "quantity parse error, quantity: %+v", t.Quantity)
+	}
+
+	var quoteQuantity = price * quantity
+	if len(t.QuoteQuantity) > 0 {
+		quoteQuantity, err = strconv.ParseFloat(t.QuoteQuantity, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "quote quantity parse error, quoteQuantity: %+v", t.QuoteQuantity)
+		}
+	}
+
+	fee, err := strconv.ParseFloat(t.Commission, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "commission parse error, commission: %+v", t.Commission)
+	}
+
+	return &types.Trade{
+		ID:            t.ID,
+		OrderID:       uint64(t.OrderID),
+		Price:         price,
+		Symbol:        t.Symbol,
+		Exchange:      types.ExchangeBinance,
+		Quantity:      quantity,
+		QuoteQuantity: quoteQuantity,
+		Side:          toGlobalSideType(binance.SideType(t.Side)),
+		IsBuyer:       t.Buyer,
+		IsMaker:       t.Maker,
+		Fee:           fee,
+		FeeCurrency:   t.CommissionAsset,
+		Time:          types.Time(millisecondTime(t.Time)),
+		IsFutures:     true,
+	}, nil
+}
+
+func toGlobalFuturesMarket(symbol futures.Symbol) types.Market {
+	market := types.Market{
+		Symbol:          symbol.Symbol,
+		LocalSymbol:     symbol.Symbol,
+		PricePrecision:  symbol.PricePrecision,
+		VolumePrecision: symbol.QuantityPrecision,
+		QuoteCurrency:   symbol.QuoteAsset,
+		BaseCurrency:    symbol.BaseAsset,
+	}
+
+	if f := symbol.MinNotionalFilter(); f != nil {
+		market.MinNotional = util.MustParseFloat(f.Notional)
+		market.MinAmount = util.MustParseFloat(f.Notional)
+	}
+
+	if f := symbol.LotSizeFilter(); f != nil {
+		market.MinQuantity = util.MustParseFloat(f.MinQuantity)
+		market.MaxQuantity = util.MustParseFloat(f.MaxQuantity)
+		market.StepSize = util.MustParseFloat(f.StepSize)
+	}
+
+	if f := symbol.PriceFilter(); f != nil {
+		market.MaxPrice = util.MustParseFloat(f.MaxPrice)
+		market.MinPrice = util.MustParseFloat(f.MinPrice)
+		market.TickSize = util.MustParseFloat(f.TickSize)
+	}
+
+	return market
+}
+
+func toGlobalMarginType(marginType string) types.MarginType {
+	switch strings.ToLower(marginType) {
+	case "isolated":
+		return types.MarginTypeIsolated
+	case "cross", "crossed":
+		return types.MarginTypeCrossed
+	}
+
+	return types.MarginType(strings.ToUpper(marginType))
+}
+
+// toGlobalFuturesBalance converts the futures wallet balance, the REST api and the user data stream share
+// the same definition: the cross wallet balance is available, and the rest of the wallet is held by the isolated positions
+func toGlobalFuturesBalance(asset string, walletBalance, crossWalletBalance fixedpoint.Value) types.Balance {
+	return types.Balance{
+		Currency:  asset,
+		Available: crossWalletBalance,
+		Locked:    walletBalance - crossWalletBalance,
+	}
+}
+
+func toGlobalFuturesPosition(risk *futures.PositionRisk) (*types.FuturesPosition, error) {
+	leverage, err := strconv.Atoi(risk.Leverage)
+	if err != nil {
+		return nil, errors.Wrapf(err, "leverage parse error, leverage: %+v", risk.Leverage)
+	}
+
+	return &types.FuturesPosition{
+		Symbol:           risk.Symbol,
+		PositionSide:     types.PositionSide(risk.PositionSide),
+		Base:             fixedpoint.MustNewFromString(risk.PositionAmt),
+		EntryPrice:       fixedpoint.MustNewFromString(risk.EntryPrice),
+		MarkPrice:        fixedpoint.MustNewFromString(risk.MarkPrice),
+		LiquidationPrice: fixedpoint.MustNewFromString(risk.LiquidationPrice),
+		UnrealizedPnL:    fixedpoint.MustNewFromString(risk.UnRealizedProfit),
+		Leverage:         leverage,
+		MarginType:       toGlobalMarginType(risk.MarginType),
+		IsolatedMargin:   fixedpoint.MustNewFromString(risk.IsolatedMargin),
+		UpdateTime:       types.Time(time.Now()),
+	}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/futures"
 	log "github.com/sirupsen/logrus"
 	"sync"
 	"time"
@@ -15,6 +16,9 @@ type DepthFrame struct {
 	client  *binance.Client
 	context context.Context
 
+	// futuresClient is set when the frame is synchronizing a futures order book
+	futuresClient *futures.Client
+
 	snapshotMutex sync.Mutex
 	snapshotDepth *DepthEvent
 
@@ -24,8 +28,12 @@ type DepthFrame struct {
 	resetC chan struct{}
 	once   sync.Once
 
+	// replay disables fetching the snapshots, the recorded snapshots are pushed by the replay stream
+	replay bool
+
 	readyCallbacks []func(snapshotDepth DepthEvent, bufEvents []DepthEvent)
 	pushCallbacks  []func(e DepthEvent)
+	fetchCallbacks []func(depth DepthEvent)
 }
 
 func (f *DepthFrame) reset() {
@@ -70,6 +78,17 @@ func (f *DepthFrame) EmitPush(e DepthEvent) {
 	}
 }
 
+// OnFetch is called with the fetched depth snapshots, e.g. for recording them with the stream messages
+func (f *DepthFrame) OnFetch(cb func(depth DepthEvent)) {
+	f.fetchCallbacks = append(f.fetchCallbacks, cb)
+}
+
+func (f *DepthFrame) EmitFetch(depth DepthEvent) {
+	for _, cb := range f.fetchCallbacks {
+		cb(depth)
+	}
+}
+
 func (f *DepthFrame) bufferEvent(e DepthEvent) {
 	if debugBinanceDepth {
 		log.Infof("buffering %s depth event FirstUpdateID = %d, FinalUpdateID = %d", f.Symbol, e.FirstUpdateID, e.FinalUpdateID)
@@ -92,6 +111,20 @@ func (f *DepthFrame) loadDepthSnapshot() error {
 		return err
 	}
 
+	f.EmitFetch(*depth)
+	return f.loadSnapshot(depth)
+}
+
+// PushSnapshot loads the recorded depth snapshot in the replay, the frame is reset if the snapshot does not match the buffered events
+func (f *DepthFrame) PushSnapshot(depth DepthEvent) {
+	if err := f.loadSnapshot(&depth); err != nil {
+		log.WithError(err).Errorf("%s depth snapshot load failed, resetting..", f.Symbol)
+		f.emitReset()
+	}
+}
+
+// loadSnapshot syncs the buffered events with the depth snapshot, and emits the ready event
+func (f *DepthFrame) loadSnapshot(depth *DepthEvent) error {
 	if len(depth.Asks) == 0 {
 		return fmt.Errorf("%s depth response error: empty asks", f.Symbol)
 	}
@@ -140,8 +173,12 @@ func (f *DepthFrame) loadDepthSnapshot() error {
 		// The first processed event should have U (final update ID) <= lastUpdateId+1 AND (first update id) >= lastUpdateId+1.
 		firstEvent := events[0]
 
-		// valid
+		// valid, the futures stream expects U <= lastUpdateId AND u >= lastUpdateId instead
 		nextID := depth.FinalUpdateID + 1
+		if f.futuresClient != nil {
+			nextID = depth.FinalUpdateID
+		}
+
 		if firstEvent.FirstUpdateID > nextID || firstEvent.FinalUpdateID < nextID {
 			return fmt.Errorf("mismatch %s final update id for order book, resetting depth", f.Symbol)
 		}
@@ -157,8 +194,13 @@ func (f *DepthFrame) loadDepthSnapshot() error {
 		log.Infof("READY %s depth, %d bufferred events", f.Symbol, len(events))
 	}
 
+	snapshotDepth := *depth
+	if len(events) > 0 {
+		snapshotDepth.FinalUpdateID = events[len(events)-1].FinalUpdateID
+	}
+
 	f.snapshotMutex.Lock()
-	f.snapshotDepth = depth
+	f.snapshotDepth = &snapshotDepth
 	f.snapshotMutex.Unlock()
 
 	f.EmitReady(*depth, events)
@@ -181,6 +223,10 @@ func (f *DepthFrame) PushEvent(e DepthEvent) {
 		// buffer the events until we loaded the snapshot
 		f.bufferEvent(e)
 
+		if f.replay {
+			return
+		}
+
 		go f.once.Do(func() {
 			if err := f.loadDepthSnapshot(); err != nil {
 				log.WithError(err).Errorf("%s depth snapshot load failed, resetting..", f.Symbol)
@@ -198,7 +244,9 @@ func (f *DepthFrame) PushEvent(e DepthEvent) {
 		return
 	}
 
-	if e.FirstUpdateID > snapshot.FinalUpdateID+1 {
+	// the futures stream links the events by the previous final update ID
+	if (f.futuresClient != nil && e.PreviousUpdateID != snapshot.FinalUpdateID) ||
+		(f.futuresClient == nil && e.FirstUpdateID > snapshot.FinalUpdateID+1) {
 		log.Infof("MISSING %s depth update event, resetting, updateID %d ~ %d (len %d)",
 			f.Symbol,
 			e.FirstUpdateID, e.FinalUpdateID, e.FinalUpdateID-e.FirstUpdateID)
@@ -219,6 +267,10 @@ func (f *DepthFrame) fetch(ctx context.Context) (*DepthEvent, error) {
 		log.Infof("fetching %s depth snapshot", f.Symbol)
 	}
 
+	if f.futuresClient != nil {
+		return f.fetchFutures(ctx)
+	}
+
 	response, err := f.client.NewDepthService().Symbol(f.Symbol).Do(ctx)
 	if err != nil {
 		return nil, err
@@ -239,4 +291,27 @@ func (f *DepthFrame) fetch(ctx context.Context) (*DepthEvent, error) {
 	}
 
 	return &event, nil
-}
\ No newline at end of file
+}
+
+func (f *DepthFrame) fetchFutures(ctx context.Context) (*DepthEvent, error) {
+	response, err := f.futuresClient.NewDepthService().Symbol(f.Symbol).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	event := DepthEvent{
+		Symbol:        f.Symbol,
+		FirstUpdateID: 0,
+		FinalUpdateID: response.LastUpdateID,
+	}
+
+	for _, entry := range response.Bids {
+		event.Bids = append(event.Bids, DepthEntry{PriceLevel: entry.Price, Quantity: entry.Quantity})
+	}
+
+	for _, entry := range response.Asks {
+		event.Asks = append(event.Asks, DepthEntry{PriceLevel: entry.Price, Quantity: entry.Quantity})
+	}
+
+	return &event, nil
+}
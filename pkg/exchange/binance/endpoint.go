@@ -0,0 +1,77 @@
+package binance
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	spotRestBaseURL    = "https://api.binance.com"
+	futuresRestBaseURL = "https://fapi.binance.com"
+	spotWsBaseURL      = "wss://stream.binance.com:9443"
+	futuresWsBaseURL   = "wss://fstream.binance.com"
+
+	testnetSpotRestBaseURL    = "https://testnet.binance.vision"
+	testnetFuturesRestBaseURL = "https://testnet.binancefuture.com"
+	testnetSpotWsBaseURL      = "wss://testnet.binance.vision"
+	testnetFuturesWsBaseURL   = "wss://stream.binancefuture.com"
+)
+
+func (e *Exchange) UseTestnet() {
+	e.EndpointSettings.UseTestnet()
+	e.applyEndpoints()
+}
+
+func (e *Exchange) UseEndpoints(restBaseURL, wsBaseURL string) {
+	e.EndpointSettings.UseEndpoints(strings.TrimSuffix(restBaseURL, "/"), strings.TrimSuffix(wsBaseURL, "/"))
+	e.applyEndpoints()
+}
+
+// applyEndpoints points the spot and the futures clients to the configured endpoints
+// and synchronizes the server time again since the time offset belongs to the previous endpoint.
+func (e *Exchange) applyEndpoints() {
+	spotURL, futuresURL := spotRestBaseURL, futuresRestBaseURL
+	if e.IsTestnet {
+		spotURL, futuresURL = testnetSpotRestBaseURL, testnetFuturesRestBaseURL
+	}
+
+	// a custom endpoint serves both the spot and the futures api paths
+	if len(e.RestBaseURL) > 0 {
+		spotURL, futuresURL = e.RestBaseURL, e.RestBaseURL
+	}
+
+	e.Client.BaseURL = spotURL
+	e.futuresClient.BaseURL = futuresURL
+
+	e.syncServerTime()
+}
+
+// syncServerTime updates the time offset of the spot client, and the futures client in the futures mode
+func (e *Exchange) syncServerTime() {
+	_, _ = e.Client.NewSetServerTimeService().Do(context.Background())
+
+	if e.IsFutures {
+		_, _ = e.futuresClient.NewSetServerTimeService().Do(context.Background())
+	}
+}
+
+// wsBaseURL returns the websocket endpoint of the stream without the /ws or /stream path
+func (s *Stream) wsBaseURL() string {
+	if len(s.WsBaseURL) > 0 {
+		return s.WsBaseURL
+	}
+
+	if s.IsTestnet {
+		if s.IsFutures {
+			return testnetFuturesWsBaseURL
+		}
+
+		return testnetSpotWsBaseURL
+	}
+
+	if s.IsFutures {
+		return futuresWsBaseURL
+	}
+
+	return spotWsBaseURL
+}
@@ -0,0 +1,43 @@
+package binance
+
+import (
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/pkg/errors"
+	"net"
+)
+
+// the binance error codes of the requests that can be sent again
+const (
+	errorCodeDisconnected     = -1001
+	errorCodeTooManyRequests  = -1003
+	errorCodeTimeout          = -1007
+	errorCodeServerBusy       = -1008
+	errorCodeInvalidTimestamp = -1021
+)
+
+// IsTransientError checks if the request is rejected for a temporary reason, e.g. the network timeout,
+// the server overload, the rate limit or the timestamp outside the recv window because of the clock drift.
+// The signed request rejected by the rate limit is not retried by the http client, it's signed again by the retry.
+func (e *Exchange) IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case errorCodeDisconnected, errorCodeTimeout, errorCodeServerBusy, errorCodeInvalidTimestamp, errorCodeTooManyRequests:
+			return true
+		}
+
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isTimestampError(err error) bool {
+	var apiErr *common.APIError
+	return errors.As(err, &apiErr) && apiErr.Code == errorCodeInvalidTimestamp
+}
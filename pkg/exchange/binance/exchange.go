@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/futures"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/pymba86/bingo/pkg/fixedpoint"
@@ -12,31 +13,69 @@ import (
 	log "github.com/sirupsen/logrus"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 const BNB = "BNB"
 
+// submitOrdersConcurrency is the number of the orders submitted at the same time
+const submitOrdersConcurrency = 5
+
+func init() {
+	types.RegisterExchange(types.ExchangeRegistration{
+		Name:    types.ExchangeBinance,
+		Aliases: []string{"bn"},
+		New: func(credentials types.ExchangeCredentials) (types.Exchange, error) {
+			return New(credentials.Key, credentials.Secret), nil
+		},
+		CredentialFields:    []types.CredentialField{types.CredentialKey, types.CredentialSecret},
+		RequiredCredentials: []types.CredentialField{types.CredentialKey, types.CredentialSecret},
+		Capabilities: []types.ExchangeCapability{
+			types.ExchangeCapabilityMargin,
+			types.ExchangeCapabilityFutures,
+			types.ExchangeCapabilityEndpoints,
+			types.ExchangeCapabilityTradeHistory,
+			types.ExchangeCapabilityTransfer,
+			types.ExchangeCapabilityWithdrawal,
+		},
+	})
+}
+
 type Exchange struct {
 	types.MarginSettings
 	types.FuturesSettings
+	types.EndpointSettings
 
 	key, secret string
 	Client      *binance.Client
+
+	futuresClient *futures.Client
+
+	// leverages caches the futures leverage applied per symbol
+	leverageMu sync.Mutex
+	leverages  map[string]int
 }
 
-func (e Exchange) Name() types.ExchangeName {
+func (e *Exchange) Name() types.ExchangeName {
 	return types.ExchangeBinance
 }
 
 func New(key, secret string) *Exchange {
 	var client = binance.NewClient(key, secret)
+	client.HTTPClient = newRateLimitedHTTPClient(false)
 	_, _ = client.NewSetServerTimeService().Do(context.Background())
+
+	// the server time of the futures client is synchronized when the futures mode is enabled
+	var futuresClient = binance.NewFuturesClient(key, secret)
+	futuresClient.HTTPClient = newRateLimitedHTTPClient(true)
+
 	return &Exchange{
 		key:    key,
 		secret: secret,
 
-		Client: client,
+		Client:        client,
+		futuresClient: futuresClient,
 	}
 }
 
@@ -103,6 +142,10 @@ func (e *Exchange) QueryTickers(ctx context.Context, symbol ...string) (map[stri
 func (e *Exchange) QueryMarkets(ctx context.Context) (types.MarketMap, error) {
 	log.Info("querying market info...")
 
+	if e.IsFutures {
+		return e.queryFuturesMarkets(ctx)
+	}
+
 	exchangeInfo, err := e.Client.NewExchangeInfoService().Do(ctx)
 	if err != nil {
 		return nil, err
@@ -157,8 +200,10 @@ func (e *Exchange) QueryAveragePrice(ctx context.Context, symbol string) (float6
 }
 
 func (e *Exchange) NewStream() types.Stream {
-	stream := NewStream(e.Client)
+	stream := NewStream(e.Client, e.futuresClient)
 	stream.MarginSettings = e.MarginSettings
+	stream.FuturesSettings = e.FuturesSettings
+	stream.EndpointSettings = e.EndpointSettings
 	return stream
 }
 
@@ -206,7 +251,10 @@ func (e *Exchange) Withdrawal(ctx context.Context, asset string, amount fixedpoi
 			req.Network(options.Network)
 		}
 		if options.AddressTag != "" {
-			req.Network(options.AddressTag)
+			req.AddressTag(options.AddressTag)
+		}
+		if options.WithdrawOrderID != "" {
+			req.WithdrawOrderID(options.WithdrawOrderID)
 		}
 	}
 
@@ -230,7 +278,8 @@ func (e *Exchange) QueryWithdrawHistory(ctx context.Context, asset string, since
 		}
 	}
 
-	txIDs := map[string]struct{}{}
+	// the pending withdraws have no transaction id yet, so the withdraws are deduplicated by the withdraw id
+	withdrawIDs := map[string]struct{}{}
 
 	for startTime.Before(until) {
 		// startTime ~ endTime must be in 90 days
@@ -254,7 +303,7 @@ func (e *Exchange) QueryWithdrawHistory(ctx context.Context, asset string, since
 		}
 
 		for _, d := range withdraws {
-			if _, ok := txIDs[d.TxID]; ok {
+			if _, ok := withdrawIDs[d.ID]; ok {
 				continue
 			}
 
@@ -279,7 +328,7 @@ func (e *Exchange) QueryWithdrawHistory(ctx context.Context, asset string, since
 				status = fmt.Sprintf("unsupported code: %d", d.Status)
 			}
 
-			txIDs[d.TxID] = struct{}{}
+			withdrawIDs[d.ID] = struct{}{}
 
 			// 2006-01-02 15:04:05
 			applyTime, err := time.Parse("2006-01-02 15:04:05", d.ApplyTime)
@@ -289,6 +338,7 @@ func (e *Exchange) QueryWithdrawHistory(ctx context.Context, asset string, since
 
 			allWithdraws = append(allWithdraws, types.Withdraw{
 				Exchange:        types.ExchangeBinance,
+				ID:              d.ID,
 				ApplyTime:       types.Time(applyTime),
 				Asset:           d.Coin,
 				Amount:          util.MustParseFloat(d.Amount),
@@ -307,6 +357,79 @@ func (e *Exchange) QueryWithdrawHistory(ctx context.Context, asset string, since
 	return allWithdraws, nil
 }
 
+func (e *Exchange) QueryDepositHistory(ctx context.Context, asset string, since, until time.Time) (allDeposits []types.Deposit, err error) {
+	startTime := since
+
+	var emptyTime = time.Time{}
+	if startTime == emptyTime {
+		startTime, err = e.getLaunchDate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	txIDs := map[string]struct{}{}
+
+	for startTime.Before(until) {
+		// startTime ~ endTime must be in 90 days
+		endTime := startTime.AddDate(0, 0, 60)
+		if endTime.After(until) {
+			endTime = until
+		}
+
+		req := e.Client.NewListDepositsService()
+		if len(asset) > 0 {
+			req.Coin(asset)
+		}
+
+		deposits, err := req.
+			StartTime(startTime.UnixNano() / int64(time.Millisecond)).
+			EndTime(endTime.UnixNano() / int64(time.Millisecond)).
+			Do(ctx)
+
+		if err != nil {
+			return allDeposits, err
+		}
+
+		for _, d := range deposits {
+			if _, ok := txIDs[d.TxID]; ok {
+				continue
+			}
+
+			// 0(0:pending,6: credited but cannot withdraw, 1:success)
+			status := ""
+			switch d.Status {
+			case 0:
+				status = "pending"
+			case 6:
+				status = "credited"
+			case 1:
+				status = "success"
+
+			default:
+				status = fmt.Sprintf("unsupported code: %d", d.Status)
+			}
+
+			txIDs[d.TxID] = struct{}{}
+			allDeposits = append(allDeposits, types.Deposit{
+				Exchange:      types.ExchangeBinance,
+				Time:          types.Time(millisecondTime(d.InsertTime)),
+				Asset:         d.Coin,
+				Amount:        util.MustParseFloat(d.Amount),
+				Address:       d.Address,
+				AddressTag:    d.AddressTag,
+				TransactionID: d.TxID,
+				Network:       d.Network,
+				Status:        status,
+			})
+		}
+
+		startTime = endTime
+	}
+
+	return allDeposits, nil
+}
+
 func (e *Exchange) QueryAccountBalances(ctx context.Context) (types.BalanceMap, error) {
 	account, err := e.QueryAccount(ctx)
 	if err != nil {
@@ -321,6 +444,18 @@ func (e *Exchange) PlatformFeeCurrency() string {
 }
 
 func (e *Exchange) QueryAccount(ctx context.Context) (*types.Account, error) {
+	if e.IsFutures {
+		return e.queryFuturesAccount(ctx)
+	}
+
+	if e.IsMargin {
+		if e.IsIsolatedMargin {
+			return e.queryIsolatedMarginAccount(ctx)
+		}
+
+		return e.queryMarginAccount(ctx)
+	}
+
 	account, err := e.Client.NewGetAccountService().Do(ctx)
 	if err != nil {
 		return nil, err
@@ -345,6 +480,10 @@ func (e *Exchange) QueryAccount(ctx context.Context) (*types.Account, error) {
 }
 
 func (e *Exchange) QueryOpenOrders(ctx context.Context, symbol string) (orders []types.Order, err error) {
+	if e.IsFutures {
+		return e.queryFuturesOpenOrders(ctx, symbol)
+	}
+
 	if e.IsMargin {
 		req := e.Client.NewListMarginOpenOrdersService().Symbol(symbol)
 		req.IsIsolated(e.IsIsolatedMargin)
@@ -365,7 +504,6 @@ func (e *Exchange) QueryOpenOrders(ctx context.Context, symbol string) (orders [
 	return toGlobalOrders(binanceOrders)
 }
 
-
 func (e *Exchange) QueryClosedOrders(ctx context.Context, symbol string, since, until time.Time, lastOrderID uint64) (orders []types.Order, err error) {
 	if until.Sub(since) >= 24*time.Hour {
 		until = since.Add(24*time.Hour - time.Millisecond)
@@ -373,6 +511,10 @@ func (e *Exchange) QueryClosedOrders(ctx context.Context, symbol string, since,
 
 	log.Infof("querying closed orders %s from %s <=> %s ...", symbol, since, until)
 
+	if e.IsFutures {
+		return e.queryFuturesClosedOrders(ctx, symbol, since, until, lastOrderID)
+	}
+
 	if e.IsMargin {
 		req := e.Client.NewListMarginOrdersService().Symbol(symbol)
 		req.IsIsolated(e.IsIsolatedMargin)
@@ -410,28 +552,69 @@ func (e *Exchange) QueryClosedOrders(ctx context.Context, symbol string, since,
 	return toGlobalOrders(binanceOrders)
 }
 
+// CancelOrders cancels the orders one by one, a *types.CancelOrdersError with the result of each order
+// is returned when some of the orders can not be canceled.
+func (e *Exchange) CancelOrders(ctx context.Context, orders ...types.Order) error {
+	var results = make([]types.CancelOrderResult, 0, len(orders))
+	var failed bool
 
-func (e *Exchange) CancelOrders(ctx context.Context, orders ...types.Order) (err2 error) {
 	for _, o := range orders {
-		var req = e.Client.NewCancelOrderService()
+		err := e.cancelOrder(ctx, o)
+		if err != nil {
+			log.WithError(err).Errorf("order cancel error: %s", o.String())
+			failed = true
+		}
 
-		// Mandatory
-		req.Symbol(o.Symbol)
+		results = append(results, types.CancelOrderResult{Order: o, Err: err})
+	}
 
-		if o.OrderID > 0 {
-			req.OrderID(int64(o.OrderID))
-		} else if len(o.ClientOrderId) > 0 {
-			req.NewClientOrderID(o.ClientOrderId)
-		}
+	if failed {
+		return &types.CancelOrdersError{Results: results}
+	}
 
-		_, err := req.Do(ctx)
-		if err != nil {
-			log.WithError(err).Errorf("order cancel error")
-			err2 = err
-		}
+	return nil
+}
+
+func (e *Exchange) cancelOrder(ctx context.Context, o types.Order) error {
+	if e.IsFutures {
+		return e.cancelFuturesOrder(ctx, o)
+	}
+
+	if e.IsMargin {
+		return e.cancelMarginOrder(ctx, o)
+	}
+
+	var req = e.Client.NewCancelOrderService()
+
+	// Mandatory
+	req.Symbol(o.Symbol)
+
+	if o.OrderID > 0 {
+		req.OrderID(int64(o.OrderID))
+	} else if len(o.ClientOrderId) > 0 {
+		req.OrigClientOrderID(toLocalSpotClientOrderID(o.ClientOrderId))
+	}
+
+	_, err := req.Do(ctx)
+	return err
+}
+
+func (e *Exchange) cancelMarginOrder(ctx context.Context, o types.Order) error {
+	var req = e.Client.NewCancelMarginOrderService()
+	req.Symbol(o.Symbol)
+
+	if e.IsIsolatedMargin {
+		req.IsIsolated(e.IsIsolatedMargin)
+	}
+
+	if o.OrderID > 0 {
+		req.OrderID(int64(o.OrderID))
+	} else if len(o.ClientOrderId) > 0 {
+		req.OrigClientOrderID(toLocalSpotClientOrderID(o.ClientOrderId))
 	}
 
-	return err2
+	_, err := req.Do(ctx)
+	return err
 }
 
 func (e *Exchange) submitMarginOrder(ctx context.Context, order types.SubmitOrder) (*types.Order, error) {
@@ -508,22 +691,7 @@ func (e *Exchange) submitMarginOrder(ctx context.Context, order types.SubmitOrde
 
 	log.Infof("margin order creation response: %+v", response)
 
-	createdOrder, err := toGlobalOrder(&binance.Order{
-		Symbol:                   response.Symbol,
-		OrderID:                  response.OrderID,
-		ClientOrderID:            response.ClientOrderID,
-		Price:                    response.Price,
-		OrigQuantity:             response.OrigQuantity,
-		ExecutedQuantity:         response.ExecutedQuantity,
-		CummulativeQuoteQuantity: response.CummulativeQuoteQuantity,
-		Status:                   response.Status,
-		TimeInForce:              response.TimeInForce,
-		Type:                     response.Type,
-		Side:                     response.Side,
-		UpdateTime:               response.TransactTime,
-		Time:                     response.TransactTime,
-		IsIsolated:               response.IsIsolated,
-	}, true)
+	createdOrder, err := toGlobalCreatedOrder(response, true)
 
 	return createdOrder, err
 }
@@ -531,31 +699,61 @@ func (e *Exchange) submitMarginOrder(ctx context.Context, order types.SubmitOrde
 // BBGO is a broker on Binance
 const spotBrokerID = "NSUYEBKM"
 
-func newSpotClientOrderID(originalID string) (clientOrderID string) {
+// spotBrokerPrefix is prepended to the spot client order ids, it's stripped from the client order ids
+// of the orders, so the client order ids given by the order executor are kept as they are
+const spotBrokerPrefix = "x-" + spotBrokerID
+
+// clientOrderIDMaxLength is the max length of the client order id of binance
+const clientOrderIDMaxLength = 36
+
+// spotBrokerClientOrderIDLength is the max length of the client order id with the broker prefix
+const spotBrokerClientOrderIDLength = 32
+
+// newClientOrderID keeps the client order id given by the order executor, which could carry the strategy tag,
+// and generates a random one if it's not given.
+func newClientOrderID(originalID string) (clientOrderID string) {
 	if originalID == types.NoClientOrderID {
 		return ""
 	}
 
-	prefix := "x-" + spotBrokerID
-	prefixLen := len(prefix)
-
 	if originalID != "" {
-		// try to keep the whole original client order ID if user specifies it.
-		if prefixLen+len(originalID) > 32 {
-			return originalID
+		if len(originalID) > clientOrderIDMaxLength {
+			return originalID[:clientOrderIDMaxLength]
 		}
 
-		clientOrderID = prefix + originalID
-		return clientOrderID
+		return originalID
 	}
 
-	clientOrderID = uuid.New().String()
-	clientOrderID = prefix + clientOrderID
-	if len(clientOrderID) > 32 {
-		return clientOrderID[0:32]
+	return uuid.New().String()
+}
+
+// newSpotClientOrderID prefixes the client order id with the broker id,
+// the client order ids that do not fit with the prefix are sent as they are.
+func newSpotClientOrderID(originalID string) (clientOrderID string) {
+	if originalID == types.NoClientOrderID {
+		return ""
+	}
+
+	if originalID == "" {
+		clientOrderID = spotBrokerPrefix + uuid.New().String()
+		return clientOrderID[:spotBrokerClientOrderIDLength]
+	}
+
+	return toLocalSpotClientOrderID(newClientOrderID(originalID))
+}
+
+// toLocalSpotClientOrderID returns the client order id of the spot order that is sent to binance
+func toLocalSpotClientOrderID(clientOrderID string) string {
+	if len(clientOrderID) == 0 || len(spotBrokerPrefix)+len(clientOrderID) > spotBrokerClientOrderIDLength {
+		return clientOrderID
 	}
 
-	return clientOrderID
+	return spotBrokerPrefix + clientOrderID
+}
+
+// toGlobalSpotClientOrderID strips the broker prefix from the client order id of the spot order
+func toGlobalSpotClientOrderID(clientOrderID string) string {
+	return strings.TrimPrefix(clientOrderID, spotBrokerPrefix)
 }
 
 func (e *Exchange) submitSpotOrder(ctx context.Context, order types.SubmitOrder) (*types.Order, error) {
@@ -618,52 +816,80 @@ func (e *Exchange) submitSpotOrder(ctx context.Context, order types.SubmitOrder)
 
 	log.Infof("spot order creation response: %+v", response)
 
-	createdOrder, err := toGlobalOrder(&binance.Order{
-		Symbol:                   response.Symbol,
-		OrderID:                  response.OrderID,
-		ClientOrderID:            response.ClientOrderID,
-		Price:                    response.Price,
-		OrigQuantity:             response.OrigQuantity,
-		ExecutedQuantity:         response.ExecutedQuantity,
-		CummulativeQuoteQuantity: response.CummulativeQuoteQuantity,
-		Status:                   response.Status,
-		TimeInForce:              response.TimeInForce,
-		Type:                     response.Type,
-		Side:                     response.Side,
-		UpdateTime:               response.TransactTime,
-		Time:                     response.TransactTime,
-		IsIsolated:               response.IsIsolated,
-		// StopPrice:
-		// IcebergQuantity:
-		// UpdateTime:
-		// IsWorking:               ,
-	}, false)
+	createdOrder, err := toGlobalCreatedOrder(response, false)
 
 	return createdOrder, err
 }
 
+// SubmitOrders submits the orders concurrently, the requests are still throttled by the rate limiter.
+// The created orders are returned in the submission order, the orders that can not be submitted
+// are reported by *types.SubmitOrdersError.
 func (e *Exchange) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (createdOrders types.OrderSlice, err error) {
-	for _, order := range orders {
-		var createdOrder *types.Order
+	results := make([]*types.Order, len(orders))
+	errs := make([]error, len(orders))
 
-		if e.IsMargin {
-			createdOrder, err = e.submitMarginOrder(ctx, order)
-		} else {
-			createdOrder, err = e.submitSpotOrder(ctx, order)
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, submitOrdersConcurrency)
+
+	for i := range orders {
+		select {
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+
+		case sem <- struct{}{}:
 		}
 
-		if err != nil {
-			return createdOrders, err
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			results[i], errs[i] = e.submitOrder(ctx, orders[i])
+		}(i)
+	}
+
+	wg.Wait()
+
+	var submitErr types.SubmitOrdersError
+	var timestampRejected bool
+	for i, createdOrder := range results {
+		if errs[i] == nil && createdOrder == nil {
+			errs[i] = errors.New("nil converted order")
 		}
 
-		if createdOrder == nil {
-			return createdOrders, errors.New("nil converted order")
+		if errs[i] != nil {
+			log.WithError(errs[i]).Errorf("can not submit %s %s %s order, quantity %f, price %f", orders[i].Symbol, orders[i].Side, orders[i].Type, orders[i].Quantity, orders[i].Price)
+			submitErr.Failures = append(submitErr.Failures, types.SubmitOrderFailure{Order: orders[i], Err: errs[i]})
+			timestampRejected = timestampRejected || isTimestampError(errs[i])
+			continue
 		}
 
 		createdOrders = append(createdOrders, *createdOrder)
 	}
 
-	return createdOrders, err
+	// the local clock drifts from the server, so the following requests need the new time offset
+	if timestampRejected {
+		e.syncServerTime()
+	}
+
+	if len(submitErr.Failures) > 0 {
+		return createdOrders, &submitErr
+	}
+
+	return createdOrders, nil
+}
+
+func (e *Exchange) submitOrder(ctx context.Context, order types.SubmitOrder) (*types.Order, error) {
+	if e.IsFutures {
+		return e.submitFuturesOrder(ctx, order)
+	} else if e.IsMargin {
+		return e.submitMarginOrder(ctx, order)
+	}
+
+	return e.submitSpotOrder(ctx, order)
 }
 
 // QueryKLines queries the Kline/candlestick bars for a symbol. Klines are uniquely identified by their open time.
@@ -686,6 +912,10 @@ func (e *Exchange) QueryKLines(ctx context.Context, symbol string, interval type
 
 	log.Infof("querying kline %s %s %v", symbol, interval, options)
 
+	if e.IsFutures {
+		return e.queryFuturesKLines(ctx, symbol, interval, limit, options)
+	}
+
 	req := e.Client.NewKlinesService().
 		Symbol(symbol).
 		Interval(string(interval)).
@@ -731,6 +961,10 @@ func (e *Exchange) QueryKLines(ctx context.Context, symbol string, interval type
 func (e *Exchange) QueryTrades(ctx context.Context, symbol string, options *types.TradeQueryOptions) (trades []types.Trade, err error) {
 	var remoteTrades []*binance.TradeV3
 
+	if e.IsFutures {
+		return e.queryFuturesTrades(ctx, symbol, options)
+	}
+
 	if e.IsMargin {
 		req := e.Client.NewListMarginTradesService().
 			IsIsolated(e.IsIsolatedMargin).
@@ -827,8 +1061,7 @@ func (e *Exchange) BatchQueryKLines(ctx context.Context, symbol string, interval
 }
 
 func (e *Exchange) QueryLastFundingRate(ctx context.Context, symbol string) (fixedpoint.Value, error) {
-	futuresClient := binance.NewFuturesClient(e.key, e.secret)
-	rates, err := futuresClient.NewFundingRateService().
+	rates, err := e.futuresClient.NewFundingRateService().
 		Symbol(symbol).
 		Limit(1).
 		Do(ctx)
@@ -0,0 +1,43 @@
+package binance
+
+import (
+	"github.com/adshao/go-binance/v2"
+	"strings"
+	"testing"
+)
+
+func TestSpotClientOrderIDBrokerPrefix(t *testing.T) {
+	tests := []struct {
+		originalID string
+		expected   string
+	}{
+		{originalID: "gridZCbtcZC0a1b2c3d", expected: "x-NSUYEBKMgridZCbtcZC0a1b2c3d"},
+		// the id that does not fit with the prefix is sent as it is
+		{originalID: "0123456789abcdef0123456789abcdef", expected: "0123456789abcdef0123456789abcdef"},
+	}
+
+	for _, test := range tests {
+		clientOrderID := newSpotClientOrderID(test.originalID)
+		if clientOrderID != test.expected {
+			t.Errorf("newSpotClientOrderID(%q) = %q, expected %q", test.originalID, clientOrderID, test.expected)
+		}
+
+		// the orders carry the original id
+		order, err := toGlobalOrder(&binance.Order{ClientOrderID: clientOrderID, Status: binance.OrderStatusTypeNew}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if order.ClientOrderId != test.originalID {
+			t.Errorf("client order id of the order = %q, expected %q", order.ClientOrderId, test.originalID)
+		}
+
+		if local := toLocalSpotClientOrderID(order.ClientOrderId); local != clientOrderID {
+			t.Errorf("toLocalSpotClientOrderID(%q) = %q, expected %q", order.ClientOrderId, local, clientOrderID)
+		}
+	}
+
+	if clientOrderID := newSpotClientOrderID(""); !strings.HasPrefix(clientOrderID, spotBrokerPrefix) || len(clientOrderID) != 32 {
+		t.Errorf("unexpected generated client order id %q", clientOrderID)
+	}
+}
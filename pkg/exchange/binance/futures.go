@@ -0,0 +1,386 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"github.com/pymba86/bingo/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+// UseFutures switches the exchange to the USDⓈ-M futures account, the server time of the futures client is synchronized
+func (e *Exchange) UseFutures() {
+	e.FuturesSettings.UseFutures()
+	_, _ = e.futuresClient.NewSetServerTimeService().Do(context.Background())
+}
+
+func (e *Exchange) queryFuturesMarkets(ctx context.Context) (types.MarketMap, error) {
+	exchangeInfo, err := e.futuresClient.NewExchangeInfoService().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	markets := types.MarketMap{}
+	for _, symbol := range exchangeInfo.Symbols {
+		markets[symbol.Symbol] = toGlobalFuturesMarket(symbol)
+	}
+
+	return markets, nil
+}
+
+func (e *Exchange) queryFuturesAccount(ctx context.Context) (*types.Account, error) {
+	futuresBalances, err := e.futuresClient.NewGetBalanceService().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var balances = map[string]types.Balance{}
+	for _, b := range futuresBalances {
+		walletBalance := fixedpoint.Must(fixedpoint.NewFromString(b.Balance))
+		crossWalletBalance := fixedpoint.Must(fixedpoint.NewFromString(b.CrossWalletBalance))
+		balances[b.Asset] = toGlobalFuturesBalance(b.Asset, walletBalance, crossWalletBalance)
+	}
+
+	a := &types.Account{}
+	a.UpdateBalances(balances)
+	return a, nil
+}
+
+func (e *Exchange) queryFuturesOpenOrders(ctx context.Context, symbol string) ([]types.Order, error) {
+	futuresOrders, err := e.futuresClient.NewListOpenOrdersService().Symbol(symbol).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGlobalFuturesOrders(futuresOrders)
+}
+
+func (e *Exchange) queryFuturesClosedOrders(ctx context.Context, symbol string, since, until time.Time, lastOrderID uint64) ([]types.Order, error) {
+	req := e.futuresClient.NewListOrdersService().Symbol(symbol)
+
+	if lastOrderID > 0 {
+		req.OrderID(int64(lastOrderID))
+	} else {
+		req.StartTime(since.UnixNano() / int64(time.Millisecond)).
+			EndTime(until.UnixNano() / int64(time.Millisecond))
+	}
+
+	futuresOrders, err := req.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toGlobalFuturesOrders(futuresOrders)
+}
+
+func (e *Exchange) cancelFuturesOrder(ctx context.Context, o types.Order) error {
+	req := e.futuresClient.NewCancelOrderService().Symbol(o.Symbol)
+
+	if o.OrderID > 0 {
+		req.OrderID(int64(o.OrderID))
+	} else if len(o.ClientOrderId) > 0 {
+		req.OrigClientOrderID(o.ClientOrderId)
+	}
+
+	_, err := req.Do(ctx)
+	return err
+}
+
+func (e *Exchange) submitFuturesOrder(ctx context.Context, order types.SubmitOrder) (*types.Order, error) {
+	orderType, err := toLocalFuturesOrderType(order.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Leverage > 0 {
+		if err := e.ensureLeverage(ctx, order.Symbol, order.Leverage); err != nil {
+			return nil, err
+		}
+	}
+
+	req := e.futuresClient.NewCreateOrderService().
+		Symbol(order.Symbol).
+		Type(orderType).
+		Side(futures.SideType(order.Side))
+
+	clientOrderID := newClientOrderID(order.ClientOrderId)
+	if len(clientOrderID) > 0 {
+		req.NewClientOrderID(clientOrderID)
+	}
+
+	// use response result format
+	req.NewOrderResponseType(futures.NewOrderRespTypeRESULT)
+
+	if order.ReduceOnly {
+		req.ReduceOnly(order.ReduceOnly)
+	}
+
+	if len(order.PositionSide) > 0 {
+		req.PositionSide(futures.PositionSideType(order.PositionSide))
+	}
+
+	if len(order.QuantityString) > 0 {
+		req.Quantity(order.QuantityString)
+	} else if order.Market.Symbol != "" {
+		req.Quantity(order.Market.FormatQuantity(order.Quantity))
+	} else {
+		req.Quantity(strconv.FormatFloat(order.Quantity, 'f', 8, 64))
+	}
+
+	// set price field for limit orders
+	switch order.Type {
+	case types.OrderTypeStopLimit, types.OrderTypeLimit, types.OrderTypeLimitMaker:
+		if len(order.PriceString) > 0 {
+			req.Price(order.PriceString)
+		} else if order.Market.Symbol != "" {
+			req.Price(order.Market.FormatPrice(order.Price))
+		}
+	}
+
+	switch order.Type {
+	case types.OrderTypeStopLimit, types.OrderTypeStopMarket:
+		if len(order.StopPriceString) == 0 {
+			return nil, fmt.Errorf("stop price string can not be empty")
+		}
+
+		req.StopPrice(order.StopPriceString)
+	}
+
+	if len(order.TimeInForce) > 0 {
+		req.TimeInForce(futures.TimeInForceType(order.TimeInForce))
+	} else {
+		switch order.Type {
+		case types.OrderTypeLimitMaker:
+			req.TimeInForce(futures.TimeInForceTypeGTX)
+		case types.OrderTypeLimit, types.OrderTypeStopLimit:
+			req.TimeInForce(futures.TimeInForceTypeGTC)
+		}
+	}
+
+	response, err := req.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("futures order creation response: %+v", response)
+
+	return toGlobalFuturesOrder(&futures.Order{
+		Symbol:           response.Symbol,
+		OrderID:          response.OrderID,
+		ClientOrderID:    response.ClientOrderID,
+		Price:            response.Price,
+		ReduceOnly:       response.ReduceOnly,
+		OrigQuantity:     response.OrigQuantity,
+		ExecutedQuantity: response.ExecutedQuantity,
+		CumQuote:         response.CumQuote,
+		Status:           response.Status,
+		TimeInForce:      response.TimeInForce,
+		Type:             response.Type,
+		Side:             response.Side,
+		StopPrice:        response.StopPrice,
+		Time:             response.UpdateTime,
+		UpdateTime:       response.UpdateTime,
+		AvgPrice:         response.AvgPrice,
+		PositionSide:     response.PositionSide,
+	})
+}
+
+func (e *Exchange) queryFuturesKLines(ctx context.Context, symbol string, interval types.Interval, limit int, options types.KLineQueryOptions) ([]types.KLine, error) {
+	req := e.futuresClient.NewKlinesService().
+		Symbol(symbol).
+		Interval(string(interval)).
+		Limit(limit)
+
+	if options.StartTime != nil {
+		req.StartTime(options.StartTime.UnixNano() / int64(time.Millisecond))
+	}
+
+	if options.EndTime != nil {
+		req.EndTime(options.EndTime.UnixNano() / int64(time.Millisecond))
+	}
+
+	resp, err := req.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var kLines []types.KLine
+	for _, k := range resp {
+		kLines = append(kLines, types.KLine{
+			Exchange:                 types.ExchangeBinance,
+			Symbol:                   symbol,
+			Interval:                 interval,
+			StartTime:                time.Unix(0, k.OpenTime*int64(time.Millisecond)),
+			EndTime:                  time.Unix(0, k.CloseTime*int64(time.Millisecond)),
+			Open:                     util.MustParseFloat(k.Open),
+			Close:                    util.MustParseFloat(k.Close),
+			High:                     util.MustParseFloat(k.High),
+			Low:                      util.MustParseFloat(k.Low),
+			Volume:                   util.MustParseFloat(k.Volume),
+			QuoteVolume:              util.MustParseFloat(k.QuoteAssetVolume),
+			TakerBuyBaseAssetVolume:  util.MustParseFloat(k.TakerBuyBaseAssetVolume),
+			TakerBuyQuoteAssetVolume: util.MustParseFloat(k.TakerBuyQuoteAssetVolume),
+			NumberOfTrades:           uint64(k.TradeNum),
+			Closed:                   true,
+		})
+	}
+
+	return kLines, nil
+}
+
+func (e *Exchange) queryFuturesTrades(ctx context.Context, symbol string, options *types.TradeQueryOptions) (trades []types.Trade, err error) {
+	req := e.futuresClient.NewListAccountTradeService().
+		Symbol(symbol)
+
+	if options.Limit > 0 {
+		req.Limit(int(options.Limit))
+	} else {
+		req.Limit(1000)
+	}
+
+	if options.StartTime != nil {
+		req.StartTime(options.StartTime.UnixNano() / int64(time.Millisecond))
+	}
+
+	if options.EndTime != nil {
+		req.EndTime(options.EndTime.UnixNano() / int64(time.Millisecond))
+	}
+
+	// BINANCE uses inclusive last trade ID
+	if options.LastTradeID > 0 {
+		req.FromID(options.LastTradeID)
+	}
+
+	remoteTrades, err := req.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range remoteTrades {
+		localTrade, err := toGlobalFuturesTrade(*t)
+		if err != nil {
+			log.WithError(err).Errorf("can not convert binance futures trade: %+v", t)
+			continue
+		}
+
+		trades = append(trades, *localTrade)
+	}
+
+	return trades, nil
+}
+
+func (e *Exchange) QueryPositions(ctx context.Context, symbols ...string) (positions []types.FuturesPosition, err error) {
+	req := e.futuresClient.NewGetPositionRiskService()
+	if len(symbols) == 1 {
+		req.Symbol(symbols[0])
+	}
+
+	risks, err := req.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := make(map[string]struct{}, len(symbols))
+	for _, symbol := range symbols {
+		filter[symbol] = struct{}{}
+	}
+
+	for _, risk := range risks {
+		if _, ok := filter[risk.Symbol]; len(filter) > 0 && !ok {
+			continue
+		}
+
+		position, err := toGlobalFuturesPosition(risk)
+		if err != nil {
+			return positions, err
+		}
+
+		positions = append(positions, *position)
+	}
+
+	return positions, nil
+}
+
+func (e *Exchange) SetLeverage(ctx context.Context, symbol string, leverage int) error {
+	resp, err := e.futuresClient.NewChangeLeverageService().
+		Symbol(symbol).
+		Leverage(leverage).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("%s leverage is changed to %dx, max notional value %s", resp.Symbol, resp.Leverage, resp.MaxNotionalValue)
+
+	e.leverageMu.Lock()
+	if e.leverages == nil {
+		e.leverages = make(map[string]int)
+	}
+	e.leverages[symbol] = resp.Leverage
+	e.leverageMu.Unlock()
+	return nil
+}
+
+// ensureLeverage changes the symbol leverage only when it differs from the last applied one.
+func (e *Exchange) ensureLeverage(ctx context.Context, symbol string, leverage int) error {
+	e.leverageMu.Lock()
+	applied, ok := e.leverages[symbol]
+	e.leverageMu.Unlock()
+
+	if ok && applied == leverage {
+		return nil
+	}
+
+	return e.SetLeverage(ctx, symbol, leverage)
+}
+
+func (e *Exchange) SetMarginType(ctx context.Context, symbol string, marginType types.MarginType) error {
+	return e.futuresClient.NewChangeMarginTypeService().
+		Symbol(symbol).
+		MarginType(futures.MarginType(marginType)).
+		Do(ctx)
+}
+
+func (e *Exchange) QueryFundingRateHistory(ctx context.Context, symbol string, since, until time.Time) (rates []types.FundingRate, err error) {
+	startTime := since
+
+	for startTime.Before(until) {
+		resp, err := e.futuresClient.NewFundingRateService().
+			Symbol(symbol).
+			StartTime(startTime.UnixNano() / int64(time.Millisecond)).
+			EndTime(until.UnixNano() / int64(time.Millisecond)).
+			Limit(1000).
+			Do(ctx)
+		if err != nil {
+			return rates, err
+		}
+
+		if len(resp) == 0 {
+			break
+		}
+
+		for _, r := range resp {
+			rate, err := fixedpoint.NewFromString(r.FundingRate)
+			if err != nil {
+				return rates, err
+			}
+
+			rates = append(rates, types.FundingRate{
+				Symbol:      r.Symbol,
+				FundingRate: rate,
+				FundingTime: types.Time(millisecondTime(r.FundingTime)),
+			})
+		}
+
+		startTime = millisecondTime(resp[len(resp)-1].FundingTime + 1)
+		if len(resp) < 1000 {
+			break
+		}
+	}
+
+	return rates, nil
+}
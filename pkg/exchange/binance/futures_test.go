@@ -0,0 +1,94 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"github.com/adshao/go-binance/v2"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExchange_EnsureLeverageCached(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v1/leverage" {
+			http.NotFound(w, r)
+			return
+		}
+
+		atomic.AddInt32(&calls, 1)
+		_ = r.ParseForm()
+		_, _ = fmt.Fprintf(w, `{"leverage":%s,"maxNotionalValue":"1000000","symbol":"%s"}`,
+			r.Form.Get("leverage"), r.Form.Get("symbol"))
+	}))
+	defer server.Close()
+
+	futuresClient := binance.NewFuturesClient("key", "secret")
+	futuresClient.BaseURL = server.URL
+	e := &Exchange{futuresClient: futuresClient}
+
+	ctx := context.Background()
+	for _, leverage := range []int{5, 5, 10, 10} {
+		if err := e.ensureLeverage(ctx, "BTCUSDT", leverage); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.ensureLeverage(ctx, "ETHUSDT", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("expected 3 leverage changes, got %d", n)
+	}
+}
+
+func TestFuturesBalancesOfRESTAndStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v2/balance" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = fmt.Fprint(w, `[{"accountAlias":"SgsR","asset":"USDT","balance":"122.60","crossWalletBalance":"100.10",`+
+			`"crossUnPnl":"1.50","availableBalance":"80.00","maxWithdrawAmount":"80.00"}]`)
+	}))
+	defer server.Close()
+
+	futuresClient := binance.NewFuturesClient("key", "secret")
+	futuresClient.BaseURL = server.URL
+	e := &Exchange{futuresClient: futuresClient}
+
+	account, err := e.queryFuturesAccount(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := ParseEvent(`{"e":"ACCOUNT_UPDATE","E":1564745798939,"T":1564745798938,` +
+		`"a":{"m":"ORDER","B":[{"a":"USDT","wb":"122.60","cw":"100.10","bc":"0"}],"P":[]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accountUpdate, ok := event.(*AccountUpdateEvent)
+	if !ok {
+		t.Fatalf("unexpected event: %T", event)
+	}
+
+	restBalance, ok := account.Balance("USDT")
+	if !ok {
+		t.Fatal("expected the USDT balance")
+	}
+
+	streamBalance := accountUpdate.BalanceMap()["USDT"]
+	if restBalance != streamBalance {
+		t.Fatalf("the balances differ, rest: %+v, stream: %+v", restBalance, streamBalance)
+	}
+
+	if restBalance.Available.Float64() != 100.10 || restBalance.Locked.Float64() != 22.50 {
+		t.Fatalf("unexpected balance: %+v", restBalance)
+	}
+}
@@ -0,0 +1,431 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adshao/go-binance/v2"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// binance only returns 100 records per page for the margin history endpoints
+const marginHistoryPageSize = 100
+
+// marginHistoryWindow is the max interval between the start time and the end time of the margin history endpoints
+const marginHistoryWindow = 30 * 24 * time.Hour
+
+type marginLoanRow struct {
+	IsolatedSymbol string           `json:"isolatedSymbol"`
+	TxID           uint64           `json:"txId"`
+	Asset          string           `json:"asset"`
+	Principal      fixedpoint.Value `json:"principal"`
+	Timestamp      int64            `json:"timestamp"`
+	Status         string           `json:"status"`
+}
+
+type marginRepayRow struct {
+	IsolatedSymbol string           `json:"isolatedSymbol"`
+	TxID           uint64           `json:"txId"`
+	Asset          string           `json:"asset"`
+	Amount         fixedpoint.Value `json:"amount"`
+	Interest       fixedpoint.Value `json:"interest"`
+	Principal      fixedpoint.Value `json:"principal"`
+	Timestamp      int64            `json:"timestamp"`
+	Status         string           `json:"status"`
+}
+
+type marginInterestRow struct {
+	IsolatedSymbol      string           `json:"isolatedSymbol"`
+	Asset               string           `json:"asset"`
+	Interest            fixedpoint.Value `json:"interest"`
+	InterestAccuredTime int64            `json:"interestAccuredTime"`
+	InterestRate        fixedpoint.Value `json:"interestRate"`
+	Principal           fixedpoint.Value `json:"principal"`
+	Type                string           `json:"type"`
+}
+
+func (e *Exchange) marginParams(asset string) url.Values {
+	params := url.Values{}
+	params.Set("asset", asset)
+	if e.IsIsolatedMargin {
+		params.Set("isIsolated", "TRUE")
+		params.Set("symbol", e.IsolatedMarginSymbol)
+	}
+
+	return params
+}
+
+func (e *Exchange) marginHistoryParams(asset string, startTime, endTime *time.Time) url.Values {
+	params := url.Values{}
+	params.Set("asset", asset)
+	params.Set("size", strconv.Itoa(marginHistoryPageSize))
+
+	if e.IsIsolatedMargin {
+		params.Set("isolatedSymbol", e.IsolatedMarginSymbol)
+	}
+
+	if startTime != nil {
+		params.Set("startTime", strconv.FormatInt(startTime.UnixNano()/int64(time.Millisecond), 10))
+	}
+
+	if endTime != nil {
+		params.Set("endTime", strconv.FormatInt(endTime.UnixNano()/int64(time.Millisecond), 10))
+	}
+
+	return params
+}
+
+// queryMarginHistory queries a margin history endpoint in the 30 days windows from the start time to the end time,
+// handle decodes the rows of a page and returns the number of rows decoded.
+// The default time range of binance is used if the start time is not given.
+func (e *Exchange) queryMarginHistory(ctx context.Context, endpoint, asset string, startTime, endTime *time.Time, handle func(rows json.RawMessage) (int, error)) error {
+	if startTime == nil {
+		return e.queryMarginHistoryPages(ctx, endpoint, e.marginHistoryParams(asset, nil, endTime), handle)
+	}
+
+	since := *startTime
+	if since.IsZero() {
+		launchDate, err := e.getLaunchDate()
+		if err != nil {
+			return err
+		}
+
+		since = launchDate
+	}
+
+	until := time.Now()
+	if endTime != nil {
+		until = *endTime
+	}
+
+	for since.Before(until) {
+		windowEnd := since.Add(marginHistoryWindow)
+		if windowEnd.After(until) {
+			windowEnd = until
+		}
+
+		if err := e.queryMarginHistoryPages(ctx, endpoint, e.marginHistoryParams(asset, &since, &windowEnd), handle); err != nil {
+			return err
+		}
+
+		// the end time is inclusive
+		since = windowEnd.Add(time.Millisecond)
+	}
+
+	return nil
+}
+
+// queryMarginHistoryPages walks through the pages of a margin history endpoint
+func (e *Exchange) queryMarginHistoryPages(ctx context.Context, endpoint string, params url.Values, handle func(rows json.RawMessage) (int, error)) error {
+	for current := 1; ; current++ {
+		params.Set("current", strconv.Itoa(current))
+
+		var resp struct {
+			Rows  json.RawMessage `json:"rows"`
+			Total int             `json:"total"`
+		}
+
+		if err := e.signedRequest(ctx, http.MethodGet, endpoint, params, &resp); err != nil {
+			return err
+		}
+
+		if len(resp.Rows) == 0 {
+			return nil
+		}
+
+		n, err := handle(resp.Rows)
+		if err != nil {
+			return err
+		}
+
+		if n == 0 || current*marginHistoryPageSize >= resp.Total {
+			return nil
+		}
+	}
+}
+
+func (e *Exchange) BorrowMarginAsset(ctx context.Context, asset string, amount fixedpoint.Value) error {
+	var tranID int64
+	if e.IsIsolatedMargin {
+		// the loan service of the client sends the legacy isolatedSymbol parameter instead of isIsolated and symbol
+		params := e.marginParams(asset)
+		params.Set("amount", amount.String())
+
+		var resp binance.TransactionResponse
+		if err := e.signedRequest(ctx, http.MethodPost, "/sapi/v1/margin/loan", params, &resp); err != nil {
+			return err
+		}
+
+		tranID = resp.TranID
+	} else {
+		resp, err := e.Client.NewMarginLoanService().
+			Asset(asset).
+			Amount(amount.String()).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		tranID = resp.TranID
+	}
+
+	log.Infof("margin borrowed %f %s, transaction id %d", amount.Float64(), asset, tranID)
+	return nil
+}
+
+func (e *Exchange) RepayMarginAsset(ctx context.Context, asset string, amount fixedpoint.Value) error {
+	var tranID int64
+	if e.IsIsolatedMargin {
+		// the repay service of the client sends the legacy isolatedSymbol parameter instead of isIsolated and symbol
+		params := e.marginParams(asset)
+		params.Set("amount", amount.String())
+
+		var resp binance.TransactionResponse
+		if err := e.signedRequest(ctx, http.MethodPost, "/sapi/v1/margin/repay", params, &resp); err != nil {
+			return err
+		}
+
+		tranID = resp.TranID
+	} else {
+		resp, err := e.Client.NewMarginRepayService().
+			Asset(asset).
+			Amount(amount.String()).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		tranID = resp.TranID
+	}
+
+	log.Infof("margin repaid %f %s, transaction id %d", amount.Float64(), asset, tranID)
+	return nil
+}
+
+func (e *Exchange) QueryMarginAssetMaxBorrowable(ctx context.Context, asset string) (fixedpoint.Value, error) {
+	if !e.IsIsolatedMargin {
+		resp, err := e.Client.NewGetMaxBorrowableService().Asset(asset).Do(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		return fixedpoint.NewFromString(resp.Amount)
+	}
+
+	// the max borrowable service of the client does not support the isolated margin
+	params := url.Values{}
+	params.Set("asset", asset)
+	params.Set("isolatedSymbol", e.IsolatedMarginSymbol)
+
+	var resp struct {
+		Amount      fixedpoint.Value `json:"amount"`
+		BorrowLimit fixedpoint.Value `json:"borrowLimit"`
+	}
+
+	if err := e.signedRequest(ctx, http.MethodGet, "/sapi/v1/margin/maxBorrowable", params, &resp); err != nil {
+		return 0, err
+	}
+
+	return resp.Amount, nil
+}
+
+func (e *Exchange) QueryLoanHistory(ctx context.Context, asset string, startTime, endTime *time.Time) (records []types.MarginLoanRecord, err error) {
+	err = e.queryMarginHistory(ctx, "/sapi/v1/margin/loan", asset, startTime, endTime, func(rows json.RawMessage) (int, error) {
+		var page []marginLoanRow
+		if err := json.Unmarshal(rows, &page); err != nil {
+			return 0, err
+		}
+
+		for _, row := range page {
+			if row.Status != "CONFIRMED" {
+				continue
+			}
+
+			records = append(records, types.MarginLoanRecord{
+				TransactionID:  row.TxID,
+				Exchange:       types.ExchangeBinance,
+				Asset:          row.Asset,
+				Principal:      row.Principal,
+				Time:           types.Time(millisecondTime(row.Timestamp)),
+				IsolatedSymbol: row.IsolatedSymbol,
+			})
+		}
+
+		return len(page), nil
+	})
+
+	return records, err
+}
+
+func (e *Exchange) QueryRepayHistory(ctx context.Context, asset string, startTime, endTime *time.Time) (records []types.MarginRepayRecord, err error) {
+	err = e.queryMarginHistory(ctx, "/sapi/v1/margin/repay", asset, startTime, endTime, func(rows json.RawMessage) (int, error) {
+		var page []marginRepayRow
+		if err := json.Unmarshal(rows, &page); err != nil {
+			return 0, err
+		}
+
+		for _, row := range page {
+			if row.Status != "CONFIRMED" {
+				continue
+			}
+
+			records = append(records, types.MarginRepayRecord{
+				TransactionID:  row.TxID,
+				Exchange:       types.ExchangeBinance,
+				Asset:          row.Asset,
+				Principal:      row.Principal,
+				Interest:       row.Interest,
+				Time:           types.Time(millisecondTime(row.Timestamp)),
+				IsolatedSymbol: row.IsolatedSymbol,
+			})
+		}
+
+		return len(page), nil
+	})
+
+	return records, err
+}
+
+func (e *Exchange) QueryInterestHistory(ctx context.Context, asset string, startTime, endTime *time.Time) (records []types.MarginInterest, err error) {
+	err = e.queryMarginHistory(ctx, "/sapi/v1/margin/interestHistory", asset, startTime, endTime, func(rows json.RawMessage) (int, error) {
+		var page []marginInterestRow
+		if err := json.Unmarshal(rows, &page); err != nil {
+			return 0, err
+		}
+
+		for _, row := range page {
+			records = append(records, types.MarginInterest{
+				Exchange:       types.ExchangeBinance,
+				Asset:          row.Asset,
+				Principal:      row.Principal,
+				Interest:       row.Interest,
+				InterestRate:   row.InterestRate,
+				IsolatedSymbol: row.IsolatedSymbol,
+				Time:           types.Time(millisecondTime(row.InterestAccuredTime)),
+			})
+		}
+
+		return len(page), nil
+	})
+
+	return records, err
+}
+
+func (e *Exchange) TransferMarginAccountAsset(ctx context.Context, asset string, amount fixedpoint.Value, direction types.TransferDirection) error {
+	if !e.IsIsolatedMargin {
+		var transferType binance.MarginTransferType
+		switch direction {
+		case types.TransferIn:
+			transferType = binance.MarginTransferTypeToMargin
+		case types.TransferOut:
+			transferType = binance.MarginTransferTypeToMain
+		default:
+			return fmt.Errorf("unsupported transfer direction: %d", direction)
+		}
+
+		resp, err := e.Client.NewMarginTransferService().
+			Asset(asset).
+			Amount(amount.String()).
+			Type(transferType).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("margin transferred %f %s, direction %d, transaction id %d", amount.Float64(), asset, direction, resp.TranID)
+		return nil
+	}
+
+	// the client has no isolated margin transfer service
+	params := url.Values{}
+	params.Set("asset", asset)
+	params.Set("amount", amount.String())
+	params.Set("symbol", e.IsolatedMarginSymbol)
+
+	switch direction {
+	case types.TransferIn:
+		params.Set("transFrom", "SPOT")
+		params.Set("transTo", "ISOLATED_MARGIN")
+	case types.TransferOut:
+		params.Set("transFrom", "ISOLATED_MARGIN")
+		params.Set("transTo", "SPOT")
+	default:
+		return fmt.Errorf("unsupported transfer direction: %d", direction)
+	}
+
+	var resp binance.TransactionResponse
+	if err := e.signedRequest(ctx, http.MethodPost, "/sapi/v1/margin/isolated/transfer", params, &resp); err != nil {
+		return err
+	}
+
+	log.Infof("margin transferred %f %s, direction %d, transaction id %d", amount.Float64(), asset, direction, resp.TranID)
+	return nil
+}
+
+// queryMarginAccount converts the cross margin account into the balances with the liabilities
+func (e *Exchange) queryMarginAccount(ctx context.Context) (*types.Account, error) {
+	marginAccount, err := e.QueryMarginAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var balances = types.BalanceMap{}
+	for _, userAsset := range marginAccount.UserAssets {
+		balances[userAsset.Asset] = types.Balance{
+			Currency:  userAsset.Asset,
+			Available: userAsset.Free,
+			Locked:    userAsset.Locked,
+			Borrowed:  userAsset.Borrowed,
+			Interest:  userAsset.Interest,
+			NetAsset:  userAsset.NetAsset,
+		}
+	}
+
+	a := &types.Account{
+		AccountType: "MARGIN",
+		MarginLevel: marginAccount.MarginLevel,
+	}
+	a.UpdateBalances(balances)
+	return a, nil
+}
+
+// queryIsolatedMarginAccount converts the isolated margin account of the isolated symbol into the balances with the liabilities
+func (e *Exchange) queryIsolatedMarginAccount(ctx context.Context) (*types.Account, error) {
+	marginAccount, err := e.QueryIsolatedMarginAccount(ctx, e.IsolatedMarginSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &types.Account{
+		AccountType: "ISOLATED_MARGIN",
+	}
+
+	var balances = types.BalanceMap{}
+	for _, marginAsset := range marginAccount.Assets {
+		if marginAsset.Symbol != e.IsolatedMarginSymbol {
+			continue
+		}
+
+		a.MarginLevel = marginAsset.MarginLevel
+
+		for _, userAsset := range []types.IsolatedUserAsset{marginAsset.BaseAsset, marginAsset.QuoteAsset} {
+			balances[userAsset.Asset] = types.Balance{
+				Currency:  userAsset.Asset,
+				Available: userAsset.Free,
+				Locked:    userAsset.Locked,
+				Borrowed:  userAsset.Borrowed,
+				Interest:  userAsset.Interest,
+				NetAsset:  userAsset.NetAsset,
+			}
+		}
+	}
+
+	a.UpdateBalances(balances)
+	return a, nil
+}
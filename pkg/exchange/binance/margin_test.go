@@ -0,0 +1,70 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"github.com/adshao/go-binance/v2"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExchange_QueryLoanHistoryWindows(t *testing.T) {
+	var mu sync.Mutex
+	var windows [][2]int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		startTime, _ := strconv.ParseInt(query.Get("startTime"), 10, 64)
+		endTime, _ := strconv.ParseInt(query.Get("endTime"), 10, 64)
+
+		mu.Lock()
+		windows = append(windows, [2]int64{startTime, endTime})
+		txID := len(windows)
+		mu.Unlock()
+
+		_, _ = fmt.Fprintf(w, `{"rows":[{"txId":%d,"asset":"BTC","principal":"0.1","timestamp":%d,"status":"CONFIRMED"}],"total":1}`, txID, startTime)
+	}))
+	defer server.Close()
+
+	client := binance.NewClient("key", "secret")
+	client.BaseURL = server.URL
+	e := &Exchange{Client: client}
+
+	startTime := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(75 * 24 * time.Hour)
+
+	records, err := e.QueryLoanHistory(context.Background(), "BTC", &startTime, &endTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(windows) != 3 || len(records) != 3 {
+		t.Fatalf("expected 3 windows, got %d windows and %d records", len(windows), len(records))
+	}
+
+	ms := func(t time.Time) int64 {
+		return t.UnixNano() / int64(time.Millisecond)
+	}
+
+	if windows[0][0] != ms(startTime) || windows[2][1] != ms(endTime) {
+		t.Fatalf("unexpected range: %v", windows)
+	}
+
+	for i, window := range windows {
+		if window[1]-window[0] > int64(marginHistoryWindow/time.Millisecond) {
+			t.Fatalf("window %d exceeds 30 days: %v", i, window)
+		}
+
+		if i > 0 && window[0] != windows[i-1][1]+1 {
+			t.Fatalf("window %d does not continue the previous window: %v", i, windows)
+		}
+	}
+
+	if records[0].Principal.Float64() != 0.1 {
+		t.Fatalf("unexpected principal: %f", records[0].Principal.Float64())
+	}
+}
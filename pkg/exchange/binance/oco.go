@@ -0,0 +1,131 @@
+package binance
+
+import (
+	"context"
+	"github.com/adshao/go-binance/v2"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// SubmitOCOOrder places the native OCO order of the spot or the margin account,
+// the limit leg is a LIMIT_MAKER order and the stop leg is a STOP_LOSS(_LIMIT) order.
+func (e *Exchange) SubmitOCOOrder(ctx context.Context, order types.SubmitOCOOrder) (types.OrderSlice, error) {
+	if e.IsFutures {
+		return nil, types.ErrOCOOrderNotSupported
+	}
+
+	formatPrice := func(price float64) string {
+		if order.Market.Symbol != "" {
+			return order.Market.FormatPrice(price)
+		}
+
+		return strconv.FormatFloat(price, 'f', 8, 64)
+	}
+
+	quantity := strconv.FormatFloat(order.Quantity, 'f', 8, 64)
+	if order.Market.Symbol != "" {
+		quantity = order.Market.FormatQuantity(order.Quantity)
+	}
+
+	clientOrderID := newSpotClientOrderID(order.ClientOrderId)
+
+	var resp *binance.CreateOCOResponse
+	if e.IsMargin {
+		var err error
+		if resp, err = e.submitMarginOCOOrder(ctx, order, quantity, formatPrice, clientOrderID); err != nil {
+			return nil, errors.Wrapf(err, "submit %s oco order error", order.Symbol)
+		}
+	} else {
+		req := e.Client.NewCreateOCOService().
+			Symbol(order.Symbol).
+			Side(binance.SideType(order.Side)).
+			Quantity(quantity).
+			Price(formatPrice(order.Price)).
+			StopPrice(formatPrice(order.StopPrice)).
+			NewOrderRespType(binance.NewOrderRespTypeRESULT)
+
+		if order.StopLimitPrice > 0 {
+			req.StopLimitPrice(formatPrice(order.StopLimitPrice)).
+				StopLimitTimeInForce(binance.TimeInForceTypeGTC)
+		}
+
+		if len(clientOrderID) > 0 {
+			req.ListClientOrderID(clientOrderID)
+		}
+
+		var err error
+		if resp, err = req.Do(ctx); err != nil {
+			return nil, errors.Wrapf(err, "submit %s oco order error", order.Symbol)
+		}
+	}
+
+	log.Infof("oco order creation response: %+v", resp)
+
+	var createdOrders types.OrderSlice
+	for _, report := range resp.OrderReports {
+		createdOrder, err := toGlobalOrder(&binance.Order{
+			Symbol:                   report.Symbol,
+			OrderID:                  report.OrderID,
+			OrderListId:              report.OrderListID,
+			ClientOrderID:            report.ClientOrderID,
+			Price:                    report.Price,
+			OrigQuantity:             report.OrigQuantity,
+			ExecutedQuantity:         report.ExecutedQuantity,
+			CummulativeQuoteQuantity: report.CummulativeQuoteQuantity,
+			Status:                   report.Status,
+			TimeInForce:              report.TimeInForce,
+			Type:                     report.Type,
+			Side:                     report.Side,
+			StopPrice:                report.StopPrice,
+			Time:                     report.TransactionTime,
+			UpdateTime:               report.TransactionTime,
+			IsIsolated:               e.IsIsolatedMargin,
+		}, e.IsMargin)
+		if err != nil {
+			return createdOrders, err
+		}
+
+		createdOrders = append(createdOrders, *createdOrder)
+	}
+
+	return createdOrders, nil
+}
+
+// submitMarginOCOOrder places the OCO order of the margin account, which is not covered by the client
+func (e *Exchange) submitMarginOCOOrder(ctx context.Context, order types.SubmitOCOOrder, quantity string, formatPrice func(price float64) string, clientOrderID string) (*binance.CreateOCOResponse, error) {
+	params := url.Values{}
+	params.Set("symbol", order.Symbol)
+	params.Set("side", string(order.Side))
+	params.Set("quantity", quantity)
+	params.Set("price", formatPrice(order.Price))
+	params.Set("stopPrice", formatPrice(order.StopPrice))
+	params.Set("newOrderRespType", string(binance.NewOrderRespTypeRESULT))
+
+	if order.StopLimitPrice > 0 {
+		params.Set("stopLimitPrice", formatPrice(order.StopLimitPrice))
+		params.Set("stopLimitTimeInForce", string(binance.TimeInForceTypeGTC))
+	}
+
+	if len(clientOrderID) > 0 {
+		params.Set("listClientOrderId", clientOrderID)
+	}
+
+	if e.IsIsolatedMargin {
+		params.Set("isIsolated", "TRUE")
+	}
+
+	if len(order.MarginSideEffect) > 0 {
+		params.Set("sideEffectType", string(order.MarginSideEffect))
+	}
+
+	var resp binance.CreateOCOResponse
+	if err := e.signedRequest(ctx, http.MethodPost, "/sapi/v1/margin/order/oco", params, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
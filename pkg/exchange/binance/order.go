@@ -0,0 +1,221 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"github.com/adshao/go-binance/v2"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (e *Exchange) QueryOrder(ctx context.Context, q types.OrderQuery) (*types.Order, error) {
+	if len(q.Symbol) == 0 {
+		return nil, errors.New("symbol is required for querying the order")
+	}
+
+	if q.OrderID == 0 && len(q.ClientOrderID) == 0 {
+		return nil, errors.New("order id or client order id is required for querying the order")
+	}
+
+	if e.IsFutures {
+		req := e.futuresClient.NewGetOrderService().Symbol(q.Symbol)
+		if q.OrderID > 0 {
+			req.OrderID(int64(q.OrderID))
+		} else {
+			req.OrigClientOrderID(q.ClientOrderID)
+		}
+
+		order, err := req.Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return toGlobalFuturesOrder(order)
+	}
+
+	var order *binance.Order
+	var err error
+
+	if e.IsMargin {
+		req := e.Client.NewGetMarginOrderService().Symbol(q.Symbol)
+		if e.IsIsolatedMargin {
+			req.IsIsolated(e.IsIsolatedMargin)
+		}
+
+		if q.OrderID > 0 {
+			req.OrderID(int64(q.OrderID))
+		} else {
+			req.OrigClientOrderID(toLocalSpotClientOrderID(q.ClientOrderID))
+		}
+
+		order, err = req.Do(ctx)
+	} else {
+		req := e.Client.NewGetOrderService().Symbol(q.Symbol)
+		if q.OrderID > 0 {
+			req.OrderID(int64(q.OrderID))
+		} else {
+			req.OrigClientOrderID(toLocalSpotClientOrderID(q.ClientOrderID))
+		}
+
+		order, err = req.Do(ctx)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toGlobalOrder(order, e.IsMargin)
+}
+
+func (e *Exchange) QueryOrderTrades(ctx context.Context, q types.OrderQuery) (trades []types.Trade, err error) {
+	if e.IsFutures {
+		return nil, errors.New("querying the trades of a futures order is not supported")
+	}
+
+	order, err := e.QueryOrder(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.queryOrderTradesSince(ctx, *order)
+}
+
+// queryOrderTradesSince walks through the trades since the order creation,
+// since the trade services of the client can not filter the trades by the order.
+func (e *Exchange) queryOrderTradesSince(ctx context.Context, order types.Order) (trades []types.Trade, err error) {
+	const limit = 1000
+
+	startTime := order.CreationTime.Time()
+	options := &types.TradeQueryOptions{StartTime: &startTime, Limit: limit}
+
+	for {
+		page, err := e.QueryTrades(ctx, order.Symbol, options)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range page {
+			if t.OrderID == order.OrderID {
+				trades = append(trades, t)
+			}
+		}
+
+		if len(page) < limit {
+			return trades, nil
+		}
+
+		// binance does not accept the start time with the trade id
+		options = &types.TradeQueryOptions{LastTradeID: page[len(page)-1].ID + 1, Limit: limit}
+	}
+}
+
+// CancelReplaceOrder cancels the order and places the replacement of the same symbol.
+// The spot account uses the cancel-replace endpoint, which places the replacement only when the cancel succeeds.
+// Binance margin has no such endpoint, so the order is canceled first and then the replacement is submitted.
+func (e *Exchange) CancelReplaceOrder(ctx context.Context, order types.Order, replacement types.SubmitOrder) (*types.Order, error) {
+	if replacement.Symbol != order.Symbol {
+		return nil, fmt.Errorf("the replacement symbol %s does not match the order symbol %s", replacement.Symbol, order.Symbol)
+	}
+
+	if e.IsFutures {
+		return nil, errors.New("cancel replace is not supported for futures orders")
+	}
+
+	if e.IsMargin {
+		if err := e.cancelMarginOrder(ctx, order); err != nil {
+			return nil, errors.Wrapf(err, "cancel margin order %d error", order.OrderID)
+		}
+
+		return e.submitMarginOrder(ctx, replacement)
+	}
+
+	params, err := spotOrderParams(replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	params.Set("cancelReplaceMode", "STOP_ON_FAILURE")
+	params.Set("newOrderRespType", string(binance.NewOrderRespTypeRESULT))
+	if order.OrderID > 0 {
+		params.Set("cancelOrderId", strconv.FormatUint(order.OrderID, 10))
+	} else {
+		params.Set("cancelOrigClientOrderId", toLocalSpotClientOrderID(order.ClientOrderId))
+	}
+
+	var resp struct {
+		CancelResult     string                       `json:"cancelResult"`
+		NewOrderResult   string                       `json:"newOrderResult"`
+		NewOrderResponse *binance.CreateOrderResponse `json:"newOrderResponse"`
+	}
+
+	if err := e.signedRequest(ctx, http.MethodPost, "/api/v3/order/cancelReplace", params, &resp); err != nil {
+		return nil, errors.Wrapf(err, "cancel replace order %d error", order.OrderID)
+	}
+
+	if resp.NewOrderResponse == nil {
+		return nil, fmt.Errorf("cancel replace order %d: cancel result %s, new order result %s", order.OrderID, resp.CancelResult, resp.NewOrderResult)
+	}
+
+	log.Infof("spot order cancel replace response: %+v", resp.NewOrderResponse)
+
+	return toGlobalCreatedOrder(resp.NewOrderResponse, false)
+}
+
+// spotOrderParams converts the submit order into the parameters of the spot order endpoints
+func spotOrderParams(order types.SubmitOrder) (url.Values, error) {
+	orderType, err := toLocalOrderType(order.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("symbol", order.Symbol)
+	params.Set("side", string(order.Side))
+	params.Set("type", string(orderType))
+
+	clientOrderID := newSpotClientOrderID(order.ClientOrderId)
+	if len(clientOrderID) > 0 {
+		params.Set("newClientOrderId", clientOrderID)
+	}
+
+	if len(order.QuantityString) > 0 {
+		params.Set("quantity", order.QuantityString)
+	} else if order.Market.Symbol != "" {
+		params.Set("quantity", order.Market.FormatQuantity(order.Quantity))
+	} else {
+		params.Set("quantity", strconv.FormatFloat(order.Quantity, 'f', 8, 64))
+	}
+
+	switch order.Type {
+	case types.OrderTypeStopLimit, types.OrderTypeLimit:
+		if len(order.PriceString) > 0 {
+			params.Set("price", order.PriceString)
+		} else if order.Market.Symbol != "" {
+			params.Set("price", order.Market.FormatPrice(order.Price))
+		}
+	}
+
+	switch order.Type {
+	case types.OrderTypeStopLimit, types.OrderTypeStopMarket:
+		if len(order.StopPriceString) == 0 {
+			return nil, fmt.Errorf("stop price string can not be empty")
+		}
+
+		params.Set("stopPrice", order.StopPriceString)
+	}
+
+	if len(order.TimeInForce) > 0 {
+		params.Set("timeInForce", order.TimeInForce)
+	} else {
+		switch order.Type {
+		case types.OrderTypeLimit, types.OrderTypeStopLimit:
+			params.Set("timeInForce", string(binance.TimeInForceTypeGTC))
+		}
+	}
+
+	return params, nil
+}
@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/futures"
 	"github.com/pkg/errors"
 	"github.com/pymba86/bingo/pkg/fixedpoint"
 	"github.com/pymba86/bingo/pkg/types"
 	"github.com/pymba86/bingo/pkg/util"
 	log "github.com/sirupsen/logrus"
 	"github.com/valyala/fastjson"
+	"strings"
 	"time"
 )
 
@@ -82,8 +84,9 @@ type ExecutionReportEvent struct {
 	CurrentExecutionType string `json:"x"`
 	CurrentOrderStatus   string `json:"X"`
 
-	OrderID int64 `json:"i"`
-	Ignored int64 `json:"I"`
+	OrderID     int64 `json:"i"`
+	OrderListID int64 `json:"g"`
+	Ignored     int64 `json:"I"`
 
 	TradeID         int64 `json:"t"`
 	TransactionTime int64 `json:"T"`
@@ -112,7 +115,7 @@ func (e *ExecutionReportEvent) Order() (*types.Order, error) {
 		Exchange: types.ExchangeBinance,
 		SubmitOrder: types.SubmitOrder{
 			Symbol:        e.Symbol,
-			ClientOrderId: e.ClientOrderID,
+			ClientOrderId: toGlobalSpotClientOrderID(e.ClientOrderID),
 			Side:          toGlobalSideType(binance.SideType(e.Side)),
 			Type:          toGlobalOrderType(binance.OrderType(e.OrderType)),
 			Quantity:      util.MustParseFloat(e.OrderQuantity),
@@ -123,6 +126,7 @@ func (e *ExecutionReportEvent) Order() (*types.Order, error) {
 		Status:           toGlobalOrderStatus(binance.OrderStatusType(e.CurrentOrderStatus)),
 		ExecutedQuantity: util.MustParseFloat(e.CumulativeFilledQuantity),
 		CreationTime:     types.Time(orderCreationTime),
+		OrderListID:      toGlobalOrderListID(e.OrderListID),
 	}, nil
 }
 
@@ -255,6 +259,38 @@ func ParseEvent(message string) (interface{}, error) {
 		return nil, err
 	}
 
+	// the combined stream wraps the payload as {"stream":"<streamName>","data":<rawPayload>}
+	if val.Exists("stream") && val.Exists("data") {
+		return parseCombinedEvent(string(val.GetStringBytes("stream")), val.Get("data"))
+	}
+
+	return parseEvent(val, message)
+}
+
+// parseCombinedEvent parses the payload of the combined stream,
+// the partial depth and the spot book ticker payloads don't have the event type, so the stream name is used.
+func parseCombinedEvent(streamName string, data *fastjson.Value) (interface{}, error) {
+	var symbol = streamName
+	var channel string
+	if i := strings.Index(streamName, "@"); i >= 0 {
+		symbol = strings.ToUpper(streamName[:i])
+		channel = streamName[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(channel, "depth") && len(channel) > 5 && channel[5] >= '0' && channel[5] <= '9':
+		return parsePartialDepthEvent(symbol, data)
+
+	case channel == "bookTicker":
+		var event BookTickerEvent
+		err := json.Unmarshal(data.MarshalTo(nil), &event)
+		return &event, err
+	}
+
+	return parseEvent(data, string(data.MarshalTo(nil)))
+}
+
+func parseEvent(val *fastjson.Value, message string) (interface{}, error) {
 	eventType := string(val.GetStringBytes("e"))
 
 	switch eventType {
@@ -286,6 +322,36 @@ func ParseEvent(message string) (interface{}, error) {
 	case "depthUpdate":
 		return parseDepthEvent(val)
 
+	case "ORDER_TRADE_UPDATE":
+		var event OrderTradeUpdateEvent
+		err := json.Unmarshal([]byte(message), &event)
+		return &event, err
+
+	case "ACCOUNT_UPDATE":
+		var event AccountUpdateEvent
+		err := json.Unmarshal([]byte(message), &event)
+		return &event, err
+
+	case "listenKeyExpired":
+		var event ListenKeyExpiredEvent
+		err := json.Unmarshal([]byte(message), &event)
+		return &event, err
+
+	case "aggTrade":
+		var event AggTradeEvent
+		err := json.Unmarshal([]byte(message), &event)
+		return &event, err
+
+	case "trade":
+		var event MarketTradeEvent
+		err := json.Unmarshal([]byte(message), &event)
+		return &event, err
+
+	case "bookTicker":
+		var event BookTickerEvent
+		err := json.Unmarshal([]byte(message), &event)
+		return &event, err
+
 	default:
 		id := val.GetInt("id")
 		if id > 0 {
@@ -308,6 +374,9 @@ type DepthEvent struct {
 	FirstUpdateID int64  `json:"U"`
 	FinalUpdateID int64  `json:"u"`
 
+	// PreviousUpdateID is only sent by the futures depth stream
+	PreviousUpdateID int64 `json:"pu"`
+
 	Bids []DepthEntry
 	Asks []DepthEntry
 }
@@ -405,8 +474,9 @@ func parseDepthEvent(val *fastjson.Value) (*DepthEvent, error) {
 			Time:  val.GetInt64("E"),
 		},
 		Symbol:        string(val.GetStringBytes("s")),
-		FirstUpdateID: val.GetInt64("U"),
-		FinalUpdateID: val.GetInt64("u"),
+		FirstUpdateID:    val.GetInt64("U"),
+		FinalUpdateID:    val.GetInt64("u"),
+		PreviousUpdateID: val.GetInt64("pu"),
 	}
 
 	for _, ev := range val.GetArray("b") {
@@ -432,6 +502,189 @@ func parseDepthEvent(val *fastjson.Value) (*DepthEvent, error) {
 	return depth, err
 }
 
+// PartialDepthEvent is the top levels of the book, e.g. <symbol>@depth5,
+// the spot payload uses "bids"/"asks" and the futures payload uses "b"/"a".
+type PartialDepthEvent struct {
+	Symbol       string
+	LastUpdateID int64
+
+	Bids []DepthEntry
+	Asks []DepthEntry
+}
+
+func (e *PartialDepthEvent) OrderBook() (types.SliceOrderBook, error) {
+	depth := DepthEvent{
+		Symbol: e.Symbol,
+		Bids:   e.Bids,
+		Asks:   e.Asks,
+	}
+
+	return depth.OrderBook()
+}
+
+func parsePartialDepthEvent(symbol string, val *fastjson.Value) (*PartialDepthEvent, error) {
+	var err error
+	var depth = &PartialDepthEvent{
+		Symbol:       symbol,
+		LastUpdateID: val.GetInt64("lastUpdateId"),
+	}
+
+	bids, asks := val.GetArray("bids"), val.GetArray("asks")
+	if val.Exists("b") {
+		depth.LastUpdateID = val.GetInt64("u")
+		bids, asks = val.GetArray("b"), val.GetArray("a")
+	}
+
+	for _, ev := range bids {
+		entry, err2 := parseDepthEntry(ev)
+		if err2 != nil {
+			err = err2
+			continue
+		}
+
+		depth.Bids = append(depth.Bids, *entry)
+	}
+
+	for _, ev := range asks {
+		entry, err2 := parseDepthEntry(ev)
+		if err2 != nil {
+			err = err2
+			continue
+		}
+
+		depth.Asks = append(depth.Asks, *entry)
+	}
+
+	return depth, err
+}
+
+/*
+aggTrade
+
+{
+  "e": "aggTrade",  // Event type
+  "E": 123456789,   // Event time
+  "s": "BNBBTC",    // Symbol
+  "a": 12345,       // Aggregate trade ID
+  "p": "0.001",     // Price
+  "q": "100",       // Quantity
+  "f": 100,         // First trade ID
+  "l": 105,         // Last trade ID
+  "T": 123456785,   // Trade time
+  "m": true,        // Is the buyer the market maker?
+  "M": true         // Ignore
+}
+*/
+type AggTradeEvent struct {
+	EventBase
+
+	Symbol       string           `json:"s"`
+	AggTradeID   int64            `json:"a"`
+	Price        fixedpoint.Value `json:"p"`
+	Quantity     fixedpoint.Value `json:"q"`
+	FirstTradeID int64            `json:"f"`
+	LastTradeID  int64            `json:"l"`
+	TradeTime    int64            `json:"T"`
+	IsBuyerMaker bool             `json:"m"`
+
+	// Ignored keeps the "M" field from overriding "m", json keys are matched case-insensitively
+	Ignored bool `json:"M"`
+}
+
+func (e *AggTradeEvent) Trade() types.Trade {
+	return toGlobalMarketTrade(e.AggTradeID, e.Symbol, e.Price, e.Quantity, e.IsBuyerMaker, e.TradeTime)
+}
+
+/*
+trade
+
+{
+  "e": "trade",     // Event type
+  "E": 123456789,   // Event time
+  "s": "BNBBTC",    // Symbol
+  "t": 12345,       // Trade ID
+  "p": "0.001",     // Price
+  "q": "100",       // Quantity
+  "b": 88,          // Buyer order ID
+  "a": 50,          // Seller order ID
+  "T": 123456785,   // Trade time
+  "m": true,        // Is the buyer the market maker?
+  "M": true         // Ignore
+}
+*/
+type MarketTradeEvent struct {
+	EventBase
+
+	Symbol        string           `json:"s"`
+	TradeID       int64            `json:"t"`
+	Price         fixedpoint.Value `json:"p"`
+	Quantity      fixedpoint.Value `json:"q"`
+	BuyerOrderID  int64            `json:"b"`
+	SellerOrderID int64            `json:"a"`
+	TradeTime     int64            `json:"T"`
+	IsBuyerMaker  bool             `json:"m"`
+
+	// Ignored keeps the "M" field from overriding "m", json keys are matched case-insensitively
+	Ignored bool `json:"M"`
+}
+
+func (e *MarketTradeEvent) Trade() types.Trade {
+	return toGlobalMarketTrade(e.TradeID, e.Symbol, e.Price, e.Quantity, e.IsBuyerMaker, e.TradeTime)
+}
+
+// toGlobalMarketTrade converts the public trade, the side is the taker side
+func toGlobalMarketTrade(id int64, symbol string, price, quantity fixedpoint.Value, isBuyerMaker bool, tradeTime int64) types.Trade {
+	side := types.SideTypeBuy
+	if isBuyerMaker {
+		side = types.SideTypeSell
+	}
+
+	return types.Trade{
+		ID:            id,
+		Exchange:      types.ExchangeBinance,
+		Price:         price.Float64(),
+		Quantity:      quantity.Float64(),
+		QuoteQuantity: price.Mul(quantity).Float64(),
+		Symbol:        symbol,
+		Side:          side,
+		IsBuyer:       side == types.SideTypeBuy,
+		Time:          types.Time(millisecondTime(tradeTime)),
+	}
+}
+
+/*
+bookTicker, the event type is only sent by the futures stream
+
+{
+  "u":400900217,     // order book updateId
+  "s":"BNBUSDT",     // symbol
+  "b":"25.35190000", // best bid price
+  "B":"31.21000000", // best bid qty
+  "a":"25.36520000", // best ask price
+  "A":"40.66000000"  // best ask qty
+}
+*/
+type BookTickerEvent struct {
+	EventBase
+
+	UpdateID int64            `json:"u"`
+	Symbol   string           `json:"s"`
+	Buy      fixedpoint.Value `json:"b"`
+	BuySize  fixedpoint.Value `json:"B"`
+	Sell     fixedpoint.Value `json:"a"`
+	SellSize fixedpoint.Value `json:"A"`
+}
+
+func (e *BookTickerEvent) BookTicker() types.BookTicker {
+	return types.BookTicker{
+		Symbol:   e.Symbol,
+		Buy:      e.Buy,
+		BuySize:  e.BuySize,
+		Sell:     e.Sell,
+		SellSize: e.SellSize,
+	}
+}
+
 type KLine struct {
 	StartTime int64 `json:"t"`
 	EndTime   int64 `json:"T"`
@@ -512,4 +765,230 @@ kline
 type EventBase struct {
 	Event string `json:"e"` // event
 	Time  int64  `json:"E"`
-}
\ No newline at end of file
+}
+/*
+ORDER_TRADE_UPDATE
+{
+  "e": "ORDER_TRADE_UPDATE",      // Event Type
+  "E": 1568879465651,             // Event Time
+  "T": 1568879465650,             // Transaction Time
+  "o": {
+    "s": "BTCUSDT",               // Symbol
+    "c": "TEST",                  // Client Order Id
+    "S": "SELL",                  // Side
+    "o": "TRAILING_STOP_MARKET",  // Order Type
+    "f": "GTC",                   // Time in Force
+    "q": "0.001",                 // Original Quantity
+    "p": "0",                     // Original Price
+    "ap": "0",                    // Average Price
+    "sp": "7103.04",              // Stop Price
+    "x": "NEW",                   // Execution Type
+    "X": "NEW",                   // Order Status
+    "i": 8886774,                 // Order Id
+    "l": "0",                     // Order Last Filled Quantity
+    "z": "0",                     // Order Filled Accumulated Quantity
+    "L": "0",                     // Last Filled Price
+    "N": "USDT",                  // Commission Asset
+    "n": "0",                     // Commission
+    "T": 1568879465651,           // Order Trade Time
+    "t": 0,                       // Trade Id
+    "b": "0",                     // Bids Notional
+    "a": "9.91",                  // Ask Notional
+    "m": false,                   // Is this trade the maker side?
+    "R": false,                   // Is this reduce only
+    "wt": "CONTRACT_PRICE",       // Stop Price Working Type
+    "ot": "TRAILING_STOP_MARKET", // Original Order Type
+    "ps": "LONG",                 // Position Side
+    "cp": false,                  // If Close-All
+    "rp": "0"                     // Realized Profit of the trade
+  }
+}
+*/
+type OrderTradeUpdate struct {
+	Symbol        string `json:"s"`
+	ClientOrderID string `json:"c"`
+	Side          string `json:"S"`
+	OrderType     string `json:"o"`
+	TimeInForce   string `json:"f"`
+
+	OriginalQuantity string `json:"q"`
+	OriginalPrice    string `json:"p"`
+	AveragePrice     string `json:"ap"`
+	StopPrice        string `json:"sp"`
+
+	CurrentExecutionType string `json:"x"`
+	CurrentOrderStatus   string `json:"X"`
+
+	OrderID int64 `json:"i"`
+
+	OrderLastFilledQuantity   string `json:"l"`
+	OrderFilledAccumulatedQty string `json:"z"`
+	LastFilledPrice           string `json:"L"`
+
+	CommissionAsset  string `json:"N"`
+	CommissionAmount string `json:"n"`
+
+	OrderTradeTime int64 `json:"T"`
+	TradeID        int64 `json:"t"`
+
+	IsMaker      bool   `json:"m"`
+	IsReduceOnly bool   `json:"R"`
+	PositionSide string `json:"ps"`
+	RealizedPnL  string `json:"rp"`
+}
+
+type OrderTradeUpdateEvent struct {
+	EventBase
+
+	TransactionTime int64            `json:"T"`
+	OrderTrade      OrderTradeUpdate `json:"o"`
+}
+
+func (e *OrderTradeUpdateEvent) Order() (*types.Order, error) {
+	switch e.OrderTrade.CurrentExecutionType {
+	case "NEW", "CANCELED", "EXPIRED", "CALCULATED":
+	case "TRADE": // For Order FILLED status. And the order has been completed.
+	default:
+		return nil, errors.New("order trade update type is not for order")
+	}
+
+	o := e.OrderTrade
+	return &types.Order{
+		Exchange: types.ExchangeBinance,
+		SubmitOrder: types.SubmitOrder{
+			Symbol:        o.Symbol,
+			ClientOrderId: o.ClientOrderID,
+			Side:          toGlobalSideType(binance.SideType(o.Side)),
+			Type:          toGlobalFuturesOrderType(futures.OrderType(o.OrderType)),
+			Quantity:      util.MustParseFloat(o.OriginalQuantity),
+			Price:         util.MustParseFloat(o.OriginalPrice),
+			TimeInForce:   o.TimeInForce,
+			ReduceOnly:    o.IsReduceOnly,
+			PositionSide:  types.PositionSide(o.PositionSide),
+		},
+		OrderID:          uint64(o.OrderID),
+		Status:           toGlobalOrderStatus(binance.OrderStatusType(o.CurrentOrderStatus)),
+		ExecutedQuantity: util.MustParseFloat(o.OrderFilledAccumulatedQty),
+		CreationTime:     types.Time(millisecondTime(o.OrderTradeTime)),
+		UpdateTime:       types.Time(millisecondTime(e.TransactionTime)),
+		IsFutures:        true,
+	}, nil
+}
+
+func (e *OrderTradeUpdateEvent) Trade() (*types.Trade, error) {
+	if e.OrderTrade.CurrentExecutionType != "TRADE" {
+		return nil, errors.New("order trade update is not a trade")
+	}
+
+	o := e.OrderTrade
+	price := util.MustParseFloat(o.LastFilledPrice)
+	quantity := util.MustParseFloat(o.OrderLastFilledQuantity)
+	return &types.Trade{
+		ID:            o.TradeID,
+		Exchange:      types.ExchangeBinance,
+		Symbol:        o.Symbol,
+		OrderID:       uint64(o.OrderID),
+		Side:          toGlobalSideType(binance.SideType(o.Side)),
+		Price:         price,
+		Quantity:      quantity,
+		QuoteQuantity: price * quantity,
+		IsBuyer:       o.Side == "BUY",
+		IsMaker:       o.IsMaker,
+		Time:          types.Time(millisecondTime(o.OrderTradeTime)),
+		Fee:           util.MustParseFloat(o.CommissionAmount),
+		FeeCurrency:   o.CommissionAsset,
+		IsFutures:     true,
+	}, nil
+}
+
+/*
+ACCOUNT_UPDATE
+{
+  "e": "ACCOUNT_UPDATE",            // Event Type
+  "E": 1564745798939,               // Event Time
+  "T": 1564745798938 ,              // Transaction
+  "a": {
+    "m": "ORDER",                   // Event reason type
+    "B": [                          // Balances
+      {
+        "a": "USDT",                // Asset
+        "wb": "122624.12345678",    // Wallet Balance
+        "cw": "100.12345678",       // Cross Wallet Balance
+        "bc": "50.12345678"         // Balance Change except PnL and Commission
+      }
+    ],
+    "P": [                          // Positions
+      {
+        "s": "BTCUSDT",             // Symbol
+        "pa": "0",                  // Position Amount
+        "ep": "0.00000",            // Entry Price
+        "cr": "200",                // (Pre-fee) Accumulated Realized
+        "up": "0",                  // Unrealized PnL
+        "mt": "isolated",           // Margin Type
+        "iw": "0.00000000",         // Isolated Wallet (if isolated position)
+        "ps": "BOTH"                // Position Side
+      }
+    ]
+  }
+}
+*/
+type FuturesBalance struct {
+	Asset              string           `json:"a"`
+	WalletBalance      fixedpoint.Value `json:"wb"`
+	CrossWalletBalance fixedpoint.Value `json:"cw"`
+	BalanceChange      fixedpoint.Value `json:"bc"`
+}
+
+type FuturesPositionUpdate struct {
+	Symbol                    string           `json:"s"`
+	PositionAmount            fixedpoint.Value `json:"pa"`
+	EntryPrice                fixedpoint.Value `json:"ep"`
+	AccumulatedRealizedProfit fixedpoint.Value `json:"cr"`
+	UnrealizedPnL             fixedpoint.Value `json:"up"`
+	MarginType                string           `json:"mt"`
+	IsolatedWallet            fixedpoint.Value `json:"iw"`
+	PositionSide              string           `json:"ps"`
+}
+
+type AccountUpdate struct {
+	EventReasonType string                  `json:"m"`
+	Balances        []FuturesBalance        `json:"B,omitempty"`
+	Positions       []FuturesPositionUpdate `json:"P,omitempty"`
+}
+
+type AccountUpdateEvent struct {
+	EventBase
+
+	TransactionTime int64         `json:"T"`
+	AccountUpdate   AccountUpdate `json:"a"`
+}
+
+func (e *AccountUpdateEvent) BalanceMap() types.BalanceMap {
+	balances := types.BalanceMap{}
+	for _, b := range e.AccountUpdate.Balances {
+		balances[b.Asset] = toGlobalFuturesBalance(b.Asset, b.WalletBalance, b.CrossWalletBalance)
+	}
+
+	return balances
+}
+
+func (e *AccountUpdateEvent) Positions() (positions []types.FuturesPosition) {
+	for _, p := range e.AccountUpdate.Positions {
+		positions = append(positions, types.FuturesPosition{
+			Symbol:         p.Symbol,
+			PositionSide:   types.PositionSide(p.PositionSide),
+			Base:           p.PositionAmount,
+			EntryPrice:     p.EntryPrice,
+			UnrealizedPnL:  p.UnrealizedPnL,
+			MarginType:     toGlobalMarginType(p.MarginType),
+			IsolatedMargin: p.IsolatedWallet,
+			UpdateTime:     types.Time(millisecondTime(e.TransactionTime)),
+		})
+	}
+
+	return positions
+}
+
+type ListenKeyExpiredEvent struct {
+	EventBase
+}
@@ -0,0 +1,398 @@
+package binance
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// the windows are paused when the used weight reaches this ratio of the limit,
+// which leaves room for the requests that are already in flight.
+const rateLimitSafetyRatio = 0.9
+
+// the requests rejected with 429 are retried at most this many times
+const maxRateLimitRetries = 3
+
+// rateLimitWindow is a rate limit of binance, the used amount of the window is reported by the header
+type rateLimitWindow struct {
+	header   string
+	interval time.Duration
+	limit    int
+
+	// order windows only throttle the new order requests
+	order bool
+}
+
+var spotRateLimitWindows = []rateLimitWindow{
+	{header: "X-MBX-USED-WEIGHT-1M", interval: time.Minute, limit: 1200},
+	{header: "X-MBX-ORDER-COUNT-10S", interval: 10 * time.Second, limit: 50, order: true},
+	{header: "X-MBX-ORDER-COUNT-1D", interval: 24 * time.Hour, limit: 160000, order: true},
+}
+
+var futuresRateLimitWindows = []rateLimitWindow{
+	{header: "X-MBX-USED-WEIGHT-1M", interval: time.Minute, limit: 2400},
+	{header: "X-MBX-ORDER-COUNT-10S", interval: 10 * time.Second, limit: 300, order: true},
+	{header: "X-MBX-ORDER-COUNT-1M", interval: time.Minute, limit: 1200, order: true},
+}
+
+// rateLimiter throttles the requests by the request weights and the order counts of binance.
+// The local token buckets keep the request rate under the limits, and the used amounts reported
+// by the response headers pause the requests until the next window when a limit is almost reached,
+// so that the requests made by the other processes of the same IP are taken into account too.
+// The weights are counted by IP, while the orders are counted by account, so the order windows
+// are kept for each api key.
+type rateLimiter struct {
+	windows []rateLimitWindow
+
+	weights *rate.Limiter
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+	accounts    map[string]*orderRateLimiter
+}
+
+// orderRateLimiter is the order count limit of an account
+type orderRateLimiter struct {
+	orders      *rate.Limiter
+	pausedUntil time.Time
+}
+
+func newRateLimiter(windows []rateLimitWindow) *rateLimiter {
+	l := &rateLimiter{windows: windows, accounts: make(map[string]*orderRateLimiter)}
+	for _, w := range windows {
+		if !w.order && l.weights == nil {
+			l.weights = newWindowLimiter(w)
+		}
+	}
+
+	return l
+}
+
+func newWindowLimiter(w rateLimitWindow) *rate.Limiter {
+	perSecond := float64(w.limit) / w.interval.Seconds() * rateLimitSafetyRatio
+	burst := w.limit / 10
+	if burst < 1 {
+		burst = 1
+	}
+
+	return rate.NewLimiter(rate.Limit(perSecond), burst)
+}
+
+// account returns the order rate limiter of the api key, it must be called with the lock held
+func (l *rateLimiter) account(apiKey string) *orderRateLimiter {
+	a, ok := l.accounts[apiKey]
+	if !ok {
+		a = &orderRateLimiter{}
+		for _, w := range l.windows {
+			if w.order {
+				a.orders = newWindowLimiter(w)
+				break
+			}
+		}
+
+		l.accounts[apiKey] = a
+	}
+
+	return a
+}
+
+var rateLimiters = struct {
+	sync.Mutex
+	m map[string]*rateLimiter
+}{m: make(map[string]*rateLimiter)}
+
+// sharedRateLimiter returns the rate limiter of the given host, the weight limits of binance are
+// counted by IP, so all the exchange instances of the process share the same limiter.
+func sharedRateLimiter(host string, futures bool) *rateLimiter {
+	key := host
+	windows := spotRateLimitWindows
+	if futures {
+		key += "/futures"
+		windows = futuresRateLimitWindows
+	}
+
+	rateLimiters.Lock()
+	defer rateLimiters.Unlock()
+
+	l, ok := rateLimiters.m[key]
+	if !ok {
+		l = newRateLimiter(windows)
+		rateLimiters.m[key] = l
+	}
+
+	return l
+}
+
+// Wait blocks until the request of the given weight is allowed, the new order requests
+// are also throttled by the order counts of the account of the api key
+func (l *rateLimiter) Wait(ctx context.Context, weight int, order bool, apiKey string) error {
+	var orders *rate.Limiter
+
+	l.mu.Lock()
+	until := l.pausedUntil
+	if order {
+		a := l.account(apiKey)
+		orders = a.orders
+		if a.pausedUntil.After(until) {
+			until = a.pausedUntil
+		}
+	}
+	l.mu.Unlock()
+
+	if d := time.Until(until); d > 0 {
+		log.Warnf("binance rate limit reached, waiting %s", d)
+
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if weight > l.weights.Burst() {
+		weight = l.weights.Burst()
+	}
+
+	if err := l.weights.WaitN(ctx, weight); err != nil {
+		return err
+	}
+
+	if orders != nil {
+		return orders.Wait(ctx)
+	}
+
+	return nil
+}
+
+// Update pauses the requests by the used amounts of the response headers and the retry-after of the rejected requests,
+// the order counts pause the new orders of the account of the api key only
+func (l *rateLimiter) Update(resp *http.Response, apiKey string) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, w := range l.windows {
+		value := resp.Header.Get(w.header)
+		if len(value) == 0 {
+			continue
+		}
+
+		used, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+
+		threshold := int(float64(w.limit) * rateLimitSafetyRatio)
+		if w.order {
+			threshold = w.limit
+		}
+
+		if used < threshold {
+			continue
+		}
+
+		until := now.Truncate(w.interval).Add(w.interval)
+		if w.order {
+			if a := l.account(apiKey); until.After(a.pausedUntil) {
+				a.pausedUntil = until
+			}
+		} else if until.After(l.pausedUntil) {
+			l.pausedUntil = until
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusTeapot:
+		retryAfter := time.Minute
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+
+		log.Errorf("binance rate limit exceeded, status code %d, backing off for %s", resp.StatusCode, retryAfter)
+
+		if until := now.Add(retryAfter); until.After(l.pausedUntil) {
+			l.pausedUntil = until
+		}
+	}
+}
+
+// IsRateLimited returns true since all the requests of the binance clients pass through the shared rate limiter
+func (e *Exchange) IsRateLimited() bool {
+	return true
+}
+
+// rateLimitTransport passes the requests of the binance clients through the shared rate limiter
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	futures bool
+}
+
+func newRateLimitedHTTPClient(futures bool) *http.Client {
+	return &http.Client{
+		Transport: &rateLimitTransport{base: http.DefaultTransport, futures: futures},
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limiter := sharedRateLimiter(req.URL.Host, t.futures)
+	apiKey := req.Header.Get("X-MBX-APIKEY")
+	weight, order := requestWeight(req)
+
+	for retry := 0; ; retry++ {
+		if err := limiter.Wait(req.Context(), weight, order, apiKey); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		limiter.Update(resp, apiKey)
+
+		// the rejected request is not processed by binance, so it's safe to send it again after the back off.
+		// 418 means the IP is banned, the ban lasts from minutes to days, hence it's returned to the caller.
+		if resp.StatusCode != http.StatusTooManyRequests || retry >= maxRateLimitRetries {
+			return resp, nil
+		}
+
+		// the timestamp of the signed request could be out of the recv window after the back off,
+		// so it's returned to the caller, which signs a new request, and the limiter holds it until the back off ends.
+		if isSignedRequest(req) {
+			return resp, nil
+		}
+
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		req = req.Clone(req.Context())
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// isSignedRequest checks if the request carries a signature in the query string or in the form body
+func isSignedRequest(req *http.Request) bool {
+	if len(req.URL.Query().Get("signature")) > 0 {
+		return true
+	}
+
+	if req.GetBody == nil {
+		return false
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return false
+	}
+
+	form, err := url.ParseQuery(string(data))
+	return err == nil && len(form.Get("signature")) > 0
+}
+
+// requestWeight returns the weight of the request and whether the request places a new order
+func requestWeight(req *http.Request) (int, bool) {
+	query := req.URL.Query()
+	hasSymbol := len(query.Get("symbol")) > 0
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	switch req.URL.Path {
+	case "/api/v3/order", "/api/v3/order/oco", "/sapi/v1/margin/order", "/sapi/v1/margin/order/oco",
+		"/fapi/v1/order", "/fapi/v1/batchOrders":
+		if req.Method == http.MethodPost {
+			return 1, true
+		}
+
+		if req.Method == http.MethodGet && req.URL.Path == "/api/v3/order" {
+			return 2, false
+		}
+
+		return 1, false
+
+	case "/api/v3/depth":
+		switch {
+		case limit > 1000:
+			return 50, false
+		case limit > 500:
+			return 10, false
+		case limit > 100:
+			return 5, false
+		}
+
+		return 1, false
+
+	case "/fapi/v1/depth":
+		switch {
+		case limit > 500:
+			return 20, false
+		case limit > 100:
+			return 10, false
+		case limit > 50:
+			return 5, false
+		}
+
+		return 2, false
+
+	case "/fapi/v1/klines":
+		switch {
+		case limit > 1000:
+			return 10, false
+		case limit >= 500:
+			return 5, false
+		case limit >= 100:
+			return 2, false
+		}
+
+		return 1, false
+
+	case "/api/v3/openOrders", "/api/v3/ticker/24hr":
+		if hasSymbol {
+			if req.URL.Path == "/api/v3/openOrders" {
+				return 3, false
+			}
+
+			return 1, false
+		}
+
+		return 40, false
+
+	case "/fapi/v1/openOrders", "/fapi/v1/ticker/24hr":
+		if hasSymbol {
+			return 1, false
+		}
+
+		return 40, false
+
+	case "/api/v3/account", "/api/v3/myTrades", "/api/v3/allOrders", "/api/v3/exchangeInfo":
+		return 10, false
+
+	case "/api/v3/historicalTrades", "/fapi/v2/account", "/fapi/v2/positionRisk", "/fapi/v1/allOrders", "/fapi/v1/userTrades":
+		return 5, false
+	}
+
+	return 1, false
+}
@@ -0,0 +1,179 @@
+package binance
+
+import (
+	"context"
+	"github.com/adshao/go-binance/v2"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rateLimitedServer rejects the first requests with 429 and a zero retry-after
+type rateLimitedServer struct {
+	mu       sync.Mutex
+	rejects  int
+	requests []*http.Request
+}
+
+func newRateLimitedServer(t *testing.T, rejects int) (*rateLimitedServer, *httptest.Server) {
+	s := &rateLimitedServer{rejects: rejects}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests = append(s.requests, r)
+		if s.rejects > 0 {
+			s.rejects--
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"code":-1003,"msg":"Too many requests."}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+func (s *rateLimitedServer) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func TestRateLimitTransportRetriesUnsignedRequests(t *testing.T) {
+	s, server := newRateLimitedServer(t, 1)
+
+	resp, err := newRateLimitedHTTPClient(false).Get(server.URL + "/api/v3/ticker/price?symbol=BTCUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || s.Requests() != 2 {
+		t.Errorf("status = %d, requests = %d, expected the rejected request to be sent again", resp.StatusCode, s.Requests())
+	}
+}
+
+func TestRateLimitTransportReturnsSignedRequests(t *testing.T) {
+	s, server := newRateLimitedServer(t, 1)
+
+	resp, err := newRateLimitedHTTPClient(false).Get(server.URL + "/api/v3/account?timestamp=1&signature=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests || s.Requests() != 1 {
+		t.Errorf("status = %d, requests = %d, expected the signed request to be returned", resp.StatusCode, s.Requests())
+	}
+}
+
+func TestRateLimitTransportReturnsSignedFormRequests(t *testing.T) {
+	s, server := newRateLimitedServer(t, 1)
+
+	form := url.Values{"symbol": []string{"BTCUSDT"}, "timestamp": []string{"1"}, "signature": []string{"abc"}}
+	resp, err := newRateLimitedHTTPClient(false).Post(server.URL+"/sapi/v1/margin/loan",
+		"application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests || s.Requests() != 1 {
+		t.Errorf("status = %d, requests = %d, expected the signed request to be returned", resp.StatusCode, s.Requests())
+	}
+}
+
+func TestBinanceClientResignsRateLimitedRequests(t *testing.T) {
+	s, server := newRateLimitedServer(t, 1)
+
+	client := binance.NewClient("key", "secret")
+	client.BaseURL = server.URL
+	client.HTTPClient = newRateLimitedHTTPClient(false)
+
+	exchange := &Exchange{Client: client}
+
+	_, err := client.NewGetAccountService().Do(context.Background())
+	if !exchange.IsTransientError(err) {
+		t.Fatalf("expected a transient error, got %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	if _, err := client.NewGetAccountService().Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.requests) != 2 || s.requests[0].URL.Query().Get("signature") == s.requests[1].URL.Query().Get("signature") {
+		t.Error("expected the retry to be signed again")
+	}
+}
+
+func TestOrderRateLimitsAreKeptByAPIKey(t *testing.T) {
+	l := newRateLimiter(spotRateLimitWindows)
+
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	resp.Header.Set("X-MBX-ORDER-COUNT-10S", "50")
+	l.Update(resp, "key1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx, 1, true, "key2"); err != nil {
+		t.Errorf("the orders of the other api key are throttled: %v", err)
+	}
+
+	if err := l.Wait(ctx, 1, false, "key1"); err != nil {
+		t.Errorf("the non-order requests of the api key are throttled: %v", err)
+	}
+
+	if err := l.Wait(ctx, 1, true, "key1"); err == nil {
+		t.Error("the orders of the api key reaching the order count limit are not throttled")
+	}
+}
+
+func TestWeightLimitsAreSharedByHost(t *testing.T) {
+	l := newRateLimiter(spotRateLimitWindows)
+
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	resp.Header.Set("X-MBX-USED-WEIGHT-1M", "1190")
+	l.Update(resp, "key1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx, 1, false, "key2"); err == nil {
+		t.Error("the weight limit of the ip is not shared by the api keys")
+	}
+}
+
+func TestSignedRequestReturnsRateLimitedRequests(t *testing.T) {
+	s, server := newRateLimitedServer(t, 1)
+
+	client := binance.NewClient("key", "secret")
+	client.BaseURL = server.URL
+	client.HTTPClient = newRateLimitedHTTPClient(false)
+
+	exchange := &Exchange{Client: client}
+
+	err := exchange.signedRequest(context.Background(), http.MethodPost, "/sapi/v1/margin/isolated/transfer", url.Values{"asset": []string{"BTC"}}, nil)
+	if !exchange.IsTransientError(err) {
+		t.Fatalf("expected a transient error, got %v", err)
+	}
+
+	if s.Requests() != 1 {
+		t.Errorf("requests = %d, expected the signed request to be returned", s.Requests())
+	}
+}
@@ -0,0 +1,115 @@
+package binance
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"sync"
+	"time"
+)
+
+type RecordType string
+
+const (
+	// RecordTypeMessage is the raw websocket message
+	RecordTypeMessage = RecordType("message")
+
+	// RecordTypeDepthSnapshot is the depth snapshot fetched for syncing the depth events
+	RecordTypeDepthSnapshot = RecordType("depthSnapshot")
+)
+
+// Record is a line of the stream recording
+type Record struct {
+	Time time.Time       `json:"time"`
+	Type RecordType      `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Recorder writes the raw messages and the depth snapshots of the stream into a gzip compressed file of json lines,
+// the recording can be fed back by the ReplayStream. The buffered records are flushed when the recorder is closed.
+type Recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	writer  *gzip.Writer
+	encoder *json.Encoder
+	closed  bool
+}
+
+func NewRecorder(filename string) (*Recorder, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := gzip.NewWriter(file)
+	return &Recorder{
+		file:    file,
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}, nil
+}
+
+// Record starts recording the stream into the file, the recorder should be closed after the stream is closed
+func (s *Stream) Record(filename string) (*Recorder, error) {
+	recorder, err := NewRecorder(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder.BindStream(s)
+	return recorder, nil
+}
+
+func (r *Recorder) BindStream(stream *Stream) {
+	stream.OnRawMessage(func(message []byte) {
+		r.write(RecordTypeMessage, message)
+	})
+
+	stream.OnDepthSnapshotEvent(func(e *DepthEvent) {
+		data, err := json.Marshal(e)
+		if err != nil {
+			log.WithError(err).Errorf("can not marshal %s depth snapshot", e.Symbol)
+			return
+		}
+
+		r.write(RecordTypeDepthSnapshot, data)
+	})
+}
+
+func (r *Recorder) write(recordType RecordType, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+
+	record := Record{
+		Time: time.Now(),
+		Type: recordType,
+		Data: data,
+	}
+
+	if err := r.encoder.Encode(record); err != nil {
+		log.WithError(err).Errorf("can not write the stream record to %s", r.file.Name())
+	}
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+
+	r.closed = true
+	if err := r.writer.Close(); err != nil {
+		_ = r.file.Close()
+		return errors.Wrapf(err, "can not flush the recording %s", r.file.Name())
+	}
+
+	return r.file.Close()
+}
@@ -0,0 +1,212 @@
+package binance
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ReplayStream feeds a stream recording back through ParseEvent and the event callbacks of the stream,
+// so the depth frames, the order books and the strategies can be regression tested against captured sessions.
+// The events are emitted from a single goroutine in the recorded order.
+type ReplayStream struct {
+	*Stream
+
+	Filename string
+
+	// Speed is the playback speed, 1 replays at the original pace, 10 replays 10 times faster,
+	// and zero replays the records without delays
+	Speed float64
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+func NewReplayStream(filename string) *ReplayStream {
+	stream := NewStream(nil, nil)
+	stream.replay = true
+
+	return &ReplayStream{
+		Stream:   stream,
+		Filename: filename,
+		Speed:    1,
+	}
+}
+
+// Connect starts replaying the recording in the background, Done is closed when the replay ends
+func (s *ReplayStream) Connect(ctx context.Context) error {
+	reader, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	s.mu.Lock()
+	s.cancel = cancel
+	s.done = done
+	s.err = nil
+	s.mu.Unlock()
+
+	s.EmitConnect(types.ConnectionReasonInitial)
+	s.EmitStart()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+
+		err := s.play(ctx, reader)
+		_ = reader.Close()
+
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+
+		s.EmitDisconnect(types.ConnectionReasonClosed)
+	}()
+
+	return nil
+}
+
+// Replay replays the whole recording in the calling goroutine
+func (s *ReplayStream) Replay(ctx context.Context) error {
+	reader, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	s.EmitConnect(types.ConnectionReasonInitial)
+	s.EmitStart()
+
+	err = s.play(ctx, reader)
+	s.EmitDisconnect(types.ConnectionReasonClosed)
+	return err
+}
+
+// Done returns the channel that is closed when the replay started by Connect ends
+func (s *ReplayStream) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// Err returns the error that stopped the replay started by Connect, nil if the recording is fully replayed
+func (s *ReplayStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close stops the replay, the disconnect event is emitted by the replay goroutine
+func (s *ReplayStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	return nil
+}
+
+type recordingReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *recordingReader) Close() error {
+	_ = r.Reader.Close()
+	return r.file.Close()
+}
+
+func (s *ReplayStream) open() (*recordingReader, error) {
+	file, err := os.Open(s.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.Wrapf(err, "can not read the recording %s", s.Filename)
+	}
+
+	return &recordingReader{Reader: reader, file: file}, nil
+}
+
+func (s *ReplayStream) play(ctx context.Context, reader io.Reader) error {
+	decoder := json.NewDecoder(reader)
+
+	var lastTime time.Time
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var record Record
+		if err := decoder.Decode(&record); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			return errors.Wrapf(err, "can not read the recording %s", s.Filename)
+		}
+
+		if s.Speed > 0 && !lastTime.IsZero() {
+			if delay := time.Duration(float64(record.Time.Sub(lastTime)) / s.Speed); delay > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+
+				case <-time.After(delay):
+				}
+			}
+		}
+
+		lastTime = record.Time
+		s.dispatchRecord(record)
+	}
+}
+
+// dispatchRecord emits the recorded message through the same path as the websocket message
+func (s *ReplayStream) dispatchRecord(record Record) {
+	switch record.Type {
+
+	case RecordTypeMessage:
+		s.EmitRawMessage(record.Data)
+
+		e, err := ParseEvent(string(record.Data))
+		if err != nil {
+			log.WithError(err).Errorf("replay event parse error")
+			return
+		}
+
+		s.dispatchEvent(e)
+
+	case RecordTypeDepthSnapshot:
+		var depth DepthEvent
+		if err := json.Unmarshal(record.Data, &depth); err != nil {
+			log.WithError(err).Errorf("replay depth snapshot parse error")
+			return
+		}
+
+		if f, ok := s.depthFrames[depth.Symbol]; ok {
+			f.PushSnapshot(depth)
+		}
+
+	default:
+		log.Warnf("unknown record type %s", record.Type)
+	}
+}
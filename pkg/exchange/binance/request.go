@@ -0,0 +1,85 @@
+package binance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/adshao/go-binance/v2/common"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signedRequest sends a signed request to the endpoints and the parameters that are not covered by the
+// services of the binance client: the margin history with the transaction ids and the isolated symbol,
+// the interest history, the isolated margin loan, repay, transfer and max borrowable, the margin OCO order
+// and the spot cancel-replace. It uses the credentials, the server time offset and the rate limited
+// http client of the spot client, the rejected request is returned as the transient api error like
+// the requests of the client, so that the caller signs it again.
+func (e *Exchange) signedRequest(ctx context.Context, method, endpoint string, params url.Values, out interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	timestamp := time.Now().UnixNano()/int64(time.Millisecond) - e.Client.TimeOffset
+	params.Set("timestamp", strconv.FormatInt(timestamp, 10))
+
+	payload := params.Encode()
+	mac := hmac.New(sha256.New, []byte(e.Client.SecretKey))
+	if _, err := mac.Write([]byte(payload)); err != nil {
+		return err
+	}
+
+	payload += "&signature=" + hex.EncodeToString(mac.Sum(nil))
+
+	fullURL := e.Client.BaseURL + endpoint
+	var req *http.Request
+	var err error
+	if method == http.MethodGet || method == http.MethodDelete {
+		req, err = http.NewRequestWithContext(ctx, method, fullURL+"?"+payload, nil)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, method, fullURL, strings.NewReader(payload))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-MBX-APIKEY", e.Client.APIKey)
+
+	resp, err := e.Client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &common.APIError{}
+		if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Code == 0 {
+			return fmt.Errorf("%s %s: unexpected status code %d: %s", method, endpoint, resp.StatusCode, data)
+		}
+
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
@@ -3,6 +3,7 @@ package binance
 import (
 	"context"
 	"github.com/adshao/go-binance/v2"
+	"github.com/adshao/go-binance/v2/futures"
 	"github.com/gorilla/websocket"
 	"github.com/pymba86/bingo/pkg/types"
 	log "github.com/sirupsen/logrus"
@@ -13,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -56,31 +58,65 @@ type StreamRequest struct {
 
 type Stream struct {
 	types.MarginSettings
+	types.FuturesSettings
+	types.EndpointSettings
 
 	types.StandardStream
 
-	Client   *binance.Client
+	Client        *binance.Client
+	FuturesClient *futures.Client
+
 	Conn     *websocket.Conn
 	ConnLock sync.Mutex
 
 	connCtx    context.Context
 	connCancel context.CancelFunc
 
+	// closeReason is the reason of closing the current connection, it's reported by the read worker
+	closeReason types.ConnectionReason
+	closed      bool
+
+	// lastMessageTime is the unix nano time of the last received message, it's used by the watchdog
+	lastMessageTime int64
+
 	publicOnly bool
 
+	// replay is true for the replay stream, the depth snapshots are replayed instead of fetched
+	replay bool
+
 	// custom callbacks
-	depthEventCallbacks       []func(e *DepthEvent)
-	kLineEventCallbacks       []func(e *KLineEvent)
-	kLineClosedEventCallbacks []func(e *KLineEvent)
+	rawMessageCallbacks         []func(message []byte)
+	depthEventCallbacks         []func(e *DepthEvent)
+	depthSnapshotEventCallbacks []func(e *DepthEvent)
+	kLineEventCallbacks         []func(e *KLineEvent)
+	kLineClosedEventCallbacks   []func(e *KLineEvent)
+
+	partialDepthEventCallbacks []func(e *PartialDepthEvent)
+	aggTradeEventCallbacks     []func(e *AggTradeEvent)
+	marketTradeEventCallbacks  []func(e *MarketTradeEvent)
+	bookTickerEventCallbacks   []func(e *BookTickerEvent)
 
 	balanceUpdateEventCallbacks           []func(event *BalanceUpdateEvent)
 	outboundAccountInfoEventCallbacks     []func(event *OutboundAccountInfoEvent)
 	outboundAccountPositionEventCallbacks []func(event *OutboundAccountPositionEvent)
 	executionReportEventCallbacks         []func(event *ExecutionReportEvent)
 
+	orderTradeUpdateEventCallbacks []func(event *OrderTradeUpdateEvent)
+	accountUpdateEventCallbacks    []func(event *AccountUpdateEvent)
+
 	depthFrames map[string]*DepthFrame
 }
 
+func (s *Stream) OnRawMessage(cb func(message []byte)) {
+	s.rawMessageCallbacks = append(s.rawMessageCallbacks, cb)
+}
+
+func (s *Stream) EmitRawMessage(message []byte) {
+	for _, cb := range s.rawMessageCallbacks {
+		cb(message)
+	}
+}
+
 func (s *Stream) OnDepthEvent(cb func(e *DepthEvent)) {
 	s.depthEventCallbacks = append(s.depthEventCallbacks, cb)
 }
@@ -91,6 +127,57 @@ func (s *Stream) EmitDepthEvent(e *DepthEvent) {
 	}
 }
 
+// OnDepthSnapshotEvent is called with the depth snapshots fetched for syncing the depth events
+func (s *Stream) OnDepthSnapshotEvent(cb func(e *DepthEvent)) {
+	s.depthSnapshotEventCallbacks = append(s.depthSnapshotEventCallbacks, cb)
+}
+
+func (s *Stream) EmitDepthSnapshotEvent(e *DepthEvent) {
+	for _, cb := range s.depthSnapshotEventCallbacks {
+		cb(e)
+	}
+}
+
+func (s *Stream) OnPartialDepthEvent(cb func(e *PartialDepthEvent)) {
+	s.partialDepthEventCallbacks = append(s.partialDepthEventCallbacks, cb)
+}
+
+func (s *Stream) EmitPartialDepthEvent(e *PartialDepthEvent) {
+	for _, cb := range s.partialDepthEventCallbacks {
+		cb(e)
+	}
+}
+
+func (s *Stream) OnAggTradeEvent(cb func(e *AggTradeEvent)) {
+	s.aggTradeEventCallbacks = append(s.aggTradeEventCallbacks, cb)
+}
+
+func (s *Stream) EmitAggTradeEvent(e *AggTradeEvent) {
+	for _, cb := range s.aggTradeEventCallbacks {
+		cb(e)
+	}
+}
+
+func (s *Stream) OnMarketTradeEvent(cb func(e *MarketTradeEvent)) {
+	s.marketTradeEventCallbacks = append(s.marketTradeEventCallbacks, cb)
+}
+
+func (s *Stream) EmitMarketTradeEvent(e *MarketTradeEvent) {
+	for _, cb := range s.marketTradeEventCallbacks {
+		cb(e)
+	}
+}
+
+func (s *Stream) OnBookTickerEvent(cb func(e *BookTickerEvent)) {
+	s.bookTickerEventCallbacks = append(s.bookTickerEventCallbacks, cb)
+}
+
+func (s *Stream) EmitBookTickerEvent(e *BookTickerEvent) {
+	for _, cb := range s.bookTickerEventCallbacks {
+		cb(e)
+	}
+}
+
 func (s *Stream) OnKLineEvent(cb func(e *KLineEvent)) {
 	s.kLineEventCallbacks = append(s.kLineEventCallbacks, cb)
 }
@@ -151,9 +238,33 @@ func (s *Stream) EmitExecutionReportEvent(event *ExecutionReportEvent) {
 	}
 }
 
+func (s *Stream) OnOrderTradeUpdateEvent(cb func(event *OrderTradeUpdateEvent)) {
+	s.orderTradeUpdateEventCallbacks = append(s.orderTradeUpdateEventCallbacks, cb)
+}
+
+func (s *Stream) EmitOrderTradeUpdateEvent(event *OrderTradeUpdateEvent) {
+	for _, cb := range s.orderTradeUpdateEventCallbacks {
+		cb(event)
+	}
+}
+
+func (s *Stream) OnAccountUpdateEvent(cb func(event *AccountUpdateEvent)) {
+	s.accountUpdateEventCallbacks = append(s.accountUpdateEventCallbacks, cb)
+}
+
+func (s *Stream) EmitAccountUpdateEvent(event *AccountUpdateEvent) {
+	for _, cb := range s.accountUpdateEventCallbacks {
+		cb(event)
+	}
+}
+
 type StreamEventHub interface {
+	OnRawMessage(cb func(message []byte))
+
 	OnDepthEvent(cb func(e *DepthEvent))
 
+	OnDepthSnapshotEvent(cb func(e *DepthEvent))
+
 	OnKLineEvent(cb func(e *KLineEvent))
 
 	OnKLineClosedEvent(cb func(e *KLineEvent))
@@ -165,15 +276,29 @@ type StreamEventHub interface {
 	OnOutboundAccountPositionEvent(cb func(event *OutboundAccountPositionEvent))
 
 	OnExecutionReportEvent(cb func(event *ExecutionReportEvent))
+
+	OnOrderTradeUpdateEvent(cb func(event *OrderTradeUpdateEvent))
+
+	OnAccountUpdateEvent(cb func(event *AccountUpdateEvent))
+
+	OnPartialDepthEvent(cb func(e *PartialDepthEvent))
+
+	OnAggTradeEvent(cb func(e *AggTradeEvent))
+
+	OnMarketTradeEvent(cb func(e *MarketTradeEvent))
+
+	OnBookTickerEvent(cb func(e *BookTickerEvent))
 }
 
-func NewStream(client *binance.Client) *Stream {
+func NewStream(client *binance.Client, futuresClient *futures.Client) *Stream {
 	stream := &Stream{
 		StandardStream: types.StandardStream{
-			ReconnectC: make(chan struct{}, 1),
+			ReconnectC:       make(chan types.ConnectionReason, 1),
+			ReconnectBackoff: types.DefaultBackoff,
 		},
-		Client:      client,
-		depthFrames: make(map[string]*DepthFrame),
+		Client:        client,
+		FuturesClient: futuresClient,
+		depthFrames:   make(map[string]*DepthFrame),
 	}
 
 	stream.OnDepthEvent(func(e *DepthEvent) {
@@ -188,10 +313,19 @@ func NewStream(client *binance.Client) *Stream {
 				context: context.Background(),
 				Symbol:  e.Symbol,
 				resetC:  make(chan struct{}, 1),
+				replay:  stream.replay,
+			}
+
+			if stream.IsFutures {
+				f.futuresClient = futuresClient
 			}
 
 			stream.depthFrames[e.Symbol] = f
 
+			f.OnFetch(func(depth DepthEvent) {
+				stream.EmitDepthSnapshotEvent(&depth)
+			})
+
 			f.OnReady(func(snapshotDepth DepthEvent, bufEvents []DepthEvent) {
 				log.Infof("depth snapshot ready: %s", snapshotDepth.String())
 
@@ -288,14 +422,83 @@ func NewStream(client *binance.Client) *Stream {
 		}
 	})
 
-	stream.OnDisconnect(func() {
-		log.Infof("resetting depth snapshots...")
+	stream.OnOrderTradeUpdateEvent(func(e *OrderTradeUpdateEvent) {
+		switch e.OrderTrade.CurrentExecutionType {
+
+		case "NEW", "CANCELED", "EXPIRED", "CALCULATED":
+			order, err := e.Order()
+			if err != nil {
+				log.WithError(err).Error("futures order convert error")
+				return
+			}
+
+			stream.EmitOrderUpdate(*order)
+
+		case "TRADE":
+			trade, err := e.Trade()
+			if err != nil {
+				log.WithError(err).Error("futures trade convert error")
+				return
+			}
+
+			stream.EmitTradeUpdate(*trade)
+
+			order, err := e.Order()
+			if err != nil {
+				log.WithError(err).Error("futures order convert error")
+				return
+			}
+
+			// Update Order with FILLED event
+			if order.Status == types.OrderStatusFilled {
+				stream.EmitOrderUpdate(*order)
+			}
+		}
+	})
+
+	stream.OnAccountUpdateEvent(func(e *AccountUpdateEvent) {
+		stream.EmitBalanceSnapshot(e.BalanceMap())
+
+		if positions := e.Positions(); len(positions) > 0 {
+			stream.EmitPositionUpdate(positions)
+		}
+	})
+
+	stream.OnPartialDepthEvent(func(e *PartialDepthEvent) {
+		book, err := e.OrderBook()
+		if err != nil {
+			log.WithError(err).Error("partial depth convert error")
+			return
+		}
+
+		stream.EmitBookSnapshot(book)
+	})
+
+	stream.OnAggTradeEvent(func(e *AggTradeEvent) {
+		stream.EmitMarketTrade(e.Trade())
+	})
+
+	stream.OnMarketTradeEvent(func(e *MarketTradeEvent) {
+		stream.EmitMarketTrade(e.Trade())
+	})
+
+	stream.OnBookTickerEvent(func(e *BookTickerEvent) {
+		stream.EmitBookTickerUpdate(e.BookTicker())
+	})
+
+	stream.OnDisconnect(func(reason types.ConnectionReason) {
+		log.Infof("stream is disconnected (%s), resetting depth snapshots...", reason)
 		for _, f := range stream.depthFrames {
 			f.emitReset()
 		}
 	})
 
-	stream.OnConnect(func() {
+	stream.OnConnect(func(reason types.ConnectionReason) {
+		// the replay stream has no connection to subscribe
+		if stream.replay {
+			return
+		}
+
 		var params []string
 		for _, subscription := range stream.Subscriptions {
 			params = append(params, convertSubscription(subscription))
@@ -306,11 +509,13 @@ func NewStream(client *binance.Client) *Stream {
 		}
 
 		log.Infof("subscribing channels: %+v", params)
+		stream.ConnLock.Lock()
 		err := stream.Conn.WriteJSON(StreamRequest{
 			Method: "SUBSCRIBE",
 			Params: params,
 			ID:     1,
 		})
+		stream.ConnLock.Unlock()
 
 		if err != nil {
 			log.WithError(err).Error("subscribe error")
@@ -320,16 +525,50 @@ func NewStream(client *binance.Client) *Stream {
 	return stream
 }
 
+// SubscribeLive subscribes the channels on the current connection without reconnecting the stream,
+// the subscriptions should be added by Subscribe first, so that they are subscribed again on reconnect.
+// The channels are subscribed on connect if the stream is not connected yet.
+func (s *Stream) SubscribeLive(subscriptions ...types.Subscription) error {
+	if s.replay {
+		return nil
+	}
+
+	var params []string
+	for _, subscription := range subscriptions {
+		params = append(params, convertSubscription(subscription))
+	}
+
+	if len(params) == 0 {
+		return nil
+	}
+
+	s.ConnLock.Lock()
+	defer s.ConnLock.Unlock()
+
+	if s.Conn == nil {
+		return nil
+	}
+
+	log.Infof("subscribing channels: %+v", params)
+	return s.Conn.WriteJSON(StreamRequest{
+		Method: "SUBSCRIBE",
+		Params: params,
+		ID:     1,
+	})
+}
+
 func (s *Stream) SetPublicOnly() {
 	s.publicOnly = true
 }
 
 func (s *Stream) dial(listenKey string) (*websocket.Conn, error) {
-	var url string
+	var url = s.wsBaseURL()
+
+	// the public streams use the combined stream endpoint so that the payloads carry the stream name
 	if s.publicOnly {
-		url = "wss://stream.binance.com:9443/ws"
+		url += "/stream"
 	} else {
-		url = "wss://stream.binance.com:9443/ws/" + listenKey
+		url += "/ws/" + listenKey
 	}
 
 	conn, _, err := defaultDialer.Dial(url, nil)
@@ -348,6 +587,11 @@ func (s *Stream) dial(listenKey string) (*websocket.Conn, error) {
 }
 
 func (s *Stream) fetchListenKey(ctx context.Context) (string, error) {
+	if s.IsFutures {
+		log.Infof("futures mode is enabled, requesting futures user stream listen key...")
+		return s.FuturesClient.NewStartUserStreamService().Do(ctx)
+	}
+
 	if s.IsMargin {
 		if s.IsIsolatedMargin {
 			log.Infof("isolated margin %s is enabled, requesting margin user stream listen key...", s.IsolatedMarginSymbol)
@@ -365,6 +609,10 @@ func (s *Stream) fetchListenKey(ctx context.Context) (string, error) {
 }
 
 func (s *Stream) keepaliveListenKey(ctx context.Context, listenKey string) error {
+	if s.IsFutures {
+		return s.FuturesClient.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(ctx)
+	}
+
 	if s.IsMargin {
 		if s.IsIsolatedMargin {
 			req := s.Client.NewKeepaliveIsolatedMarginUserStreamService().ListenKey(listenKey)
@@ -380,7 +628,7 @@ func (s *Stream) keepaliveListenKey(ctx context.Context, listenKey string) error
 }
 
 func (s *Stream) Connect(ctx context.Context) error {
-	err := s.connect(ctx)
+	err := s.connect(ctx, types.ConnectionReasonInitial)
 	if err != nil {
 		return err
 	}
@@ -398,24 +646,42 @@ func (s *Stream) reconnector(ctx context.Context) {
 		case <-ctx.Done():
 			return
 
-		case <-s.ReconnectC:
-			log.Warnf("received reconnect signal, reconnecting...")
-			time.Sleep(3 * time.Second)
+		case reason := <-s.ReconnectC:
+			log.Warnf("received reconnect signal (%s), reconnecting...", reason)
+
+			for attempt := 0; ; attempt++ {
+				delay := s.ReconnectBackoff.Duration(attempt)
+				log.Infof("reconnecting in %s, attempt %d...", delay, attempt+1)
+
+				select {
+				case <-ctx.Done():
+					return
+
+				case <-time.After(delay):
+				}
+
+				if s.isClosed() {
+					return
+				}
+
+				if err := s.connect(ctx, types.ConnectionReasonReconnect); err != nil {
+					log.WithError(err).Errorf("connect error, try to reconnect again...")
+					continue
+				}
 
-			if err := s.connect(ctx); err != nil {
-				log.WithError(err).Errorf("connect error, try to reconnect again...")
-				s.Reconnect()
+				break
 			}
 		}
 	}
 }
 
-func (s *Stream) connect(ctx context.Context) error {
+func (s *Stream) connect(ctx context.Context, reason types.ConnectionReason) error {
 	var err error
 	var listenKey string
 	if s.publicOnly {
 		log.Infof("stream is set to public only mode")
 	} else {
+		// a new listen key is requested for every connection, so an expired listen key is regenerated on reconnect
 		log.Infof("request listen key for creating user data stream...")
 
 		listenKey, err = s.fetchListenKey(ctx)
@@ -437,13 +703,18 @@ func (s *Stream) connect(ctx context.Context) error {
 	// should only start one connection one time, so we lock the mutex
 	s.ConnLock.Lock()
 
-	// ensure the previous context is cancelled
+	// ensure the previous context is cancelled and the previous connection is closed
 	if s.connCancel != nil {
 		s.connCancel()
 	}
 
+	if s.Conn != nil {
+		_ = s.Conn.Close()
+	}
+
 	// create a new context
-	s.connCtx, s.connCancel = context.WithCancel(ctx)
+	connCtx, connCancel := context.WithCancel(ctx)
+	s.connCtx, s.connCancel = connCtx, connCancel
 	conn.SetPongHandler(func(string) error {
 		if err := conn.SetReadDeadline(time.Now().Add(readTimeout * 2)); err != nil {
 			log.WithError(err).Error("pong handler can not set read deadline")
@@ -452,20 +723,65 @@ func (s *Stream) connect(ctx context.Context) error {
 	})
 
 	s.Conn = conn
+	s.closeReason = ""
 	s.ConnLock.Unlock()
 
-	s.EmitConnect()
+	atomic.StoreInt64(&s.lastMessageTime, time.Now().UnixNano())
+
+	s.EmitConnect(reason)
 
 	if !s.publicOnly {
-		go s.listenKeyKeepAlive(s.connCtx, listenKey)
+		go s.listenKeyKeepAlive(connCtx, conn, listenKey)
+	}
+
+	go s.read(connCtx, connCancel, conn)
+	go s.ping(connCtx, conn)
+
+	if s.StallTimeout > 0 {
+		go s.watchdog(connCtx, conn)
 	}
 
-	go s.read(s.connCtx)
-	go s.ping(s.connCtx)
 	return nil
 }
 
-func (s *Stream) ping(ctx context.Context) {
+// closeConn closes the connection with the given reason, the read worker of the connection picks up the reason and reconnects
+func (s *Stream) closeConn(conn *websocket.Conn, reason types.ConnectionReason) {
+	s.ConnLock.Lock()
+	if s.Conn == conn && len(s.closeReason) == 0 {
+		s.closeReason = reason
+	}
+	s.ConnLock.Unlock()
+
+	_ = conn.Close()
+}
+
+// disconnectReason returns the reason set by closeConn, or the reason derived from the read error
+func (s *Stream) disconnectReason(conn *websocket.Conn, err error) types.ConnectionReason {
+	s.ConnLock.Lock()
+	reason := s.closeReason
+	if s.Conn != conn {
+		reason = ""
+	}
+	s.ConnLock.Unlock()
+
+	if len(reason) > 0 {
+		return reason
+	}
+
+	if _, ok := err.(*websocket.CloseError); ok {
+		return types.ConnectionReasonRemoteClosed
+	}
+
+	return types.ConnectionReasonNetworkError
+}
+
+func (s *Stream) isClosed() bool {
+	s.ConnLock.Lock()
+	defer s.ConnLock.Unlock()
+	return s.closed
+}
+
+func (s *Stream) ping(ctx context.Context, conn *websocket.Conn) {
 	pingTicker := time.NewTicker(readTimeout / 2)
 	defer pingTicker.Stop()
 
@@ -477,13 +793,38 @@ func (s *Stream) ping(ctx context.Context) {
 			return
 
 		case <-pingTicker.C:
-			s.ConnLock.Lock()
-			conn := s.Conn
-			s.ConnLock.Unlock()
-
 			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(pongWaitTime)); err != nil {
 				log.WithError(err).Error("ping error", err)
-				s.Reconnect()
+				s.closeConn(conn, types.ConnectionReasonNetworkError)
+				return
+			}
+		}
+	}
+}
+
+// watchdog forces a reconnect when no message arrives within the stall timeout,
+// the pong frames are not counted, so a silently stalled feed is detected.
+func (s *Stream) watchdog(ctx context.Context, conn *websocket.Conn) {
+	interval := s.StallTimeout / 4
+	if interval <= 0 {
+		interval = s.StallTimeout
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			lastMessageTime := time.Unix(0, atomic.LoadInt64(&s.lastMessageTime))
+			if time.Since(lastMessageTime) > s.StallTimeout {
+				log.Warnf("no message is received since %s, the stream is stalled", lastMessageTime)
+				s.closeConn(conn, types.ConnectionReasonStalled)
+				return
 			}
 		}
 	}
@@ -492,7 +833,7 @@ func (s *Stream) ping(ctx context.Context) {
 // From Binance
 // Keepalive a user data stream to prevent a time out. User data streams will close after 60 minutes.
 // It's recommended to send a ping about every 30 minutes.
-func (s *Stream) listenKeyKeepAlive(ctx context.Context, listenKey string) {
+func (s *Stream) listenKeyKeepAlive(ctx context.Context, conn *websocket.Conn, listenKey string) {
 	keepAliveTicker := time.NewTicker(20 * time.Minute)
 	defer keepAliveTicker.Stop()
 
@@ -523,8 +864,9 @@ func (s *Stream) listenKeyKeepAlive(ctx context.Context, listenKey string) {
 						continue
 
 					default:
+						// the listen key is expired or invalidated, reconnect with a new listen key
 						log.WithError(err).Errorf("listen key keep-alive unexpected error: %v key: %s", err, MaskKey(listenKey))
-						s.Reconnect()
+						s.closeConn(conn, types.ConnectionReasonListenKeyExpired)
 						return
 
 					}
@@ -535,12 +877,12 @@ func (s *Stream) listenKeyKeepAlive(ctx context.Context, listenKey string) {
 	}
 }
 
-func (s *Stream) read(ctx context.Context) {
+func (s *Stream) read(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn) {
+	reason := types.ConnectionReasonClosed
 	defer func() {
-		if s.connCancel != nil {
-			s.connCancel()
-		}
-		s.EmitDisconnect()
+		// stop the workers of this connection
+		cancel()
+		s.EmitDisconnect(reason)
 	}()
 
 	for {
@@ -550,46 +892,28 @@ func (s *Stream) read(ctx context.Context) {
 			return
 
 		default:
-
-			s.ConnLock.Lock()
-			conn := s.Conn
-			s.ConnLock.Unlock()
-
 			if err := conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
 				log.WithError(err).Errorf("set read deadline error: %s", err.Error())
 			}
 
 			mt, message, err := conn.ReadMessage()
 			if err != nil {
-				// if it's a network timeout error, we should re-connect
-				switch err := err.(type) {
-
-				// if it's a websocket related error
-				case *websocket.CloseError:
-					if err.Code == websocket.CloseNormalClosure {
-						return
-					}
-
-					_ = conn.Close()
-					// for unexpected close error, we should re-connect
-					// emit reconnect to start a new connection
-					s.Reconnect()
+				// the connection is closed by Close or replaced by a new connection
+				if ctx.Err() != nil {
 					return
+				}
 
-				case net.Error:
-					log.WithError(err).Error("websocket network error")
-					_ = conn.Close()
-					s.Reconnect()
-					return
+				reason = s.disconnectReason(conn, err)
+				log.WithError(err).Errorf("websocket disconnected: %s", reason)
 
-				default:
-					log.WithError(err).Error("unexpected connection error")
-					_ = conn.Close()
-					s.Reconnect()
-					return
-				}
+				// emit reconnect to start a new connection
+				_ = conn.Close()
+				s.Reconnect(reason)
+				return
 			}
 
+			atomic.StoreInt64(&s.lastMessageTime, time.Now().UnixNano())
+
 			// skip non-text messages
 			if mt != websocket.TextMessage {
 				continue
@@ -597,33 +921,66 @@ func (s *Stream) read(ctx context.Context) {
 
 			log.Debug(string(message))
 
+			s.EmitRawMessage(message)
+
 			e, err := ParseEvent(string(message))
 			if err != nil {
 				log.WithError(err).Errorf("websocket event parse error")
 				continue
 			}
 
-			switch e := e.(type) {
+			if _, ok := e.(*ListenKeyExpiredEvent); ok {
+				log.Warnf("listen key expired, reconnecting...")
+				reason = types.ConnectionReasonListenKeyExpired
+				_ = conn.Close()
+				s.Reconnect(reason)
+				return
+			}
+
+			s.dispatchEvent(e)
+		}
+	}
+}
 
-			case *OutboundAccountPositionEvent:
-				s.EmitOutboundAccountPositionEvent(e)
+// dispatchEvent emits the parsed event to the event callbacks
+func (s *Stream) dispatchEvent(e interface{}) {
+	switch e := e.(type) {
 
-			case *OutboundAccountInfoEvent:
-				s.EmitOutboundAccountInfoEvent(e)
+	case *OutboundAccountPositionEvent:
+		s.EmitOutboundAccountPositionEvent(e)
 
-			case *BalanceUpdateEvent:
-				s.EmitBalanceUpdateEvent(e)
+	case *OutboundAccountInfoEvent:
+		s.EmitOutboundAccountInfoEvent(e)
 
-			case *KLineEvent:
-				s.EmitKLineEvent(e)
+	case *BalanceUpdateEvent:
+		s.EmitBalanceUpdateEvent(e)
 
-			case *DepthEvent:
-				s.EmitDepthEvent(e)
+	case *KLineEvent:
+		s.EmitKLineEvent(e)
 
-			case *ExecutionReportEvent:
-				s.EmitExecutionReportEvent(e)
-			}
-		}
+	case *DepthEvent:
+		s.EmitDepthEvent(e)
+
+	case *ExecutionReportEvent:
+		s.EmitExecutionReportEvent(e)
+
+	case *OrderTradeUpdateEvent:
+		s.EmitOrderTradeUpdateEvent(e)
+
+	case *AccountUpdateEvent:
+		s.EmitAccountUpdateEvent(e)
+
+	case *PartialDepthEvent:
+		s.EmitPartialDepthEvent(e)
+
+	case *AggTradeEvent:
+		s.EmitAggTradeEvent(e)
+
+	case *MarketTradeEvent:
+		s.EmitMarketTradeEvent(e)
+
+	case *BookTickerEvent:
+		s.EmitBookTickerEvent(e)
 	}
 }
 
@@ -631,7 +988,9 @@ func (s *Stream) invalidateListenKey(ctx context.Context, listenKey string) (err
 	// should use background context to invalidate the user stream
 	log.Infof("closing listen key: %s", MaskKey(listenKey))
 
-	if s.IsMargin {
+	if s.IsFutures {
+		err = s.FuturesClient.NewCloseUserStreamService().ListenKey(listenKey).Do(ctx)
+	} else if s.IsMargin {
 		if s.IsIsolatedMargin {
 			req := s.Client.NewCloseIsolatedMarginUserStreamService().ListenKey(listenKey)
 			req.Symbol(s.IsolatedMarginSymbol)
@@ -654,16 +1013,21 @@ func (s *Stream) invalidateListenKey(ctx context.Context, listenKey string) (err
 }
 
 func (s *Stream) Close() error {
-	log.Infof("closing user data stream...")
+	log.Infof("closing stream...")
 
+	s.ConnLock.Lock()
+	defer s.ConnLock.Unlock()
+
+	s.closed = true
 	if s.connCancel != nil {
 		s.connCancel()
 	}
 
-	s.ConnLock.Lock()
-	err := s.Conn.Close()
-	s.ConnLock.Unlock()
-	return err
+	if s.Conn == nil {
+		return nil
+	}
+
+	return s.Conn.Close()
 }
 
 func MaskKey(key string) string {
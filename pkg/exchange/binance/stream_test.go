@@ -0,0 +1,341 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"github.com/adshao/go-binance/v2"
+	"github.com/gorilla/websocket"
+	"github.com/pymba86/bingo/pkg/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testUpgrader = websocket.Upgrader{}
+
+// streamStandIn is a local stand-in of the binance user data stream and websocket endpoints,
+// it creates a new listen key for every listen key request
+type streamStandIn struct {
+	t *testing.T
+
+	server *httptest.Server
+
+	mu sync.Mutex
+
+	// failHandshakes is the number of the websocket handshakes that are rejected
+	failHandshakes int
+
+	listenKeys       []string
+	closedListenKeys []string
+	handshakes       []time.Time
+	connections      []string
+
+	// serve is called with the connection and its number, the connection is closed when it returns
+	serve func(conn *websocket.Conn, n int)
+}
+
+func newStreamStandIn(t *testing.T, serve func(conn *websocket.Conn, n int)) *streamStandIn {
+	s := &streamStandIn{t: t, serve: serve}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *streamStandIn) handle(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/api/v3/userDataStream":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPost:
+			listenKey := fmt.Sprintf("listenkey%d", len(s.listenKeys)+1)
+			s.listenKeys = append(s.listenKeys, listenKey)
+			_, _ = fmt.Fprintf(w, `{"listenKey":"%s"}`, listenKey)
+
+		case http.MethodDelete:
+			// the listen key of the delete request is sent in the form body
+			body, _ := ioutil.ReadAll(r.Body)
+			form, _ := url.ParseQuery(string(body))
+			s.closedListenKeys = append(s.closedListenKeys, form.Get("listenKey"))
+			_, _ = w.Write([]byte(`{}`))
+
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+
+	case r.URL.Path == "/stream" || strings.HasPrefix(r.URL.Path, "/ws/"):
+		s.mu.Lock()
+		s.handshakes = append(s.handshakes, time.Now())
+		if s.failHandshakes > 0 {
+			s.failHandshakes--
+			s.mu.Unlock()
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		s.connections = append(s.connections, r.URL.Path)
+		n := len(s.connections)
+		s.mu.Unlock()
+
+		conn, err := testUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			s.t.Error(err)
+			return
+		}
+
+		defer conn.Close()
+
+		if s.serve != nil {
+			s.serve(conn, n)
+		}
+
+		// keep the connection open until the client closes it
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *streamStandIn) URL() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+func (s *streamStandIn) Connections() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.connections...)
+}
+
+func (s *streamStandIn) Handshakes() []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time(nil), s.handshakes...)
+}
+
+func (s *streamStandIn) ClosedListenKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.closedListenKeys...)
+}
+
+// connectionRecorder records the reasons passed to OnConnect and OnDisconnect
+type connectionRecorder struct {
+	mu         sync.Mutex
+	connects   []types.ConnectionReason
+	disconnect []types.ConnectionReason
+}
+
+func recordConnections(stream *Stream) *connectionRecorder {
+	r := &connectionRecorder{}
+	stream.OnConnect(func(reason types.ConnectionReason) {
+		r.mu.Lock()
+		r.connects = append(r.connects, reason)
+		r.mu.Unlock()
+	})
+	stream.OnDisconnect(func(reason types.ConnectionReason) {
+		r.mu.Lock()
+		r.disconnect = append(r.disconnect, reason)
+		r.mu.Unlock()
+	})
+	return r
+}
+
+func (r *connectionRecorder) Reasons() (connects, disconnects []types.ConnectionReason) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]types.ConnectionReason(nil), r.connects...), append([]types.ConnectionReason(nil), r.disconnect...)
+}
+
+func newTestStream(standIn *streamStandIn, publicOnly bool) *Stream {
+	client := binance.NewClient("key", "secret")
+	client.BaseURL = standIn.server.URL
+
+	stream := NewStream(client, nil)
+	stream.WsBaseURL = standIn.URL()
+	stream.SetReconnectBackoff(types.Backoff{Initial: 20 * time.Millisecond, Max: time.Second, Multiplier: 2})
+	if publicOnly {
+		stream.SetPublicOnly()
+	}
+
+	return stream
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func equalReasons(a, b []types.ConnectionReason) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestStreamReconnectBackoff(t *testing.T) {
+	standIn := newStreamStandIn(t, func(conn *websocket.Conn, n int) {
+		// the first connection is dropped by the server
+		if n == 1 {
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "restart"))
+		}
+	})
+
+	stream := newTestStream(standIn, true)
+	recorder := recordConnections(stream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the handshakes of the first reconnect attempts are rejected
+	stream.OnDisconnect(func(reason types.ConnectionReason) {
+		if reason == types.ConnectionReasonRemoteClosed {
+			standIn.mu.Lock()
+			standIn.failHandshakes = 2
+			standIn.mu.Unlock()
+		}
+	})
+
+	if err := stream.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer stream.Close()
+
+	waitFor(t, "the reconnected connection", func() bool {
+		connects, _ := recorder.Reasons()
+		return len(connects) == 2
+	})
+
+	// the initial handshake, two rejected attempts and the successful attempt
+	handshakes := standIn.Handshakes()
+	if len(handshakes) != 4 {
+		t.Fatalf("handshakes = %d, expected 4", len(handshakes))
+	}
+
+	// the delay between the attempts grows by the backoff multiplier
+	for i, minimum := range []time.Duration{40 * time.Millisecond, 80 * time.Millisecond} {
+		if gap := handshakes[i+2].Sub(handshakes[i+1]); gap < minimum {
+			t.Errorf("delay before attempt %d = %s, expected at least %s", i+2, gap, minimum)
+		}
+	}
+
+	connects, disconnects := recorder.Reasons()
+	if !equalReasons(connects, []types.ConnectionReason{types.ConnectionReasonInitial, types.ConnectionReasonReconnect}) {
+		t.Errorf("connect reasons = %v", connects)
+	}
+
+	if !equalReasons(disconnects, []types.ConnectionReason{types.ConnectionReasonRemoteClosed}) {
+		t.Errorf("disconnect reasons = %v", disconnects)
+	}
+}
+
+func TestStreamStallWatchdog(t *testing.T) {
+	// the server never sends a message
+	standIn := newStreamStandIn(t, nil)
+
+	stream := newTestStream(standIn, true)
+	stream.SetStallTimeout(100 * time.Millisecond)
+	recorder := recordConnections(stream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := stream.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer stream.Close()
+
+	waitFor(t, "the reconnect of the stalled stream", func() bool {
+		connects, _ := recorder.Reasons()
+		return len(connects) >= 2
+	})
+
+	connects, disconnects := recorder.Reasons()
+	if len(connects) < 2 || connects[0] != types.ConnectionReasonInitial || connects[1] != types.ConnectionReasonReconnect {
+		t.Errorf("connect reasons = %v", connects)
+	}
+
+	if len(disconnects) < 1 || disconnects[0] != types.ConnectionReasonStalled {
+		t.Errorf("disconnect reasons = %v", disconnects)
+	}
+}
+
+func TestStreamListenKeyExpired(t *testing.T) {
+	standIn := newStreamStandIn(t, func(conn *websocket.Conn, n int) {
+		if n == 1 {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"listenKeyExpired","E":1576653824250}`))
+		}
+	})
+
+	stream := newTestStream(standIn, false)
+	recorder := recordConnections(stream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := stream.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "the connection of the new listen key", func() bool {
+		connects, _ := recorder.Reasons()
+		return len(connects) == 2
+	})
+
+	connections := standIn.Connections()
+	if connections[0] != "/ws/listenkey1" || connections[1] != "/ws/listenkey2" {
+		t.Errorf("connections = %v, expected a new listen key for the reconnect", connections)
+	}
+
+	// the expired listen key is invalidated when its keepalive worker stops
+	waitFor(t, "the expired listen key to be closed", func() bool {
+		closed := standIn.ClosedListenKeys()
+		return len(closed) == 1 && closed[0] == "listenkey1"
+	})
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "the disconnect of the closed stream", func() bool {
+		_, disconnects := recorder.Reasons()
+		return len(disconnects) == 2
+	})
+
+	connects, disconnects := recorder.Reasons()
+	if !equalReasons(connects, []types.ConnectionReason{types.ConnectionReasonInitial, types.ConnectionReasonReconnect}) {
+		t.Errorf("connect reasons = %v", connects)
+	}
+
+	expected := []types.ConnectionReason{types.ConnectionReasonListenKeyExpired, types.ConnectionReasonClosed}
+	if !equalReasons(disconnects, expected) {
+		t.Errorf("disconnect reasons = %v, expected %v", disconnects, expected)
+	}
+}
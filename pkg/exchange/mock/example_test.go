@@ -0,0 +1,99 @@
+package mock_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/engine"
+	"github.com/pymba86/bingo/pkg/exchange/mock"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"time"
+)
+
+// buyTheDipStrategy buys at the market when a kline closes below the price
+type buyTheDipStrategy struct {
+	Symbol   string
+	Price    float64
+	Quantity float64
+}
+
+func (s *buyTheDipStrategy) Id() string {
+	return "buyTheDip"
+}
+
+func (s *buyTheDipStrategy) Subscribe(session *engine.ExchangeSession) {
+	session.MarketDataStream.Subscribe(types.KLineChannel, s.Symbol, types.SubscribeOptions{Interval: "1m"})
+}
+
+func (s *buyTheDipStrategy) Run(ctx context.Context, orderExecutor engine.OrderExecutor, session *engine.ExchangeSession) error {
+	session.MarketDataStream.OnKLineClosed(func(kline types.KLine) {
+		if kline.Symbol != s.Symbol || kline.Close >= s.Price {
+			return
+		}
+
+		if _, err := orderExecutor.SubmitOrders(ctx, types.SubmitOrder{
+			Symbol:   s.Symbol,
+			Side:     types.SideTypeBuy,
+			Type:     types.OrderTypeMarket,
+			Quantity: s.Quantity,
+		}); err != nil {
+			fmt.Println("submit error:", err)
+		}
+	})
+
+	return nil
+}
+
+func Example() {
+	ctx := context.Background()
+
+	exchange := mock.New()
+	exchange.AddMarkets(types.Market{
+		Symbol:          "BTCUSDT",
+		PricePrecision:  2,
+		VolumePrecision: 6,
+		BaseCurrency:    "BTC",
+		QuoteCurrency:   "USDT",
+		TickSize:        0.01,
+		StepSize:        0.000001,
+	})
+	exchange.SetBalance("USDT", fixedpoint.NewFromFloat(1000.0))
+
+	harness, err := mock.NewHarness(ctx, exchange)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := harness.Run(ctx, &buyTheDipStrategy{Symbol: "BTCUSDT", Price: 100.0, Quantity: 2.0}); err != nil {
+		panic(err)
+	}
+
+	defer harness.Shutdown(ctx)
+
+	for _, price := range []float64{105.0, 99.0} {
+		startTime := exchange.Now()
+		exchange.PushKLine(types.KLine{
+			Symbol:    "BTCUSDT",
+			Interval:  types.Interval1m,
+			StartTime: startTime,
+			EndTime:   startTime.Add(time.Minute),
+			Open:      price,
+			High:      price,
+			Low:       price,
+			Close:     price,
+			Closed:    true,
+		})
+	}
+
+	trades, _ := exchange.QueryTrades(ctx, "BTCUSDT", nil)
+	for _, trade := range trades {
+		fmt.Printf("%s %.2f @ %.2f\n", trade.Side, trade.Quantity, trade.Price)
+	}
+
+	balances, _ := exchange.QueryAccountBalances(ctx)
+	fmt.Printf("BTC %.2f, USDT %.2f\n", balances["BTC"].Available.Float64(), balances["USDT"].Available.Float64())
+
+	// Output:
+	// BUY 2.00 @ 99.00
+	// BTC 2.00, USDT 802.00
+}
@@ -0,0 +1,582 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ExchangeName is the default name of the mock exchange
+const ExchangeName = types.ExchangeName("mock")
+
+// DefaultStartTime is the initial time of the mock exchange clock
+var DefaultStartTime = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Exchange is an in-memory exchange for the strategy tests. The markets, the balances and the kline history are
+// set up by the test, the market data is pushed step by step, and the orders are matched by a deterministic
+// matching engine. The events are emitted synchronously to the streams of the exchange before the push returns.
+type Exchange struct {
+	types.MarginSettings
+
+	name types.ExchangeName
+
+	// MakerFeeRate and TakerFeeRate are the fee rates of the fills, the fee is charged in the received currency
+	MakerFeeRate float64
+	TakerFeeRate float64
+
+	mu sync.Mutex
+
+	now      time.Time
+	markets  types.MarketMap
+	balances types.BalanceMap
+
+	// klines are the kline history of each symbol and interval, keyed by "<symbol>/<interval>"
+	klines     map[string][]types.KLine
+	books      map[string]*types.SliceOrderBook
+	lastPrices map[string]float64
+
+	orders       map[uint64]*mockOrder
+	closedOrders []types.Order
+	trades       []types.Trade
+
+	streams []*Stream
+
+	lastOrderID uint64
+	lastTradeID int64
+}
+
+func New() *Exchange {
+	return NewWithName(ExchangeName)
+}
+
+// NewWithName creates a mock exchange that reports the given exchange name, the orders and trades are tagged with it
+func NewWithName(name types.ExchangeName) *Exchange {
+	return &Exchange{
+		name:       name,
+		now:        DefaultStartTime,
+		markets:    make(types.MarketMap),
+		balances:   make(types.BalanceMap),
+		klines:     make(map[string][]types.KLine),
+		books:      make(map[string]*types.SliceOrderBook),
+		lastPrices: make(map[string]float64),
+		orders:     make(map[uint64]*mockOrder),
+	}
+}
+
+func (e *Exchange) Name() types.ExchangeName {
+	return e.name
+}
+
+func (e *Exchange) PlatformFeeCurrency() string {
+	return ""
+}
+
+// Now returns the time of the mock exchange clock
+func (e *Exchange) Now() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.now
+}
+
+// SetTime moves the mock exchange clock, the clock is also moved forward by the end time of the pushed klines
+func (e *Exchange) SetTime(t time.Time) {
+	e.mu.Lock()
+	e.now = t
+	e.mu.Unlock()
+}
+
+// AddMarkets defines the markets of the exchange
+func (e *Exchange) AddMarkets(markets ...types.Market) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, market := range markets {
+		e.markets[market.Symbol] = market
+	}
+}
+
+// SetBalance sets the available balance of the currency, the balance update is emitted to the private streams
+func (e *Exchange) SetBalance(currency string, available fixedpoint.Value) {
+	e.mu.Lock()
+	balance := e.balance(currency)
+	balance.Available = available
+	e.balances[currency] = balance
+	event := e.emitBalances(currency)
+	e.mu.Unlock()
+
+	event()
+}
+
+// SetKLines replaces the kline history of the symbol and the interval without emitting them
+func (e *Exchange) SetKLines(symbol string, interval types.Interval, klines []types.KLine) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	history := make([]types.KLine, len(klines))
+	copy(history, klines)
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].StartTime.Before(history[j].StartTime)
+	})
+
+	e.klines[klineKey(symbol, interval)] = history
+	if n := len(history); n > 0 {
+		e.lastPrices[symbol] = history[n-1].Close
+	}
+}
+
+func (e *Exchange) NewStream() types.Stream {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	stream := &Stream{exchange: e}
+	e.streams = append(e.streams, stream)
+	return stream
+}
+
+func (e *Exchange) QueryMarkets(ctx context.Context) (types.MarketMap, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	markets := make(types.MarketMap)
+	for symbol, market := range e.markets {
+		markets[symbol] = market
+	}
+
+	return markets, nil
+}
+
+func (e *Exchange) QueryTicker(ctx context.Context, symbol string) (*types.Ticker, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.ticker(symbol)
+}
+
+func (e *Exchange) QueryTickers(ctx context.Context, symbols ...string) (map[string]types.Ticker, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(symbols) == 0 {
+		for symbol := range e.lastPrices {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	tickers := make(map[string]types.Ticker)
+	for _, symbol := range symbols {
+		ticker, err := e.ticker(symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		tickers[symbol] = *ticker
+	}
+
+	return tickers, nil
+}
+
+func (e *Exchange) ticker(symbol string) (*types.Ticker, error) {
+	if _, ok := e.markets[symbol]; !ok {
+		return nil, fmt.Errorf("market %s is not defined", symbol)
+	}
+
+	ticker := &types.Ticker{Time: e.now, Last: e.lastPrices[symbol]}
+	if book, ok := e.books[symbol]; ok {
+		if bid, ok := book.BestBid(); ok {
+			ticker.Buy = bid.Price.Float64()
+		}
+
+		if ask, ok := book.BestAsk(); ok {
+			ticker.Sell = ask.Price.Float64()
+		}
+	}
+
+	if ticker.Last == 0 && ticker.Buy == 0 && ticker.Sell == 0 {
+		return nil, fmt.Errorf("no market data of %s", symbol)
+	}
+
+	return ticker, nil
+}
+
+// QueryKLines returns the kline history like binance, the klines from the start time,
+// or the latest klines before the end time if the start time is not given
+func (e *Exchange) QueryKLines(ctx context.Context, symbol string, interval types.Interval, options types.KLineQueryOptions) ([]types.KLine, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var klines []types.KLine
+	for _, k := range e.klines[klineKey(symbol, interval)] {
+		if options.StartTime != nil && k.StartTime.Before(*options.StartTime) {
+			continue
+		}
+
+		if options.EndTime != nil && k.StartTime.After(*options.EndTime) {
+			continue
+		}
+
+		klines = append(klines, k)
+	}
+
+	if options.Limit > 0 && len(klines) > options.Limit {
+		if options.StartTime != nil {
+			klines = klines[:options.Limit]
+		} else {
+			klines = klines[len(klines)-options.Limit:]
+		}
+	}
+
+	return klines, nil
+}
+
+func (e *Exchange) QueryAccount(ctx context.Context) (*types.Account, error) {
+	balances, err := e.QueryAccountBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	account := types.NewAccount()
+	account.MakerFeeRate = fixedpoint.NewFromFloat(e.MakerFeeRate)
+	account.TakerFeeRate = fixedpoint.NewFromFloat(e.TakerFeeRate)
+	account.AccountType = "SPOT"
+	if e.IsMargin {
+		account.AccountType = "MARGIN"
+	}
+
+	account.UpdateBalances(balances)
+	return account, nil
+}
+
+func (e *Exchange) QueryAccountBalances(ctx context.Context) (types.BalanceMap, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.balances.Copy(), nil
+}
+
+func (e *Exchange) QueryOpenOrders(ctx context.Context, symbol string) (orders []types.Order, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, o := range e.openOrders(symbol) {
+		orders = append(orders, o.order)
+	}
+
+	return orders, nil
+}
+
+// QueryClosedOrders returns the filled, canceled and rejected orders of the symbol in the order of closing
+func (e *Exchange) QueryClosedOrders(ctx context.Context, symbol string, since, until time.Time, lastOrderID uint64) (orders []types.Order, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, o := range e.closedOrders {
+		if o.Symbol != symbol || o.OrderID <= lastOrderID {
+			continue
+		}
+
+		if o.CreationTime.Time().Before(since) || !until.IsZero() && o.CreationTime.Time().After(until) {
+			continue
+		}
+
+		orders = append(orders, o)
+	}
+
+	return orders, nil
+}
+
+// QueryTrades returns the trades of the symbol in the order of execution
+func (e *Exchange) QueryTrades(ctx context.Context, symbol string, options *types.TradeQueryOptions) (trades []types.Trade, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, t := range e.trades {
+		if t.Symbol != symbol {
+			continue
+		}
+
+		if options != nil {
+			if options.LastTradeID > 0 && t.ID <= options.LastTradeID {
+				continue
+			}
+
+			if options.StartTime != nil && t.Time.Time().Before(*options.StartTime) {
+				continue
+			}
+
+			if options.EndTime != nil && t.Time.Time().After(*options.EndTime) {
+				continue
+			}
+
+			if options.Limit > 0 && int64(len(trades)) >= options.Limit {
+				break
+			}
+		}
+
+		trades = append(trades, t)
+	}
+
+	return trades, nil
+}
+
+func (e *Exchange) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (createdOrders types.OrderSlice, err error) {
+	var submitErr types.SubmitOrdersError
+
+	for _, order := range orders {
+		createdOrder, err := e.submitOrder(order)
+		if err != nil {
+			submitErr.Failures = append(submitErr.Failures, types.SubmitOrderFailure{Order: order, Err: err})
+			continue
+		}
+
+		createdOrders = append(createdOrders, *createdOrder)
+	}
+
+	if len(submitErr.Failures) > 0 {
+		return createdOrders, &submitErr
+	}
+
+	return createdOrders, nil
+}
+
+func (e *Exchange) submitOrder(order types.SubmitOrder) (*types.Order, error) {
+	if err := validateOrder(order); err != nil {
+		return nil, err
+	}
+
+	var events []func()
+
+	e.mu.Lock()
+	market, ok := e.markets[order.Symbol]
+	if !ok {
+		e.mu.Unlock()
+		return nil, fmt.Errorf("market %s is not defined", order.Symbol)
+	}
+
+	o := &mockOrder{market: market, lockPrice: lockPrice(order)}
+
+	// the balance of the whole order is locked like the exchange does, the market buy order is paid at the fill
+	lockCurrency, lockAmount := o.lockedBalance(order.Side, order.Quantity)
+	if lockAmount > 0 {
+		if err := e.lockBalance(lockCurrency, lockAmount); err != nil {
+			e.mu.Unlock()
+			return nil, err
+		}
+	}
+
+	e.lastOrderID++
+	o.order = types.Order{
+		SubmitOrder:  order,
+		Exchange:     e.name,
+		OrderID:      e.lastOrderID,
+		Status:       types.OrderStatusNew,
+		IsWorking:    true,
+		CreationTime: types.Time(e.now),
+		UpdateTime:   types.Time(e.now),
+		IsMargin:     e.IsMargin,
+		IsIsolated:   e.IsIsolatedMargin,
+	}
+	o.order.Market = market
+
+	// the generated client order ids are deterministic
+	switch order.ClientOrderId {
+	case "":
+		o.order.ClientOrderId = fmt.Sprintf("mock-%d", o.order.OrderID)
+	case types.NoClientOrderID:
+		o.order.ClientOrderId = ""
+	}
+
+	e.orders[o.order.OrderID] = o
+	events = append(events, e.emitOrder(o))
+	if lockAmount > 0 {
+		events = append(events, e.emitBalances(lockCurrency))
+	}
+
+	events = append(events, e.matchArrival(o)...)
+	createdOrder := o.order
+	e.mu.Unlock()
+
+	runEvents(events)
+	return &createdOrder, nil
+}
+
+func validateOrder(order types.SubmitOrder) error {
+	if order.Quantity <= 0 {
+		return fmt.Errorf("invalid order quantity %f", order.Quantity)
+	}
+
+	switch order.Type {
+	case types.OrderTypeLimit, types.OrderTypeLimitMaker, types.OrderTypeIOCLimit, types.OrderTypeStopLimit:
+		if order.Price <= 0 {
+			return fmt.Errorf("invalid order price %f", order.Price)
+		}
+
+	case types.OrderTypeMarket, types.OrderTypeStopMarket:
+
+	default:
+		return fmt.Errorf("unsupported order type %s", order.Type)
+	}
+
+	switch order.Type {
+	case types.OrderTypeStopLimit, types.OrderTypeStopMarket:
+		if order.StopPrice <= 0 {
+			return fmt.Errorf("invalid order stop price %f", order.StopPrice)
+		}
+	}
+
+	return nil
+}
+
+func (e *Exchange) CancelOrders(ctx context.Context, orders ...types.Order) error {
+	var cancelErr types.CancelOrdersError
+	var failed bool
+	var events []func()
+
+	e.mu.Lock()
+	for _, order := range orders {
+		o, ok := e.orders[order.OrderID]
+		if !ok {
+			failed = true
+			cancelErr.Results = append(cancelErr.Results, types.CancelOrderResult{Order: order, Err: fmt.Errorf("unknown order %d", order.OrderID)})
+			continue
+		}
+
+		events = append(events, e.closeOrder(o, types.OrderStatusCanceled)...)
+		cancelErr.Results = append(cancelErr.Results, types.CancelOrderResult{Order: o.order})
+	}
+	e.mu.Unlock()
+
+	runEvents(events)
+
+	if failed {
+		return &cancelErr
+	}
+
+	return nil
+}
+
+// openOrders returns the open orders of the symbol in the order of the submission
+func (e *Exchange) openOrders(symbol string) (orders []*mockOrder) {
+	for _, o := range e.orders {
+		if o.order.Symbol == symbol {
+			orders = append(orders, o)
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].order.OrderID < orders[j].order.OrderID
+	})
+
+	return orders
+}
+
+func (e *Exchange) lockBalance(currency string, amount float64) error {
+	balance := e.balance(currency)
+	locked := fixedpoint.NewFromFloat(amount)
+	if balance.Available < locked {
+		return fmt.Errorf("insufficient %s balance: available %f, required %f", currency, balance.Available.Float64(), amount)
+	}
+
+	balance.Available -= locked
+	balance.Locked += locked
+	e.balances[currency] = balance
+	return nil
+}
+
+func (e *Exchange) unlockBalance(currency string, amount float64) {
+	balance := e.balance(currency)
+	unlocked := fixedpoint.NewFromFloat(amount)
+	if unlocked > balance.Locked {
+		unlocked = balance.Locked
+	}
+
+	balance.Locked -= unlocked
+	balance.Available += unlocked
+	e.balances[currency] = balance
+}
+
+func (e *Exchange) balance(currency string) types.Balance {
+	balance, ok := e.balances[currency]
+	if !ok {
+		balance = types.Balance{Currency: currency}
+	}
+
+	return balance
+}
+
+// publicStreams and privateStreams return the open streams that receive the market data and the user data
+func (e *Exchange) publicStreams() []*Stream {
+	var streams []*Stream
+	for _, s := range e.streams {
+		if !s.closed && s.publicOnly {
+			streams = append(streams, s)
+		}
+	}
+
+	return streams
+}
+
+func (e *Exchange) privateStreams() []*Stream {
+	var streams []*Stream
+	for _, s := range e.streams {
+		if !s.closed && !s.publicOnly {
+			streams = append(streams, s)
+		}
+	}
+
+	return streams
+}
+
+// emitOrder returns the event of the current order state, the events are emitted after the lock is released
+func (e *Exchange) emitOrder(o *mockOrder) func() {
+	order := o.order
+	streams := e.privateStreams()
+	return func() {
+		for _, s := range streams {
+			s.EmitOrderUpdate(order)
+		}
+	}
+}
+
+func (e *Exchange) emitTrade(trade types.Trade) func() {
+	streams := e.privateStreams()
+	return func() {
+		for _, s := range streams {
+			s.EmitTradeUpdate(trade)
+		}
+	}
+}
+
+func (e *Exchange) emitBalances(currencies ...string) func() {
+	balances := make(types.BalanceMap)
+	for _, currency := range currencies {
+		if len(currency) > 0 {
+			balances[currency] = e.balance(currency)
+		}
+	}
+
+	streams := e.privateStreams()
+	return func() {
+		if len(balances) == 0 {
+			return
+		}
+
+		for _, s := range streams {
+			s.EmitBalanceUpdate(balances)
+		}
+	}
+}
+
+func runEvents(events []func()) {
+	for _, event := range events {
+		event()
+	}
+}
+
+func klineKey(symbol string, interval types.Interval) string {
+	return symbol + "/" + string(interval)
+}
@@ -0,0 +1,76 @@
+package mock
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/engine"
+)
+
+// SessionName is the name of the mock exchange session in the harness environment
+const SessionName = "mock"
+
+// Harness runs the strategies on a session of the mock exchange, so that a strategy can be tested step by step:
+// set up the markets and the balances of the exchange, create the harness, run the strategy,
+// then push the market data and check the orders, the trades and the balances of the exchange.
+type Harness struct {
+	Exchange    *Exchange
+	Environment *engine.Environment
+	Session     *engine.ExchangeSession
+	Trader      *engine.Trader
+}
+
+// NewHarness creates an environment with a single initialized session on the exchange,
+// the session is a margin session if the margin is enabled on the exchange.
+func NewHarness(ctx context.Context, exchange *Exchange) (*Harness, error) {
+	settings := exchange.GetMarginSettings()
+	session := &engine.ExchangeSession{
+		Margin:               settings.IsMargin,
+		IsolatedMargin:       settings.IsIsolatedMargin,
+		IsolatedMarginSymbol: settings.IsolatedMarginSymbol,
+	}
+
+	if err := engine.InitExchangeSessionWithExchange(SessionName, session, exchange); err != nil {
+		return nil, err
+	}
+
+	environ := engine.NewEnvironment()
+	environ.AddExchangeSession(SessionName, session)
+	if err := environ.Init(ctx); err != nil {
+		return nil, err
+	}
+
+	return &Harness{
+		Exchange:    exchange,
+		Environment: environ,
+		Session:     session,
+		Trader:      engine.NewTrader(environ),
+	}, nil
+}
+
+// Run attaches the strategies on the mock session, runs them and connects the session streams.
+// It should be called once, the strategies receive the market data pushed to the exchange after it returns.
+func (h *Harness) Run(ctx context.Context, strategies ...engine.SingleExchangeStrategy) error {
+	if err := h.Trader.AttachStrategyOn(SessionName, strategies...); err != nil {
+		return err
+	}
+
+	if err := h.Trader.Run(ctx); err != nil {
+		return err
+	}
+
+	if err := h.Session.UserDataStream.Connect(ctx); err != nil {
+		return err
+	}
+
+	return h.Session.MarketDataStream.Connect(ctx)
+}
+
+// Shutdown gracefully stops the strategies and closes the session streams
+func (h *Harness) Shutdown(ctx context.Context) error {
+	h.Trader.Graceful.Shutdown(ctx)
+
+	if err := h.Session.MarketDataStream.Close(); err != nil {
+		return err
+	}
+
+	return h.Session.UserDataStream.Close()
+}
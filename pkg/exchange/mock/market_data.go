@@ -0,0 +1,108 @@
+package mock
+
+import (
+	"github.com/pymba86/bingo/pkg/types"
+)
+
+// The market data is pushed by the test step by step. Each push matches the open orders first,
+// then emits the market data to the public only streams, so the fills of a step are received
+// before the kline or the book that caused them.
+
+// PushKLine updates the kline history and matches the open orders with the price range of the kline.
+// The closed kline moves the clock to its end time and is also emitted as a closed kline.
+func (e *Exchange) PushKLine(kline types.KLine) {
+	e.mu.Lock()
+	if kline.Closed && kline.EndTime.After(e.now) {
+		e.now = kline.EndTime
+	}
+
+	key := klineKey(kline.Symbol, kline.Interval)
+	history := e.klines[key]
+	if n := len(history); n > 0 && history[n-1].StartTime.Equal(kline.StartTime) {
+		history[n-1] = kline
+	} else {
+		history = append(history, kline)
+	}
+	e.klines[key] = history
+
+	e.lastPrices[kline.Symbol] = kline.Close
+	events := e.matchPriceRange(kline.Symbol, kline.Low, kline.High)
+
+	streams := e.publicStreams()
+	e.mu.Unlock()
+
+	runEvents(events)
+
+	for _, s := range streams {
+		s.EmitKLine(kline)
+		if kline.Closed {
+			s.EmitKLineClosed(kline)
+		}
+	}
+}
+
+// PushKLines pushes the klines in order
+func (e *Exchange) PushKLines(klines ...types.KLine) {
+	for _, kline := range klines {
+		e.PushKLine(kline)
+	}
+}
+
+// PushBookSnapshot replaces the order book of the symbol and matches the open orders with it
+func (e *Exchange) PushBookSnapshot(book types.SliceOrderBook) {
+	e.mu.Lock()
+	e.book(book.Symbol).Load(book)
+	events := e.matchBook(book.Symbol)
+	streams := e.publicStreams()
+	e.mu.Unlock()
+
+	runEvents(events)
+
+	for _, s := range streams {
+		s.EmitBookSnapshot(book)
+	}
+}
+
+// PushBookUpdate applies the price levels to the order book of the symbol, the levels of zero volume are removed
+func (e *Exchange) PushBookUpdate(update types.SliceOrderBook) {
+	e.mu.Lock()
+	e.book(update.Symbol).Update(update)
+	events := e.matchBook(update.Symbol)
+	streams := e.publicStreams()
+	e.mu.Unlock()
+
+	runEvents(events)
+
+	for _, s := range streams {
+		s.EmitBookUpdate(update)
+	}
+}
+
+// PushMarketTrade updates the last price and matches the open orders with the trade price
+func (e *Exchange) PushMarketTrade(trade types.Trade) {
+	e.mu.Lock()
+	if trade.Time.Time().After(e.now) {
+		e.now = trade.Time.Time()
+	}
+
+	e.lastPrices[trade.Symbol] = trade.Price
+	events := e.matchPriceRange(trade.Symbol, trade.Price, trade.Price)
+	streams := e.publicStreams()
+	e.mu.Unlock()
+
+	runEvents(events)
+
+	for _, s := range streams {
+		s.EmitMarketTrade(trade)
+	}
+}
+
+func (e *Exchange) book(symbol string) *types.SliceOrderBook {
+	book, ok := e.books[symbol]
+	if !ok {
+		book = types.NewSliceOrderBook(symbol)
+		e.books[symbol] = book
+	}
+
+	return book
+}
@@ -0,0 +1,322 @@
+package mock
+
+import (
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"math"
+)
+
+// the remaining quantity below it is treated as filled
+const quantityEpsilon = 1e-12
+
+type mockOrder struct {
+	order  types.Order
+	market types.Market
+
+	// lockPrice is the price of locking the quote balance of the buy order, zero for the market order
+	lockPrice float64
+
+	// triggered is true once the stop price of the stop order is reached
+	triggered bool
+}
+
+func lockPrice(order types.SubmitOrder) float64 {
+	switch order.Type {
+	case types.OrderTypeStopMarket:
+		return order.StopPrice
+	case types.OrderTypeMarket:
+		return 0
+	}
+
+	return order.Price
+}
+
+// lockedBalance returns the balance locked by the quantity of the order
+func (o *mockOrder) lockedBalance(side types.SideType, quantity float64) (currency string, amount float64) {
+	if side == types.SideTypeSell {
+		return o.market.BaseCurrency, quantity
+	}
+
+	return o.market.QuoteCurrency, quantity * o.lockPrice
+}
+
+func (o *mockOrder) remainingQuantity() float64 {
+	return o.order.Quantity - o.order.ExecutedQuantity
+}
+
+func (o *mockOrder) isStop() bool {
+	return o.order.Type == types.OrderTypeStopLimit || o.order.Type == types.OrderTypeStopMarket
+}
+
+// isMarket checks if the order is filled by any price, the stop market order is a market order once it's triggered
+func (o *mockOrder) isMarket() bool {
+	return o.order.Type == types.OrderTypeMarket || o.order.Type == types.OrderTypeStopMarket
+}
+
+// crosses checks if the limit order is marketable at the price
+func (o *mockOrder) crosses(price float64) bool {
+	if o.order.Side == types.SideTypeBuy {
+		return price <= o.order.Price
+	}
+
+	return price >= o.order.Price
+}
+
+// liquidity returns the opposite side of the book from the best price, the last price with an unlimited size
+// is used when the symbol has no book
+func (e *Exchange) liquidity(o *mockOrder) types.PriceVolumeSlice {
+	if book, ok := e.books[o.order.Symbol]; ok {
+		levels := book.Asks
+		if o.order.Side == types.SideTypeSell {
+			levels = book.Bids
+		}
+
+		if len(levels) > 0 {
+			return levels
+		}
+	}
+
+	if price, ok := e.lastPrices[o.order.Symbol]; ok && price > 0 {
+		return types.PriceVolumeSlice{{Price: fixedpoint.NewFromFloat(price)}}
+	}
+
+	return nil
+}
+
+// matchArrival matches the new order as a taker, the untriggered stop order waits for the last price
+func (e *Exchange) matchArrival(o *mockOrder) []func() {
+	if o.isStop() {
+		price, ok := e.lastPrices[o.order.Symbol]
+		if !ok || !stopReached(o, price, price) {
+			return nil
+		}
+
+		o.triggered = true
+	}
+
+	return e.take(o)
+}
+
+// take fills the order by the book levels as a taker, the book is not consumed by the fills.
+// The remaining quantity of the market order is canceled when the book runs out, and the remaining
+// quantity of the limit order is left on the book, except the IOC order.
+func (e *Exchange) take(o *mockOrder) (events []func()) {
+	levels := e.liquidity(o)
+	if len(levels) == 0 && o.isMarket() {
+		return e.closeOrder(o, types.OrderStatusRejected)
+	}
+
+	if o.order.Type == types.OrderTypeLimitMaker {
+		if len(levels) > 0 && o.crosses(levels[0].Price.Float64()) {
+			return e.closeOrder(o, types.OrderStatusRejected)
+		}
+
+		return nil
+	}
+
+	for _, level := range levels {
+		remaining := o.remainingQuantity()
+		if remaining <= quantityEpsilon {
+			return events
+		}
+
+		price := level.Price.Float64()
+		if !o.isMarket() && !o.crosses(price) {
+			break
+		}
+
+		quantity := remaining
+		if level.Volume > 0 {
+			quantity = math.Min(remaining, level.Volume.Float64())
+		}
+
+		events = append(events, e.fill(o, quantity, price, false)...)
+		if _, ok := e.orders[o.order.OrderID]; !ok {
+			return events
+		}
+	}
+
+	if o.isMarket() || o.order.Type == types.OrderTypeIOCLimit {
+		events = append(events, e.closeOrder(o, types.OrderStatusCanceled)...)
+	}
+
+	return events
+}
+
+// matchPriceRange matches the open orders of the symbol with the traded price range of a kline or a market trade,
+// the resting limit orders that the range goes through are fully filled at their own prices
+func (e *Exchange) matchPriceRange(symbol string, low, high float64) (events []func()) {
+	for _, o := range e.openOrders(symbol) {
+		if o.isStop() && !o.triggered {
+			if !stopReached(o, low, high) {
+				continue
+			}
+
+			o.triggered = true
+			if o.isMarket() {
+				events = append(events, e.fill(o, o.remainingQuantity(), o.order.StopPrice, false)...)
+				continue
+			}
+		}
+
+		if o.order.Side == types.SideTypeBuy && low <= o.order.Price ||
+			o.order.Side == types.SideTypeSell && high >= o.order.Price {
+			events = append(events, e.fill(o, o.remainingQuantity(), o.order.Price, true)...)
+		}
+	}
+
+	return events
+}
+
+// matchBook matches the open orders of the symbol with the book, the triggered stop orders take the book,
+// and the resting limit orders that the book crosses are fully filled at their own prices
+func (e *Exchange) matchBook(symbol string) (events []func()) {
+	book, ok := e.books[symbol]
+	if !ok {
+		return nil
+	}
+
+	bid, hasBid := book.BestBid()
+	ask, hasAsk := book.BestAsk()
+
+	for _, o := range e.openOrders(symbol) {
+		if o.isStop() && !o.triggered {
+			if !hasBid || !hasAsk || !stopReached(o, bid.Price.Float64(), ask.Price.Float64()) {
+				continue
+			}
+
+			o.triggered = true
+			events = append(events, e.take(o)...)
+			continue
+		}
+
+		if o.order.Side == types.SideTypeBuy && hasAsk && o.crosses(ask.Price.Float64()) ||
+			o.order.Side == types.SideTypeSell && hasBid && o.crosses(bid.Price.Float64()) {
+			events = append(events, e.fill(o, o.remainingQuantity(), o.order.Price, true)...)
+		}
+	}
+
+	return events
+}
+
+// stopReached checks the stop price of the stop order, the buy stop is reached by the high price and the sell stop by the low price
+func stopReached(o *mockOrder, low, high float64) bool {
+	if o.order.Side == types.SideTypeBuy {
+		return high > 0 && high >= o.order.StopPrice
+	}
+
+	return low > 0 && low <= o.order.StopPrice
+}
+
+// fill executes the quantity of the order at the price, the fee is charged in the received currency
+func (e *Exchange) fill(o *mockOrder, quantity, price float64, isMaker bool) (events []func()) {
+	if quantity <= 0 || price <= 0 {
+		return nil
+	}
+
+	feeRate := e.TakerFeeRate
+	if isMaker {
+		feeRate = e.MakerFeeRate
+	}
+
+	market := o.market
+	quoteQuantity := quantity * price
+
+	var fee float64
+	var feeCurrency string
+
+	switch o.order.Side {
+	case types.SideTypeBuy:
+		quoteBalance := e.balance(market.QuoteCurrency)
+		released := fixedpoint.NewFromFloat(quantity * o.lockPrice)
+		cost := fixedpoint.NewFromFloat(quoteQuantity)
+		if quoteBalance.Available+released < cost {
+			return e.closeOrder(o, types.OrderStatusRejected)
+		}
+
+		if released > quoteBalance.Locked {
+			released = quoteBalance.Locked
+		}
+
+		quoteBalance.Locked -= released
+		quoteBalance.Available += released - cost
+		e.balances[market.QuoteCurrency] = quoteBalance
+
+		fee, feeCurrency = quantity*feeRate, market.BaseCurrency
+		baseBalance := e.balance(market.BaseCurrency)
+		baseBalance.Available += fixedpoint.NewFromFloat(quantity - fee)
+		e.balances[market.BaseCurrency] = baseBalance
+
+	case types.SideTypeSell:
+		baseBalance := e.balance(market.BaseCurrency)
+		sold := fixedpoint.NewFromFloat(quantity)
+		if sold > baseBalance.Locked {
+			sold = baseBalance.Locked
+		}
+
+		baseBalance.Locked -= sold
+		e.balances[market.BaseCurrency] = baseBalance
+
+		fee, feeCurrency = quoteQuantity*feeRate, market.QuoteCurrency
+		quoteBalance := e.balance(market.QuoteCurrency)
+		quoteBalance.Available += fixedpoint.NewFromFloat(quoteQuantity - fee)
+		e.balances[market.QuoteCurrency] = quoteBalance
+	}
+
+	e.lastTradeID++
+	trade := types.Trade{
+		ID:            e.lastTradeID,
+		OrderID:       o.order.OrderID,
+		Exchange:      o.order.Exchange,
+		Price:         price,
+		Quantity:      quantity,
+		QuoteQuantity: quoteQuantity,
+		Symbol:        o.order.Symbol,
+		Side:          o.order.Side,
+		IsBuyer:       o.order.Side == types.SideTypeBuy,
+		IsMaker:       isMaker,
+		Time:          types.Time(e.now),
+		Fee:           fee,
+		FeeCurrency:   feeCurrency,
+		IsMargin:      o.order.IsMargin,
+		IsIsolated:    o.order.IsIsolated,
+	}
+	e.trades = append(e.trades, trade)
+
+	o.order.ExecutedQuantity += quantity
+	o.order.UpdateTime = types.Time(e.now)
+	if o.remainingQuantity() <= quantityEpsilon {
+		o.order.Status = types.OrderStatusFilled
+		o.order.IsWorking = false
+		e.removeOrder(o)
+	} else {
+		o.order.Status = types.OrderStatusPartiallyFilled
+	}
+
+	return []func(){
+		e.emitTrade(trade),
+		e.emitOrder(o),
+		e.emitBalances(market.BaseCurrency, market.QuoteCurrency),
+	}
+}
+
+// closeOrder closes the order with the status and unlocks the balance of the remaining quantity
+func (e *Exchange) closeOrder(o *mockOrder, status types.OrderStatus) []func() {
+	currency, amount := o.lockedBalance(o.order.Side, o.remainingQuantity())
+	if amount > 0 {
+		e.unlockBalance(currency, amount)
+	}
+
+	o.order.Status = status
+	o.order.IsWorking = false
+	o.order.UpdateTime = types.Time(e.now)
+	e.removeOrder(o)
+
+	return []func(){e.emitOrder(o), e.emitBalances(currency)}
+}
+
+// removeOrder moves the closed order to the order history
+func (e *Exchange) removeOrder(o *mockOrder) {
+	delete(e.orders, o.order.OrderID)
+	e.closedOrders = append(e.closedOrders, o.order)
+}
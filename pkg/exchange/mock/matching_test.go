@@ -0,0 +1,185 @@
+package mock
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"math"
+	"testing"
+	"time"
+)
+
+var testMarket = types.Market{
+	Symbol:          "BTCUSDT",
+	PricePrecision:  2,
+	VolumePrecision: 6,
+	BaseCurrency:    "BTC",
+	QuoteCurrency:   "USDT",
+	TickSize:        0.01,
+	StepSize:        0.000001,
+}
+
+func newTestExchange() *Exchange {
+	e := New()
+	e.AddMarkets(testMarket)
+	e.SetBalance("BTC", fixedpoint.NewFromFloat(1.0))
+	e.SetBalance("USDT", fixedpoint.NewFromFloat(1000.0))
+	return e
+}
+
+func pushTestBook(e *Exchange, bids, asks [][2]float64) {
+	book := types.SliceOrderBook{Symbol: testMarket.Symbol}
+	for _, level := range bids {
+		book.Bids = append(book.Bids, types.PriceVolume{Price: fixedpoint.NewFromFloat(level[0]), Volume: fixedpoint.NewFromFloat(level[1])})
+	}
+
+	for _, level := range asks {
+		book.Asks = append(book.Asks, types.PriceVolume{Price: fixedpoint.NewFromFloat(level[0]), Volume: fixedpoint.NewFromFloat(level[1])})
+	}
+
+	e.PushBookSnapshot(book)
+}
+
+func submitTestOrder(t *testing.T, e *Exchange, order types.SubmitOrder) types.Order {
+	t.Helper()
+
+	order.Symbol = testMarket.Symbol
+	createdOrders, err := e.SubmitOrders(context.Background(), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return createdOrders[0]
+}
+
+func assertBalance(t *testing.T, e *Exchange, currency string, available, locked float64) {
+	t.Helper()
+
+	balance := e.balance(currency)
+	if math.Abs(balance.Available.Float64()-available) > 1e-8 || math.Abs(balance.Locked.Float64()-locked) > 1e-8 {
+		t.Fatalf("expected %s available %f locked %f, got available %f locked %f",
+			currency, available, locked, balance.Available.Float64(), balance.Locked.Float64())
+	}
+}
+
+func TestExchange_CrossingLimitOrder(t *testing.T) {
+	e := newTestExchange()
+	pushTestBook(e, [][2]float64{{99, 1}}, [][2]float64{{100, 2}})
+
+	// the buy limit above the best ask is filled as a taker at the ask price
+	order := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeBuy, Type: types.OrderTypeLimit, Quantity: 1, Price: 101})
+
+	trades, _ := e.QueryTrades(context.Background(), testMarket.Symbol, nil)
+	if len(trades) != 1 || trades[0].Price != 100 || trades[0].IsMaker || trades[0].OrderID != order.OrderID {
+		t.Fatalf("expected a taker fill at 100, got %+v", trades)
+	}
+
+	if len(e.orders) != 0 {
+		t.Fatal("expected the order to be filled")
+	}
+
+	assertBalance(t, e, "USDT", 900, 0)
+	assertBalance(t, e, "BTC", 2, 0)
+}
+
+func TestExchange_PartialFillAndCancel(t *testing.T) {
+	e := newTestExchange()
+	pushTestBook(e, [][2]float64{{99, 1}}, [][2]float64{{100, 0.4}, {102, 1}})
+
+	// the buy limit at 100 takes the 0.4 of the ask level and rests the remaining quantity
+	order := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeBuy, Type: types.OrderTypeLimit, Quantity: 1, Price: 100})
+
+	openOrders, _ := e.QueryOpenOrders(context.Background(), testMarket.Symbol)
+	if len(openOrders) != 1 || openOrders[0].Status != types.OrderStatusPartiallyFilled || openOrders[0].ExecutedQuantity != 0.4 {
+		t.Fatalf("expected a partially filled order, got %+v", openOrders)
+	}
+
+	assertBalance(t, e, "USDT", 900, 60)
+	assertBalance(t, e, "BTC", 1.4, 0)
+
+	// the cancel releases the balance locked by the remaining quantity
+	if err := e.CancelOrders(context.Background(), order); err != nil {
+		t.Fatal(err)
+	}
+
+	assertBalance(t, e, "USDT", 960, 0)
+
+	closedOrders, _ := e.QueryClosedOrders(context.Background(), testMarket.Symbol, time.Time{}, time.Time{}, 0)
+	if len(closedOrders) != 1 || closedOrders[0].Status != types.OrderStatusCanceled {
+		t.Fatalf("expected a canceled order, got %+v", closedOrders)
+	}
+
+	// the closed order can not be canceled again
+	if err := e.CancelOrders(context.Background(), order); err == nil {
+		t.Fatal("expected the cancel of the closed order to fail")
+	}
+}
+
+func TestExchange_RestingLimitOrderFilledByKLine(t *testing.T) {
+	e := newTestExchange()
+
+	order := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeSell, Type: types.OrderTypeLimit, Quantity: 1, Price: 110})
+	assertBalance(t, e, "BTC", 0, 1)
+
+	pushTestKLine(e, 100, 105)
+	if len(e.orders) != 1 {
+		t.Fatal("expected the order to rest below the high price")
+	}
+
+	pushTestKLine(e, 104, 111)
+
+	trades, _ := e.QueryTrades(context.Background(), testMarket.Symbol, nil)
+	if len(trades) != 1 || trades[0].Price != 110 || !trades[0].IsMaker || trades[0].OrderID != order.OrderID {
+		t.Fatalf("expected a maker fill at 110, got %+v", trades)
+	}
+
+	assertBalance(t, e, "BTC", 0, 0)
+	assertBalance(t, e, "USDT", 1110, 0)
+}
+
+func TestExchange_StopOrders(t *testing.T) {
+	e := newTestExchange()
+	pushTestKLine(e, 99, 101)
+
+	stopMarket := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeSell, Type: types.OrderTypeStopMarket, Quantity: 0.5, StopPrice: 95})
+	stopLimit := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeSell, Type: types.OrderTypeStopLimit, Quantity: 0.5, StopPrice: 90, Price: 89})
+
+	// the stops are not reached
+	pushTestKLine(e, 96, 100)
+	if len(e.orders) != 2 {
+		t.Fatalf("expected the stop orders to wait, got %d open orders", len(e.orders))
+	}
+
+	// the stop market order is filled at the stop price, the stop limit order rests at its limit price
+	pushTestKLine(e, 94, 97)
+
+	trades, _ := e.QueryTrades(context.Background(), testMarket.Symbol, nil)
+	if len(trades) != 1 || trades[0].OrderID != stopMarket.OrderID || trades[0].Price != 95 {
+		t.Fatalf("expected the stop market order to be filled at 95, got %+v", trades)
+	}
+
+	pushTestKLine(e, 88, 93)
+
+	trades, _ = e.QueryTrades(context.Background(), testMarket.Symbol, nil)
+	if len(trades) != 2 || trades[1].OrderID != stopLimit.OrderID || trades[1].Price != 89 {
+		t.Fatalf("expected the stop limit order to be filled at 89, got %+v", trades)
+	}
+
+	assertBalance(t, e, "BTC", 0, 0)
+	assertBalance(t, e, "USDT", 1000+47.5+44.5, 0)
+}
+
+func pushTestKLine(e *Exchange, low, high float64) {
+	startTime := e.Now()
+	e.PushKLine(types.KLine{
+		Symbol:    testMarket.Symbol,
+		Interval:  types.Interval1m,
+		StartTime: startTime,
+		EndTime:   startTime.Add(time.Minute),
+		Open:      low,
+		High:      high,
+		Low:       low,
+		Close:     high,
+		Closed:    true,
+	})
+}
@@ -0,0 +1,64 @@
+package mock
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/types"
+)
+
+// Stream receives the events of the mock exchange synchronously. The public only stream receives
+// the market data pushed by the test, the other streams receive the order, trade and balance updates
+// of the matching engine like a user data stream.
+type Stream struct {
+	types.StandardStream
+
+	// LiveSubscriptions are the subscriptions added after the stream is connected
+	LiveSubscriptions []types.Subscription
+
+	exchange   *Exchange
+	publicOnly bool
+	closed     bool
+}
+
+func (s *Stream) SetPublicOnly() {
+	s.publicOnly = true
+}
+
+// Connect emits the start of the stream, the private stream also emits the balance snapshot
+func (s *Stream) Connect(ctx context.Context) error {
+	s.exchange.mu.Lock()
+	s.closed = false
+	s.exchange.mu.Unlock()
+
+	s.EmitConnect(types.ConnectionReasonInitial)
+	s.EmitStart()
+
+	if s.publicOnly {
+		return nil
+	}
+
+	balances, err := s.exchange.QueryAccountBalances(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.EmitBalanceSnapshot(balances)
+	return nil
+}
+
+// SubscribeLive records the subscriptions added on the connected stream,
+// the public only stream emits all the market data pushed by the test anyway
+func (s *Stream) SubscribeLive(subscriptions ...types.Subscription) error {
+	s.exchange.mu.Lock()
+	s.LiveSubscriptions = append(s.LiveSubscriptions, subscriptions...)
+	s.exchange.mu.Unlock()
+	return nil
+}
+
+func (s *Stream) Close() error {
+	s.exchange.mu.Lock()
+	s.closed = true
+	s.exchange.mu.Unlock()
+
+	s.EmitDisconnect(types.ConnectionReasonClosed)
+	return nil
+}
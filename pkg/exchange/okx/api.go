@@ -0,0 +1,184 @@
+package okx
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+)
+
+// the okx rest api responds the numbers and the timestamps as strings
+
+type Instrument struct {
+	InstrumentType string           `json:"instType"`
+	InstrumentID   string           `json:"instId"`
+	BaseCurrency   string           `json:"baseCcy"`
+	QuoteCurrency  string           `json:"quoteCcy"`
+	TickSize       fixedpoint.Value `json:"tickSz"`
+	LotSize        fixedpoint.Value `json:"lotSz"`
+	MinSize        fixedpoint.Value `json:"minSz"`
+	MaxLimitSize   fixedpoint.Value `json:"maxLmtSz"`
+	MaxMarketSize  fixedpoint.Value `json:"maxMktSz"`
+	State          string           `json:"state"`
+}
+
+type Ticker struct {
+	InstrumentType string                     `json:"instType"`
+	InstrumentID   string                     `json:"instId"`
+	Last           fixedpoint.Value           `json:"last"`
+	LastSize       fixedpoint.Value           `json:"lastSz"`
+	AskPrice       fixedpoint.Value           `json:"askPx"`
+	AskSize        fixedpoint.Value           `json:"askSz"`
+	BidPrice       fixedpoint.Value           `json:"bidPx"`
+	BidSize        fixedpoint.Value           `json:"bidSz"`
+	Open24H        fixedpoint.Value           `json:"open24h"`
+	High24H        fixedpoint.Value           `json:"high24h"`
+	Low24H         fixedpoint.Value           `json:"low24h"`
+	Volume24H      fixedpoint.Value           `json:"vol24h"`
+	Timestamp      types.MillisecondTimestamp `json:"ts"`
+}
+
+// Candle is the candle array [ts, o, h, l, c, vol, volCcy, volCcyQuote, confirm],
+// the volume of the spot candle is in the base currency
+type Candle struct {
+	StartTime   types.MillisecondTimestamp
+	Open        fixedpoint.Value
+	High        fixedpoint.Value
+	Low         fixedpoint.Value
+	Close       fixedpoint.Value
+	Volume      fixedpoint.Value
+	QuoteVolume fixedpoint.Value
+	Confirmed   bool
+}
+
+func (c *Candle) UnmarshalJSON(data []byte) error {
+	var fields []string
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	if len(fields) < 9 {
+		return fmt.Errorf("unexpected okx candle: %s", data)
+	}
+
+	if err := c.StartTime.UnmarshalJSON([]byte(`"` + fields[0] + `"`)); err != nil {
+		return err
+	}
+
+	values := []*fixedpoint.Value{&c.Open, &c.High, &c.Low, &c.Close, &c.Volume, nil, &c.QuoteVolume}
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+
+		var err error
+		if *v, err = fixedpoint.NewFromString(fields[i+1]); err != nil {
+			return err
+		}
+	}
+
+	c.Confirmed = fields[8] == "1"
+	return nil
+}
+
+type BalanceDetail struct {
+	Currency     string                     `json:"ccy"`
+	Available    fixedpoint.Value           `json:"availBal"`
+	Frozen       fixedpoint.Value           `json:"frozenBal"`
+	CashBalance  fixedpoint.Value           `json:"cashBal"`
+	Equity       fixedpoint.Value           `json:"eq"`
+	UpdateTime   types.MillisecondTimestamp `json:"uTime"`
+	Liabilities  fixedpoint.Value           `json:"liab"`
+	InterestOwed fixedpoint.Value           `json:"interest"`
+}
+
+type AccountBalance struct {
+	TotalEquity fixedpoint.Value           `json:"totalEq"`
+	UpdateTime  types.MillisecondTimestamp `json:"uTime"`
+	Details     []BalanceDetail            `json:"details"`
+}
+
+// TradeFee is the fee rate of the account, the negative rate is charged and the positive rate is the rebate
+type TradeFee struct {
+	Maker fixedpoint.Value `json:"maker"`
+	Taker fixedpoint.Value `json:"taker"`
+}
+
+// OrderDetail is the order of the rest api and the orders channel, the fill fields are only set by the channel
+type OrderDetail struct {
+	InstrumentType      string                     `json:"instType"`
+	InstrumentID        string                     `json:"instId"`
+	OrderID             string                     `json:"ordId"`
+	ClientOrderID       string                     `json:"clOrdId"`
+	Price               fixedpoint.Value           `json:"px"`
+	Size                fixedpoint.Value           `json:"sz"`
+	OrderType           string                     `json:"ordType"`
+	Side                string                     `json:"side"`
+	TargetCurrency      string                     `json:"tgtCcy"`
+	State               string                     `json:"state"`
+	AccumulatedFillSize fixedpoint.Value           `json:"accFillSz"`
+	AveragePrice        fixedpoint.Value           `json:"avgPx"`
+	CreationTime        types.MillisecondTimestamp `json:"cTime"`
+	UpdateTime          types.MillisecondTimestamp `json:"uTime"`
+
+	// the last fill of the order update
+	TradeID     string                     `json:"tradeId"`
+	FillPrice   fixedpoint.Value           `json:"fillPx"`
+	FillSize    fixedpoint.Value           `json:"fillSz"`
+	FillTime    types.MillisecondTimestamp `json:"fillTime"`
+	FillFee     fixedpoint.Value           `json:"fillFee"`
+	FillFeeCcy  string                     `json:"fillFeeCcy"`
+	ExecuteType string                     `json:"execType"`
+}
+
+// Fill is the trade of the fills history
+type Fill struct {
+	InstrumentType string                     `json:"instType"`
+	InstrumentID   string                     `json:"instId"`
+	TradeID        string                     `json:"tradeId"`
+	OrderID        string                     `json:"ordId"`
+	ClientOrderID  string                     `json:"clOrdId"`
+	BillID         string                     `json:"billId"`
+	FillPrice      fixedpoint.Value           `json:"fillPx"`
+	FillSize       fixedpoint.Value           `json:"fillSz"`
+	Side           string                     `json:"side"`
+	ExecuteType    string                     `json:"execType"`
+	Fee            fixedpoint.Value           `json:"fee"`
+	FeeCurrency    string                     `json:"feeCcy"`
+	Timestamp      types.MillisecondTimestamp `json:"ts"`
+}
+
+// OrderResult is the result of an order in the batch order requests, the sCode is "0" when the order is accepted
+type OrderResult struct {
+	OrderID       string `json:"ordId"`
+	ClientOrderID string `json:"clOrdId"`
+	Code          string `json:"sCode"`
+	Message       string `json:"sMsg"`
+}
+
+func (r OrderResult) Err() error {
+	if r.Code == "0" || r.Code == "" {
+		return nil
+	}
+
+	return &APIError{Code: r.Code, Message: r.Message}
+}
+
+// PlaceOrderRequest is an order of the batch-orders request
+type PlaceOrderRequest struct {
+	InstrumentID   string `json:"instId"`
+	TradeMode      string `json:"tdMode"`
+	ClientOrderID  string `json:"clOrdId,omitempty"`
+	Side           string `json:"side"`
+	OrderType      string `json:"ordType"`
+	Size           string `json:"sz"`
+	Price          string `json:"px,omitempty"`
+	TargetCurrency string `json:"tgtCcy,omitempty"`
+}
+
+// CancelOrderRequest is an order of the cancel-batch-orders request, the order is identified by ordId or clOrdId
+type CancelOrderRequest struct {
+	InstrumentID  string `json:"instId"`
+	OrderID       string `json:"ordId,omitempty"`
+	ClientOrderID string `json:"clOrdId,omitempty"`
+}
@@ -0,0 +1,146 @@
+package okx
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const defaultHTTPTimeout = 15 * time.Second
+
+// APIError is the error code and message of the okx response envelope
+type APIError struct {
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("okx api error %s: %s", e.Code, e.Message)
+}
+
+// responseEnvelope is the common response format of the okx rest api,
+// the code is "0" when the request succeeds
+type responseEnvelope struct {
+	Code    string          `json:"code"`
+	Message string          `json:"msg"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// RestClient sends the signed requests of the okx v5 rest api
+type RestClient struct {
+	BaseURL    string
+	HTTPClient *http.Client
+
+	Key, Secret, Passphrase string
+
+	// Simulated sends the requests to the demo trading environment
+	Simulated bool
+}
+
+func NewRestClient(key, secret, passphrase string) *RestClient {
+	return &RestClient{
+		BaseURL:    restBaseURL,
+		HTTPClient: &http.Client{Timeout: defaultHTTPTimeout},
+		Key:        key,
+		Secret:     secret,
+		Passphrase: passphrase,
+	}
+}
+
+// sign returns the base64 encoded HMAC SHA256 signature of the prehash string timestamp + method + requestPath + body
+func sign(secret, timestamp, method, requestPath, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + method + requestPath + body))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *RestClient) publicRequest(ctx context.Context, path string, params url.Values, data interface{}) error {
+	return c.request(ctx, http.MethodGet, path, params, nil, false, data)
+}
+
+func (c *RestClient) privateRequest(ctx context.Context, method, path string, params url.Values, payload interface{}, data interface{}) error {
+	return c.request(ctx, method, path, params, payload, true, data)
+}
+
+// request sends the request and decodes the data of the response envelope into data.
+// The data is decoded even if the envelope code is not "0", since the batch order endpoints
+// report the result of each order in the data.
+func (c *RestClient) request(ctx context.Context, method, path string, params url.Values, payload interface{}, signed bool, data interface{}) error {
+	requestPath := path
+	if len(params) > 0 {
+		requestPath += "?" + params.Encode()
+	}
+
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+requestPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.Simulated {
+		req.Header.Set("x-simulated-trading", "1")
+	}
+
+	if signed {
+		if len(c.Key) == 0 || len(c.Secret) == 0 || len(c.Passphrase) == 0 {
+			return errors.New("okx private api requires the api key, secret and passphrase")
+		}
+
+		timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+		req.Header.Set("OK-ACCESS-KEY", c.Key)
+		req.Header.Set("OK-ACCESS-SIGN", sign(c.Secret, timestamp, method, requestPath, string(body)))
+		req.Header.Set("OK-ACCESS-TIMESTAMP", timestamp)
+		req.Header.Set("OK-ACCESS-PASSPHRASE", c.Passphrase)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var envelope responseEnvelope
+	if err := json.Unmarshal(content, &envelope); err != nil {
+		return errors.Wrapf(err, "unexpected okx response, status %d: %s", resp.StatusCode, content)
+	}
+
+	if data != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, data); err != nil {
+			return errors.Wrapf(err, "can not decode the okx response of %s", path)
+		}
+	}
+
+	if envelope.Code != "0" {
+		return &APIError{Code: envelope.Code, Message: envelope.Message}
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package okx
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	content, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return content
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *RestClient {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewRestClient("key", "secret", "passphrase")
+	client.BaseURL = server.URL
+	return client
+}
+
+func TestRestClientSignsPrivateRequests(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		timestamp := r.Header.Get("OK-ACCESS-TIMESTAMP")
+		if len(timestamp) == 0 {
+			t.Error("missing OK-ACCESS-TIMESTAMP")
+		}
+
+		if got := r.Header.Get("OK-ACCESS-KEY"); got != "key" {
+			t.Errorf("OK-ACCESS-KEY = %q", got)
+		}
+
+		if got := r.Header.Get("OK-ACCESS-PASSPHRASE"); got != "passphrase" {
+			t.Errorf("OK-ACCESS-PASSPHRASE = %q", got)
+		}
+
+		expected := sign("secret", timestamp, r.Method, r.URL.RequestURI(), string(body))
+		if got := r.Header.Get("OK-ACCESS-SIGN"); got != expected {
+			t.Errorf("OK-ACCESS-SIGN = %q, expected %q", got, expected)
+		}
+
+		if got := r.Header.Get("x-simulated-trading"); got != "1" {
+			t.Errorf("x-simulated-trading = %q", got)
+		}
+
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[{"ordId":"1","clOrdId":"","sCode":"0","sMsg":""}]}`))
+	})
+	client.Simulated = true
+
+	var results []OrderResult
+	params := url.Values{"instId": []string{"BTC-USDT"}}
+	payload := []CancelOrderRequest{{InstrumentID: "BTC-USDT", OrderID: "1"}}
+	if err := client.privateRequest(context.Background(), http.MethodPost, "/api/v5/trade/cancel-batch-orders", params, payload, &results); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].OrderID != "1" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestRestClientPublicRequestIsNotSigned(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if len(r.Header.Get("OK-ACCESS-SIGN")) > 0 || len(r.Header.Get("OK-ACCESS-PASSPHRASE")) > 0 {
+			t.Error("the public request is signed")
+		}
+
+		_, _ = w.Write([]byte(`{"code":"0","msg":"","data":[]}`))
+	})
+
+	if err := client.publicRequest(context.Background(), "/api/v5/market/tickers", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestClientRequiresPassphrase(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request without the passphrase is sent")
+	})
+	client.Passphrase = ""
+
+	if err := client.privateRequest(context.Background(), http.MethodGet, "/api/v5/account/balance", nil, nil, nil); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestRestClientEnvelopeErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		response  string
+		code      string
+		transient bool
+	}{
+		{name: "rate limited", response: `{"code":"50011","msg":"Too Many Requests","data":[]}`, code: "50011", transient: true},
+		{name: "timestamp expired", response: `{"code":"50102","msg":"Timestamp request expired","data":[]}`, code: "50102", transient: true},
+		{name: "invalid sign", response: `{"code":"50113","msg":"Invalid Sign","data":[]}`, code: "50113"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(test.response))
+			})
+
+			err := client.privateRequest(context.Background(), http.MethodGet, "/api/v5/account/balance", nil, nil, nil)
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Code != test.code {
+				t.Fatalf("expected api error %s, got %v", test.code, err)
+			}
+
+			if got := (&Exchange{}).IsTransientError(err); got != test.transient {
+				t.Errorf("IsTransientError = %v, expected %v", got, test.transient)
+			}
+		})
+	}
+}
+
+func TestRestClientUnexpectedResponse(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte(`<html>bad gateway</html>`))
+	})
+
+	if err := client.publicRequest(context.Background(), "/api/v5/market/tickers", nil, nil); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSubmitOrdersBatchResults(t *testing.T) {
+	exchange := New("key", "secret", "passphrase")
+	exchange.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v5/trade/batch-orders" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		_, _ = w.Write(readFixture(t, "batch_orders_partial.json"))
+	})
+
+	orders := []types.SubmitOrder{
+		{ClientOrderId: "a1", Symbol: "BTCUSDT", Side: types.SideTypeBuy, Type: types.OrderTypeLimit, Quantity: 0.01, Price: 30000},
+		{ClientOrderId: "a2", Symbol: "BTCUSDT", Side: types.SideTypeBuy, Type: types.OrderTypeLimit, Quantity: 100, Price: 30000},
+	}
+
+	createdOrders, err := exchange.SubmitOrders(context.Background(), orders...)
+	if len(createdOrders) != 1 || createdOrders[0].OrderID != 12345689 || createdOrders[0].ClientOrderId != "a1" {
+		t.Fatalf("unexpected created orders %+v", createdOrders)
+	}
+
+	var submitErr *types.SubmitOrdersError
+	if !errors.As(err, &submitErr) {
+		t.Fatalf("expected SubmitOrdersError, got %v", err)
+	}
+
+	if len(submitErr.Failures) != 1 || submitErr.Failures[0].Order.ClientOrderId != "a2" {
+		t.Fatalf("unexpected failures %+v", submitErr.Failures)
+	}
+
+	var apiErr *APIError
+	if !errors.As(submitErr.Failures[0].Err, &apiErr) || apiErr.Code != "51008" {
+		t.Errorf("unexpected failure error %v", submitErr.Failures[0].Err)
+	}
+}
+
+func TestSubmitOrdersRequestError(t *testing.T) {
+	exchange := New("key", "secret", "passphrase")
+	exchange.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":"50011","msg":"Too Many Requests","data":[]}`))
+	})
+
+	createdOrders, err := exchange.SubmitOrders(context.Background(),
+		types.SubmitOrder{Symbol: "BTCUSDT", Side: types.SideTypeSell, Type: types.OrderTypeMarket, Quantity: 0.01})
+	if len(createdOrders) != 0 {
+		t.Fatalf("unexpected created orders %+v", createdOrders)
+	}
+
+	var submitErr *types.SubmitOrdersError
+	if !errors.As(err, &submitErr) || len(submitErr.Failures) != 1 {
+		t.Fatalf("expected a failed order, got %v", err)
+	}
+}
@@ -0,0 +1,352 @@
+package okx
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// instrumentIDs maps the global symbols to the okx instrument ids, e.g. BTCUSDT to BTC-USDT,
+// it's filled by QueryMarkets
+var instrumentIDs sync.Map
+
+// quoteCurrencies are used for splitting the symbols that are not queried from the markets
+var quoteCurrencies = []string{"USDT", "USDC", "DAI", "BTC", "ETH", "OKB", "EUR", "USD"}
+
+func registerInstrumentID(symbol, instrumentID string) {
+	instrumentIDs.Store(symbol, instrumentID)
+}
+
+// toLocalSymbol converts the global symbol to the okx instrument id
+func toLocalSymbol(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	if instrumentID, ok := instrumentIDs.Load(symbol); ok {
+		return instrumentID.(string)
+	}
+
+	if strings.Contains(symbol, "-") {
+		return symbol
+	}
+
+	for _, quote := range quoteCurrencies {
+		if strings.HasSuffix(symbol, quote) && len(symbol) > len(quote) {
+			return symbol[:len(symbol)-len(quote)] + "-" + quote
+		}
+	}
+
+	return symbol
+}
+
+// toGlobalSymbol converts the okx instrument id to the global symbol
+func toGlobalSymbol(instrumentID string) string {
+	return strings.ReplaceAll(instrumentID, "-", "")
+}
+
+// precision returns the number of the decimal places of the tick size or the lot size
+func precision(size fixedpoint.Value) int {
+	f := size.Float64()
+	if f <= 0 {
+		return 0
+	}
+
+	return int(math.Max(0, math.Round(-math.Log10(f))))
+}
+
+func toGlobalMarket(instrument Instrument) types.Market {
+	return types.Market{
+		Symbol:          toGlobalSymbol(instrument.InstrumentID),
+		LocalSymbol:     instrument.InstrumentID,
+		PricePrecision:  precision(instrument.TickSize),
+		VolumePrecision: precision(instrument.LotSize),
+		QuoteCurrency:   instrument.QuoteCurrency,
+		BaseCurrency:    instrument.BaseCurrency,
+		MinQuantity:     instrument.MinSize.Float64(),
+		MaxQuantity:     instrument.MaxLimitSize.Float64(),
+		StepSize:        instrument.LotSize.Float64(),
+		MinPrice:        instrument.TickSize.Float64(),
+		TickSize:        instrument.TickSize.Float64(),
+	}
+}
+
+func toGlobalTicker(ticker Ticker) types.Ticker {
+	return types.Ticker{
+		Time:   ticker.Timestamp.Time(),
+		Volume: ticker.Volume24H.Float64(),
+		Last:   ticker.Last.Float64(),
+		Open:   ticker.Open24H.Float64(),
+		High:   ticker.High24H.Float64(),
+		Low:    ticker.Low24H.Float64(),
+		Buy:    ticker.BidPrice.Float64(),
+		Sell:   ticker.AskPrice.Float64(),
+	}
+}
+
+// toLocalInterval converts the interval to the okx bar, the intervals from 6h use the utc bars
+// so that the klines are aligned with the other exchanges
+func toLocalInterval(interval types.Interval) (string, error) {
+	switch interval {
+	case types.Interval1m, types.Interval5m, types.Interval15m, types.Interval30m:
+		return string(interval), nil
+
+	case types.Interval1h, types.Interval2h, types.Interval4h:
+		return strings.ToUpper(string(interval)), nil
+
+	case types.Interval6h, types.Interval12h, types.Interval1d, types.Interval3d:
+		return strings.ToUpper(string(interval)) + "utc", nil
+	}
+
+	return "", fmt.Errorf("unsupported okx interval: %s", interval)
+}
+
+func toGlobalKLine(symbol string, interval types.Interval, candle Candle) types.KLine {
+	startTime := candle.StartTime.Time()
+	return types.KLine{
+		Exchange:    types.ExchangeOKX,
+		Symbol:      symbol,
+		Interval:    interval,
+		StartTime:   startTime,
+		EndTime:     startTime.Add(interval.Duration() - time.Millisecond),
+		Open:        candle.Open.Float64(),
+		Close:       candle.Close.Float64(),
+		High:        candle.High.Float64(),
+		Low:         candle.Low.Float64(),
+		Volume:      candle.Volume.Float64(),
+		QuoteVolume: candle.QuoteVolume.Float64(),
+		Closed:      candle.Confirmed,
+	}
+}
+
+func toGlobalBalances(details []BalanceDetail) types.BalanceMap {
+	balances := types.BalanceMap{}
+	for _, detail := range details {
+		balances[detail.Currency] = types.Balance{
+			Currency:  detail.Currency,
+			Available: detail.Available,
+			Locked:    detail.Frozen,
+		}
+	}
+
+	return balances
+}
+
+func toLocalSideType(side types.SideType) string {
+	return strings.ToLower(string(side))
+}
+
+// toLocalOrderType returns the okx order type of the order, the market orders are sized in the base currency
+func toLocalOrderType(order types.SubmitOrder) (string, error) {
+	switch order.Type {
+	case types.OrderTypeLimit:
+		switch strings.ToUpper(order.TimeInForce) {
+		case "IOC":
+			return "ioc", nil
+
+		case "FOK":
+			return "fok", nil
+		}
+
+		return "limit", nil
+
+	case types.OrderTypeLimitMaker:
+		return "post_only", nil
+
+	case types.OrderTypeMarket:
+		return "market", nil
+
+	case types.OrderTypeIOCLimit:
+		return "ioc", nil
+	}
+
+	return "", fmt.Errorf("unsupported okx order type: %s", order.Type)
+}
+
+func toGlobalOrderType(orderType string) (types.OrderType, string) {
+	switch orderType {
+	case "market":
+		return types.OrderTypeMarket, ""
+
+	case "post_only":
+		return types.OrderTypeLimitMaker, ""
+
+	case "ioc":
+		return types.OrderTypeIOCLimit, "IOC"
+
+	case "fok":
+		return types.OrderTypeLimit, "FOK"
+	}
+
+	return types.OrderTypeLimit, "GTC"
+}
+
+func toGlobalOrderStatus(state string) types.OrderStatus {
+	switch state {
+	case "live":
+		return types.OrderStatusNew
+
+	case "partially_filled":
+		return types.OrderStatusPartiallyFilled
+
+	case "filled":
+		return types.OrderStatusFilled
+
+	case "canceled", "mmp_canceled":
+		return types.OrderStatusCanceled
+	}
+
+	return types.OrderStatus(strings.ToUpper(state))
+}
+
+// clientOrderIDMaxLength is the max length of the okx client order id, it only allows the alphanumerics
+const clientOrderIDMaxLength = 32
+
+// newClientOrderID removes the characters that okx does not accept from the custom client order id,
+// the client order ids of the strategy order executors are alphanumeric and kept as they are.
+func newClientOrderID(originalID string) string {
+	if originalID == types.NoClientOrderID {
+		return ""
+	}
+
+	clientOrderID := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+
+		return -1
+	}, originalID)
+
+	if len(clientOrderID) == 0 {
+		clientOrderID = strings.ReplaceAll(uuid.New().String(), "-", "")
+	}
+
+	if len(clientOrderID) > clientOrderIDMaxLength {
+		clientOrderID = clientOrderID[:clientOrderIDMaxLength]
+	}
+
+	return clientOrderID
+}
+
+func parseID(id string) (uint64, error) {
+	if len(id) == 0 {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(id, 10, 64)
+}
+
+func toGlobalOrder(detail OrderDetail) (*types.Order, error) {
+	orderID, err := parseID(detail.OrderID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid okx order id %s", detail.OrderID)
+	}
+
+	side, err := types.StrToSideType(detail.Side)
+	if err != nil {
+		return nil, err
+	}
+
+	orderType, timeInForce := toGlobalOrderType(detail.OrderType)
+	status := toGlobalOrderStatus(detail.State)
+
+	price := detail.Price
+	if orderType == types.OrderTypeMarket {
+		price = detail.AveragePrice
+	}
+
+	return &types.Order{
+		SubmitOrder: types.SubmitOrder{
+			ClientOrderId: detail.ClientOrderID,
+			Symbol:        toGlobalSymbol(detail.InstrumentID),
+			Side:          side,
+			Type:          orderType,
+			Quantity:      detail.Size.Float64(),
+			Price:         price.Float64(),
+			TimeInForce:   timeInForce,
+		},
+		Exchange:         types.ExchangeOKX,
+		OrderID:          orderID,
+		Status:           status,
+		ExecutedQuantity: detail.AccumulatedFillSize.Float64(),
+		IsWorking:        status == types.OrderStatusNew || status == types.OrderStatusPartiallyFilled,
+		CreationTime:     types.Time(detail.CreationTime.Time()),
+		UpdateTime:       types.Time(detail.UpdateTime.Time()),
+	}, nil
+}
+
+func toGlobalOrders(details []OrderDetail) (orders []types.Order, err error) {
+	for _, detail := range details {
+		order, err := toGlobalOrder(detail)
+		if err != nil {
+			return orders, err
+		}
+
+		orders = append(orders, *order)
+	}
+
+	return orders, nil
+}
+
+// toGlobalTrade converts the fill, okx reports the charged fee as a negative number
+func toGlobalTrade(fill Fill) (*types.Trade, error) {
+	tradeID, err := strconv.ParseInt(fill.TradeID, 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid okx trade id %s", fill.TradeID)
+	}
+
+	orderID, err := parseID(fill.OrderID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid okx order id %s", fill.OrderID)
+	}
+
+	side, err := types.StrToSideType(fill.Side)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Trade{
+		ID:            tradeID,
+		OrderID:       orderID,
+		Exchange:      types.ExchangeOKX,
+		Price:         fill.FillPrice.Float64(),
+		Quantity:      fill.FillSize.Float64(),
+		QuoteQuantity: fill.FillPrice.Mul(fill.FillSize).Float64(),
+		Symbol:        toGlobalSymbol(fill.InstrumentID),
+		Side:          side,
+		IsBuyer:       side == types.SideTypeBuy,
+		IsMaker:       fill.ExecuteType == "M",
+		Time:          types.Time(fill.Timestamp.Time()),
+		Fee:           fill.Fee.Neg().Float64(),
+		FeeCurrency:   fill.FeeCurrency,
+	}, nil
+}
+
+// Trade returns the last fill of the order update, ok is false if the update is not a fill
+func (detail OrderDetail) Trade() (trade *types.Trade, ok bool, err error) {
+	if len(detail.TradeID) == 0 {
+		return nil, false, nil
+	}
+
+	trade, err = toGlobalTrade(Fill{
+		InstrumentType: detail.InstrumentType,
+		InstrumentID:   detail.InstrumentID,
+		TradeID:        detail.TradeID,
+		OrderID:        detail.OrderID,
+		ClientOrderID:  detail.ClientOrderID,
+		FillPrice:      detail.FillPrice,
+		FillSize:       detail.FillSize,
+		Side:           detail.Side,
+		ExecuteType:    detail.ExecuteType,
+		Fee:            detail.FillFee,
+		FeeCurrency:    detail.FillFeeCcy,
+		Timestamp:      detail.FillTime,
+	})
+
+	return trade, err == nil, err
+}
@@ -0,0 +1,138 @@
+package okx
+
+import (
+	"encoding/json"
+	"github.com/pymba86/bingo/pkg/engine"
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+)
+
+func TestToGlobalOrder(t *testing.T) {
+	e, ok := parseFixture(t, "orders.json").(*OrderEvent)
+	if !ok || len(e.Orders) != 1 {
+		t.Fatal("expected *OrderEvent with an order")
+	}
+
+	order, err := toGlobalOrder(e.Orders[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order.OrderID != 312269865356374016 || order.ClientOrderId != "gridZCbtcZC1a2b3c4d" {
+		t.Errorf("unexpected order ids %d %s", order.OrderID, order.ClientOrderId)
+	}
+
+	if order.Symbol != "BTCUSDT" || order.Side != types.SideTypeBuy || order.Type != types.OrderTypeLimit {
+		t.Errorf("unexpected order %+v", order)
+	}
+
+	if order.Status != types.OrderStatusPartiallyFilled || !order.IsWorking || order.ExecutedQuantity != 0.01 {
+		t.Errorf("unexpected order status %s %v %f", order.Status, order.IsWorking, order.ExecutedQuantity)
+	}
+
+	if order.Price != 30000 || order.Quantity != 0.02 {
+		t.Errorf("unexpected order price %f quantity %f", order.Price, order.Quantity)
+	}
+}
+
+func TestToGlobalOrderTypes(t *testing.T) {
+	tests := []struct {
+		orderType   string
+		expected    types.OrderType
+		timeInForce string
+	}{
+		{orderType: "limit", expected: types.OrderTypeLimit, timeInForce: "GTC"},
+		{orderType: "market", expected: types.OrderTypeMarket},
+		{orderType: "post_only", expected: types.OrderTypeLimitMaker},
+		{orderType: "ioc", expected: types.OrderTypeIOCLimit, timeInForce: "IOC"},
+		{orderType: "fok", expected: types.OrderTypeLimit, timeInForce: "FOK"},
+	}
+
+	for _, test := range tests {
+		orderType, timeInForce := toGlobalOrderType(test.orderType)
+		if orderType != test.expected || timeInForce != test.timeInForce {
+			t.Errorf("%s: got %s %s, expected %s %s", test.orderType, orderType, timeInForce, test.expected, test.timeInForce)
+		}
+	}
+}
+
+func TestOrderUpdateTrade(t *testing.T) {
+	e := parseFixture(t, "orders.json").(*OrderEvent)
+
+	trade, ok, err := e.Orders[0].Trade()
+	if err != nil || !ok {
+		t.Fatalf("expected the fill of the order update, got %v", err)
+	}
+
+	if trade.ID != 242589207 || trade.OrderID != 312269865356374016 || !trade.IsMaker || !trade.IsBuyer {
+		t.Errorf("unexpected trade %+v", trade)
+	}
+
+	// okx reports the charged fee as a negative number
+	if trade.Fee != 0.00001 || trade.FeeCurrency != "BTC" {
+		t.Errorf("unexpected fee %f %s", trade.Fee, trade.FeeCurrency)
+	}
+
+	detail := e.Orders[0]
+	detail.TradeID = ""
+	if _, ok, _ := detail.Trade(); ok {
+		t.Error("the order update without a fill is converted to a trade")
+	}
+}
+
+func TestToGlobalTrade(t *testing.T) {
+	var envelope responseEnvelope
+	if err := json.Unmarshal(readFixture(t, "fills_history.json"), &envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	var fills []Fill
+	if err := json.Unmarshal(envelope.Data, &fills); err != nil {
+		t.Fatal(err)
+	}
+
+	trade, err := toGlobalTrade(fills[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trade.ID != 123 || trade.Side != types.SideTypeSell || trade.IsBuyer || trade.IsMaker {
+		t.Errorf("unexpected trade %+v", trade)
+	}
+
+	if trade.Price != 29000.5 || trade.Quantity != 0.5 || trade.QuoteQuantity != 14500.25 {
+		t.Errorf("unexpected trade price %f quantity %f quote quantity %f", trade.Price, trade.Quantity, trade.QuoteQuantity)
+	}
+
+	if trade.Fee != 14.50025 || trade.FeeCurrency != "USDT" {
+		t.Errorf("unexpected fee %f %s", trade.Fee, trade.FeeCurrency)
+	}
+}
+
+func TestNewClientOrderID(t *testing.T) {
+	for _, id := range []struct{ strategy, instance string }{
+		{"grid", "btcusdt"},
+		{"grid_a", "BTC-USDT.1"},
+		{"Zeta", ""},
+		{"long strategy name", "long instance name"},
+	} {
+		clientOrderID := engine.NewStrategyClientOrderID(id.strategy, id.instance)
+		encoded := newClientOrderID(clientOrderID)
+		if encoded != clientOrderID || len(encoded) > clientOrderIDMaxLength {
+			t.Errorf("client order id %s is changed to %s", clientOrderID, encoded)
+		}
+
+		tag, ok := engine.ParseStrategyTag(encoded)
+		if !ok || tag != engine.StrategyTag(id.strategy, id.instance) {
+			t.Errorf("tag of %s = %s, expected %s", encoded, tag, engine.StrategyTag(id.strategy, id.instance))
+		}
+	}
+
+	if got := newClientOrderID("my-order:1"); got != "myorder1" {
+		t.Errorf("custom client order id = %s", got)
+	}
+
+	if got := newClientOrderID(types.NoClientOrderID); got != "" {
+		t.Errorf("no client order id = %s", got)
+	}
+}
@@ -0,0 +1,52 @@
+package okx
+
+import (
+	"strings"
+)
+
+const (
+	restBaseURL = "https://www.okx.com"
+	wsBaseURL   = "wss://ws.okx.com:8443"
+
+	// the demo trading shares the rest endpoint, the requests are marked by the x-simulated-trading header
+	testnetWsBaseURL = "wss://wspap.okx.com:8443"
+)
+
+// the websocket paths, the candle channels are only served by the business endpoint
+const (
+	publicWsPath   = "/ws/v5/public"
+	privateWsPath  = "/ws/v5/private"
+	businessWsPath = "/ws/v5/business"
+)
+
+func (e *Exchange) UseTestnet() {
+	e.EndpointSettings.UseTestnet()
+	e.applyEndpoints()
+}
+
+func (e *Exchange) UseEndpoints(restBaseURL, wsBaseURL string) {
+	e.EndpointSettings.UseEndpoints(strings.TrimSuffix(restBaseURL, "/"), strings.TrimSuffix(wsBaseURL, "/"))
+	e.applyEndpoints()
+}
+
+func (e *Exchange) applyEndpoints() {
+	e.client.Simulated = e.IsTestnet
+
+	e.client.BaseURL = restBaseURL
+	if len(e.RestBaseURL) > 0 {
+		e.client.BaseURL = e.RestBaseURL
+	}
+}
+
+// wsBaseURL returns the websocket endpoint of the stream without the /ws/v5 path
+func (s *Stream) wsBaseURL() string {
+	if len(s.WsBaseURL) > 0 {
+		return s.WsBaseURL
+	}
+
+	if s.IsTestnet {
+		return testnetWsBaseURL
+	}
+
+	return wsBaseURL
+}
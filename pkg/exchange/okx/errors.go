@@ -0,0 +1,38 @@
+package okx
+
+import (
+	"github.com/pkg/errors"
+	"net"
+)
+
+// the okx error codes of the requests that can be sent again
+const (
+	errorCodeServiceUnavailable = "50001"
+	errorCodeTimeout            = "50004"
+	errorCodeRateLimited        = "50011"
+	errorCodeSystemBusy         = "50013"
+	errorCodeSystemError        = "50026"
+	errorCodeTimestampExpired   = "50102"
+)
+
+// IsTransientError checks if the request is rejected for a temporary reason, e.g. the network timeout,
+// the server overload, the rate limit or the expired timestamp because of the clock drift.
+func (e *Exchange) IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case errorCodeServiceUnavailable, errorCodeTimeout, errorCodeRateLimited, errorCodeSystemBusy,
+			errorCodeSystemError, errorCodeTimestampExpired:
+			return true
+		}
+
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
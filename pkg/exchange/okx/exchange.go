@@ -0,0 +1,573 @@
+package okx
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const OKB = "OKB"
+
+// batchSize is the max number of the orders in a batch order request
+const batchSize = 20
+
+// the max number of the records of a page
+const (
+	candlesLimit        = 300
+	historyCandlesLimit = 100
+	ordersLimit         = 100
+	fillsLimit          = 100
+)
+
+func init() {
+	types.RegisterExchange(types.ExchangeRegistration{
+		Name:    types.ExchangeOKX,
+		Aliases: []string{"okex"},
+		New: func(credentials types.ExchangeCredentials) (types.Exchange, error) {
+			return New(credentials.Key, credentials.Secret, credentials.Passphrase), nil
+		},
+		CredentialFields:    []types.CredentialField{types.CredentialKey, types.CredentialSecret, types.CredentialPassphrase},
+		RequiredCredentials: []types.CredentialField{types.CredentialKey, types.CredentialSecret, types.CredentialPassphrase},
+		Capabilities: []types.ExchangeCapability{
+			types.ExchangeCapabilityEndpoints,
+			types.ExchangeCapabilityTradeHistory,
+		},
+	})
+}
+
+// Exchange is the okx spot exchange, the private api requires the passphrase of the api key
+type Exchange struct {
+	types.EndpointSettings
+
+	key, secret, passphrase string
+
+	client *RestClient
+}
+
+func New(key, secret, passphrase string) *Exchange {
+	return &Exchange{
+		key:        key,
+		secret:     secret,
+		passphrase: passphrase,
+		client:     NewRestClient(key, secret, passphrase),
+	}
+}
+
+func (e *Exchange) Name() types.ExchangeName {
+	return types.ExchangeOKX
+}
+
+func (e *Exchange) PlatformFeeCurrency() string {
+	return OKB
+}
+
+func (e *Exchange) NewStream() types.Stream {
+	stream := NewStream(e.key, e.secret, e.passphrase)
+	stream.EndpointSettings = e.EndpointSettings
+	return stream
+}
+
+func (e *Exchange) QueryMarkets(ctx context.Context) (types.MarketMap, error) {
+	log.Info("querying market info...")
+
+	var instruments []Instrument
+	params := url.Values{"instType": {"SPOT"}}
+	if err := e.client.publicRequest(ctx, "/api/v5/public/instruments", params, &instruments); err != nil {
+		return nil, err
+	}
+
+	markets := types.MarketMap{}
+	for _, instrument := range instruments {
+		market := toGlobalMarket(instrument)
+		registerInstrumentID(market.Symbol, market.LocalSymbol)
+		markets[market.Symbol] = market
+	}
+
+	return markets, nil
+}
+
+func (e *Exchange) QueryTicker(ctx context.Context, symbol string) (*types.Ticker, error) {
+	var tickers []Ticker
+	params := url.Values{"instId": {toLocalSymbol(symbol)}}
+	if err := e.client.publicRequest(ctx, "/api/v5/market/ticker", params, &tickers); err != nil {
+		return nil, err
+	}
+
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("ticker of %s not found", symbol)
+	}
+
+	ticker := toGlobalTicker(tickers[0])
+	return &ticker, nil
+}
+
+func (e *Exchange) QueryTickers(ctx context.Context, symbol ...string) (map[string]types.Ticker, error) {
+	var tickers = make(map[string]types.Ticker)
+
+	if len(symbol) == 1 {
+		ticker, err := e.QueryTicker(ctx, symbol[0])
+		if err != nil {
+			return nil, err
+		}
+
+		tickers[strings.ToUpper(symbol[0])] = *ticker
+		return tickers, nil
+	}
+
+	var okxTickers []Ticker
+	params := url.Values{"instType": {"SPOT"}}
+	if err := e.client.publicRequest(ctx, "/api/v5/market/tickers", params, &okxTickers); err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]struct{})
+	for _, s := range symbol {
+		m[strings.ToUpper(s)] = struct{}{}
+	}
+
+	for _, ticker := range okxTickers {
+		globalSymbol := toGlobalSymbol(ticker.InstrumentID)
+		if _, ok := m[globalSymbol]; len(symbol) != 0 && !ok {
+			continue
+		}
+
+		tickers[globalSymbol] = toGlobalTicker(ticker)
+	}
+
+	return tickers, nil
+}
+
+// QueryKLines queries the klines in the ascending order. The latest klines are queried from the candles endpoint,
+// the klines of the given time range are queried from the history candles endpoint, which serves all the history.
+// okx paginates the candles by the open time with the exclusive after (earlier than) and before (newer than) parameters.
+func (e *Exchange) QueryKLines(ctx context.Context, symbol string, interval types.Interval, options types.KLineQueryOptions) ([]types.KLine, error) {
+	bar, err := toLocalInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("querying kline %s %s %v", symbol, interval, options)
+
+	path := "/api/v5/market/candles"
+	maxLimit := candlesLimit
+	if options.StartTime != nil || options.EndTime != nil {
+		path = "/api/v5/market/history-candles"
+		maxLimit = historyCandlesLimit
+	}
+
+	limit := maxLimit
+	if options.Limit > 0 && options.Limit < maxLimit {
+		limit = options.Limit
+	}
+
+	params := url.Values{
+		"instId": {toLocalSymbol(symbol)},
+		"bar":    {bar},
+		"limit":  {strconv.Itoa(limit)},
+	}
+
+	var after time.Time
+	if options.StartTime != nil {
+		params.Set("before", strconv.FormatInt(toMilliseconds(options.StartTime.Add(-time.Millisecond)), 10))
+		after = options.StartTime.Add(time.Duration(limit) * interval.Duration())
+	}
+
+	if options.EndTime != nil {
+		if end := options.EndTime.Add(time.Millisecond); after.IsZero() || end.Before(after) {
+			after = end
+		}
+	}
+
+	if !after.IsZero() {
+		params.Set("after", strconv.FormatInt(toMilliseconds(after), 10))
+	}
+
+	var candles []Candle
+	if err := e.client.publicRequest(ctx, path, params, &candles); err != nil {
+		return nil, err
+	}
+
+	var kLines []types.KLine
+	for i := len(candles) - 1; i >= 0; i-- {
+		kLines = append(kLines, toGlobalKLine(strings.ToUpper(symbol), interval, candles[i]))
+	}
+
+	return kLines, nil
+}
+
+func (e *Exchange) QueryAccount(ctx context.Context) (*types.Account, error) {
+	balances, err := e.QueryAccountBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &types.Account{}
+
+	var fees []TradeFee
+	params := url.Values{"instType": {"SPOT"}}
+	if err := e.client.privateRequest(ctx, http.MethodGet, "/api/v5/account/trade-fee", params, nil, &fees); err != nil {
+		log.WithError(err).Warn("can not query the okx trade fee rates")
+	} else if len(fees) > 0 {
+		// okx reports the charged fee rates as negative numbers
+		a.MakerCommission = fees[0].Maker.Neg()
+		a.TakerCommission = fees[0].Taker.Neg()
+	}
+
+	a.UpdateBalances(balances)
+	return a, nil
+}
+
+func (e *Exchange) QueryAccountBalances(ctx context.Context) (types.BalanceMap, error) {
+	var accounts []AccountBalance
+	if err := e.client.privateRequest(ctx, http.MethodGet, "/api/v5/account/balance", nil, nil, &accounts); err != nil {
+		return nil, err
+	}
+
+	if len(accounts) == 0 {
+		return types.BalanceMap{}, nil
+	}
+
+	return toGlobalBalances(accounts[0].Details), nil
+}
+
+// SubmitOrders submits the orders by the batch order requests of 20 orders, the created orders are returned
+// in the submission order, the orders that can not be submitted are reported by *types.SubmitOrdersError.
+func (e *Exchange) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (createdOrders types.OrderSlice, err error) {
+	var submitErr types.SubmitOrdersError
+	fail := func(order types.SubmitOrder, err error) {
+		log.WithError(err).Errorf("can not submit %s %s %s order, quantity %f, price %f", order.Symbol, order.Side, order.Type, order.Quantity, order.Price)
+		submitErr.Failures = append(submitErr.Failures, types.SubmitOrderFailure{Order: order, Err: err})
+	}
+
+	var batch []types.SubmitOrder
+	var requests []PlaceOrderRequest
+
+	flush := func() {
+		if len(requests) == 0 {
+			return
+		}
+
+		var results []OrderResult
+		err := e.client.privateRequest(ctx, http.MethodPost, "/api/v5/trade/batch-orders", nil, requests, &results)
+
+		// the partially failed batch responds an error code with the result of each order
+		var apiErr *APIError
+		if err != nil && !(errors.As(err, &apiErr) && len(results) == len(requests)) {
+			for _, order := range batch {
+				fail(order, err)
+			}
+		} else {
+			for i, order := range batch {
+				if i >= len(results) {
+					fail(order, errors.New("missing okx order result"))
+					continue
+				}
+
+				createdOrder, err := toCreatedOrder(order, requests[i], results[i])
+				if err != nil {
+					fail(order, err)
+					continue
+				}
+
+				createdOrders = append(createdOrders, *createdOrder)
+			}
+		}
+
+		batch, requests = nil, nil
+	}
+
+	for _, order := range orders {
+		req, err := toPlaceOrderRequest(order)
+		if err != nil {
+			fail(order, err)
+			continue
+		}
+
+		batch = append(batch, order)
+		requests = append(requests, req)
+		if len(requests) == batchSize {
+			flush()
+		}
+	}
+
+	flush()
+
+	if len(submitErr.Failures) > 0 {
+		return createdOrders, &submitErr
+	}
+
+	return createdOrders, nil
+}
+
+func toPlaceOrderRequest(order types.SubmitOrder) (PlaceOrderRequest, error) {
+	orderType, err := toLocalOrderType(order)
+	if err != nil {
+		return PlaceOrderRequest{}, err
+	}
+
+	req := PlaceOrderRequest{
+		InstrumentID:  toLocalSymbol(order.Symbol),
+		TradeMode:     "cash",
+		ClientOrderID: newClientOrderID(order.ClientOrderId),
+		Side:          toLocalSideType(order.Side),
+		OrderType:     orderType,
+	}
+
+	if len(order.QuantityString) > 0 {
+		req.Size = order.QuantityString
+	} else if order.Market.Symbol != "" {
+		req.Size = order.Market.FormatQuantity(order.Quantity)
+	} else {
+		req.Size = strconv.FormatFloat(order.Quantity, 'f', -1, 64)
+	}
+
+	if orderType == "market" {
+		// the market buy order is sized in the quote currency by default
+		req.TargetCurrency = "base_ccy"
+		return req, nil
+	}
+
+	if len(order.PriceString) > 0 {
+		req.Price = order.PriceString
+	} else if order.Market.Symbol != "" {
+		req.Price = order.Market.FormatPrice(order.Price)
+	} else {
+		req.Price = strconv.FormatFloat(order.Price, 'f', -1, 64)
+	}
+
+	return req, nil
+}
+
+func toCreatedOrder(order types.SubmitOrder, req PlaceOrderRequest, result OrderResult) (*types.Order, error) {
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	orderID, err := parseID(result.OrderID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid okx order id %s", result.OrderID)
+	}
+
+	now := time.Now()
+	order.ClientOrderId = req.ClientOrderID
+	if len(result.ClientOrderID) > 0 {
+		order.ClientOrderId = result.ClientOrderID
+	}
+
+	_, order.TimeInForce = toGlobalOrderType(req.OrderType)
+
+	return &types.Order{
+		SubmitOrder:  order,
+		Exchange:     types.ExchangeOKX,
+		OrderID:      orderID,
+		Status:       types.OrderStatusNew,
+		IsWorking:    true,
+		CreationTime: types.Time(now),
+		UpdateTime:   types.Time(now),
+	}, nil
+}
+
+// CancelOrders cancels the orders by the batch cancel requests, a *types.CancelOrdersError with the result of each order
+// is returned when some of the orders can not be canceled.
+func (e *Exchange) CancelOrders(ctx context.Context, orders ...types.Order) error {
+	var results = make([]types.CancelOrderResult, 0, len(orders))
+	var failed bool
+
+	for start := 0; start < len(orders); start += batchSize {
+		end := start + batchSize
+		if end > len(orders) {
+			end = len(orders)
+		}
+
+		batch := orders[start:end]
+
+		var requests []CancelOrderRequest
+		for _, o := range batch {
+			req := CancelOrderRequest{InstrumentID: toLocalSymbol(o.Symbol)}
+			if o.OrderID > 0 {
+				req.OrderID = strconv.FormatUint(o.OrderID, 10)
+			} else {
+				req.ClientOrderID = o.ClientOrderId
+			}
+
+			requests = append(requests, req)
+		}
+
+		var cancelResults []OrderResult
+		err := e.client.privateRequest(ctx, http.MethodPost, "/api/v5/trade/cancel-batch-orders", nil, requests, &cancelResults)
+
+		var apiErr *APIError
+		partial := err != nil && errors.As(err, &apiErr) && len(cancelResults) == len(batch)
+
+		for i, o := range batch {
+			var orderErr error
+			if err != nil && !partial {
+				orderErr = err
+			} else if i < len(cancelResults) {
+				orderErr = cancelResults[i].Err()
+			}
+
+			if orderErr != nil {
+				log.WithError(orderErr).Errorf("order cancel error: %s", o.String())
+				failed = true
+			}
+
+			results = append(results, types.CancelOrderResult{Order: o, Err: orderErr})
+		}
+	}
+
+	if failed {
+		return &types.CancelOrdersError{Results: results}
+	}
+
+	return nil
+}
+
+func (e *Exchange) QueryOpenOrders(ctx context.Context, symbol string) (orders []types.Order, err error) {
+	params := url.Values{
+		"instType": {"SPOT"},
+		"instId":   {toLocalSymbol(symbol)},
+		"limit":    {strconv.Itoa(ordersLimit)},
+	}
+
+	for {
+		var details []OrderDetail
+		if err := e.client.privateRequest(ctx, http.MethodGet, "/api/v5/trade/orders-pending", params, nil, &details); err != nil {
+			return orders, err
+		}
+
+		pageOrders, err := toGlobalOrders(details)
+		if err != nil {
+			return orders, err
+		}
+
+		orders = append(orders, pageOrders...)
+		if len(details) < ordersLimit {
+			return orders, nil
+		}
+
+		// the orders are paginated from the newest to the oldest
+		params.Set("after", details[len(details)-1].OrderID)
+	}
+}
+
+// QueryClosedOrders queries the filled and the canceled orders of the last 7 days in the ascending order of the order ids,
+// the orders are queried from the given order id if lastOrderID is not zero.
+func (e *Exchange) QueryClosedOrders(ctx context.Context, symbol string, since, until time.Time, lastOrderID uint64) (orders []types.Order, err error) {
+	log.Infof("querying closed orders %s from %s <=> %s ...", symbol, since, until)
+
+	params := url.Values{
+		"instType": {"SPOT"},
+		"instId":   {toLocalSymbol(symbol)},
+		"limit":    {strconv.Itoa(ordersLimit)},
+	}
+
+	if lastOrderID == 0 {
+		params.Set("begin", strconv.FormatInt(toMilliseconds(since), 10))
+		params.Set("end", strconv.FormatInt(toMilliseconds(until), 10))
+	}
+
+	for {
+		var details []OrderDetail
+		if err := e.client.privateRequest(ctx, http.MethodGet, "/api/v5/trade/orders-history", params, nil, &details); err != nil {
+			return nil, err
+		}
+
+		pageOrders, err := toGlobalOrders(details)
+		if err != nil {
+			return nil, err
+		}
+
+		reached := false
+		for _, order := range pageOrders {
+			if order.OrderID < lastOrderID {
+				reached = true
+				continue
+			}
+
+			orders = append(orders, order)
+		}
+
+		if reached || len(details) < ordersLimit {
+			break
+		}
+
+		params.Set("after", details[len(details)-1].OrderID)
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].OrderID < orders[j].OrderID
+	})
+
+	return orders, nil
+}
+
+// QueryTrades queries the fills of the last 3 months in the ascending order of the trade ids.
+// okx paginates the fills from the newest to the oldest by the bill id, so the pages are walked back
+// until the given LastTradeID, which is inclusive like binance, and the oldest fills up to the limit are returned.
+func (e *Exchange) QueryTrades(ctx context.Context, symbol string, options *types.TradeQueryOptions) (trades []types.Trade, err error) {
+	params := url.Values{
+		"instType": {"SPOT"},
+		"instId":   {toLocalSymbol(symbol)},
+		"limit":    {strconv.Itoa(fillsLimit)},
+	}
+
+	if options.StartTime != nil {
+		params.Set("begin", strconv.FormatInt(toMilliseconds(*options.StartTime), 10))
+	}
+
+	if options.EndTime != nil {
+		params.Set("end", strconv.FormatInt(toMilliseconds(*options.EndTime), 10))
+	}
+
+	for {
+		var fills []Fill
+		if err := e.client.privateRequest(ctx, http.MethodGet, "/api/v5/trade/fills-history", params, nil, &fills); err != nil {
+			return nil, err
+		}
+
+		reached := false
+		for _, fill := range fills {
+			trade, err := toGlobalTrade(fill)
+			if err != nil {
+				log.WithError(err).Errorf("can not convert okx fill: %+v", fill)
+				continue
+			}
+
+			if trade.ID < options.LastTradeID {
+				reached = true
+				continue
+			}
+
+			trades = append(trades, *trade)
+		}
+
+		if reached || len(fills) < fillsLimit {
+			break
+		}
+
+		params.Set("after", fills[len(fills)-1].BillID)
+	}
+
+	sort.Slice(trades, func(i, j int) bool {
+		return trades[i].ID < trades[j].ID
+	})
+
+	if options.Limit > 0 && int64(len(trades)) > options.Limit {
+		trades = trades[:options.Limit]
+	}
+
+	return trades, nil
+}
+
+func toMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,259 @@
+package okx
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"strconv"
+	"strings"
+)
+
+// the okx websocket channels
+const (
+	channelBooks        = "books"
+	channelBooks5       = "books5"
+	channelBBO          = "bbo-tbt"
+	channelTrades       = "trades"
+	channelTickers      = "tickers"
+	channelCandlePrefix = "candle"
+	channelOrders       = "orders"
+	channelAccount      = "account"
+)
+
+// WebsocketArg is the channel argument of the subscription and the data push
+type WebsocketArg struct {
+	Channel        string `json:"channel"`
+	InstrumentType string `json:"instType,omitempty"`
+	InstrumentID   string `json:"instId,omitempty"`
+}
+
+// WebsocketOp is the operation request, e.g. login and subscribe
+type WebsocketOp struct {
+	Op   string      `json:"op"`
+	Args interface{} `json:"args"`
+}
+
+type WebsocketLogin struct {
+	APIKey     string `json:"apiKey"`
+	Passphrase string `json:"passphrase"`
+	Timestamp  string `json:"timestamp"`
+	Sign       string `json:"sign"`
+}
+
+// WebsocketEvent is the response of the operations, the code is set when the operation fails
+type WebsocketEvent struct {
+	Event        string       `json:"event"`
+	Code         string       `json:"code"`
+	Message      string       `json:"msg"`
+	Arg          WebsocketArg `json:"arg"`
+	ConnectionID string       `json:"connId"`
+}
+
+func (e *WebsocketEvent) Err() error {
+	if e.Event == "error" || (len(e.Code) > 0 && e.Code != "0") {
+		return &APIError{Code: e.Code, Message: e.Message}
+	}
+
+	return nil
+}
+
+type websocketMessage struct {
+	WebsocketEvent
+
+	Action string          `json:"action"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// BookData is the book levels of the books channels, a level is [price, size, deprecated, number of orders]
+type BookData struct {
+	Asks      [][]fixedpoint.Value       `json:"asks"`
+	Bids      [][]fixedpoint.Value       `json:"bids"`
+	Timestamp types.MillisecondTimestamp `json:"ts"`
+	Checksum  int64                      `json:"checksum"`
+	SeqID     int64                      `json:"seqId"`
+	PrevSeqID int64                      `json:"prevSeqId"`
+}
+
+// BookEvent is the push of the books channels, the action is snapshot or update,
+// the books5 and the bbo-tbt channels only push the snapshots
+type BookEvent struct {
+	InstrumentID string
+	Action       string
+	Books        []BookData
+}
+
+func (e *BookEvent) IsSnapshot() bool {
+	return e.Action != "update"
+}
+
+func (e *BookEvent) OrderBook() types.SliceOrderBook {
+	book := types.SliceOrderBook{Symbol: toGlobalSymbol(e.InstrumentID)}
+	for _, data := range e.Books {
+		book.Bids = append(book.Bids, toPriceVolumes(data.Bids)...)
+		book.Asks = append(book.Asks, toPriceVolumes(data.Asks)...)
+	}
+
+	return book
+}
+
+func toPriceVolumes(levels [][]fixedpoint.Value) (pvs types.PriceVolumeSlice) {
+	for _, level := range levels {
+		if len(level) < 2 {
+			continue
+		}
+
+		pvs = append(pvs, types.PriceVolume{Price: level[0], Volume: level[1]})
+	}
+
+	return pvs
+}
+
+type CandleEvent struct {
+	InstrumentID string
+	Interval     types.Interval
+	Candles      []Candle
+}
+
+func (e *CandleEvent) KLines() (kLines []types.KLine) {
+	for _, candle := range e.Candles {
+		kLines = append(kLines, toGlobalKLine(toGlobalSymbol(e.InstrumentID), e.Interval, candle))
+	}
+
+	return kLines
+}
+
+type MarketTrade struct {
+	InstrumentID string                     `json:"instId"`
+	TradeID      string                     `json:"tradeId"`
+	Price        fixedpoint.Value           `json:"px"`
+	Size         fixedpoint.Value           `json:"sz"`
+	Side         string                     `json:"side"`
+	Timestamp    types.MillisecondTimestamp `json:"ts"`
+}
+
+// Trade converts the public trade, the side is the taker side
+func (t MarketTrade) Trade() (types.Trade, error) {
+	side, err := types.StrToSideType(t.Side)
+	if err != nil {
+		return types.Trade{}, err
+	}
+
+	id, err := strconv.ParseInt(t.TradeID, 10, 64)
+	if err != nil {
+		return types.Trade{}, errors.Wrapf(err, "invalid okx trade id %s", t.TradeID)
+	}
+
+	return types.Trade{
+		ID:            id,
+		Exchange:      types.ExchangeOKX,
+		Price:         t.Price.Float64(),
+		Quantity:      t.Size.Float64(),
+		QuoteQuantity: t.Price.Mul(t.Size).Float64(),
+		Symbol:        toGlobalSymbol(t.InstrumentID),
+		Side:          side,
+		IsBuyer:       side == types.SideTypeBuy,
+		Time:          types.Time(t.Timestamp.Time()),
+	}, nil
+}
+
+type MarketTradeEvent struct {
+	Trades []MarketTrade
+}
+
+type TickerEvent struct {
+	Tickers []Ticker
+}
+
+func (e *TickerEvent) BookTickers() (bookTickers []types.BookTicker) {
+	for _, ticker := range e.Tickers {
+		bookTickers = append(bookTickers, types.BookTicker{
+			Symbol:   toGlobalSymbol(ticker.InstrumentID),
+			Buy:      ticker.BidPrice,
+			BuySize:  ticker.BidSize,
+			Sell:     ticker.AskPrice,
+			SellSize: ticker.AskSize,
+		})
+	}
+
+	return bookTickers
+}
+
+type OrderEvent struct {
+	Orders []OrderDetail
+}
+
+type AccountEvent struct {
+	Accounts []AccountBalance
+}
+
+func (e *AccountEvent) BalanceMap() types.BalanceMap {
+	balances := types.BalanceMap{}
+	for _, account := range e.Accounts {
+		for currency, balance := range toGlobalBalances(account.Details) {
+			balances[currency] = balance
+		}
+	}
+
+	return balances
+}
+
+// ParseEvent parses the websocket message, it returns *WebsocketEvent for the operation responses
+// and the channel events for the data pushes
+func ParseEvent(message []byte) (interface{}, error) {
+	var msg websocketMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return nil, err
+	}
+
+	if len(msg.Event) > 0 {
+		return &msg.WebsocketEvent, nil
+	}
+
+	channel := msg.Arg.Channel
+	switch {
+
+	case channel == channelBooks || channel == channelBooks5 || channel == channelBBO:
+		e := &BookEvent{InstrumentID: msg.Arg.InstrumentID, Action: msg.Action}
+		return e, json.Unmarshal(msg.Data, &e.Books)
+
+	case strings.HasPrefix(channel, channelCandlePrefix):
+		interval, err := toGlobalInterval(strings.TrimPrefix(channel, channelCandlePrefix))
+		if err != nil {
+			return nil, err
+		}
+
+		e := &CandleEvent{InstrumentID: msg.Arg.InstrumentID, Interval: interval}
+		return e, json.Unmarshal(msg.Data, &e.Candles)
+
+	case channel == channelTrades:
+		e := &MarketTradeEvent{}
+		return e, json.Unmarshal(msg.Data, &e.Trades)
+
+	case channel == channelTickers:
+		e := &TickerEvent{}
+		return e, json.Unmarshal(msg.Data, &e.Tickers)
+
+	case channel == channelOrders:
+		e := &OrderEvent{}
+		return e, json.Unmarshal(msg.Data, &e.Orders)
+
+	case channel == channelAccount:
+		e := &AccountEvent{}
+		return e, json.Unmarshal(msg.Data, &e.Accounts)
+	}
+
+	return nil, fmt.Errorf("unsupported okx websocket message: %s", message)
+}
+
+// toGlobalInterval converts the okx bar back to the interval
+func toGlobalInterval(bar string) (types.Interval, error) {
+	for interval := range types.SupportedIntervals {
+		if b, err := toLocalInterval(interval); err == nil && b == bar {
+			return interval, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported okx bar: %s", bar)
+}
@@ -0,0 +1,188 @@
+package okx
+
+import (
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+	"time"
+)
+
+func parseFixture(t *testing.T, name string) interface{} {
+	t.Helper()
+
+	e, err := ParseEvent(readFixture(t, name))
+	if err != nil {
+		t.Fatalf("can not parse %s: %v", name, err)
+	}
+
+	return e
+}
+
+func TestParseBookEvents(t *testing.T) {
+	snapshot, ok := parseFixture(t, "books_snapshot.json").(*BookEvent)
+	if !ok {
+		t.Fatal("expected *BookEvent")
+	}
+
+	if !snapshot.IsSnapshot() || snapshot.InstrumentID != "BTC-USDT" {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+
+	book := snapshot.OrderBook()
+	if book.Symbol != "BTCUSDT" || len(book.Asks) != 2 || len(book.Bids) != 1 {
+		t.Fatalf("unexpected book %+v", book)
+	}
+
+	if book.Asks[0].Price.Float64() != 41006.8 || book.Asks[0].Volume.Float64() != 0.60038921 {
+		t.Errorf("unexpected ask %+v", book.Asks[0])
+	}
+
+	update, ok := parseFixture(t, "books_update.json").(*BookEvent)
+	if !ok {
+		t.Fatal("expected *BookEvent")
+	}
+
+	if update.IsSnapshot() || update.Books[0].PrevSeqID != 123456 || update.Books[0].SeqID != 123457 {
+		t.Errorf("unexpected update %+v", update)
+	}
+}
+
+func TestParseCandleEvent(t *testing.T) {
+	e, ok := parseFixture(t, "candle.json").(*CandleEvent)
+	if !ok {
+		t.Fatal("expected *CandleEvent")
+	}
+
+	if e.Interval != types.Interval1h {
+		t.Errorf("interval = %s", e.Interval)
+	}
+
+	kLines := e.KLines()
+	if len(kLines) != 1 {
+		t.Fatalf("unexpected klines %+v", kLines)
+	}
+
+	k := kLines[0]
+	if k.Symbol != "BTCUSDT" || !k.Closed || k.Open != 42500 || k.High != 48199.9 || k.Close != 41006.1 {
+		t.Errorf("unexpected kline %+v", k)
+	}
+
+	if !k.StartTime.Equal(time.Unix(0, 1629993600000*int64(time.Millisecond))) {
+		t.Errorf("start time = %s", k.StartTime)
+	}
+}
+
+func TestParseMarketTradeEvent(t *testing.T) {
+	e, ok := parseFixture(t, "trades.json").(*MarketTradeEvent)
+	if !ok || len(e.Trades) != 1 {
+		t.Fatal("expected *MarketTradeEvent with a trade")
+	}
+
+	trade, err := e.Trades[0].Trade()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trade.ID != 130639474 || trade.Symbol != "BTCUSDT" || trade.Side != types.SideTypeBuy || trade.Price != 42219.9 {
+		t.Errorf("unexpected trade %+v", trade)
+	}
+}
+
+func TestParseTickerEvent(t *testing.T) {
+	e, ok := parseFixture(t, "tickers.json").(*TickerEvent)
+	if !ok {
+		t.Fatal("expected *TickerEvent")
+	}
+
+	bookTickers := e.BookTickers()
+	if len(bookTickers) != 1 {
+		t.Fatalf("unexpected book tickers %+v", bookTickers)
+	}
+
+	ticker := bookTickers[0]
+	if ticker.Symbol != "BTCUSDT" || ticker.Buy.Float64() != 8888.88 || ticker.Sell.Float64() != 9999.99 {
+		t.Errorf("unexpected book ticker %+v", ticker)
+	}
+}
+
+func TestParseAccountEvent(t *testing.T) {
+	e, ok := parseFixture(t, "account.json").(*AccountEvent)
+	if !ok {
+		t.Fatal("expected *AccountEvent")
+	}
+
+	balances := e.BalanceMap()
+	btc, ok := balances["BTC"]
+	if !ok || btc.Available.Float64() != 1.5 || btc.Locked.Float64() != 0.5 {
+		t.Errorf("unexpected btc balance %+v", btc)
+	}
+
+	if _, ok := balances["USDT"]; !ok {
+		t.Error("missing usdt balance")
+	}
+}
+
+func TestParseOperationEvents(t *testing.T) {
+	e, ok := parseFixture(t, "login_error.json").(*WebsocketEvent)
+	if !ok {
+		t.Fatal("expected *WebsocketEvent")
+	}
+
+	if err := e.Err(); err == nil {
+		t.Error("expected the login error")
+	}
+
+	subscribed, err := ParseEvent([]byte(`{"event":"subscribe","arg":{"channel":"tickers","instId":"BTC-USDT"},"connId":"a4d3ae55"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event := subscribed.(*WebsocketEvent); event.Err() != nil || event.Arg.Channel != channelTickers {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	if _, err := ParseEvent([]byte(`{"arg":{"channel":"unknown"},"data":[]}`)); err == nil {
+		t.Error("expected an error of the unsupported channel")
+	}
+}
+
+func TestCheckBookSequence(t *testing.T) {
+	stream := NewStream("", "", "")
+
+	event := func(action string, prevSeqID, seqID int64) *BookEvent {
+		return &BookEvent{
+			InstrumentID: "BTC-USDT",
+			Action:       action,
+			Books:        []BookData{{PrevSeqID: prevSeqID, SeqID: seqID}},
+		}
+	}
+
+	if stream.checkBookSequence(event("update", 1, 2)) {
+		t.Error("the update before the snapshot is accepted")
+	}
+
+	if !stream.checkBookSequence(parseFixture(t, "books_snapshot.json").(*BookEvent)) {
+		t.Error("the snapshot is rejected")
+	}
+
+	if !stream.checkBookSequence(parseFixture(t, "books_update.json").(*BookEvent)) {
+		t.Error("the following update is rejected")
+	}
+
+	// the sequence id is unchanged when there is no change of the book
+	if !stream.checkBookSequence(event("update", 123457, 123457)) {
+		t.Error("the heartbeat update is rejected")
+	}
+
+	if stream.checkBookSequence(event("update", 123460, 123461)) {
+		t.Error("the gap is not detected")
+	}
+
+	if stream.checkBookSequence(event("update", 123461, 123462)) {
+		t.Error("the update after the gap is accepted before a new snapshot")
+	}
+
+	// the snapshot channels do not carry the sequence ids
+	if !stream.checkBookSequence(event("snapshot", 0, 0)) {
+		t.Error("the books5 snapshot is rejected")
+	}
+}
@@ -0,0 +1,766 @@
+package okx
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var defaultDialer = &websocket.Dialer{
+	Proxy:            http.ProxyFromEnvironment,
+	HandshakeTimeout: 45 * time.Second,
+	ReadBufferSize:   4096 * 2,
+}
+
+// from OKX document:
+// If there's a network problem, the system will automatically disable the connection.
+// The connection will break automatically if the subscription is not established or data has not been pushed for more than 30 seconds.
+// The client should send the string 'ping' when no data is received within N seconds (N < 30), and expect the string 'pong'.
+const readTimeout = 30 * time.Second
+
+const pingInterval = 20 * time.Second
+
+const loginTimeout = 10 * time.Second
+
+// endpointConn is a websocket endpoint of the stream and the channels subscribed on it
+type endpointConn struct {
+	path  string
+	login bool
+	args  []WebsocketArg
+}
+
+// Stream is the okx websocket stream. The public only stream subscribes the market data channels,
+// the candles are served by the business endpoint, so the stream holds a connection for each endpoint.
+// The private stream logs in and subscribes the orders and the account channels of the spot account.
+type Stream struct {
+	types.EndpointSettings
+	types.StandardStream
+
+	key, secret, passphrase string
+
+	ConnLock sync.Mutex
+
+	// writeLock serializes the writes of the ping workers and the subscriptions
+	writeLock sync.Mutex
+
+	// disconnect closes the current connections, it's created for each connect
+	disconnect func(reason types.ConnectionReason)
+
+	// conns are the current connections by the endpoint path
+	conns map[string]*websocket.Conn
+	closed     bool
+
+	// dispatchLock serializes the events of the connections
+	dispatchLock sync.Mutex
+
+	// lastMessageTime is the unix nano time of the last received message, it's used by the watchdog
+	lastMessageTime int64
+
+	// bookSeqIDs is the last sequence id of the books channel of each instrument, it's used for detecting the gaps
+	bookSeqIDs map[string]int64
+
+	publicOnly bool
+
+	rawMessageCallbacks       []func(message []byte)
+	bookEventCallbacks        []func(e *BookEvent)
+	candleEventCallbacks      []func(e *CandleEvent)
+	marketTradeEventCallbacks []func(e *MarketTradeEvent)
+	tickerEventCallbacks      []func(e *TickerEvent)
+	orderEventCallbacks       []func(e *OrderEvent)
+	accountEventCallbacks     []func(e *AccountEvent)
+}
+
+func (s *Stream) OnRawMessage(cb func(message []byte)) {
+	s.rawMessageCallbacks = append(s.rawMessageCallbacks, cb)
+}
+
+func (s *Stream) EmitRawMessage(message []byte) {
+	for _, cb := range s.rawMessageCallbacks {
+		cb(message)
+	}
+}
+
+func (s *Stream) OnBookEvent(cb func(e *BookEvent)) {
+	s.bookEventCallbacks = append(s.bookEventCallbacks, cb)
+}
+
+func (s *Stream) EmitBookEvent(e *BookEvent) {
+	for _, cb := range s.bookEventCallbacks {
+		cb(e)
+	}
+}
+
+func (s *Stream) OnCandleEvent(cb func(e *CandleEvent)) {
+	s.candleEventCallbacks = append(s.candleEventCallbacks, cb)
+}
+
+func (s *Stream) EmitCandleEvent(e *CandleEvent) {
+	for _, cb := range s.candleEventCallbacks {
+		cb(e)
+	}
+}
+
+func (s *Stream) OnMarketTradeEvent(cb func(e *MarketTradeEvent)) {
+	s.marketTradeEventCallbacks = append(s.marketTradeEventCallbacks, cb)
+}
+
+func (s *Stream) EmitMarketTradeEvent(e *MarketTradeEvent) {
+	for _, cb := range s.marketTradeEventCallbacks {
+		cb(e)
+	}
+}
+
+func (s *Stream) OnTickerEvent(cb func(e *TickerEvent)) {
+	s.tickerEventCallbacks = append(s.tickerEventCallbacks, cb)
+}
+
+func (s *Stream) EmitTickerEvent(e *TickerEvent) {
+	for _, cb := range s.tickerEventCallbacks {
+		cb(e)
+	}
+}
+
+func (s *Stream) OnOrderEvent(cb func(e *OrderEvent)) {
+	s.orderEventCallbacks = append(s.orderEventCallbacks, cb)
+}
+
+func (s *Stream) EmitOrderEvent(e *OrderEvent) {
+	for _, cb := range s.orderEventCallbacks {
+		cb(e)
+	}
+}
+
+func (s *Stream) OnAccountEvent(cb func(e *AccountEvent)) {
+	s.accountEventCallbacks = append(s.accountEventCallbacks, cb)
+}
+
+func (s *Stream) EmitAccountEvent(e *AccountEvent) {
+	for _, cb := range s.accountEventCallbacks {
+		cb(e)
+	}
+}
+
+func NewStream(key, secret, passphrase string) *Stream {
+	stream := &Stream{
+		StandardStream: types.StandardStream{
+			ReconnectC:       make(chan types.ConnectionReason, 1),
+			ReconnectBackoff: types.DefaultBackoff,
+		},
+		key:        key,
+		secret:     secret,
+		passphrase: passphrase,
+		bookSeqIDs: make(map[string]int64),
+	}
+
+	stream.OnBookEvent(func(e *BookEvent) {
+		if e.IsSnapshot() {
+			stream.EmitBookSnapshot(e.OrderBook())
+		} else {
+			stream.EmitBookUpdate(e.OrderBook())
+		}
+	})
+
+	stream.OnCandleEvent(func(e *CandleEvent) {
+		for _, kline := range e.KLines() {
+			if kline.Closed {
+				stream.EmitKLineClosed(kline)
+			} else {
+				stream.EmitKLine(kline)
+			}
+		}
+	})
+
+	stream.OnMarketTradeEvent(func(e *MarketTradeEvent) {
+		for _, t := range e.Trades {
+			trade, err := t.Trade()
+			if err != nil {
+				log.WithError(err).Error("market trade convert error")
+				continue
+			}
+
+			stream.EmitMarketTrade(trade)
+		}
+	})
+
+	stream.OnTickerEvent(func(e *TickerEvent) {
+		for _, bookTicker := range e.BookTickers() {
+			stream.EmitBookTickerUpdate(bookTicker)
+		}
+	})
+
+	stream.OnOrderEvent(func(e *OrderEvent) {
+		for _, detail := range e.Orders {
+			// the fill is emitted before the order update, like the trade execution report of binance
+			trade, ok, err := detail.Trade()
+			if err != nil {
+				log.WithError(err).Error("trade convert error")
+			} else if ok {
+				stream.EmitTradeUpdate(*trade)
+			}
+
+			order, err := toGlobalOrder(detail)
+			if err != nil {
+				log.WithError(err).Error("order convert error")
+				continue
+			}
+
+			stream.EmitOrderUpdate(*order)
+		}
+	})
+
+	stream.OnAccountEvent(func(e *AccountEvent) {
+		stream.EmitBalanceUpdate(e.BalanceMap())
+	})
+
+	return stream
+}
+
+func (s *Stream) SetPublicOnly() {
+	s.publicOnly = true
+}
+
+// SubscribeLive subscribes the channels on the current connections without reconnecting the stream,
+// the subscriptions should be added by Subscribe first, so that they are subscribed again on reconnect.
+// The stream is reconnected when the channels need an endpoint that is not connected yet.
+func (s *Stream) SubscribeLive(subscriptions ...types.Subscription) error {
+	if !s.publicOnly {
+		return nil
+	}
+
+	var args = make(map[string][]WebsocketArg)
+	for _, subscription := range subscriptions {
+		arg, err := convertSubscription(subscription)
+		if err != nil {
+			return err
+		}
+
+		if subscription.Channel == types.KLineChannel {
+			args[businessWsPath] = append(args[businessWsPath], arg)
+		} else {
+			args[publicWsPath] = append(args[publicWsPath], arg)
+		}
+	}
+
+	s.ConnLock.Lock()
+	conns := s.conns
+	s.ConnLock.Unlock()
+
+	// the channels are subscribed on connect
+	if conns == nil {
+		return nil
+	}
+
+	for path, pathArgs := range args {
+		conn, ok := conns[path]
+		if !ok {
+			log.Infof("endpoint %s is not connected, reconnecting the stream to subscribe %+v", path, pathArgs)
+			s.Reconnect(types.ConnectionReasonResubscribe)
+			return nil
+		}
+
+		if err := s.subscribe(conn, pathArgs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// endpoints returns the endpoints and the channels of the stream
+func (s *Stream) endpoints() []endpointConn {
+	if !s.publicOnly {
+		return []endpointConn{{
+			path:  privateWsPath,
+			login: true,
+			args: []WebsocketArg{
+				{Channel: channelOrders, InstrumentType: "SPOT"},
+				{Channel: channelAccount},
+			},
+		}}
+	}
+
+	public := endpointConn{path: publicWsPath}
+	business := endpointConn{path: businessWsPath}
+
+	for _, subscription := range s.Subscriptions {
+		arg, err := convertSubscription(subscription)
+		if err != nil {
+			log.WithError(err).Errorf("can not subscribe %s %s", subscription.Channel, subscription.Symbol)
+			continue
+		}
+
+		if subscription.Channel == types.KLineChannel {
+			business.args = append(business.args, arg)
+		} else {
+			public.args = append(public.args, arg)
+		}
+	}
+
+	if len(business.args) > 0 && len(public.args) == 0 {
+		return []endpointConn{business}
+	}
+
+	if len(business.args) > 0 {
+		return []endpointConn{public, business}
+	}
+
+	return []endpointConn{public}
+}
+
+// convertSubscription converts the subscription to the okx channel argument,
+// the book depth 1 and 5 use the snapshot channels, the other depths use the full incremental books
+func convertSubscription(s types.Subscription) (WebsocketArg, error) {
+	arg := WebsocketArg{InstrumentID: toLocalSymbol(s.Symbol)}
+
+	switch s.Channel {
+	case types.BookChannel:
+		switch s.Options.Depth {
+		case "1":
+			arg.Channel = channelBBO
+
+		case "5":
+			arg.Channel = channelBooks5
+
+		default:
+			arg.Channel = channelBooks
+		}
+
+	case types.KLineChannel:
+		bar, err := toLocalInterval(types.Interval(s.Options.Interval))
+		if err != nil {
+			return arg, err
+		}
+
+		arg.Channel = channelCandlePrefix + bar
+
+	case types.MarketTradeChannel:
+		arg.Channel = channelTrades
+
+	case types.BookTickerChannel:
+		arg.Channel = channelTickers
+
+	default:
+		return arg, errors.Errorf("unsupported okx channel: %s", s.Channel)
+	}
+
+	return arg, nil
+}
+
+func (s *Stream) Connect(ctx context.Context) error {
+	err := s.connect(ctx, types.ConnectionReasonInitial)
+	if err != nil {
+		return err
+	}
+
+	// start one re-connector goroutine with the base context
+	go s.reconnector(ctx)
+
+	s.EmitStart()
+	return nil
+}
+
+func (s *Stream) reconnector(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case reason := <-s.ReconnectC:
+			log.Warnf("received reconnect signal (%s), reconnecting...", reason)
+
+			for attempt := 0; ; attempt++ {
+				delay := s.ReconnectBackoff.Duration(attempt)
+				log.Infof("reconnecting in %s, attempt %d...", delay, attempt+1)
+
+				select {
+				case <-ctx.Done():
+					return
+
+				case <-time.After(delay):
+				}
+
+				if s.isClosed() {
+					return
+				}
+
+				if err := s.connect(ctx, types.ConnectionReasonReconnect); err != nil {
+					log.WithError(err).Errorf("connect error, try to reconnect again...")
+					continue
+				}
+
+				break
+			}
+		}
+	}
+}
+
+func (s *Stream) connect(ctx context.Context, reason types.ConnectionReason) error {
+	var conns []*websocket.Conn
+	closeConns := func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}
+
+	endpoints := s.endpoints()
+	for _, endpoint := range endpoints {
+		conn, err := s.dial(endpoint)
+		if err != nil {
+			closeConns()
+			return err
+		}
+
+		conns = append(conns, conn)
+	}
+
+	log.Infof("websocket connected")
+
+	connCtx, connCancel := context.WithCancel(ctx)
+
+	var once sync.Once
+	disconnect := func(reason types.ConnectionReason) {
+		once.Do(func() {
+			connCancel()
+			closeConns()
+			s.EmitDisconnect(reason)
+
+			if reason != types.ConnectionReasonClosed && !s.isClosed() {
+				s.Reconnect(reason)
+			}
+		})
+	}
+
+	// should only start one connection one time, so we lock the mutex
+	s.ConnLock.Lock()
+	if s.closed {
+		s.ConnLock.Unlock()
+		connCancel()
+		closeConns()
+		return errors.New("stream is closed")
+	}
+
+	previous := s.disconnect
+	s.disconnect = disconnect
+	s.conns = make(map[string]*websocket.Conn, len(conns))
+	for i, conn := range conns {
+		s.conns[endpoints[i].path] = conn
+	}
+	s.ConnLock.Unlock()
+
+	// ensure the previous connections are closed
+	if previous != nil {
+		previous(types.ConnectionReasonClosed)
+	}
+
+	s.dispatchLock.Lock()
+	s.bookSeqIDs = make(map[string]int64)
+	s.dispatchLock.Unlock()
+
+	atomic.StoreInt64(&s.lastMessageTime, time.Now().UnixNano())
+
+	s.EmitConnect(reason)
+
+	for i, conn := range conns {
+		if err := s.subscribe(conn, endpoints[i].args); err != nil {
+			log.WithError(err).Error("subscribe error")
+		}
+
+		go s.read(connCtx, conn, disconnect)
+		go s.ping(connCtx, conn, disconnect)
+	}
+
+	if s.StallTimeout > 0 {
+		go s.watchdog(connCtx, disconnect)
+	}
+
+	return nil
+}
+
+// dial connects to the endpoint, the private endpoint is logged in before it's returned
+func (s *Stream) dial(endpoint endpointConn) (*websocket.Conn, error) {
+	conn, _, err := defaultDialer.Dial(s.wsBaseURL()+endpoint.path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.login {
+		if err := s.login(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// login sends the login request and waits for the login response
+func (s *Stream) login(conn *websocket.Conn) error {
+	if len(s.key) == 0 || len(s.secret) == 0 || len(s.passphrase) == 0 {
+		return errors.New("okx private stream requires the api key, secret and passphrase")
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	err := conn.WriteJSON(WebsocketOp{
+		Op: "login",
+		Args: []WebsocketLogin{{
+			APIKey:     s.key,
+			Passphrase: s.passphrase,
+			Timestamp:  timestamp,
+			Sign:       sign(s.secret, timestamp, http.MethodGet, "/users/self/verify", ""),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(loginTimeout)); err != nil {
+		return err
+	}
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return errors.Wrap(err, "okx login error")
+		}
+
+		e, err := ParseEvent(message)
+		if err != nil {
+			continue
+		}
+
+		if event, ok := e.(*WebsocketEvent); ok && (event.Event == "login" || event.Event == "error") {
+			if err := event.Err(); err != nil {
+				return errors.Wrap(err, "okx login error")
+			}
+
+			log.Infof("okx private stream is logged in")
+			return nil
+		}
+	}
+}
+
+func (s *Stream) subscribe(conn *websocket.Conn, args []WebsocketArg) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	log.Infof("subscribing channels: %+v", args)
+	return s.writeJSON(conn, WebsocketOp{Op: "subscribe", Args: args})
+}
+
+func (s *Stream) writeJSON(conn *websocket.Conn, v interface{}) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	return conn.WriteJSON(v)
+}
+
+// resubscribe subscribes the channel again for a new snapshot
+func (s *Stream) resubscribe(conn *websocket.Conn, arg WebsocketArg) {
+	if err := s.writeJSON(conn, WebsocketOp{Op: "unsubscribe", Args: []WebsocketArg{arg}}); err != nil {
+		log.WithError(err).Error("unsubscribe error")
+		return
+	}
+
+	if err := s.subscribe(conn, []WebsocketArg{arg}); err != nil {
+		log.WithError(err).Error("subscribe error")
+	}
+}
+
+func (s *Stream) isClosed() bool {
+	s.ConnLock.Lock()
+	defer s.ConnLock.Unlock()
+	return s.closed
+}
+
+// ping sends the text ping, okx responds the text pong
+func (s *Stream) ping(ctx context.Context, conn *websocket.Conn, disconnect func(reason types.ConnectionReason)) {
+	pingTicker := time.NewTicker(pingInterval)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+
+		case <-ctx.Done():
+			log.Debug("ping worker stopped")
+			return
+
+		case <-pingTicker.C:
+			s.writeLock.Lock()
+			err := conn.WriteMessage(websocket.TextMessage, []byte("ping"))
+			s.writeLock.Unlock()
+
+			if err != nil {
+				log.WithError(err).Error("ping error")
+				disconnect(types.ConnectionReasonNetworkError)
+				return
+			}
+		}
+	}
+}
+
+// watchdog forces a reconnect when no message arrives within the stall timeout,
+// the pong messages are not counted, so a silently stalled feed is detected.
+func (s *Stream) watchdog(ctx context.Context, disconnect func(reason types.ConnectionReason)) {
+	interval := s.StallTimeout / 4
+	if interval <= 0 {
+		interval = s.StallTimeout
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			lastMessageTime := time.Unix(0, atomic.LoadInt64(&s.lastMessageTime))
+			if time.Since(lastMessageTime) > s.StallTimeout {
+				log.Warnf("no message is received since %s, the stream is stalled", lastMessageTime)
+				disconnect(types.ConnectionReasonStalled)
+				return
+			}
+		}
+	}
+}
+
+func (s *Stream) read(ctx context.Context, conn *websocket.Conn, disconnect func(reason types.ConnectionReason)) {
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+			log.WithError(err).Errorf("set read deadline error: %s", err.Error())
+		}
+
+		mt, message, err := conn.ReadMessage()
+		if err != nil {
+			// the connection is closed by Close or by the other workers of the connection
+			if ctx.Err() != nil {
+				return
+			}
+
+			reason := types.ConnectionReasonNetworkError
+			if _, ok := err.(*websocket.CloseError); ok {
+				reason = types.ConnectionReasonRemoteClosed
+			}
+
+			log.WithError(err).Errorf("websocket disconnected: %s", reason)
+			disconnect(reason)
+			return
+		}
+
+		// skip non-text messages and the pong messages
+		if mt != websocket.TextMessage || string(message) == "pong" {
+			continue
+		}
+
+		atomic.StoreInt64(&s.lastMessageTime, time.Now().UnixNano())
+
+		log.Debug(string(message))
+
+		s.dispatchLock.Lock()
+		s.handleMessage(ctx, conn, message)
+		s.dispatchLock.Unlock()
+	}
+}
+
+func (s *Stream) handleMessage(ctx context.Context, conn *websocket.Conn, message []byte) {
+	// the message of the replaced connection is dropped
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.EmitRawMessage(message)
+
+	e, err := ParseEvent(message)
+	if err != nil {
+		log.WithError(err).Errorf("websocket event parse error")
+		return
+	}
+
+	if event, ok := e.(*WebsocketEvent); ok {
+		if err := event.Err(); err != nil {
+			log.WithError(err).Errorf("okx websocket %s error", event.Event)
+		}
+
+		return
+	}
+
+	if e, ok := e.(*BookEvent); ok && !s.checkBookSequence(e) {
+		log.Warnf("okx %s book sequence is broken, resubscribing...", e.InstrumentID)
+		s.resubscribe(conn, WebsocketArg{Channel: channelBooks, InstrumentID: e.InstrumentID})
+		return
+	}
+
+	s.dispatchEvent(e)
+}
+
+// checkBookSequence checks if the update of the books channel follows the last sequence id
+func (s *Stream) checkBookSequence(e *BookEvent) bool {
+	for _, data := range e.Books {
+		if data.SeqID == 0 {
+			continue
+		}
+
+		if e.IsSnapshot() {
+			s.bookSeqIDs[e.InstrumentID] = data.SeqID
+			continue
+		}
+
+		lastSeqID, ok := s.bookSeqIDs[e.InstrumentID]
+		if !ok || data.PrevSeqID != lastSeqID {
+			delete(s.bookSeqIDs, e.InstrumentID)
+			return false
+		}
+
+		s.bookSeqIDs[e.InstrumentID] = data.SeqID
+	}
+
+	return true
+}
+
+// dispatchEvent emits the parsed event to the event callbacks
+func (s *Stream) dispatchEvent(e interface{}) {
+	switch e := e.(type) {
+
+	case *BookEvent:
+		s.EmitBookEvent(e)
+
+	case *CandleEvent:
+		s.EmitCandleEvent(e)
+
+	case *MarketTradeEvent:
+		s.EmitMarketTradeEvent(e)
+
+	case *TickerEvent:
+		s.EmitTickerEvent(e)
+
+	case *OrderEvent:
+		s.EmitOrderEvent(e)
+
+	case *AccountEvent:
+		s.EmitAccountEvent(e)
+	}
+}
+
+func (s *Stream) Close() error {
+	log.Infof("closing stream...")
+
+	s.ConnLock.Lock()
+	s.closed = true
+	disconnect := s.disconnect
+	s.ConnLock.Unlock()
+
+	if disconnect != nil {
+		disconnect(types.ConnectionReasonClosed)
+	}
+
+	return nil
+}
@@ -0,0 +1,331 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/pymba86/bingo/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testUpgrader = websocket.Upgrader{}
+
+// wsStandIn is a local stand-in of the okx websocket server, it accepts the login and the subscriptions
+// of each connection and calls serve after the channels are subscribed
+type wsStandIn struct {
+	t *testing.T
+
+	server *httptest.Server
+
+	mu            sync.Mutex
+	connections   map[string]int
+	subscriptions map[string][]WebsocketArg
+
+	// serve is called with the path and the number of the connection after the subscribe request
+	serve func(conn *websocket.Conn, path string, n int)
+}
+
+func newWsStandIn(t *testing.T, serve func(conn *websocket.Conn, path string, n int)) *wsStandIn {
+	s := &wsStandIn{
+		t:             t,
+		connections:   make(map[string]int),
+		subscriptions: make(map[string][]WebsocketArg),
+		serve:         serve,
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *wsStandIn) URL() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http")
+}
+
+func (s *wsStandIn) handle(w http.ResponseWriter, r *http.Request) {
+	conn, err := testUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.connections[r.URL.Path]++
+	n := s.connections[r.URL.Path]
+	s.mu.Unlock()
+
+	for {
+		var op struct {
+			Op   string          `json:"op"`
+			Args json.RawMessage `json:"args"`
+		}
+
+		if err := conn.ReadJSON(&op); err != nil {
+			return
+		}
+
+		switch op.Op {
+		case "login":
+			var args []WebsocketLogin
+			if err := json.Unmarshal(op.Args, &args); err != nil || len(args) != 1 {
+				s.t.Errorf("unexpected login args %s", op.Args)
+				return
+			}
+
+			login := args[0]
+			code := "0"
+			if login.APIKey != "key" || login.Passphrase != "passphrase" ||
+				login.Sign != sign("secret", login.Timestamp, http.MethodGet, "/users/self/verify", "") {
+				code = "60009"
+			}
+
+			_ = conn.WriteJSON(map[string]string{"event": "login", "code": code, "msg": ""})
+
+		case "subscribe":
+			var args []WebsocketArg
+			if err := json.Unmarshal(op.Args, &args); err != nil {
+				s.t.Errorf("unexpected subscribe args %s", op.Args)
+				return
+			}
+
+			s.mu.Lock()
+			s.subscriptions[r.URL.Path] = append(s.subscriptions[r.URL.Path], args...)
+			s.mu.Unlock()
+
+			for _, arg := range args {
+				_ = conn.WriteJSON(map[string]interface{}{"event": "subscribe", "arg": arg})
+			}
+
+			if s.serve != nil {
+				s.serve(conn, r.URL.Path, n)
+			}
+		}
+	}
+}
+
+func (s *wsStandIn) Subscriptions(path string) []WebsocketArg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]WebsocketArg(nil), s.subscriptions[path]...)
+}
+
+func (s *wsStandIn) Connections(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections[path]
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newTestStream(url, key, secret, passphrase string) *Stream {
+	stream := NewStream(key, secret, passphrase)
+	stream.WsBaseURL = url
+	stream.SetReconnectBackoff(types.Backoff{Initial: 10 * time.Millisecond, Max: 20 * time.Millisecond})
+	return stream
+}
+
+func TestStreamLoginAndSubscribe(t *testing.T) {
+	standIn := newWsStandIn(t, func(conn *websocket.Conn, path string, n int) {
+		_ = conn.WriteMessage(websocket.TextMessage, readFixture(t, "orders.json"))
+	})
+
+	stream := newTestStream(standIn.URL(), "key", "secret", "passphrase")
+
+	var mu sync.Mutex
+	var trades []types.Trade
+	var orders []types.Order
+	stream.OnTradeUpdate(func(trade types.Trade) {
+		mu.Lock()
+		trades = append(trades, trade)
+		mu.Unlock()
+	})
+	stream.OnOrderUpdate(func(order types.Order) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(trades) == 0 {
+			t.Error("the order update is emitted before the trade")
+		}
+
+		orders = append(orders, order)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := stream.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer stream.Close()
+
+	waitFor(t, "the order update", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(orders) == 1
+	})
+
+	subscriptions := standIn.Subscriptions(privateWsPath)
+	if len(subscriptions) != 2 || subscriptions[0].Channel != channelOrders || subscriptions[0].InstrumentType != "SPOT" ||
+		subscriptions[1].Channel != channelAccount {
+		t.Errorf("unexpected subscriptions %+v", subscriptions)
+	}
+}
+
+func TestStreamLoginError(t *testing.T) {
+	standIn := newWsStandIn(t, nil)
+
+	stream := newTestStream(standIn.URL(), "key", "wrong secret", "passphrase")
+	if err := stream.Connect(context.Background()); err == nil {
+		_ = stream.Close()
+		t.Fatal("expected the login error")
+	}
+}
+
+func TestStreamPublicEndpoints(t *testing.T) {
+	standIn := newWsStandIn(t, func(conn *websocket.Conn, path string, n int) {
+		if path == businessWsPath {
+			_ = conn.WriteMessage(websocket.TextMessage, readFixture(t, "candle.json"))
+		}
+	})
+
+	stream := newTestStream(standIn.URL(), "", "", "")
+	stream.SetPublicOnly()
+	stream.Subscribe(types.BookChannel, "BTCUSDT", types.SubscribeOptions{Depth: "5"})
+	stream.Subscribe(types.KLineChannel, "BTCUSDT", types.SubscribeOptions{Interval: "1h"})
+
+	kLines := make(chan types.KLine, 1)
+	stream.OnKLineClosed(func(kline types.KLine) {
+		kLines <- kline
+	})
+
+	if err := stream.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	defer stream.Close()
+
+	select {
+	case kline := <-kLines:
+		if kline.Symbol != "BTCUSDT" || kline.Interval != types.Interval1h {
+			t.Errorf("unexpected kline %+v", kline)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the kline")
+	}
+
+	public := standIn.Subscriptions(publicWsPath)
+	if len(public) != 1 || public[0].Channel != channelBooks5 || public[0].InstrumentID != "BTC-USDT" {
+		t.Errorf("unexpected public subscriptions %+v", public)
+	}
+
+	business := standIn.Subscriptions(businessWsPath)
+	if len(business) != 1 || business[0].Channel != "candle1H" {
+		t.Errorf("unexpected business subscriptions %+v", business)
+	}
+}
+
+func TestStreamReconnect(t *testing.T) {
+	standIn := newWsStandIn(t, func(conn *websocket.Conn, path string, n int) {
+		// the first connection is dropped after the subscription
+		if n == 1 {
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "restart"))
+			_ = conn.Close()
+			return
+		}
+
+		_ = conn.WriteMessage(websocket.TextMessage, readFixture(t, "account.json"))
+	})
+
+	stream := newTestStream(standIn.URL(), "key", "secret", "passphrase")
+
+	var mu sync.Mutex
+	var connectReasons, disconnectReasons []types.ConnectionReason
+	stream.OnConnect(func(reason types.ConnectionReason) {
+		mu.Lock()
+		connectReasons = append(connectReasons, reason)
+		mu.Unlock()
+	})
+	stream.OnDisconnect(func(reason types.ConnectionReason) {
+		mu.Lock()
+		disconnectReasons = append(disconnectReasons, reason)
+		mu.Unlock()
+	})
+
+	balances := make(chan types.BalanceMap, 1)
+	stream.OnBalanceUpdate(func(b types.BalanceMap) {
+		balances <- b
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := stream.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-balances:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the balance update of the reconnected stream")
+	}
+
+	if n := standIn.Connections(privateWsPath); n != 2 {
+		t.Errorf("connections = %d, expected 2", n)
+	}
+
+	// the reconnected stream logs in and subscribes the channels again
+	if n := len(standIn.Subscriptions(privateWsPath)); n != 4 {
+		t.Errorf("subscriptions = %d, expected 4", n)
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	expectedConnects := []types.ConnectionReason{types.ConnectionReasonInitial, types.ConnectionReasonReconnect}
+	if !equalReasons(connectReasons, expectedConnects) {
+		t.Errorf("connect reasons = %v, expected %v", connectReasons, expectedConnects)
+	}
+
+	expectedDisconnects := []types.ConnectionReason{types.ConnectionReasonRemoteClosed, types.ConnectionReasonClosed}
+	if !equalReasons(disconnectReasons, expectedDisconnects) {
+		t.Errorf("disconnect reasons = %v, expected %v", disconnectReasons, expectedDisconnects)
+	}
+}
+
+func equalReasons(a, b []types.ConnectionReason) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,341 @@
+package paper
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultFeeRate is the maker and the taker fee rate of the simulated fills, 0.1%
+const DefaultFeeRate = 0.001
+
+// Exchange simulates the order execution on the live market data of the source exchange.
+// The market data queries and the market data streams are served by the source exchange,
+// the orders, the balances and the user data stream are simulated locally.
+type Exchange struct {
+	types.Exchange
+
+	MakerFeeRate float64
+	TakerFeeRate float64
+
+	stream *Stream
+
+	mu          sync.Mutex
+	balances    types.BalanceMap
+	markets     types.MarketMap
+	orders      map[uint64]*simulatedOrder
+	books       map[string]*types.MutexOrderBook
+	quotes      map[string]quote
+	lastPrices  map[string]float64
+	lastOrderID uint64
+	lastTradeID int64
+}
+
+// New creates a paper trading exchange on the source exchange, starting with the given virtual balances
+func New(source types.Exchange, balances types.BalanceMap) *Exchange {
+	e := &Exchange{
+		Exchange:     source,
+		MakerFeeRate: DefaultFeeRate,
+		TakerFeeRate: DefaultFeeRate,
+		balances:     make(types.BalanceMap),
+		markets:      make(types.MarketMap),
+		orders:       make(map[uint64]*simulatedOrder),
+		books:        make(map[string]*types.MutexOrderBook),
+		quotes:       make(map[string]quote),
+		lastPrices:   make(map[string]float64),
+	}
+
+	for currency, balance := range balances {
+		balance.Currency = currency
+		e.balances[currency] = balance
+	}
+
+	e.stream = &Stream{exchange: e}
+	return e
+}
+
+// UserDataStream returns the simulated user data stream
+func (e *Exchange) UserDataStream() types.Stream {
+	return e.stream
+}
+
+func (e *Exchange) QueryMarkets(ctx context.Context) (types.MarketMap, error) {
+	markets, err := e.Exchange.QueryMarkets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.markets = markets
+	e.mu.Unlock()
+
+	return markets, nil
+}
+
+func (e *Exchange) QueryAccount(ctx context.Context) (*types.Account, error) {
+	balances, err := e.QueryAccountBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	account := types.NewAccount()
+	account.MakerFeeRate = fixedpoint.NewFromFloat(e.MakerFeeRate)
+	account.TakerFeeRate = fixedpoint.NewFromFloat(e.TakerFeeRate)
+	account.AccountType = "PAPER"
+	account.UpdateBalances(balances)
+	return account, nil
+}
+
+func (e *Exchange) QueryAccountBalances(ctx context.Context) (types.BalanceMap, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.balances.Copy(), nil
+}
+
+func (e *Exchange) QueryOpenOrders(ctx context.Context, symbol string) (orders []types.Order, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, o := range e.openOrders(symbol) {
+		orders = append(orders, o.order)
+	}
+
+	return orders, nil
+}
+
+// SubmitOrders places the orders on the simulator, the marketable orders are filled by the current best price
+func (e *Exchange) SubmitOrders(ctx context.Context, orders ...types.SubmitOrder) (createdOrders types.OrderSlice, err error) {
+	var submitErr types.SubmitOrdersError
+
+	for _, order := range orders {
+		createdOrder, err := e.submitOrder(ctx, order)
+		if err != nil {
+			submitErr.Failures = append(submitErr.Failures, types.SubmitOrderFailure{Order: order, Err: err})
+			continue
+		}
+
+		createdOrders = append(createdOrders, *createdOrder)
+	}
+
+	if len(submitErr.Failures) > 0 {
+		return createdOrders, &submitErr
+	}
+
+	return createdOrders, nil
+}
+
+func (e *Exchange) submitOrder(ctx context.Context, order types.SubmitOrder) (*types.Order, error) {
+	market, err := e.market(ctx, order.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Quantity <= 0 {
+		return nil, fmt.Errorf("invalid order quantity %f", order.Quantity)
+	}
+
+	switch order.Type {
+	case types.OrderTypeLimit, types.OrderTypeLimitMaker, types.OrderTypeIOCLimit, types.OrderTypeStopLimit:
+		if order.Price <= 0 {
+			return nil, fmt.Errorf("invalid order price %f", order.Price)
+		}
+	}
+
+	switch order.Type {
+	case types.OrderTypeStopLimit, types.OrderTypeStopMarket:
+		if order.StopPrice <= 0 {
+			return nil, fmt.Errorf("invalid order stop price %f", order.StopPrice)
+		}
+	}
+
+	var events []func()
+
+	e.mu.Lock()
+	o := &simulatedOrder{market: market}
+	o.lockPrice = lockPrice(order)
+
+	// the balance of the whole order is locked like the exchange does, the market buy order is paid at the fill
+	lockCurrency, lockAmount := o.lockedBalance(order.Side, order.Quantity)
+	if lockAmount > 0 {
+		if err := e.lockBalance(lockCurrency, lockAmount); err != nil {
+			e.mu.Unlock()
+			return nil, err
+		}
+	}
+
+	e.lastOrderID++
+	now := types.Time(time.Now())
+	o.order = types.Order{
+		SubmitOrder:  order,
+		Exchange:     e.Name(),
+		OrderID:      e.lastOrderID,
+		Status:       types.OrderStatusNew,
+		IsWorking:    true,
+		CreationTime: now,
+		UpdateTime:   now,
+	}
+	o.order.Market = market
+
+	switch order.ClientOrderId {
+	case "":
+		o.order.ClientOrderId = uuid.New().String()
+	case types.NoClientOrderID:
+		o.order.ClientOrderId = ""
+	}
+
+	e.orders[o.order.OrderID] = o
+	events = append(events, e.emitOrder(o))
+	if lockAmount > 0 {
+		events = append(events, e.emitBalances(lockCurrency))
+	}
+
+	// the order is matched on arrival as a taker
+	events = append(events, e.matchOrder(o, false)...)
+	createdOrder := o.order
+	e.mu.Unlock()
+
+	runEvents(events)
+	return &createdOrder, nil
+}
+
+func (e *Exchange) CancelOrders(ctx context.Context, orders ...types.Order) error {
+	var cancelErr types.CancelOrdersError
+	var failed bool
+	var events []func()
+
+	e.mu.Lock()
+	for _, order := range orders {
+		o, ok := e.orders[order.OrderID]
+		if !ok || !o.isOpen() {
+			failed = true
+			cancelErr.Results = append(cancelErr.Results, types.CancelOrderResult{Order: order, Err: fmt.Errorf("unknown order %d", order.OrderID)})
+			continue
+		}
+
+		events = append(events, e.closeOrder(o, types.OrderStatusCanceled)...)
+		cancelErr.Results = append(cancelErr.Results, types.CancelOrderResult{Order: o.order})
+	}
+	e.mu.Unlock()
+
+	runEvents(events)
+
+	if failed {
+		return &cancelErr
+	}
+
+	return nil
+}
+
+func (e *Exchange) market(ctx context.Context, symbol string) (types.Market, error) {
+	e.mu.Lock()
+	market, ok := e.markets[symbol]
+	loaded := len(e.markets) > 0
+	e.mu.Unlock()
+
+	if ok {
+		return market, nil
+	}
+
+	if !loaded {
+		markets, err := e.QueryMarkets(ctx)
+		if err != nil {
+			return market, err
+		}
+
+		if market, ok = markets[symbol]; ok {
+			return market, nil
+		}
+	}
+
+	return market, fmt.Errorf("market %s is not defined", symbol)
+}
+
+// openOrders returns the open orders of the symbol in the order of the submission
+func (e *Exchange) openOrders(symbol string) (orders []*simulatedOrder) {
+	for _, o := range e.orders {
+		if o.order.Symbol == symbol && o.isOpen() {
+			orders = append(orders, o)
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].order.OrderID < orders[j].order.OrderID
+	})
+
+	return orders
+}
+
+func (e *Exchange) lockBalance(currency string, amount float64) error {
+	balance := e.balance(currency)
+	locked := fixedpoint.NewFromFloat(amount)
+	if balance.Available < locked {
+		return fmt.Errorf("insufficient %s balance: available %f, required %f", currency, balance.Available.Float64(), amount)
+	}
+
+	balance.Available -= locked
+	balance.Locked += locked
+	e.balances[currency] = balance
+	return nil
+}
+
+func (e *Exchange) unlockBalance(currency string, amount float64) {
+	balance := e.balance(currency)
+	unlocked := fixedpoint.NewFromFloat(amount)
+	if unlocked > balance.Locked {
+		unlocked = balance.Locked
+	}
+
+	balance.Locked -= unlocked
+	balance.Available += unlocked
+	e.balances[currency] = balance
+}
+
+func (e *Exchange) balance(currency string) types.Balance {
+	balance, ok := e.balances[currency]
+	if !ok {
+		balance = types.Balance{Currency: currency}
+	}
+
+	return balance
+}
+
+// emitOrder returns the event of the current order state, the events are emitted after the lock is released
+func (e *Exchange) emitOrder(o *simulatedOrder) func() {
+	order := o.order
+	return func() {
+		e.stream.EmitOrderUpdate(order)
+	}
+}
+
+func (e *Exchange) emitBalances(currencies ...string) func() {
+	balances := make(types.BalanceMap)
+	for _, currency := range currencies {
+		if len(currency) > 0 {
+			balances[currency] = e.balance(currency)
+		}
+	}
+
+	return func() {
+		if len(balances) > 0 {
+			e.stream.EmitBalanceUpdate(balances)
+		}
+	}
+}
+
+func runEvents(events []func()) {
+	for _, event := range events {
+		event()
+	}
+}
+
+func logOrder(o *simulatedOrder, message string) {
+	log.Infof("paper trade: %s %s %s %s %f @ %f: %s", o.order.Symbol, o.order.Side, o.order.Type,
+		o.order.Status, o.order.Quantity, o.order.Price, message)
+}
@@ -0,0 +1,395 @@
+package paper
+
+import (
+	"fmt"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"math"
+	"time"
+)
+
+// the remaining quantity below it is treated as filled
+const quantityEpsilon = 1e-12
+
+// quote is the top of the book
+type quote struct {
+	bid, bidSize float64
+	ask, askSize float64
+}
+
+type simulatedOrder struct {
+	order  types.Order
+	market types.Market
+
+	// lockPrice is the price of locking the quote balance of the buy order, zero for the market order
+	lockPrice float64
+
+	// resting is true once the order is on the book, the fills after that are maker fills
+	resting bool
+
+	// triggered is true once the stop price of the stop order is reached
+	triggered bool
+}
+
+func lockPrice(order types.SubmitOrder) float64 {
+	switch order.Type {
+	case types.OrderTypeStopMarket:
+		return order.StopPrice
+	case types.OrderTypeMarket:
+		return 0
+	}
+
+	return order.Price
+}
+
+// lockedBalance returns the balance locked by the quantity of the order
+func (o *simulatedOrder) lockedBalance(side types.SideType, quantity float64) (currency string, amount float64) {
+	if side == types.SideTypeSell {
+		return o.market.BaseCurrency, quantity
+	}
+
+	return o.market.QuoteCurrency, quantity * o.lockPrice
+}
+
+func (o *simulatedOrder) remainingQuantity() float64 {
+	return o.order.Quantity - o.order.ExecutedQuantity
+}
+
+func (o *simulatedOrder) isOpen() bool {
+	return o.order.Status == types.OrderStatusNew || o.order.Status == types.OrderStatusPartiallyFilled
+}
+
+func (o *simulatedOrder) isStop() bool {
+	return o.order.Type == types.OrderTypeStopLimit || o.order.Type == types.OrderTypeStopMarket
+}
+
+// isMarket checks if the order is filled by any price, the stop market order is a market order once it's triggered
+func (o *simulatedOrder) isMarket() bool {
+	return o.order.Type == types.OrderTypeMarket || o.order.Type == types.OrderTypeStopMarket
+}
+
+// BindMarketData fills the orders by the order book, the book ticker and the market trades of the market data stream,
+// the symbols of the orders need to be subscribed with the book, the book ticker or the market trade channel,
+// wrap the stream with NewMarketDataStream to subscribe the book ticker of the symbols that are not.
+func (e *Exchange) BindMarketData(stream types.Stream) {
+	stream.OnBookSnapshot(func(book types.SliceOrderBook) {
+		e.handleBook(book, true)
+	})
+
+	stream.OnBookUpdate(func(book types.SliceOrderBook) {
+		e.handleBook(book, false)
+	})
+
+	stream.OnBookTickerUpdate(func(bookTicker types.BookTicker) {
+		e.updateQuote(bookTicker.Symbol, quote{
+			bid:     bookTicker.Buy.Float64(),
+			bidSize: bookTicker.BuySize.Float64(),
+			ask:     bookTicker.Sell.Float64(),
+			askSize: bookTicker.SellSize.Float64(),
+		})
+	})
+
+	stream.OnMarketTrade(e.handleMarketTrade)
+
+	stream.OnKLine(func(kline types.KLine) {
+		e.mu.Lock()
+		e.lastPrices[kline.Symbol] = kline.Close
+		e.mu.Unlock()
+	})
+}
+
+func (e *Exchange) handleBook(update types.SliceOrderBook, snapshot bool) {
+	e.mu.Lock()
+	book, ok := e.books[update.Symbol]
+	if !ok {
+		book = types.NewMutexOrderBook(update.Symbol)
+		e.books[update.Symbol] = book
+	}
+	e.mu.Unlock()
+
+	if snapshot {
+		book.Load(update)
+	} else {
+		book.Update(update)
+	}
+
+	bid, ask, ok := book.BestBidAndAsk()
+	if !ok {
+		return
+	}
+
+	e.updateQuote(update.Symbol, quote{
+		bid:     bid.Price.Float64(),
+		bidSize: bid.Volume.Float64(),
+		ask:     ask.Price.Float64(),
+		askSize: ask.Volume.Float64(),
+	})
+}
+
+// updateQuote matches the open orders of the symbol with the new top of the book
+func (e *Exchange) updateQuote(symbol string, q quote) {
+	var events []func()
+
+	e.mu.Lock()
+	e.quotes[symbol] = q
+	for _, o := range e.openOrders(symbol) {
+		events = append(events, e.matchOrder(o, true)...)
+	}
+	e.mu.Unlock()
+
+	runEvents(events)
+}
+
+// handleMarketTrade triggers the stop orders, and fills the resting limit orders that the trade price goes through
+func (e *Exchange) handleMarketTrade(trade types.Trade) {
+	var events []func()
+
+	e.mu.Lock()
+	e.lastPrices[trade.Symbol] = trade.Price
+
+	for _, o := range e.openOrders(trade.Symbol) {
+		if o.isStop() && !o.triggered {
+			if !stopReached(o, trade.Price, trade.Price) {
+				continue
+			}
+
+			o.triggered = true
+			o.resting = false
+			logOrder(o, fmt.Sprintf("stop price triggered by the trade price %f", trade.Price))
+		}
+
+		if o.isMarket() {
+			price := trade.Price
+			if q, ok := e.quotes[trade.Symbol]; ok {
+				price = q.bid
+				if o.order.Side == types.SideTypeBuy {
+					price = q.ask
+				}
+			}
+
+			events = append(events, e.fill(o, o.remainingQuantity(), price, false)...)
+			continue
+		}
+
+		if !o.resting {
+			continue
+		}
+
+		if o.order.Side == types.SideTypeBuy && trade.Price <= o.order.Price ||
+			o.order.Side == types.SideTypeSell && trade.Price >= o.order.Price {
+			events = append(events, e.fill(o, math.Min(o.remainingQuantity(), trade.Quantity), o.order.Price, true)...)
+		}
+	}
+	e.mu.Unlock()
+
+	runEvents(events)
+}
+
+// stopReached checks the stop price of the stop order, the buy stop is reached by the ask and the sell stop by the bid
+func stopReached(o *simulatedOrder, bid, ask float64) bool {
+	if o.order.Side == types.SideTypeBuy {
+		return ask > 0 && ask >= o.order.StopPrice
+	}
+
+	return bid > 0 && bid <= o.order.StopPrice
+}
+
+// matchOrder fills the order by the top of the book, isUpdate is false when the order just arrives
+func (e *Exchange) matchOrder(o *simulatedOrder, isUpdate bool) (events []func()) {
+	q, ok := e.quotes[o.order.Symbol]
+	if !ok {
+		// the market order can not wait for the book, fill it by the last price
+		if o.order.Type == types.OrderTypeMarket {
+			if price, ok := e.lastPrices[o.order.Symbol]; ok {
+				return e.fill(o, o.remainingQuantity(), price, false)
+			}
+
+			logOrder(o, "no market data to fill the market order")
+			return e.closeOrder(o, types.OrderStatusRejected)
+		}
+
+		if o.order.Type == types.OrderTypeIOCLimit {
+			return e.closeOrder(o, types.OrderStatusCanceled)
+		}
+
+		o.resting = true
+		return nil
+	}
+
+	if o.isStop() && !o.triggered {
+		if !stopReached(o, q.bid, q.ask) {
+			o.resting = true
+			return nil
+		}
+
+		// the triggered stop limit order is placed on the book as a new order
+		o.triggered = true
+		o.resting = false
+		logOrder(o, fmt.Sprintf("stop price triggered by the book %f/%f", q.bid, q.ask))
+	}
+
+	price, size := q.ask, q.askSize
+	if o.order.Side == types.SideTypeSell {
+		price, size = q.bid, q.bidSize
+	}
+
+	if o.isMarket() {
+		if price <= 0 {
+			return nil
+		}
+
+		return e.fill(o, o.remainingQuantity(), price, false)
+	}
+
+	marketable := price > 0 && (o.order.Side == types.SideTypeBuy && price <= o.order.Price ||
+		o.order.Side == types.SideTypeSell && price >= o.order.Price)
+
+	if !marketable {
+		if o.order.Type == types.OrderTypeIOCLimit && !isUpdate {
+			return e.closeOrder(o, types.OrderStatusCanceled)
+		}
+
+		o.resting = true
+		return nil
+	}
+
+	if o.resting {
+		// the book moves through the resting order, it's filled at its own price
+		return e.fill(o, math.Min(o.remainingQuantity(), sizeOrAll(size, o.remainingQuantity())), o.order.Price, true)
+	}
+
+	if o.order.Type == types.OrderTypeLimitMaker {
+		logOrder(o, "the limit maker order would immediately match")
+		return e.closeOrder(o, types.OrderStatusRejected)
+	}
+
+	events = e.fill(o, math.Min(o.remainingQuantity(), sizeOrAll(size, o.remainingQuantity())), price, false)
+
+	if o.isOpen() {
+		if o.order.Type == types.OrderTypeIOCLimit {
+			return append(events, e.closeOrder(o, types.OrderStatusCanceled)...)
+		}
+
+		o.resting = true
+	}
+
+	return events
+}
+
+// sizeOrAll returns the size of the book level, or the whole quantity if the size is unknown
+func sizeOrAll(size, quantity float64) float64 {
+	if size > 0 {
+		return size
+	}
+
+	return quantity
+}
+
+// fill executes the quantity of the order at the price, the fee is charged in the received currency
+func (e *Exchange) fill(o *simulatedOrder, quantity, price float64, isMaker bool) (events []func()) {
+	if quantity <= 0 || price <= 0 {
+		return nil
+	}
+
+	feeRate := e.TakerFeeRate
+	if isMaker {
+		feeRate = e.MakerFeeRate
+	}
+
+	market := o.market
+	quoteQuantity := quantity * price
+
+	var fee float64
+	var feeCurrency string
+
+	switch o.order.Side {
+	case types.SideTypeBuy:
+		quoteBalance := e.balance(market.QuoteCurrency)
+		released := fixedpoint.NewFromFloat(quantity * o.lockPrice)
+		cost := fixedpoint.NewFromFloat(quoteQuantity)
+		if quoteBalance.Available+released < cost {
+			logOrder(o, fmt.Sprintf("insufficient %s balance for the fill", market.QuoteCurrency))
+			return e.closeOrder(o, types.OrderStatusRejected)
+		}
+
+		if released > quoteBalance.Locked {
+			released = quoteBalance.Locked
+		}
+
+		quoteBalance.Locked -= released
+		quoteBalance.Available += released - cost
+		e.balances[market.QuoteCurrency] = quoteBalance
+
+		fee, feeCurrency = quantity*feeRate, market.BaseCurrency
+		baseBalance := e.balance(market.BaseCurrency)
+		baseBalance.Available += fixedpoint.NewFromFloat(quantity - fee)
+		e.balances[market.BaseCurrency] = baseBalance
+
+	case types.SideTypeSell:
+		baseBalance := e.balance(market.BaseCurrency)
+		sold := fixedpoint.NewFromFloat(quantity)
+		if sold > baseBalance.Locked {
+			sold = baseBalance.Locked
+		}
+
+		baseBalance.Locked -= sold
+		e.balances[market.BaseCurrency] = baseBalance
+
+		fee, feeCurrency = quoteQuantity*feeRate, market.QuoteCurrency
+		quoteBalance := e.balance(market.QuoteCurrency)
+		quoteBalance.Available += fixedpoint.NewFromFloat(quoteQuantity - fee)
+		e.balances[market.QuoteCurrency] = quoteBalance
+	}
+
+	now := time.Now()
+	e.lastTradeID++
+	trade := types.Trade{
+		ID:            e.lastTradeID,
+		OrderID:       o.order.OrderID,
+		Exchange:      o.order.Exchange,
+		Price:         price,
+		Quantity:      quantity,
+		QuoteQuantity: quoteQuantity,
+		Symbol:        o.order.Symbol,
+		Side:          o.order.Side,
+		IsBuyer:       o.order.Side == types.SideTypeBuy,
+		IsMaker:       isMaker,
+		Time:          types.Time(now),
+		Fee:           fee,
+		FeeCurrency:   feeCurrency,
+	}
+
+	o.order.ExecutedQuantity += quantity
+	o.order.UpdateTime = types.Time(now)
+	if o.remainingQuantity() <= quantityEpsilon {
+		o.order.Status = types.OrderStatusFilled
+		o.order.IsWorking = false
+		delete(e.orders, o.order.OrderID)
+	} else {
+		o.order.Status = types.OrderStatusPartiallyFilled
+	}
+
+	logOrder(o, fmt.Sprintf("filled %f @ %f", quantity, price))
+
+	return []func(){
+		func() { e.stream.EmitTradeUpdate(trade) },
+		e.emitOrder(o),
+		e.emitBalances(market.BaseCurrency, market.QuoteCurrency),
+	}
+}
+
+// closeOrder closes the order with the status and unlocks the balance of the remaining quantity
+func (e *Exchange) closeOrder(o *simulatedOrder, status types.OrderStatus) []func() {
+	currency, amount := o.lockedBalance(o.order.Side, o.remainingQuantity())
+	if amount > 0 {
+		e.unlockBalance(currency, amount)
+	}
+
+	o.order.Status = status
+	o.order.IsWorking = false
+	o.order.UpdateTime = types.Time(time.Now())
+	delete(e.orders, o.order.OrderID)
+	logOrder(o, "closed")
+
+	return []func(){e.emitOrder(o), e.emitBalances(currency)}
+}
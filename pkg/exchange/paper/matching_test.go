@@ -0,0 +1,294 @@
+package paper
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"github.com/pymba86/bingo/pkg/types"
+	"math"
+	"testing"
+)
+
+var testMarket = types.Market{
+	Symbol:          "BTCUSDT",
+	PricePrecision:  2,
+	VolumePrecision: 6,
+	BaseCurrency:    "BTC",
+	QuoteCurrency:   "USDT",
+	TickSize:        0.01,
+	StepSize:        0.000001,
+}
+
+// testSource is the source exchange of the market definitions
+type testSource struct {
+	types.Exchange
+}
+
+func (s *testSource) Name() types.ExchangeName {
+	return types.ExchangeBinance
+}
+
+func (s *testSource) QueryMarkets(ctx context.Context) (types.MarketMap, error) {
+	return types.MarketMap{testMarket.Symbol: testMarket}, nil
+}
+
+// testStream is the market data stream that is driven by the tests
+type testStream struct {
+	types.StandardStream
+}
+
+func (s *testStream) SetPublicOnly() {}
+
+func (s *testStream) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (s *testStream) Close() error {
+	return nil
+}
+
+// newTestExchange returns the paper exchange without the fees and the market data stream that drives it
+func newTestExchange() (*Exchange, *testStream) {
+	e := New(&testSource{}, types.BalanceMap{
+		"BTC":  {Available: fixedpoint.NewFromFloat(1.0)},
+		"USDT": {Available: fixedpoint.NewFromFloat(1000.0)},
+	})
+	e.MakerFeeRate = 0
+	e.TakerFeeRate = 0
+
+	stream := &testStream{}
+	e.BindMarketData(stream)
+	return e, stream
+}
+
+func pushTestBook(stream *testStream, bids, asks [][2]float64) {
+	book := types.SliceOrderBook{Symbol: testMarket.Symbol}
+	for _, level := range bids {
+		book.Bids = append(book.Bids, types.PriceVolume{Price: fixedpoint.NewFromFloat(level[0]), Volume: fixedpoint.NewFromFloat(level[1])})
+	}
+
+	for _, level := range asks {
+		book.Asks = append(book.Asks, types.PriceVolume{Price: fixedpoint.NewFromFloat(level[0]), Volume: fixedpoint.NewFromFloat(level[1])})
+	}
+
+	stream.EmitBookSnapshot(book)
+}
+
+func pushTestBookTicker(stream *testStream, bid, bidSize, ask, askSize float64) {
+	stream.EmitBookTickerUpdate(types.BookTicker{
+		Symbol:   testMarket.Symbol,
+		Buy:      fixedpoint.NewFromFloat(bid),
+		BuySize:  fixedpoint.NewFromFloat(bidSize),
+		Sell:     fixedpoint.NewFromFloat(ask),
+		SellSize: fixedpoint.NewFromFloat(askSize),
+	})
+}
+
+func submitTestOrder(t *testing.T, e *Exchange, order types.SubmitOrder) types.Order {
+	t.Helper()
+
+	order.Symbol = testMarket.Symbol
+	createdOrders, err := e.SubmitOrders(context.Background(), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return createdOrders[0]
+}
+
+// collectTrades returns the trades of the simulated user data stream
+func collectTrades(e *Exchange) *[]types.Trade {
+	var trades []types.Trade
+	e.UserDataStream().OnTradeUpdate(func(trade types.Trade) {
+		trades = append(trades, trade)
+	})
+
+	return &trades
+}
+
+func assertBalance(t *testing.T, e *Exchange, currency string, available, locked float64) {
+	t.Helper()
+
+	balances, err := e.QueryAccountBalances(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance := balances[currency]
+	if math.Abs(balance.Available.Float64()-available) > 1e-8 || math.Abs(balance.Locked.Float64()-locked) > 1e-8 {
+		t.Fatalf("expected %s available %f locked %f, got available %f locked %f",
+			currency, available, locked, balance.Available.Float64(), balance.Locked.Float64())
+	}
+}
+
+func TestExchange_RestingLimitOrderCrossed(t *testing.T) {
+	e, stream := newTestExchange()
+	trades := collectTrades(e)
+	pushTestBook(stream, [][2]float64{{99, 1}}, [][2]float64{{100, 2}})
+
+	// the buy limit below the best ask rests on the book
+	order := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeBuy, Type: types.OrderTypeLimit, Quantity: 1, Price: 98})
+	if order.Status != types.OrderStatusNew || len(*trades) != 0 {
+		t.Fatalf("expected the order to rest, got %+v and the trades %+v", order, *trades)
+	}
+
+	assertBalance(t, e, "USDT", 902, 98)
+
+	// the ask moves through the resting order, it's filled as a maker at its own price
+	pushTestBookTicker(stream, 97, 1, 97.5, 2)
+
+	if len(*trades) != 1 || (*trades)[0].Price != 98 || !(*trades)[0].IsMaker || (*trades)[0].OrderID != order.OrderID {
+		t.Fatalf("expected a maker fill at 98, got %+v", *trades)
+	}
+
+	assertBalance(t, e, "USDT", 902, 0)
+	assertBalance(t, e, "BTC", 2, 0)
+}
+
+func TestExchange_PartialFillsFromBookSize(t *testing.T) {
+	e, stream := newTestExchange()
+	trades := collectTrades(e)
+	pushTestBook(stream, [][2]float64{{99, 1}}, [][2]float64{{100, 0.4}, {102, 1}})
+
+	// the buy limit at 100 takes the 0.4 of the best ask and rests the remaining quantity
+	submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeBuy, Type: types.OrderTypeLimit, Quantity: 1, Price: 100})
+
+	openOrders, _ := e.QueryOpenOrders(context.Background(), testMarket.Symbol)
+	if len(openOrders) != 1 || openOrders[0].Status != types.OrderStatusPartiallyFilled || openOrders[0].ExecutedQuantity != 0.4 {
+		t.Fatalf("expected a partially filled order, got %+v", openOrders)
+	}
+
+	if len(*trades) != 1 || (*trades)[0].IsMaker {
+		t.Fatalf("expected a taker fill, got %+v", *trades)
+	}
+
+	// the resting order is filled by the size of the new ask level only
+	pushTestBookTicker(stream, 99, 1, 100, 0.3)
+
+	openOrders, _ = e.QueryOpenOrders(context.Background(), testMarket.Symbol)
+	if len(openOrders) != 1 || math.Abs(openOrders[0].ExecutedQuantity-0.7) > 1e-9 {
+		t.Fatalf("expected 0.7 to be executed, got %+v", openOrders)
+	}
+
+	if len(*trades) != 2 || (*trades)[1].Quantity != 0.3 || !(*trades)[1].IsMaker {
+		t.Fatalf("expected a maker fill of 0.3, got %+v", *trades)
+	}
+
+	assertBalance(t, e, "USDT", 900, 30)
+	assertBalance(t, e, "BTC", 1.7, 0)
+}
+
+func TestExchange_LimitMakerRejected(t *testing.T) {
+	e, stream := newTestExchange()
+	trades := collectTrades(e)
+	pushTestBook(stream, [][2]float64{{99, 1}}, [][2]float64{{100, 2}})
+
+	// the limit maker order would take the ask
+	order := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeBuy, Type: types.OrderTypeLimitMaker, Quantity: 1, Price: 101})
+	if order.Status != types.OrderStatusRejected || len(*trades) != 0 {
+		t.Fatalf("expected the order to be rejected, got %+v and the trades %+v", order, *trades)
+	}
+
+	assertBalance(t, e, "USDT", 1000, 0)
+
+	// the limit maker order below the ask rests
+	order = submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeBuy, Type: types.OrderTypeLimitMaker, Quantity: 1, Price: 99})
+	if order.Status != types.OrderStatusNew {
+		t.Fatalf("expected the order to rest, got %+v", order)
+	}
+}
+
+func TestExchange_IOCLimitCanceled(t *testing.T) {
+	e, stream := newTestExchange()
+	pushTestBook(stream, [][2]float64{{99, 1}}, [][2]float64{{100, 0.4}, {102, 1}})
+
+	// the remaining quantity of the IOC order is canceled instead of resting
+	order := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeBuy, Type: types.OrderTypeIOCLimit, Quantity: 1, Price: 100})
+	if order.Status != types.OrderStatusCanceled || order.ExecutedQuantity != 0.4 {
+		t.Fatalf("expected the order to be canceled after 0.4, got %+v", order)
+	}
+
+	// the IOC order that can not be filled is canceled
+	order = submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeBuy, Type: types.OrderTypeIOCLimit, Quantity: 1, Price: 98})
+	if order.Status != types.OrderStatusCanceled || order.ExecutedQuantity != 0 {
+		t.Fatalf("expected the order to be canceled, got %+v", order)
+	}
+
+	openOrders, _ := e.QueryOpenOrders(context.Background(), testMarket.Symbol)
+	if len(openOrders) != 0 {
+		t.Fatalf("expected no open orders, got %+v", openOrders)
+	}
+
+	assertBalance(t, e, "USDT", 960, 0)
+	assertBalance(t, e, "BTC", 1.4, 0)
+}
+
+func TestExchange_StopTriggeredByTrade(t *testing.T) {
+	e, stream := newTestExchange()
+	trades := collectTrades(e)
+
+	// there is no book, the stop market order waits for the market trades
+	order := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeSell, Type: types.OrderTypeStopMarket, Quantity: 0.5, StopPrice: 95})
+	assertBalance(t, e, "BTC", 0.5, 0.5)
+
+	stream.EmitMarketTrade(types.Trade{Symbol: testMarket.Symbol, Price: 96, Quantity: 1})
+	if len(*trades) != 0 {
+		t.Fatalf("expected the stop order to wait, got %+v", *trades)
+	}
+
+	stream.EmitMarketTrade(types.Trade{Symbol: testMarket.Symbol, Price: 94, Quantity: 1})
+	if len(*trades) != 1 || (*trades)[0].OrderID != order.OrderID || (*trades)[0].Price != 94 {
+		t.Fatalf("expected the stop market order to be filled at 94, got %+v", *trades)
+	}
+
+	assertBalance(t, e, "BTC", 0.5, 0)
+	assertBalance(t, e, "USDT", 1047, 0)
+}
+
+func TestExchange_StopTriggeredByBook(t *testing.T) {
+	e, stream := newTestExchange()
+	trades := collectTrades(e)
+	pushTestBook(stream, [][2]float64{{99, 1}}, [][2]float64{{100, 1}})
+
+	order := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeSell, Type: types.OrderTypeStopLimit, Quantity: 0.5, StopPrice: 90, Price: 89})
+	if order.Status != types.OrderStatusNew {
+		t.Fatalf("expected the stop order to wait, got %+v", order)
+	}
+
+	// the bid reaches the stop price, the triggered limit order takes the bid
+	pushTestBookTicker(stream, 89.5, 1, 90, 1)
+
+	if len(*trades) != 1 || (*trades)[0].OrderID != order.OrderID || (*trades)[0].Price != 89.5 || (*trades)[0].IsMaker {
+		t.Fatalf("expected the stop limit order to be filled at 89.5, got %+v", *trades)
+	}
+
+	assertBalance(t, e, "BTC", 0.5, 0)
+	assertBalance(t, e, "USDT", 1044.75, 0)
+}
+
+func TestExchange_CancelReleasesLockedBalance(t *testing.T) {
+	e, stream := newTestExchange()
+	pushTestBook(stream, [][2]float64{{99, 1}}, [][2]float64{{100, 1}})
+
+	sell := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeSell, Type: types.OrderTypeLimit, Quantity: 1, Price: 110})
+	buy := submitTestOrder(t, e, types.SubmitOrder{Side: types.SideTypeBuy, Type: types.OrderTypeLimit, Quantity: 2, Price: 90})
+	assertBalance(t, e, "BTC", 0, 1)
+	assertBalance(t, e, "USDT", 820, 180)
+
+	if err := e.CancelOrders(context.Background(), sell, buy); err != nil {
+		t.Fatal(err)
+	}
+
+	assertBalance(t, e, "BTC", 1, 0)
+	assertBalance(t, e, "USDT", 1000, 0)
+
+	// the closed order can not be canceled again
+	if err := e.CancelOrders(context.Background(), sell); err == nil {
+		t.Fatal("expected the cancel of the closed order to fail")
+	}
+
+	// the orders can not lock more than the available balance
+	if _, err := e.SubmitOrders(context.Background(), types.SubmitOrder{Symbol: testMarket.Symbol, Side: types.SideTypeSell,
+		Type: types.OrderTypeLimit, Quantity: 2, Price: 110}); err == nil {
+		t.Fatal("expected the order to be rejected by the balance")
+	}
+}
@@ -0,0 +1,115 @@
+package paper
+
+import (
+	"context"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// Stream is the simulated user data stream, the order, trade and balance updates are emitted by the exchange simulator
+type Stream struct {
+	types.StandardStream
+
+	exchange *Exchange
+}
+
+func (s *Stream) SetPublicOnly() {}
+
+// Connect emits the start and the balance snapshot like a connected user data stream
+func (s *Stream) Connect(ctx context.Context) error {
+	s.EmitConnect(types.ConnectionReasonInitial)
+	s.EmitStart()
+
+	balances, err := s.exchange.QueryAccountBalances(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.EmitBalanceSnapshot(balances)
+	return nil
+}
+
+func (s *Stream) Close() error {
+	s.EmitDisconnect(types.ConnectionReasonClosed)
+	return nil
+}
+
+// liveSubscriber is implemented by the streams that can subscribe the channels on the current connection
+type liveSubscriber interface {
+	SubscribeLive(subscriptions ...types.Subscription) error
+}
+
+// MarketDataStream subscribes the book ticker of the symbols that are subscribed by the other channels,
+// since the simulated orders are matched by the top of the book, e.g. the orders of a strategy
+// that only subscribes the klines would never be filled as the makers.
+type MarketDataStream struct {
+	types.Stream
+
+	mu sync.Mutex
+
+	// quoted are the symbols with the book ticker or the book subscribed
+	quoted map[string]struct{}
+}
+
+func NewMarketDataStream(stream types.Stream) *MarketDataStream {
+	return &MarketDataStream{Stream: stream, quoted: make(map[string]struct{})}
+}
+
+func (s *MarketDataStream) Subscribe(channel types.Channel, symbol string, options types.SubscribeOptions) {
+	s.Stream.Subscribe(channel, symbol, options)
+
+	if s.quote(channel, symbol) {
+		log.Infof("paper trade: subscribing the %s book ticker for matching the orders", symbol)
+		s.Stream.Subscribe(types.BookTickerChannel, symbol, types.SubscribeOptions{})
+	}
+}
+
+// SubscribeLive subscribes the channels on the connection of the source stream, with the book tickers of the symbols
+func (s *MarketDataStream) SubscribeLive(subscriptions ...types.Subscription) error {
+	subscriber, ok := s.Stream.(liveSubscriber)
+	if !ok {
+		log.Warnf("paper trade: the market data stream can not subscribe on the connection, please restart to subscribe %+v", subscriptions)
+		return nil
+	}
+
+	// the book tickers added by Subscribe are only subscribed on the next connection,
+	// so they are subscribed with the channels of the symbols here too
+	var quoted = map[string]struct{}{}
+	for _, subscription := range subscriptions {
+		switch subscription.Channel {
+		case types.BookChannel, types.BookTickerChannel:
+			quoted[subscription.Symbol] = struct{}{}
+		}
+	}
+
+	for _, subscription := range subscriptions {
+		if _, ok := quoted[subscription.Symbol]; ok {
+			continue
+		}
+
+		quoted[subscription.Symbol] = struct{}{}
+		subscriptions = append(subscriptions, types.Subscription{Channel: types.BookTickerChannel, Symbol: subscription.Symbol})
+	}
+
+	return subscriber.SubscribeLive(subscriptions...)
+}
+
+// quote returns true if the book ticker of the symbol needs to be subscribed for the channel
+func (s *MarketDataStream) quote(channel types.Channel, symbol string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.quoted[symbol]; ok {
+		return false
+	}
+
+	s.quoted[symbol] = struct{}{}
+
+	switch channel {
+	case types.BookChannel, types.BookTickerChannel:
+		return false
+	}
+
+	return true
+}
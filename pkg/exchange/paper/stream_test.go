@@ -0,0 +1,63 @@
+package paper
+
+import (
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+)
+
+// liveTestStream records the subscriptions on the current connection
+type liveTestStream struct {
+	testStream
+
+	live []types.Subscription
+}
+
+func (s *liveTestStream) SubscribeLive(subscriptions ...types.Subscription) error {
+	s.live = append(s.live, subscriptions...)
+	return nil
+}
+
+func countSubscriptions(subscriptions []types.Subscription, channel types.Channel, symbol string) (n int) {
+	for _, subscription := range subscriptions {
+		if subscription.Channel == channel && subscription.Symbol == symbol {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestMarketDataStream_SubscribesBookTicker(t *testing.T) {
+	source := &liveTestStream{}
+	stream := NewMarketDataStream(source)
+
+	stream.Subscribe(types.KLineChannel, "BTCUSDT", types.SubscribeOptions{Interval: "1m"})
+	stream.Subscribe(types.KLineChannel, "BTCUSDT", types.SubscribeOptions{Interval: "1h"})
+	stream.Subscribe(types.BookChannel, "ETHUSDT", types.SubscribeOptions{})
+	stream.Subscribe(types.KLineChannel, "ETHUSDT", types.SubscribeOptions{Interval: "1m"})
+
+	if n := countSubscriptions(source.Subscriptions, types.BookTickerChannel, "BTCUSDT"); n != 1 {
+		t.Errorf("expected the BTCUSDT book ticker to be subscribed once, got %d", n)
+	}
+
+	if n := countSubscriptions(source.Subscriptions, types.BookTickerChannel, "ETHUSDT"); n != 0 {
+		t.Errorf("expected the ETHUSDT book to be used, got %d book ticker subscriptions", n)
+	}
+
+	if err := stream.SubscribeLive(
+		types.Subscription{Channel: types.KLineChannel, Symbol: "BNBUSDT", Options: types.SubscribeOptions{Interval: "1m"}},
+		types.Subscription{Channel: types.MarketTradeChannel, Symbol: "BNBUSDT"},
+		types.Subscription{Channel: types.BookTickerChannel, Symbol: "ADAUSDT"},
+		types.Subscription{Channel: types.KLineChannel, Symbol: "ADAUSDT", Options: types.SubscribeOptions{Interval: "1m"}},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := countSubscriptions(source.live, types.BookTickerChannel, "BNBUSDT"); n != 1 {
+		t.Errorf("expected the BNBUSDT book ticker to be subscribed live once, got %d", n)
+	}
+
+	if n := countSubscriptions(source.live, types.BookTickerChannel, "ADAUSDT"); n != 1 {
+		t.Errorf("expected the ADAUSDT book ticker to be subscribed live once, got %d", n)
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	"time"
+)
+
+type DepositService struct {
+	DB *sqlx.DB
+}
+
+func NewDepositService(db *sqlx.DB) *DepositService {
+	return &DepositService{db}
+}
+
+const insertDepositSQL = `
+			INSERT INTO deposits (exchange, asset, address, amount, txn_id, network, status, time)
+			VALUES (:exchange, :asset, :address, :amount, :txn_id, :network, :status, :time)`
+
+// Sync syncs the deposit records of the exchange since the oldest pending deposit,
+// the deposits are matched by the transaction id and their statuses are updated.
+func (s *DepositService) Sync(ctx context.Context, ex types.Exchange, startTime time.Time) error {
+	transferService, ok := ex.(types.ExchangeTransferService)
+	if !ok {
+		return nil
+	}
+
+	history := &transferHistory{
+		DB:            s.DB,
+		table:         "deposits",
+		finalStatuses: types.DepositFinalStatuses,
+		match:         "exchange = :exchange AND txn_id = :txn_id",
+		update:        "amount = :amount, address = :address, network = :network, status = :status",
+		insert:        insertDepositSQL,
+	}
+
+	return history.sync(ctx, ex.Name(), startTime, func(ctx context.Context, since, until time.Time) (records []transferRecord, err error) {
+		deposits, err := transferService.QueryDepositHistory(ctx, "", since, until)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, deposit := range deposits {
+			records = append(records, deposit)
+		}
+
+		return records, nil
+	})
+}
+
+func (s *DepositService) QueryLast(ex types.ExchangeName, limit int) ([]types.Deposit, error) {
+	sql := "SELECT * FROM deposits WHERE exchange = :exchange ORDER BY time DESC LIMIT :limit"
+	rows, err := s.DB.NamedQuery(sql, map[string]interface{}{
+		"exchange": ex,
+		"limit":    limit,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "query last deposit error")
+	}
+
+	defer rows.Close()
+
+	return s.scanRows(rows)
+}
+
+func (s *DepositService) Query(ex types.ExchangeName, asset string) ([]types.Deposit, error) {
+	sql := "SELECT * FROM deposits WHERE exchange = :exchange AND asset = :asset ORDER BY time ASC"
+	rows, err := s.DB.NamedQuery(sql, map[string]interface{}{
+		"exchange": ex,
+		"asset":    asset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return s.scanRows(rows)
+}
+
+func (s *DepositService) scanRows(rows *sqlx.Rows) (deposits []types.Deposit, err error) {
+	for rows.Next() {
+		var deposit types.Deposit
+		if err := rows.StructScan(&deposit); err != nil {
+			return deposits, err
+		}
+
+		deposits = append(deposits, deposit)
+	}
+
+	return deposits, rows.Err()
+}
+
+func (s *DepositService) Insert(deposit types.Deposit) error {
+	_, err := s.DB.NamedExec(insertDepositSQL, deposit)
+	return err
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+type MarginService struct {
+	DB *sqlx.DB
+}
+
+func NewMarginService(db *sqlx.DB) *MarginService {
+	return &MarginService{db}
+}
+
+// Sync syncs the loan, repay and interest records of the given asset since the last synced record
+func (s *MarginService) Sync(ctx context.Context, exchange types.Exchange, asset string, startTime time.Time) error {
+	marginExchange, ok := exchange.(types.MarginExchange)
+	if !ok {
+		return nil
+	}
+
+	marginSettings := marginExchange.GetMarginSettings()
+	if !marginSettings.IsMargin {
+		return nil
+	}
+
+	borrowRepayService, ok := exchange.(types.MarginBorrowRepayService)
+	if !ok {
+		return errors.Errorf("exchange %s does not support the margin borrow repay service", exchange.Name())
+	}
+
+	isolatedSymbol := marginSettings.IsolatedMarginSymbol
+
+	if err := s.syncLoans(ctx, borrowRepayService, exchange.Name(), asset, isolatedSymbol, startTime); err != nil {
+		return err
+	}
+
+	if err := s.syncRepays(ctx, borrowRepayService, exchange.Name(), asset, isolatedSymbol, startTime); err != nil {
+		return err
+	}
+
+	return s.syncInterests(ctx, borrowRepayService, exchange.Name(), asset, isolatedSymbol, startTime)
+}
+
+func (s *MarginService) syncLoans(ctx context.Context, service types.MarginBorrowRepayService, ex types.ExchangeName, asset, isolatedSymbol string, startTime time.Time) error {
+	lastTime, err := s.queryLastTime("margin_loans", ex, asset, isolatedSymbol)
+	if err != nil {
+		return err
+	}
+
+	if lastTime != nil && lastTime.After(startTime) {
+		startTime = lastTime.Add(time.Millisecond)
+	}
+
+	records, err := service.QueryLoanHistory(ctx, asset, &startTime, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		log.Infof("inserting margin loan: %s", record.String())
+		if err := s.InsertLoan(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MarginService) syncRepays(ctx context.Context, service types.MarginBorrowRepayService, ex types.ExchangeName, asset, isolatedSymbol string, startTime time.Time) error {
+	lastTime, err := s.queryLastTime("margin_repays", ex, asset, isolatedSymbol)
+	if err != nil {
+		return err
+	}
+
+	if lastTime != nil && lastTime.After(startTime) {
+		startTime = lastTime.Add(time.Millisecond)
+	}
+
+	records, err := service.QueryRepayHistory(ctx, asset, &startTime, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		log.Infof("inserting margin repay: %s", record.String())
+		if err := s.InsertRepay(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MarginService) syncInterests(ctx context.Context, service types.MarginBorrowRepayService, ex types.ExchangeName, asset, isolatedSymbol string, startTime time.Time) error {
+	lastTime, err := s.queryLastTime("margin_interests", ex, asset, isolatedSymbol)
+	if err != nil {
+		return err
+	}
+
+	if lastTime != nil && lastTime.After(startTime) {
+		startTime = lastTime.Add(time.Millisecond)
+	}
+
+	records, err := service.QueryInterestHistory(ctx, asset, &startTime, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		log.Infof("inserting margin interest: %s", record.String())
+		if err := s.InsertInterest(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryLastTime returns the time of the last record in the given margin table, nil is returned if there is no record
+func (s *MarginService) queryLastTime(table string, ex types.ExchangeName, asset, isolatedSymbol string) (*time.Time, error) {
+	sql := "SELECT time FROM " + table + " WHERE exchange = :exchange AND asset = :asset AND isolated_symbol = :isolated_symbol ORDER BY time DESC LIMIT 1"
+	rows, err := s.DB.NamedQuery(sql, map[string]interface{}{
+		"exchange":        ex,
+		"asset":           asset,
+		"isolated_symbol": isolatedSymbol,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "query last %s record error", table)
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var lastTime types.Time
+	if err := rows.Scan(&lastTime); err != nil {
+		return nil, err
+	}
+
+	t := lastTime.Time()
+	return &t, nil
+}
+
+func (s *MarginService) QueryLoans(ex types.ExchangeName, asset string) (records []types.MarginLoanRecord, err error) {
+	err = s.DB.Select(&records, "SELECT * FROM margin_loans WHERE exchange = ? AND asset = ? ORDER BY time ASC", ex, asset)
+	return records, err
+}
+
+func (s *MarginService) QueryRepays(ex types.ExchangeName, asset string) (records []types.MarginRepayRecord, err error) {
+	err = s.DB.Select(&records, "SELECT * FROM margin_repays WHERE exchange = ? AND asset = ? ORDER BY time ASC", ex, asset)
+	return records, err
+}
+
+func (s *MarginService) QueryInterests(ex types.ExchangeName, asset string) (records []types.MarginInterest, err error) {
+	err = s.DB.Select(&records, "SELECT * FROM margin_interests WHERE exchange = ? AND asset = ? ORDER BY time ASC", ex, asset)
+	return records, err
+}
+
+func (s *MarginService) InsertLoan(record types.MarginLoanRecord) error {
+	_, err := s.DB.NamedExec(`
+			INSERT INTO margin_loans (transaction_id, exchange, asset, principal, time, isolated_symbol)
+			VALUES (:transaction_id, :exchange, :asset, :principal, :time, :isolated_symbol)`,
+		record)
+	return err
+}
+
+func (s *MarginService) InsertRepay(record types.MarginRepayRecord) error {
+	_, err := s.DB.NamedExec(`
+			INSERT INTO margin_repays (transaction_id, exchange, asset, principal, interest, time, isolated_symbol)
+			VALUES (:transaction_id, :exchange, :asset, :principal, :interest, :time, :isolated_symbol)`,
+		record)
+	return err
+}
+
+func (s *MarginService) InsertInterest(record types.MarginInterest) error {
+	_, err := s.DB.NamedExec(`
+			INSERT INTO margin_interests (exchange, asset, principal, interest, interest_rate, time, isolated_symbol)
+			VALUES (:exchange, :asset, :principal, :interest, :interest_rate, :time, :isolated_symbol)`,
+		record)
+	return err
+}
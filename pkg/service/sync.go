@@ -7,7 +7,10 @@ import (
 )
 
 type SyncService struct {
-	TradeService *TradeService
+	TradeService    *TradeService
+	MarginService   *MarginService
+	WithdrawService *WithdrawService
+	DepositService  *DepositService
 }
 
 func (s *SyncService) SyncSessionSymbols(ctx context.Context, exchange types.Exchange,
@@ -21,3 +24,37 @@ func (s *SyncService) SyncSessionSymbols(ctx context.Context, exchange types.Exc
 
 	return nil
 }
+
+// SyncMarginHistory syncs the loan, repay and interest records of the margin assets
+func (s *SyncService) SyncMarginHistory(ctx context.Context, exchange types.Exchange,
+	startTime time.Time, assets ...string) error {
+
+	if s.MarginService == nil {
+		return nil
+	}
+
+	for _, asset := range assets {
+		if err := s.MarginService.Sync(ctx, exchange, asset, startTime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SyncTransferHistory syncs the deposit and withdraw records of the exchange
+func (s *SyncService) SyncTransferHistory(ctx context.Context, exchange types.Exchange, startTime time.Time) error {
+	if s.WithdrawService != nil {
+		if err := s.WithdrawService.Sync(ctx, exchange, startTime); err != nil {
+			return err
+		}
+	}
+
+	if s.DepositService != nil {
+		if err := s.DepositService.Sync(ctx, exchange, startTime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -20,6 +20,13 @@ type QueryTradesOptions struct {
 	Symbol   string
 	LastGID  int64
 
+	// Strategy filters the trades marked by the strategy tag, which is the strategy id of a strategy
+	// without the instance id, or a single instance with "<strategy>:<instance>"
+	Strategy string
+
+	// AllInstances also matches the trades of all the instances of the strategy id given by Strategy
+	AllInstances bool
+
 	// ASC or DESC
 	Ordering string
 	Limit    int
@@ -160,8 +167,11 @@ func (s *TradeService) Query(options QueryTradesOptions) ([]types.Trade, error)
 	log.Info(sql)
 
 	args := map[string]interface{}{
-		"exchange": options.Exchange,
-		"symbol":   options.Symbol,
+		"exchange":        options.Exchange,
+		"symbol":          options.Symbol,
+		"gid":             options.LastGID,
+		"strategy":        options.Strategy,
+		"strategy_prefix": escapeLikePattern(options.Strategy) + ":%",
 	}
 	rows, err := s.DB.NamedQuery(sql, args)
 	if err != nil {
@@ -237,6 +247,14 @@ func (s *TradeService) UpdatePnL(ctx context.Context, id int64, pnl float64) err
 
 }
 
+// likePatternEscaper escapes the wildcards of the LIKE patterns with '!', since the backslash
+// is not the default escape character of sqlite and it's disabled by the NO_BACKSLASH_ESCAPES mode of mysql
+var likePatternEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func escapeLikePattern(s string) string {
+	return likePatternEscaper.Replace(s)
+}
+
 func queryTradesSQL(options QueryTradesOptions) string {
 	ordering := "ASC"
 	switch v := strings.ToUpper(options.Ordering); v {
@@ -254,6 +272,14 @@ func queryTradesSQL(options QueryTradesOptions) string {
 		where = append(where, `symbol = :symbol`)
 	}
 
+	if len(options.Strategy) > 0 {
+		if options.AllInstances {
+			where = append(where, `(strategy = :strategy OR strategy LIKE :strategy_prefix ESCAPE '!')`)
+		} else {
+			where = append(where, `strategy = :strategy`)
+		}
+	}
+
 	if options.LastGID > 0 {
 		switch ordering {
 		case "ASC":
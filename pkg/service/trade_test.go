@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pymba86/bingo/migrations"
+	"github.com/pymba86/bingo/pkg/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscapeLikePattern(t *testing.T) {
+	tests := map[string]string{
+		"grid":          "grid",
+		"grid_a":        "grid!_a",
+		"grid_a:BTC-US": "grid!_a:BTC-US",
+		"100%!":         "100!%!!",
+	}
+
+	for s, expected := range tests {
+		if escaped := escapeLikePattern(s); escaped != expected {
+			t.Errorf("escapeLikePattern(%q) = %q, expected %q", s, escaped, expected)
+		}
+	}
+}
+
+func TestQueryTradesSQL_Strategy(t *testing.T) {
+	// a bare strategy tag matches its own trades only, not the trades of the instances
+	sql := queryTradesSQL(QueryTradesOptions{Strategy: "grid_a"})
+	if strings.Contains(sql, "LIKE") || !strings.Contains(sql, "strategy = :strategy") {
+		t.Fatalf("expected the strategy tag to be matched exactly: %s", sql)
+	}
+
+	sql = queryTradesSQL(QueryTradesOptions{Strategy: "grid_a", AllInstances: true, Ordering: "DESC", Limit: 10})
+
+	if !strings.Contains(sql, `strategy LIKE :strategy_prefix ESCAPE '!'`) {
+		t.Fatalf("expected the strategy prefix to be escaped: %s", sql)
+	}
+
+	if !strings.HasSuffix(sql, "ORDER BY gid DESC LIMIT 10") {
+		t.Fatalf("unexpected ordering: %s", sql)
+	}
+}
+
+func TestTradeService_QueryStrategy(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// each connection of the memory database is a new database
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	if err := migrations.Up(ctx, db, "sqlite3"); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewTradeService(db)
+	for i, tag := range []string{"grid", "grid:btc", "grid:eth", "grid_a"} {
+		trade := types.Trade{
+			ID:            int64(i + 1),
+			Exchange:      types.ExchangeBinance,
+			Symbol:        "BTCUSDT",
+			Side:          types.SideTypeBuy,
+			Price:         100,
+			Quantity:      1,
+			QuoteQuantity: 100,
+			Time:          types.Time(time.Date(2021, time.October, 20, 0, 0, i, 0, time.UTC)),
+		}
+
+		if err := s.Insert(trade); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Mark(ctx, trade.ID, tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		options  QueryTradesOptions
+		expected int
+	}{
+		{options: QueryTradesOptions{Strategy: "grid"}, expected: 1},
+		{options: QueryTradesOptions{Strategy: "grid:btc"}, expected: 1},
+		{options: QueryTradesOptions{Strategy: "grid", AllInstances: true}, expected: 3},
+	}
+
+	for _, test := range tests {
+		trades, err := s.Query(test.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(trades) != test.expected {
+			t.Errorf("query %+v: expected %d trades, got %d", test.options, test.expected, len(trades))
+		}
+	}
+}
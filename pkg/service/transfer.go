@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// transferRecord is a deposit or a withdraw record of the exchange
+type transferRecord interface {
+	String() string
+}
+
+// transferHistory is the table of the deposit or the withdraw records, the records are matched by the exchange id,
+// so the unfinished records are synced again until their statuses are final.
+type transferHistory struct {
+	DB *sqlx.DB
+
+	table         string
+	finalStatuses []string
+
+	// match is the condition of the stored record with the same exchange id as the named record
+	match string
+
+	// update sets the columns of the matched record, insert inserts the new record
+	update, insert string
+}
+
+// sync upserts the records of the exchange since the oldest unfinished record, or since the last record if all are finished
+func (h *transferHistory) sync(ctx context.Context, ex types.ExchangeName, startTime time.Time,
+	query func(ctx context.Context, since, until time.Time) ([]transferRecord, error)) error {
+
+	since, err := h.queryStartTime(ex)
+	if err != nil {
+		return err
+	}
+
+	if since != nil {
+		startTime = *since
+	}
+
+	records, err := query(ctx, startTime, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := h.upsert(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryStartTime returns the time of the oldest unfinished record, or the time of the last record if all are finished.
+// The records without the status are synced before the status column is added, they were inserted when finished.
+func (h *transferHistory) queryStartTime(ex types.ExchangeName) (*time.Time, error) {
+	var args = map[string]interface{}{
+		"exchange": ex,
+	}
+
+	var params []string
+	for i, status := range h.finalStatuses {
+		param := fmt.Sprintf("status%d", i)
+		params = append(params, ":"+param)
+		args[param] = status
+	}
+
+	unfinished := "exchange = :exchange AND status <> ''"
+	if len(params) > 0 {
+		unfinished += " AND status NOT IN (" + strings.Join(params, ", ") + ")"
+	}
+
+	t, err := h.queryTime(unfinished+" ORDER BY time ASC LIMIT 1", args)
+	if err != nil || t != nil {
+		return t, err
+	}
+
+	return h.queryTime("exchange = :exchange ORDER BY time DESC LIMIT 1", args)
+}
+
+func (h *transferHistory) queryTime(condition string, args map[string]interface{}) (*time.Time, error) {
+	rows, err := h.DB.NamedQuery("SELECT time FROM "+h.table+" WHERE "+condition, args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "query %s start time error", h.table)
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var t types.Time
+	if err := rows.Scan(&t); err != nil {
+		return nil, err
+	}
+
+	tt := t.Time()
+	return &tt, nil
+}
+
+// upsert updates the stored record with the same exchange id, or inserts the record
+func (h *transferHistory) upsert(record transferRecord) error {
+	rows, err := h.DB.NamedQuery("SELECT gid FROM "+h.table+" WHERE "+h.match+" LIMIT 1", record)
+	if err != nil {
+		return errors.Wrapf(err, "query %s record error", h.table)
+	}
+
+	exists := rows.Next()
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		log.Debugf("updating %s", record.String())
+		_, err = h.DB.NamedExec("UPDATE "+h.table+" SET "+h.update+" WHERE "+h.match, record)
+		return err
+	}
+
+	log.Infof("inserting %s", record.String())
+	_, err = h.DB.NamedExec(h.insert, record)
+	return err
+}
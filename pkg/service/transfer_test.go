@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/pymba86/bingo/migrations"
+	"github.com/pymba86/bingo/pkg/types"
+	"testing"
+	"time"
+)
+
+type transferTestExchange struct {
+	types.Exchange
+
+	deposits  []types.Deposit
+	withdraws []types.Withdraw
+
+	// since is the start time of the last history query
+	since time.Time
+}
+
+func (e *transferTestExchange) Name() types.ExchangeName {
+	return types.ExchangeBinance
+}
+
+func (e *transferTestExchange) QueryDepositHistory(ctx context.Context, asset string, since, until time.Time) ([]types.Deposit, error) {
+	e.since = since
+	return e.deposits, nil
+}
+
+func (e *transferTestExchange) QueryWithdrawHistory(ctx context.Context, asset string, since, until time.Time) ([]types.Withdraw, error) {
+	e.since = since
+	return e.withdraws, nil
+}
+
+func newTransferTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each connection of the memory database is a new database
+	db.SetMaxOpenConns(1)
+
+	if err := migrations.Up(context.Background(), db, "sqlite3"); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestWithdrawService_SyncUpdatesPendingWithdraws(t *testing.T) {
+	db := newTransferTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	s := NewWithdrawService(db)
+	pendingTime := time.Date(2021, time.October, 20, 0, 0, 0, 0, time.UTC)
+	exchange := &transferTestExchange{
+		withdraws: []types.Withdraw{
+			{Exchange: types.ExchangeBinance, ID: "1", Asset: "BTC", Amount: 0.1, Status: "processing", ApplyTime: types.Time(pendingTime)},
+			{Exchange: types.ExchangeBinance, ID: "2", Asset: "BTC", Amount: 0.2, Status: "completed", TransactionID: "tx2",
+				ApplyTime: types.Time(pendingTime.Add(time.Hour))},
+		},
+	}
+
+	if err := s.Sync(ctx, exchange, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the pending withdraw is synced before it has the transaction id
+	withdraws, err := s.Query(types.ExchangeBinance, "BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(withdraws) != 2 || withdraws[0].ID != "1" || withdraws[0].Status != "processing" {
+		t.Fatalf("unexpected withdraws: %+v", withdraws)
+	}
+
+	exchange.withdraws[0].Status = "completed"
+	exchange.withdraws[0].TransactionID = "tx1"
+
+	if err := s.Sync(ctx, exchange, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the sync starts from the pending withdraw, not from the last one
+	if !exchange.since.Equal(pendingTime) {
+		t.Errorf("expected the sync to start from the pending withdraw at %s, got %s", pendingTime, exchange.since)
+	}
+
+	withdraws, err = s.Query(types.ExchangeBinance, "BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(withdraws) != 2 || withdraws[0].Status != "completed" || withdraws[0].TransactionID != "tx1" {
+		t.Fatalf("expected the pending withdraw to be updated, got %+v", withdraws)
+	}
+
+	// all withdraws are finished, the sync starts from the last one
+	if err := s.Sync(ctx, exchange, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !exchange.since.Equal(pendingTime.Add(time.Hour)) {
+		t.Errorf("expected the sync to start from the last withdraw, got %s", exchange.since)
+	}
+}
+
+func TestWithdrawService_SyncMatchesUnidentifiedWithdraws(t *testing.T) {
+	db := newTransferTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	s := NewWithdrawService(db)
+	applyTime := types.Time(time.Date(2021, time.October, 20, 0, 0, 0, 0, time.UTC))
+
+	// the withdraw is stored without the withdraw id and the status
+	if err := s.Insert(types.Withdraw{Exchange: types.ExchangeBinance, Asset: "BTC", Amount: 0.1, TransactionID: "tx1", ApplyTime: applyTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	exchange := &transferTestExchange{
+		withdraws: []types.Withdraw{
+			{Exchange: types.ExchangeBinance, ID: "1", Asset: "BTC", Amount: 0.1, Status: "completed", TransactionID: "tx1", ApplyTime: applyTime},
+		},
+	}
+
+	if err := s.Sync(ctx, exchange, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	withdraws, err := s.Query(types.ExchangeBinance, "BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(withdraws) != 1 || withdraws[0].ID != "1" || withdraws[0].Status != "completed" {
+		t.Fatalf("expected the stored withdraw to be matched by the transaction id, got %+v", withdraws)
+	}
+}
+
+func TestDepositService_SyncUpdatesPendingDeposits(t *testing.T) {
+	db := newTransferTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	s := NewDepositService(db)
+	exchange := &transferTestExchange{
+		deposits: []types.Deposit{
+			{Exchange: types.ExchangeBinance, Asset: "BTC", Amount: 0.1, TransactionID: "tx1", Status: "pending",
+				Time: types.Time(time.Date(2021, time.October, 20, 0, 0, 0, 0, time.UTC))},
+		},
+	}
+
+	if err := s.Sync(ctx, exchange, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	exchange.deposits[0].Status = "success"
+
+	if err := s.Sync(ctx, exchange, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	deposits, err := s.Query(types.ExchangeBinance, "BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deposits) != 1 || deposits[0].Status != "success" {
+		t.Fatalf("expected the pending deposit to be updated, got %+v", deposits)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	"time"
+)
+
+type WithdrawService struct {
+	DB *sqlx.DB
+}
+
+func NewWithdrawService(db *sqlx.DB) *WithdrawService {
+	return &WithdrawService{db}
+}
+
+const insertWithdrawSQL = `
+			INSERT INTO withdraws (exchange, withdraw_id, asset, network, address, amount, txn_id, txn_fee, txn_fee_currency, status, time)
+			VALUES (:exchange, :withdraw_id, :asset, :network, :address, :amount, :txn_id, :txn_fee, :txn_fee_currency, :status, :time)`
+
+// Sync syncs the withdraw records of the exchange since the oldest unfinished withdraw,
+// the withdraws are matched by the withdraw id and their statuses and transaction ids are updated.
+func (s *WithdrawService) Sync(ctx context.Context, ex types.Exchange, startTime time.Time) error {
+	transferService, ok := ex.(types.ExchangeTransferService)
+	if !ok {
+		return nil
+	}
+
+	// the withdraws synced before the withdraw id column is added are matched by the transaction id
+	history := &transferHistory{
+		DB:            s.DB,
+		table:         "withdraws",
+		finalStatuses: types.WithdrawFinalStatuses,
+		match: "exchange = :exchange AND (withdraw_id = :withdraw_id AND withdraw_id <> ''" +
+			" OR withdraw_id = '' AND txn_id = :txn_id AND txn_id <> '')",
+		update: "withdraw_id = :withdraw_id, txn_id = :txn_id, txn_fee = :txn_fee, txn_fee_currency = :txn_fee_currency," +
+			" network = :network, status = :status",
+		insert: insertWithdrawSQL,
+	}
+
+	return history.sync(ctx, ex.Name(), startTime, func(ctx context.Context, since, until time.Time) (records []transferRecord, err error) {
+		withdraws, err := transferService.QueryWithdrawHistory(ctx, "", since, until)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, withdraw := range withdraws {
+			// the withdraw can not be matched without the exchange id, it's synced once it's sent to the network
+			if len(withdraw.ID) == 0 && len(withdraw.TransactionID) == 0 {
+				continue
+			}
+
+			records = append(records, withdraw)
+		}
+
+		return records, nil
+	})
+}
+
+func (s *WithdrawService) QueryLast(ex types.ExchangeName, limit int) ([]types.Withdraw, error) {
+	sql := "SELECT * FROM withdraws WHERE exchange = :exchange ORDER BY time DESC LIMIT :limit"
+	rows, err := s.DB.NamedQuery(sql, map[string]interface{}{
+		"exchange": ex,
+		"limit":    limit,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "query last withdraw error")
+	}
+
+	defer rows.Close()
+
+	return s.scanRows(rows)
+}
+
+func (s *WithdrawService) Query(ex types.ExchangeName, asset string) ([]types.Withdraw, error) {
+	sql := "SELECT * FROM withdraws WHERE exchange = :exchange AND asset = :asset ORDER BY time ASC"
+	rows, err := s.DB.NamedQuery(sql, map[string]interface{}{
+		"exchange": ex,
+		"asset":    asset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return s.scanRows(rows)
+}
+
+func (s *WithdrawService) scanRows(rows *sqlx.Rows) (withdraws []types.Withdraw, err error) {
+	for rows.Next() {
+		var withdraw types.Withdraw
+		if err := rows.StructScan(&withdraw); err != nil {
+			return withdraws, err
+		}
+
+		withdraws = append(withdraws, withdraw)
+	}
+
+	return withdraws, rows.Err()
+}
+
+func (s *WithdrawService) Insert(withdraw types.Withdraw) error {
+	_, err := s.DB.NamedExec(insertWithdrawSQL, withdraw)
+	return err
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/pymba86/bingo/pkg/types"
+	"time"
+)
+
+// the reason column is a VARCHAR(256)
+const maxWithdrawalRequestReasonLength = 256
+
+// WithdrawalRequestService persists the withdrawal requests of the session withdrawers
+type WithdrawalRequestService struct {
+	DB *sqlx.DB
+}
+
+func NewWithdrawalRequestService(db *sqlx.DB) *WithdrawalRequestService {
+	return &WithdrawalRequestService{db}
+}
+
+func (s *WithdrawalRequestService) Insert(request types.WithdrawalRequest) error {
+	if len(request.Reason) > maxWithdrawalRequestReasonLength {
+		request.Reason = request.Reason[:maxWithdrawalRequestReasonLength]
+	}
+
+	_, err := s.DB.NamedExec(`
+			INSERT INTO withdrawal_requests (exchange, withdraw_order_id, asset, amount, address, network, status, reason, time)
+			VALUES (:exchange, :withdraw_order_id, :asset, :amount, :address, :network, :status, :reason, :time)`,
+		request)
+	return err
+}
+
+// QuerySince returns the withdrawal requests of the asset since the given time
+func (s *WithdrawalRequestService) QuerySince(ex types.ExchangeName, asset string, since time.Time) ([]types.WithdrawalRequest, error) {
+	sql := "SELECT * FROM withdrawal_requests WHERE exchange = :exchange AND asset = :asset AND time >= :since ORDER BY time ASC"
+	rows, err := s.DB.NamedQuery(sql, map[string]interface{}{
+		"exchange": ex,
+		"asset":    asset,
+		"since":    since,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "query withdrawal requests error")
+	}
+
+	defer rows.Close()
+
+	var requests []types.WithdrawalRequest
+	for rows.Next() {
+		var request types.WithdrawalRequest
+		if err := rows.StructScan(&request); err != nil {
+			return requests, err
+		}
+
+		requests = append(requests, request)
+	}
+
+	return requests, rows.Err()
+}
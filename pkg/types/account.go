@@ -27,6 +27,9 @@ type Account struct {
 
 	TotalAccountValue fixedpoint.Value `json:"totalAccountValue,omitempty"`
 
+	// MarginLevel is the margin level of the margin account, it's zero for the spot account
+	MarginLevel fixedpoint.Value `json:"marginLevel,omitempty"`
+
 	balances BalanceMap
 }
 
@@ -131,13 +134,34 @@ func (a *Account) UpdateBalances(balances BalanceMap) {
 	}
 }
 
+// updateBalancesFromStream updates the balances from the stream events,
+// the stream events don't carry the liabilities of the margin account, so the known liabilities are kept.
+func (a *Account) updateBalancesFromStream(balances BalanceMap) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.balances == nil {
+		a.balances = make(BalanceMap)
+	}
+
+	for _, balance := range balances {
+		if current, ok := a.balances[balance.Currency]; ok && balance.Borrowed == 0 && balance.Interest == 0 {
+			balance.Borrowed = current.Borrowed
+			balance.Interest = current.Interest
+		}
+
+		balance.NetAsset = balance.Net()
+		a.balances[balance.Currency] = balance
+	}
+}
+
 func printBalanceUpdate(balances BalanceMap) {
 	logrus.Infof("balance update: %+v", balances)
 }
 
 func (a *Account) BindStream(stream Stream) {
-	stream.OnBalanceUpdate(a.UpdateBalances)
-	stream.OnBalanceSnapshot(a.UpdateBalances)
+	stream.OnBalanceUpdate(a.updateBalancesFromStream)
+	stream.OnBalanceSnapshot(a.updateBalancesFromStream)
 	if debugBalance {
 		stream.OnBalanceUpdate(printBalanceUpdate)
 	}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff is the delay policy of the retries, the delay grows exponentially from Initial up to Max
+type Backoff struct {
+	Initial    time.Duration `json:"initial,omitempty" yaml:"initial,omitempty"`
+	Max        time.Duration `json:"max,omitempty" yaml:"max,omitempty"`
+	Multiplier float64       `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+
+	// Jitter randomizes the delay by the given fraction, e.g. 0.2 means ±20%
+	Jitter float64 `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+}
+
+var DefaultBackoff = Backoff{
+	Initial:    time.Second,
+	Max:        time.Minute,
+	Multiplier: 2.0,
+	Jitter:     0.2,
+}
+
+// Duration returns the delay before the given attempt, the first attempt is 0
+func (b Backoff) Duration(attempt int) time.Duration {
+	if b.Initial <= 0 {
+		b.Initial = DefaultBackoff.Initial
+	}
+
+	if b.Max <= 0 {
+		b.Max = DefaultBackoff.Max
+	}
+
+	if b.Multiplier < 1.0 {
+		b.Multiplier = DefaultBackoff.Multiplier
+	}
+
+	delay := float64(b.Initial) * math.Pow(b.Multiplier, float64(attempt))
+	if delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(delay)
+}
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDuration(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+
+	for attempt, delay := range expected {
+		if got := b.Duration(attempt); got != delay {
+			t.Errorf("attempt %d: delay = %s, expected %s", attempt, got, delay)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 2, Jitter: 0.2}
+
+	for i := 0; i < 100; i++ {
+		if got := b.Duration(1); got < 1600*time.Millisecond || got > 2400*time.Millisecond {
+			t.Fatalf("delay %s is out of the jitter range", got)
+		}
+	}
+}
+
+func TestBackoffDefaults(t *testing.T) {
+	if got := (Backoff{}).Duration(0); got != DefaultBackoff.Initial {
+		t.Errorf("delay = %s, expected the default initial delay %s", got, DefaultBackoff.Initial)
+	}
+}
@@ -0,0 +1,30 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+type Deposit struct {
+	GID           int64        `json:"gid" db:"gid"`
+	Exchange      ExchangeName `json:"exchange" db:"exchange"`
+	Time          Time         `json:"time" db:"time"`
+	Amount        float64      `json:"amount" db:"amount"`
+	Asset         string       `json:"asset" db:"asset"`
+	Address       string       `json:"address" db:"address"`
+	AddressTag    string       `json:"addressTag"`
+	TransactionID string       `json:"transactionID" db:"txn_id"`
+	Network       string       `json:"network" db:"network"`
+	Status        string       `json:"status" db:"status"`
+}
+
+func (d Deposit) String() string {
+	return fmt.Sprintf("deposit %s %f from %s at %s", d.Asset, d.Amount, d.Address, d.Time.Time())
+}
+
+func (d Deposit) EffectiveTime() time.Time {
+	return d.Time.Time()
+}
+
+// DepositFinalStatuses are the statuses of the deposits that won't change anymore
+var DepositFinalStatuses = []string{"success"}
@@ -0,0 +1,30 @@
+package types
+
+// EndpointExchange is an exchange whose REST and websocket endpoints can be switched,
+// e.g. to the testnet or to a local mock server.
+type EndpointExchange interface {
+	UseTestnet()
+	UseEndpoints(restBaseURL, wsBaseURL string)
+	GetEndpointSettings() EndpointSettings
+}
+
+type EndpointSettings struct {
+	IsTestnet bool
+
+	// RestBaseURL and WsBaseURL override the default (or the testnet) endpoints when they are not empty
+	RestBaseURL string
+	WsBaseURL   string
+}
+
+func (s *EndpointSettings) UseTestnet() {
+	s.IsTestnet = true
+}
+
+func (s *EndpointSettings) UseEndpoints(restBaseURL, wsBaseURL string) {
+	s.RestBaseURL = restBaseURL
+	s.WsBaseURL = wsBaseURL
+}
+
+func (s EndpointSettings) GetEndpointSettings() EndpointSettings {
+	return s
+}
@@ -5,7 +5,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
-	"strings"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
 	"time"
 )
 
@@ -27,29 +27,29 @@ func (n *ExchangeName) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	switch s {
-	case "binance":
-		*n = ExchangeName(s)
-		return nil
-
+	r, ok := LookupExchange(s)
+	if !ok {
+		return fmt.Errorf("unknown or unsupported exchange name: %s, valid names are: %v", s, SupportedExchanges)
 	}
 
-	return fmt.Errorf("unknown or unsupported exchange name: %s, valid names are: max, binance, ftx", s)
+	*n = r.Name
+	return nil
 }
 
+// the exchanges linked into the default build, they are registered by their packages
 const (
 	ExchangeBinance = ExchangeName("binance")
+	ExchangeOKX     = ExchangeName("okx")
 )
 
-var SupportedExchanges = []ExchangeName{"binance"}
-
+// ValidExchangeName returns the registered exchange of the name or the alias
 func ValidExchangeName(a string) (ExchangeName, error) {
-	switch strings.ToLower(a) {
-	case "binance", "bn":
-		return ExchangeBinance, nil
+	r, ok := LookupExchange(a)
+	if !ok {
+		return "", fmt.Errorf("invalid exchange name: %s", a)
 	}
 
-	return "", fmt.Errorf("invalid exchange name: %s", a)
+	return r.Name, nil
 }
 
 type Exchange interface {
@@ -73,6 +73,34 @@ type ExchangeTradeService interface {
 	CancelOrders(ctx context.Context, orders ...Order) error
 }
 
+// ExchangeOrderQueryService queries a single order and its trades
+type ExchangeOrderQueryService interface {
+	QueryOrder(ctx context.Context, q OrderQuery) (*Order, error)
+	QueryOrderTrades(ctx context.Context, q OrderQuery) ([]Trade, error)
+}
+
+// ExchangeOrderAmendService replaces an open order with a new order
+type ExchangeOrderAmendService interface {
+	CancelReplaceOrder(ctx context.Context, order Order, replacement SubmitOrder) (*Order, error)
+}
+
+// ExchangeOCOOrderService places the native OCO orders, the created orders share the same OrderListID
+type ExchangeOCOOrderService interface {
+	SubmitOCOOrder(ctx context.Context, order SubmitOCOOrder) (OrderSlice, error)
+}
+
+// RateLimitedExchange throttles its own requests by the rate limits of the exchange
+type RateLimitedExchange interface {
+	IsRateLimited() bool
+}
+
+// ExchangeErrorClassifier tells the errors that are worth retrying, the request is rejected
+// for a temporary reason and the same request could succeed later, e.g. a timestamp error
+// caused by the clock drift.
+type ExchangeErrorClassifier interface {
+	IsTransientError(err error) bool
+}
+
 type ExchangeMarketDataService interface {
 	NewStream() Stream
 
@@ -96,3 +124,12 @@ type ExchangeTradeHistoryService interface {
 	QueryTrades(ctx context.Context, symbol string, options *TradeQueryOptions) ([]Trade, error)
 	QueryClosedOrders(ctx context.Context, symbol string, since, until time.Time, lastOrderID uint64) (orders []Order, err error)
 }
+
+type ExchangeTransferService interface {
+	QueryDepositHistory(ctx context.Context, asset string, since, until time.Time) (allDeposits []Deposit, err error)
+	QueryWithdrawHistory(ctx context.Context, asset string, since, until time.Time) (allWithdraws []Withdraw, err error)
+}
+
+type ExchangeWithdrawalService interface {
+	Withdrawal(ctx context.Context, asset string, amount fixedpoint.Value, address string, options *WithdrawalOptions) error
+}
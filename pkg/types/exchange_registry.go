@@ -0,0 +1,198 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CredentialField is a credential of the exchange api, the value is the key of the session config
+type CredentialField string
+
+const (
+	CredentialKey        = CredentialField("key")
+	CredentialSecret     = CredentialField("secret")
+	CredentialPassphrase = CredentialField("passphrase")
+	CredentialSubAccount = CredentialField("subAccount")
+)
+
+// ExchangeCredentials are the api credentials passed to the exchange constructor
+type ExchangeCredentials struct {
+	Key        string
+	Secret     string
+	Passphrase string
+	SubAccount string
+}
+
+// Get returns the value of the credential field
+func (c ExchangeCredentials) Get(field CredentialField) string {
+	switch field {
+	case CredentialKey:
+		return c.Key
+	case CredentialSecret:
+		return c.Secret
+	case CredentialPassphrase:
+		return c.Passphrase
+	case CredentialSubAccount:
+		return c.SubAccount
+	}
+
+	return ""
+}
+
+// Set sets the value of the credential field
+func (c *ExchangeCredentials) Set(field CredentialField, value string) {
+	switch field {
+	case CredentialKey:
+		c.Key = value
+	case CredentialSecret:
+		c.Secret = value
+	case CredentialPassphrase:
+		c.Passphrase = value
+	case CredentialSubAccount:
+		c.SubAccount = value
+	}
+}
+
+// ExchangeCapability is a feature of the exchange that the session config can enable
+type ExchangeCapability string
+
+const (
+	// ExchangeCapabilityMargin is the cross and the isolated margin account, see MarginExchange
+	ExchangeCapabilityMargin = ExchangeCapability("margin")
+
+	// ExchangeCapabilityFutures is the futures account, see FuturesExchange
+	ExchangeCapabilityFutures = ExchangeCapability("futures")
+
+	// ExchangeCapabilityEndpoints is the testnet and the custom endpoints, see EndpointExchange
+	ExchangeCapabilityEndpoints = ExchangeCapability("endpoints")
+
+	ExchangeCapabilityTradeHistory = ExchangeCapability("tradeHistory")
+	ExchangeCapabilityTransfer     = ExchangeCapability("transfer")
+	ExchangeCapabilityWithdrawal   = ExchangeCapability("withdrawal")
+)
+
+// ExchangeConstructor creates the exchange with the api credentials, the credentials are empty for the public only sessions
+type ExchangeConstructor func(credentials ExchangeCredentials) (Exchange, error)
+
+// ExchangeRegistration describes an exchange linked into the build
+type ExchangeRegistration struct {
+	Name    ExchangeName
+	Aliases []string
+
+	New ExchangeConstructor
+
+	// CredentialFields are the credentials the exchange uses, RequiredCredentials must be set for the private api
+	CredentialFields    []CredentialField
+	RequiredCredentials []CredentialField
+
+	Capabilities []ExchangeCapability
+}
+
+func (r *ExchangeRegistration) HasCapability(capability ExchangeCapability) bool {
+	for _, c := range r.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *ExchangeRegistration) UsesCredential(field CredentialField) bool {
+	for _, f := range r.CredentialFields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateCredentials checks that the required credentials are set
+func (r *ExchangeRegistration) ValidateCredentials(credentials ExchangeCredentials) error {
+	var missing []string
+	for _, field := range r.RequiredCredentials {
+		if len(credentials.Get(field)) == 0 {
+			missing = append(missing, string(field))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("empty %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+var exchangeRegistryLock sync.RWMutex
+var exchangeRegistry = make(map[ExchangeName]*ExchangeRegistration)
+
+// SupportedExchanges are the names of the registered exchanges, sorted by name
+var SupportedExchanges []ExchangeName
+
+// RegisterExchange links the exchange into the build, it's called by the exchange packages at init(),
+// the same way the strategies are registered by engine.RegisterStrategy
+func RegisterExchange(r ExchangeRegistration) {
+	if len(r.Name) == 0 {
+		panic(fmt.Errorf("exchange registration without a name"))
+	}
+
+	if r.New == nil {
+		panic(fmt.Errorf("exchange %s is registered without a constructor", r.Name))
+	}
+
+	exchangeRegistryLock.Lock()
+	defer exchangeRegistryLock.Unlock()
+
+	for _, name := range append([]string{r.Name.String()}, r.Aliases...) {
+		if existing := lookupExchange(name); existing != nil {
+			panic(fmt.Errorf("exchange %s: name %s is already registered by exchange %s", r.Name, name, existing.Name))
+		}
+	}
+
+	exchangeRegistry[r.Name] = &r
+
+	SupportedExchanges = append(SupportedExchanges, r.Name)
+	sort.Slice(SupportedExchanges, func(i, j int) bool {
+		return SupportedExchanges[i] < SupportedExchanges[j]
+	})
+}
+
+// LookupExchange finds the registered exchange by the name or the alias, the name is case-insensitive
+func LookupExchange(name string) (*ExchangeRegistration, bool) {
+	exchangeRegistryLock.RLock()
+	defer exchangeRegistryLock.RUnlock()
+
+	r := lookupExchange(name)
+	return r, r != nil
+}
+
+func lookupExchange(name string) *ExchangeRegistration {
+	for _, r := range exchangeRegistry {
+		if strings.EqualFold(r.Name.String(), name) {
+			return r
+		}
+
+		for _, alias := range r.Aliases {
+			if strings.EqualFold(alias, name) {
+				return r
+			}
+		}
+	}
+
+	return nil
+}
+
+// RegisteredExchanges returns the registered exchanges sorted by name
+func RegisteredExchanges() (registrations []ExchangeRegistration) {
+	exchangeRegistryLock.RLock()
+	defer exchangeRegistryLock.RUnlock()
+
+	for _, name := range SupportedExchanges {
+		registrations = append(registrations, *exchangeRegistry[name])
+	}
+
+	return registrations
+}
@@ -0,0 +1,84 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"time"
+)
+
+type FuturesExchange interface {
+	UseFutures()
+	GetFuturesSettings() FuturesSettings
+
+	QueryPositions(ctx context.Context, symbols ...string) ([]FuturesPosition, error)
+	SetLeverage(ctx context.Context, symbol string, leverage int) error
+	SetMarginType(ctx context.Context, symbol string, marginType MarginType) error
+	QueryFundingRateHistory(ctx context.Context, symbol string, since, until time.Time) ([]FundingRate, error)
+}
+
+type FuturesSettings struct {
+	IsFutures bool
+}
+
+func (s *FuturesSettings) UseFutures() {
+	s.IsFutures = true
+}
+
+func (s FuturesSettings) GetFuturesSettings() FuturesSettings {
+	return s
+}
+
+type MarginType string
+
+const (
+	MarginTypeIsolated MarginType = "ISOLATED"
+	MarginTypeCrossed  MarginType = "CROSSED"
+)
+
+// FuturesPosition is a derivative position, a short position has a negative Base
+type FuturesPosition struct {
+	Symbol       string       `json:"symbol"`
+	PositionSide PositionSide `json:"positionSide"`
+
+	Base             fixedpoint.Value `json:"base"`
+	EntryPrice       fixedpoint.Value `json:"entryPrice"`
+	MarkPrice        fixedpoint.Value `json:"markPrice,omitempty"`
+	LiquidationPrice fixedpoint.Value `json:"liquidationPrice,omitempty"`
+	UnrealizedPnL    fixedpoint.Value `json:"unrealizedPnL"`
+
+	Leverage       int              `json:"leverage,omitempty"`
+	MarginType     MarginType       `json:"marginType"`
+	IsolatedMargin fixedpoint.Value `json:"isolatedMargin,omitempty"`
+
+	UpdateTime Time `json:"updateTime"`
+}
+
+func (p FuturesPosition) String() string {
+	return fmt.Sprintf("FUTURES POSITION %s %s %s: base = %f, entry price = %f, mark price = %f, liquidation price = %f, unrealized pnl = %f, leverage = %dx",
+		p.Symbol,
+		p.PositionSide,
+		p.MarginType,
+		p.Base.Float64(),
+		p.EntryPrice.Float64(),
+		p.MarkPrice.Float64(),
+		p.LiquidationPrice.Float64(),
+		p.UnrealizedPnL.Float64(),
+		p.Leverage)
+}
+
+// PlainText is used for telegram-styled messages
+func (p FuturesPosition) PlainText() string {
+	return fmt.Sprintf("Futures position %s %s: base = %f @ %f, unrealized pnl = %f",
+		p.Symbol,
+		p.PositionSide,
+		p.Base.Float64(),
+		p.EntryPrice.Float64(),
+		p.UnrealizedPnL.Float64())
+}
+
+type FundingRate struct {
+	Symbol      string           `json:"symbol"`
+	FundingRate fixedpoint.Value `json:"fundingRate"`
+	FundingTime Time             `json:"fundingTime"`
+}
@@ -1,22 +1,11 @@
 package types
 
-import "github.com/pymba86/bingo/pkg/fixedpoint"
-
-type FuturesExchange interface {
-	UseFutures()
-}
-
-type FuturesSettings struct {
-	IsFutures bool
-}
-
-func (s *FuturesSettings) UseFutures() {
-	s.IsFutures = true
-}
-
-func (s FuturesSettings) GetFuturesSettings() FuturesSettings {
-	return s
-}
+import (
+	"context"
+	"fmt"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
+	"time"
+)
 
 type MarginExchange interface {
 	UseMargin()
@@ -106,3 +95,70 @@ type IsolatedUserAsset struct {
 	RepayEnabled  bool             `json:"repayEnabled"`
 	TotalAsset    fixedpoint.Value `json:"totalAsset"`
 }
+
+// MarginBorrowRepayService manages the loans of the margin account,
+// the isolated margin symbol is taken from the margin settings of the exchange
+type MarginBorrowRepayService interface {
+	BorrowMarginAsset(ctx context.Context, asset string, amount fixedpoint.Value) error
+	RepayMarginAsset(ctx context.Context, asset string, amount fixedpoint.Value) error
+	QueryMarginAssetMaxBorrowable(ctx context.Context, asset string) (fixedpoint.Value, error)
+
+	QueryLoanHistory(ctx context.Context, asset string, startTime, endTime *time.Time) ([]MarginLoanRecord, error)
+	QueryRepayHistory(ctx context.Context, asset string, startTime, endTime *time.Time) ([]MarginRepayRecord, error)
+	QueryInterestHistory(ctx context.Context, asset string, startTime, endTime *time.Time) ([]MarginInterest, error)
+
+	TransferMarginAccountAsset(ctx context.Context, asset string, amount fixedpoint.Value, direction TransferDirection) error
+}
+
+type TransferDirection int
+
+const (
+	// TransferIn moves the asset from the spot account into the margin account
+	TransferIn TransferDirection = 1
+	// TransferOut moves the asset from the margin account back to the spot account
+	TransferOut TransferDirection = 2
+)
+
+type MarginLoanRecord struct {
+	GID            int64            `json:"gid" db:"gid"`
+	TransactionID  uint64           `json:"transactionID" db:"transaction_id"`
+	Exchange       ExchangeName     `json:"exchange" db:"exchange"`
+	Asset          string           `json:"asset" db:"asset"`
+	Principal      fixedpoint.Value `json:"principal" db:"principal"`
+	Time           Time             `json:"time" db:"time"`
+	IsolatedSymbol string           `json:"isolatedSymbol" db:"isolated_symbol"`
+}
+
+func (r MarginLoanRecord) String() string {
+	return fmt.Sprintf("LOAN %s %s %f txID %d %s", r.Exchange, r.Asset, r.Principal.Float64(), r.TransactionID, r.Time)
+}
+
+type MarginRepayRecord struct {
+	GID            int64            `json:"gid" db:"gid"`
+	TransactionID  uint64           `json:"transactionID" db:"transaction_id"`
+	Exchange       ExchangeName     `json:"exchange" db:"exchange"`
+	Asset          string           `json:"asset" db:"asset"`
+	Principal      fixedpoint.Value `json:"principal" db:"principal"`
+	Interest       fixedpoint.Value `json:"interest" db:"interest"`
+	Time           Time             `json:"time" db:"time"`
+	IsolatedSymbol string           `json:"isolatedSymbol" db:"isolated_symbol"`
+}
+
+func (r MarginRepayRecord) String() string {
+	return fmt.Sprintf("REPAY %s %s %f (interest %f) txID %d %s", r.Exchange, r.Asset, r.Principal.Float64(), r.Interest.Float64(), r.TransactionID, r.Time)
+}
+
+type MarginInterest struct {
+	GID            int64            `json:"gid" db:"gid"`
+	Exchange       ExchangeName     `json:"exchange" db:"exchange"`
+	Asset          string           `json:"asset" db:"asset"`
+	Principal      fixedpoint.Value `json:"principal" db:"principal"`
+	Interest       fixedpoint.Value `json:"interest" db:"interest"`
+	InterestRate   fixedpoint.Value `json:"interestRate" db:"interest_rate"`
+	IsolatedSymbol string           `json:"isolatedSymbol" db:"isolated_symbol"`
+	Time           Time             `json:"time" db:"time"`
+}
+
+func (r MarginInterest) String() string {
+	return fmt.Sprintf("INTEREST %s %s %f on %f (rate %f) %s", r.Exchange, r.Asset, r.Interest.Float64(), r.Principal.Float64(), r.InterestRate.Float64(), r.Time)
+}
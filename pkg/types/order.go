@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"strings"
+	"time"
 )
 
 type MarginOrderSideEffectType string
@@ -54,6 +55,15 @@ const (
 
 const NoClientOrderID = "0"
 
+// PositionSide is the futures position side of an order, BOTH is used in the one-way position mode
+type PositionSide string
+
+const (
+	PositionSideBoth  PositionSide = "BOTH"
+	PositionSideLong  PositionSide = "LONG"
+	PositionSideShort PositionSide = "SHORT"
+)
+
 type OrderStatus string
 
 const (
@@ -86,8 +96,12 @@ type SubmitOrder struct {
 	GroupID uint32 `json:"groupID,omitempty"`
 
 	MarginSideEffect MarginOrderSideEffectType `json:"marginSideEffect,omitempty"`
-}
 
+	// futures order options
+	ReduceOnly   bool         `json:"reduceOnly,omitempty"`
+	PositionSide PositionSide `json:"positionSide,omitempty"`
+	Leverage     int          `json:"leverage,omitempty"`
+}
 
 type Order struct {
 	SubmitOrder
@@ -103,6 +117,10 @@ type Order struct {
 
 	IsMargin   bool `json:"isMargin" db:"is_margin"`
 	IsIsolated bool `json:"isIsolated" db:"is_isolated"`
+	IsFutures  bool `json:"isFutures" db:"is_futures"`
+
+	// OrderListID links the orders of an OCO order, zero if the order is not in an order list
+	OrderListID uint64 `json:"orderListID,omitempty" db:"-"`
 }
 
 func (o Order) Backup() SubmitOrder {
@@ -112,4 +130,128 @@ func (o Order) Backup() SubmitOrder {
 	// ClientOrderID can not be reused
 	so.ClientOrderId = ""
 	return so
-}
\ No newline at end of file
+}
+
+func (o Order) String() string {
+	return fmt.Sprintf("ORDER %s %s %s %s %f/%f @ %f -> %s orderID %d %s",
+		o.Exchange.String(),
+		o.Symbol,
+		o.Side,
+		o.Type,
+		o.ExecutedQuantity,
+		o.Quantity,
+		o.Price,
+		o.Status,
+		o.OrderID,
+		o.UpdateTime.Time().Format(time.StampMilli))
+}
+
+// PlainText is used for telegram-styled messages
+func (o Order) PlainText() string {
+	return fmt.Sprintf("Order %s %s %s %s %f @ %f -> %s",
+		o.Exchange.String(),
+		o.Symbol,
+		o.Side,
+		o.Type,
+		o.Quantity,
+		o.Price,
+		o.Status)
+}
+
+// OrderQuery identifies an order by the order ID, or by the client order ID when the order ID is zero
+type OrderQuery struct {
+	Symbol        string
+	OrderID       uint64
+	ClientOrderID string
+}
+
+// CancelOrderResult is the cancel result of an order, Err is nil if the order is canceled
+type CancelOrderResult struct {
+	Order Order
+	Err   error
+}
+
+// CancelOrdersError is returned when some of the orders can not be canceled,
+// Results holds the results of all the orders, including the canceled ones.
+type CancelOrdersError struct {
+	Results []CancelOrderResult
+}
+
+func (e *CancelOrdersError) Failed() (results []CancelOrderResult) {
+	for _, result := range e.Results {
+		if result.Err != nil {
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+func (e *CancelOrdersError) Error() string {
+	failed := e.Failed()
+
+	var messages []string
+	for _, result := range failed {
+		messages = append(messages, fmt.Sprintf("order %d %s: %v", result.Order.OrderID, result.Order.ClientOrderId, result.Err))
+	}
+
+	return fmt.Sprintf("%d of %d orders can not be canceled: %s", len(failed), len(e.Results), strings.Join(messages, "; "))
+}
+
+// SubmitOrderFailure is an order that can not be submitted and the cause
+type SubmitOrderFailure struct {
+	Order SubmitOrder
+	Err   error
+}
+
+// SubmitOrdersError is returned when some of the orders can not be submitted,
+// the orders created successfully are still returned along with the error.
+type SubmitOrdersError struct {
+	Failures []SubmitOrderFailure
+}
+
+// Orders returns the orders that can not be submitted
+func (e *SubmitOrdersError) Orders() (orders []SubmitOrder) {
+	for _, failure := range e.Failures {
+		orders = append(orders, failure.Order)
+	}
+
+	return orders
+}
+
+func (e *SubmitOrdersError) Error() string {
+	var messages []string
+	for _, failure := range e.Failures {
+		messages = append(messages, fmt.Sprintf("%s %s %s %f @ %f: %v",
+			failure.Order.Symbol, failure.Order.Side, failure.Order.Type, failure.Order.Quantity, failure.Order.Price, failure.Err))
+	}
+
+	return fmt.Sprintf("%d orders can not be submitted: %s", len(e.Failures), strings.Join(messages, "; "))
+}
+
+// ErrOCOOrderNotSupported is returned by the exchanges that can not place the native OCO orders in the current mode
+var ErrOCOOrderNotSupported = errors.New("oco order is not supported")
+
+// SubmitOCOOrder is a limit order and a stop order linked together (one-cancels-the-other),
+// once one of them is filled or partially filled, the other one is canceled.
+// It's usually the take-profit and the stop-loss of a position.
+type SubmitOCOOrder struct {
+	// ClientOrderId is the client id of the order list
+	ClientOrderId string `json:"clientOrderID"`
+
+	Symbol   string   `json:"symbol"`
+	Side     SideType `json:"side"`
+	Quantity float64  `json:"quantity"`
+
+	// Price is the price of the limit leg
+	Price float64 `json:"price"`
+
+	// StopPrice triggers the stop leg, the stop leg is a stop limit order at StopLimitPrice,
+	// or a stop market order if StopLimitPrice is zero
+	StopPrice      float64 `json:"stopPrice"`
+	StopLimitPrice float64 `json:"stopLimitPrice,omitempty"`
+
+	Market Market `json:"-"`
+
+	MarginSideEffect MarginOrderSideEffectType `json:"marginSideEffect,omitempty"`
+}
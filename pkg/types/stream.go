@@ -3,6 +3,7 @@ package types
 import (
 	"context"
 	"github.com/gorilla/websocket"
+	"time"
 )
 
 type Channel string
@@ -11,25 +12,54 @@ var BookChannel = Channel("book")
 
 var KLineChannel = Channel("kline")
 
+// MarketTradeChannel is the public trades of the market
+var MarketTradeChannel = Channel("trade")
+
+// BookTickerChannel is the best bid and ask of the market
+var BookTickerChannel = Channel("bookTicker")
+
+// ConnectionReason tells why the stream is connected or disconnected
+type ConnectionReason string
+
+const (
+	ConnectionReasonInitial          = ConnectionReason("initial")
+	ConnectionReasonReconnect        = ConnectionReason("reconnect")
+	ConnectionReasonClosed           = ConnectionReason("closed")
+	ConnectionReasonRemoteClosed     = ConnectionReason("remote closed")
+	ConnectionReasonNetworkError     = ConnectionReason("network error")
+	ConnectionReasonStalled          = ConnectionReason("stalled")
+	ConnectionReasonListenKeyExpired = ConnectionReason("listen key expired")
+	ConnectionReasonResubscribe      = ConnectionReason("resubscribe")
+)
+
 type Stream interface {
 	StandardStreamEventHub
 
 	Subscribe(channel Channel, symbol string, options SubscribeOptions)
 	SetPublicOnly()
+	SetReconnectBackoff(backoff Backoff)
+	SetStallTimeout(timeout time.Duration)
 	Connect(ctx context.Context) error
 	Close() error
 }
 
 type StandardStream struct {
-	ReconnectC chan struct{}
+	// ReconnectC carries the reason of the reconnect request
+	ReconnectC chan ConnectionReason
 
 	Subscriptions []Subscription
 
+	// ReconnectBackoff is the delay policy between the reconnect attempts
+	ReconnectBackoff Backoff
+
+	// StallTimeout forces a reconnect when no message is received within it, zero disables the watchdog
+	StallTimeout time.Duration
+
 	startCallbacks []func()
 
-	connectCallbacks []func()
+	connectCallbacks []func(reason ConnectionReason)
 
-	disconnectCallbacks []func()
+	disconnectCallbacks []func(reason ConnectionReason)
 
 	// private trade update callbacks
 	tradeUpdateCallbacks []func(trade Trade)
@@ -42,6 +72,9 @@ type StandardStream struct {
 
 	balanceUpdateCallbacks []func(balances BalanceMap)
 
+	// futures position update callbacks
+	positionUpdateCallbacks []func(positions []FuturesPosition)
+
 	kLineClosedCallbacks []func(kline KLine)
 
 	kLineCallbacks []func(kline KLine)
@@ -49,6 +82,11 @@ type StandardStream struct {
 	bookUpdateCallbacks []func(book SliceOrderBook)
 
 	bookSnapshotCallbacks []func(book SliceOrderBook)
+
+	// public market trade callbacks
+	marketTradeCallbacks []func(trade Trade)
+
+	bookTickerUpdateCallbacks []func(bookTicker BookTicker)
 }
 
 func (stream *StandardStream) Subscribe(channel Channel, symbol string, options SubscribeOptions) {
@@ -59,9 +97,18 @@ func (stream *StandardStream) Subscribe(channel Channel, symbol string, options
 	})
 }
 
-func (stream *StandardStream) Reconnect() {
+func (stream *StandardStream) SetReconnectBackoff(backoff Backoff) {
+	stream.ReconnectBackoff = backoff
+}
+
+func (stream *StandardStream) SetStallTimeout(timeout time.Duration) {
+	stream.StallTimeout = timeout
+}
+
+// Reconnect requests a reconnect, the request is dropped if there is already a pending one
+func (stream *StandardStream) Reconnect(reason ConnectionReason) {
 	select {
-	case stream.ReconnectC <- struct{}{}:
+	case stream.ReconnectC <- reason:
 	default:
 	}
 }
@@ -87,23 +134,23 @@ func (stream *StandardStream) EmitStart() {
 	}
 }
 
-func (stream *StandardStream) OnConnect(cb func()) {
+func (stream *StandardStream) OnConnect(cb func(reason ConnectionReason)) {
 	stream.connectCallbacks = append(stream.connectCallbacks, cb)
 }
 
-func (stream *StandardStream) EmitConnect() {
+func (stream *StandardStream) EmitConnect(reason ConnectionReason) {
 	for _, cb := range stream.connectCallbacks {
-		cb()
+		cb(reason)
 	}
 }
 
-func (stream *StandardStream) OnDisconnect(cb func()) {
+func (stream *StandardStream) OnDisconnect(cb func(reason ConnectionReason)) {
 	stream.disconnectCallbacks = append(stream.disconnectCallbacks, cb)
 }
 
-func (stream *StandardStream) EmitDisconnect() {
+func (stream *StandardStream) EmitDisconnect(reason ConnectionReason) {
 	for _, cb := range stream.disconnectCallbacks {
-		cb()
+		cb(reason)
 	}
 }
 
@@ -187,12 +234,42 @@ func (stream *StandardStream) EmitBookSnapshot(book SliceOrderBook) {
 	}
 }
 
+func (stream *StandardStream) OnPositionUpdate(cb func(positions []FuturesPosition)) {
+	stream.positionUpdateCallbacks = append(stream.positionUpdateCallbacks, cb)
+}
+
+func (stream *StandardStream) EmitPositionUpdate(positions []FuturesPosition) {
+	for _, cb := range stream.positionUpdateCallbacks {
+		cb(positions)
+	}
+}
+
+func (stream *StandardStream) OnMarketTrade(cb func(trade Trade)) {
+	stream.marketTradeCallbacks = append(stream.marketTradeCallbacks, cb)
+}
+
+func (stream *StandardStream) EmitMarketTrade(trade Trade) {
+	for _, cb := range stream.marketTradeCallbacks {
+		cb(trade)
+	}
+}
+
+func (stream *StandardStream) OnBookTickerUpdate(cb func(bookTicker BookTicker)) {
+	stream.bookTickerUpdateCallbacks = append(stream.bookTickerUpdateCallbacks, cb)
+}
+
+func (stream *StandardStream) EmitBookTickerUpdate(bookTicker BookTicker) {
+	for _, cb := range stream.bookTickerUpdateCallbacks {
+		cb(bookTicker)
+	}
+}
+
 type StandardStreamEventHub interface {
 	OnStart(cb func())
 
-	OnConnect(cb func())
+	OnConnect(cb func(reason ConnectionReason))
 
-	OnDisconnect(cb func())
+	OnDisconnect(cb func(reason ConnectionReason))
 
 	OnTradeUpdate(cb func(trade Trade))
 
@@ -202,6 +279,8 @@ type StandardStreamEventHub interface {
 
 	OnBalanceUpdate(cb func(balances BalanceMap))
 
+	OnPositionUpdate(cb func(positions []FuturesPosition))
+
 	OnKLineClosed(cb func(kline KLine))
 
 	OnKLine(cb func(kline KLine))
@@ -209,11 +288,20 @@ type StandardStreamEventHub interface {
 	OnBookUpdate(cb func(book SliceOrderBook))
 
 	OnBookSnapshot(cb func(book SliceOrderBook))
+
+	OnMarketTrade(cb func(trade Trade))
+
+	OnBookTickerUpdate(cb func(bookTicker BookTicker))
 }
 
 type SubscribeOptions struct {
 	Interval string `json:"interval,omitempty"`
-	Depth    string `json:"depth,omitempty"`
+
+	// Depth is the number of the book levels, e.g. 5, 10 or 20, the full book is subscribed when it's empty
+	Depth string `json:"depth,omitempty"`
+
+	// Speed is the update speed of the book, e.g. 100ms
+	Speed string `json:"speed,omitempty"`
 }
 
 func (o SubscribeOptions) String() string {
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"fmt"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
 	"time"
 )
 
@@ -13,4 +15,20 @@ type Ticker struct {
 	Low    float64 // `low` from Max, `lowPrice` from binance
 	Buy    float64 // `buy` from Max, `bidPrice` from binance
 	Sell   float64 // `sell` from Max, `askPrice` from binance
-}
\ No newline at end of file
+}
+
+// BookTicker is the best bid and ask of the market
+type BookTicker struct {
+	Symbol   string
+	Buy      fixedpoint.Value // best bid price
+	BuySize  fixedpoint.Value
+	Sell     fixedpoint.Value // best ask price
+	SellSize fixedpoint.Value
+}
+
+func (b BookTicker) String() string {
+	return fmt.Sprintf("BookTicker %s: bid %f (%f) ask %f (%f)",
+		b.Symbol,
+		b.Buy.Float64(), b.BuySize.Float64(),
+		b.Sell.Float64(), b.SellSize.Float64())
+}
@@ -62,6 +62,7 @@ type Trade struct {
 
 	IsMargin   bool `json:"isMargin" db:"is_margin"`
 	IsIsolated bool `json:"isIsolated" db:"is_isolated"`
+	IsFutures  bool `json:"isFutures" db:"is_futures"`
 
 	StrategyID sql.NullString  `json:"strategyID" db:"strategy"`
 	PnL        sql.NullFloat64 `json:"pnl" db:"pnl"`
@@ -2,17 +2,22 @@ package types
 
 import (
 	"fmt"
+	"github.com/pymba86/bingo/pkg/fixedpoint"
 	"time"
 )
 
 type Withdraw struct {
-	GID        int64        `json:"gid" db:"gid"`
-	Exchange   ExchangeName `json:"exchange" db:"exchange"`
-	Asset      string       `json:"asset" db:"asset"`
-	Amount     float64      `json:"amount" db:"amount"`
-	Address    string       `json:"address" db:"address"`
-	AddressTag string       `json:"addressTag"`
-	Status     string       `json:"status"`
+	GID      int64        `json:"gid" db:"gid"`
+	Exchange ExchangeName `json:"exchange" db:"exchange"`
+
+	// ID is the withdraw id of the exchange, the transaction id is empty until the withdraw is sent to the network
+	ID string `json:"id" db:"withdraw_id"`
+
+	Asset      string  `json:"asset" db:"asset"`
+	Amount     float64 `json:"amount" db:"amount"`
+	Address    string  `json:"address" db:"address"`
+	AddressTag string  `json:"addressTag"`
+	Status     string  `json:"status" db:"status"`
 
 	TransactionID          string  `json:"transactionID" db:"txn_id"`
 	TransactionFee         float64 `json:"transactionFee" db:"txn_fee"`
@@ -30,7 +35,46 @@ func (w Withdraw) EffectiveTime() time.Time {
 	return w.ApplyTime.Time()
 }
 
+// WithdrawFinalStatuses are the statuses of the withdraws that won't change anymore
+var WithdrawFinalStatuses = []string{"completed", "cancelled", "rejected", "failure"}
+
 type WithdrawalOptions struct {
 	Network    string
 	AddressTag string
-}
\ No newline at end of file
+
+	// WithdrawOrderID is the client id of the withdrawal, the withdraw history returns it as the WithdrawOrderID
+	WithdrawOrderID string
+}
+
+type WithdrawalRequestStatus string
+
+const (
+	WithdrawalRequestSent     = WithdrawalRequestStatus("sent")
+	WithdrawalRequestDryRun   = WithdrawalRequestStatus("dry_run")
+	WithdrawalRequestRejected = WithdrawalRequestStatus("rejected")
+	WithdrawalRequestFailed   = WithdrawalRequestStatus("failed")
+)
+
+// WithdrawalRequest is a withdrawal requested through the session withdrawer,
+// the reason is the rejection of the withdrawal policy or the error of the exchange
+type WithdrawalRequest struct {
+	GID             int64                   `json:"gid" db:"gid"`
+	Exchange        ExchangeName            `json:"exchange" db:"exchange"`
+	WithdrawOrderID string                  `json:"withdrawOrderId" db:"withdraw_order_id"`
+	Asset           string                  `json:"asset" db:"asset"`
+	Amount          fixedpoint.Value        `json:"amount" db:"amount"`
+	Address         string                  `json:"address" db:"address"`
+	Network         string                  `json:"network,omitempty" db:"network"`
+	Status          WithdrawalRequestStatus `json:"status" db:"status"`
+	Reason          string                  `json:"reason,omitempty" db:"reason"`
+	Time            Time                    `json:"time" db:"time"`
+}
+
+func (r WithdrawalRequest) String() string {
+	s := fmt.Sprintf("withdrawal %s %f to %s at %s: %s", r.Asset, r.Amount.Float64(), r.Address, r.Time.Time().Format(time.RFC3339), r.Status)
+	if len(r.Reason) > 0 {
+		s += ", " + r.Reason
+	}
+
+	return s
+}